@@ -0,0 +1,158 @@
+package gdb
+
+// Funcs run the batch commands of many keys, in cluster mode the keys are grouped by their nodes
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const defaultBatchFanout = 4
+
+// BatchError is returned by BatchGet, BatchSet, BatchDel, GetObjects, SetObjects and SetObjectsEX with
+// RedisClientOption.BatchPartial if some keys failed, the results of the other keys are still returned
+type BatchError struct {
+	Errs map[int]error // errors by the indexes of the failed keys
+}
+
+func (e *BatchError) Error() string {
+	first := sortedIndexes(e.Errs)[0]
+	return fmt.Sprintf("%d batch keys failed, key %d: %v", len(e.Errs), first, e.Errs[first])
+}
+
+// Unwrap return the errors of the keys, so errors.Is and errors.As match any of them
+func (e *BatchError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errs))
+	for _, i := range sortedIndexes(e.Errs) {
+		errs = append(errs, e.Errs[i])
+	}
+	return errs
+}
+
+// Failed return whether the key of the index failed
+func (e *BatchError) Failed(i int) bool {
+	_, ok := e.Errs[i]
+	return ok
+}
+
+func sortedIndexes(errs map[int]error) []int {
+	idxs := make([]int, 0, len(errs))
+	for i := range errs {
+		idxs = append(idxs, i)
+	}
+	sort.Ints(idxs)
+	return idxs
+}
+
+// batchGroups split the indexes of the keys by the masters of their slots in cluster mode,
+// the keys are in one group in the other modes
+func (rc *redisClient) batchGroups(ctx context.Context, keys []string) ([][]int, error) {
+	c, ok := rc.client.(*redis.ClusterClient)
+	if !ok {
+		return groupKeys(keys, nil)
+	}
+	return groupKeys(keys, func(key string) (string, error) {
+		// the slot is computed with the prefixed key like the hooked commands
+		node, err := c.MasterForKey(ctx, rc.namespace+key)
+		if err != nil {
+			return "", err
+		}
+		return node.Options().Addr, nil
+	})
+}
+
+// groupKeys split the indexes of the keys by their nodes, the groups are in the order of their first keys
+// and the indexes are in the input order in every group. A nil nodeOf put all the keys in one group.
+func groupKeys(keys []string, nodeOf func(key string) (string, error)) ([][]int, error) {
+	if nodeOf == nil || len(keys) <= 1 {
+		all := make([]int, len(keys))
+		for i := range all {
+			all[i] = i
+		}
+		return [][]int{all}, nil
+	}
+	var groups [][]int
+	nodes := make(map[string]int)
+	for i, key := range keys {
+		node, err := nodeOf(key)
+		if err != nil {
+			return nil, err
+		}
+		g, ok := nodes[node]
+		if !ok {
+			g = len(groups)
+			nodes[node] = g
+			groups = append(groups, nil)
+		}
+		groups[g] = append(groups[g], i)
+	}
+	return groups, nil
+}
+
+// runGroups run every group by run, at most fanout groups concurrently, 0 means defaultBatchFanout
+func runGroups(groups [][]int, fanout int, run func(idxs []int)) {
+	if len(groups) == 1 {
+		run(groups[0])
+		return
+	}
+	if fanout <= 0 {
+		fanout = defaultBatchFanout
+	}
+	sem := make(chan struct{}, fanout)
+	var wg sync.WaitGroup
+	for _, idxs := range groups {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(idxs []int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			run(idxs)
+		}(idxs)
+	}
+	wg.Wait()
+}
+
+// batch queue a command of every key by queue, and run the commands in a pipeline for every group of
+// batchGroups, at most batchFanout pipelines concurrently. The redis.Nil of the commands is not an error.
+// Without batchPartial the error of the first failed key is returned, otherwise a *BatchError of all of them.
+func (rc *redisClient) batch(ctx context.Context, keys []string,
+	queue func(pipeliner redis.Pipeliner, i int) redis.Cmder) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	groups, err := rc.batchGroups(ctx, keys)
+	if err != nil {
+		return err
+	}
+	cmds := make([]redis.Cmder, len(keys))
+	run := func(idxs []int) {
+		// the errors are kept by the commands
+		_, _ = rc.client.Pipelined(ctx, func(pipeliner redis.Pipeliner) error {
+			for _, i := range idxs {
+				cmds[i] = queue(pipeliner, i)
+			}
+			return nil
+		})
+	}
+	runGroups(groups, rc.batchFanout, run)
+
+	errs := make(map[int]error)
+	for i, cmd := range cmds {
+		if err = cmd.Err(); err != nil && !rc.IsErrNil(err) {
+			if !rc.batchPartial {
+				return err
+			}
+			errs[i] = err
+		}
+	}
+	if len(errs) > 0 {
+		return &BatchError{Errs: errs}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package gdb
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	gmarshaller "github.com/oldjon/gutil/marshaller"
+)
+
+func TestGroupKeys(t *testing.T) {
+	keys := []string{"a:1", "b:1", "a:2", "c:1", "b:2"}
+	groups, err := groupKeys(keys, func(key string) (string, error) {
+		node, _, _ := strings.Cut(key, ":")
+		return node, nil
+	})
+	if want := [][]int{{0, 2}, {1, 4}, {3}}; err != nil || !reflect.DeepEqual(groups, want) {
+		t.Fatalf("groupKeys = %v, %v", groups, err)
+	}
+	if groups, _ = groupKeys(keys, nil); !reflect.DeepEqual(groups, [][]int{{0, 1, 2, 3, 4}}) {
+		t.Fatalf("groupKeys without nodes = %v", groups)
+	}
+	errNode := errors.New("no node")
+	if _, err = groupKeys(keys, func(string) (string, error) { return "", errNode }); !errors.Is(err, errNode) {
+		t.Fatalf("groupKeys node error = %v", err)
+	}
+}
+
+func TestRunGroupsFanout(t *testing.T) {
+	groups := make([][]int, 10)
+	for i := range groups {
+		groups[i] = []int{i}
+	}
+	var running, peak int32
+	var mu sync.Mutex
+	var done []int
+	runGroups(groups, 3, func(idxs []int) {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		mu.Lock()
+		done = append(done, idxs...)
+		mu.Unlock()
+		atomic.AddInt32(&running, -1)
+	})
+	if peak > 3 || len(done) != len(groups) {
+		t.Fatalf("peak = %d, done = %v", peak, done)
+	}
+}
+
+func TestBatchPartial(t *testing.T) {
+	ctx := context.Background()
+	strict, server, _ := newMemRedisClient(t)
+	partial, err := NewRedisClient(&RedisClientOption{
+		Mode:         Single,
+		Addr:         "memredis",
+		Marshaller:   &gmarshaller.JsonMarshaller{},
+		Dialer:       server.Dial,
+		BatchPartial: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer partial.Close()
+	if err = strict.SetObjects(ctx, []string{"foo:1", "foo:3"}, []*Foo{{F: 1}, {F: 3}}); err != nil {
+		t.Fatal(err)
+	}
+	if err = strict.HSet(ctx, "foo:2", "f", 1); err != nil {
+		t.Fatal(err)
+	}
+	keys := []string{"foo:1", "foo:2", "foo:3", "foo:4"}
+
+	if cmds, err := strict.BatchGet(ctx, keys); err == nil || cmds != nil {
+		t.Fatalf("strict BatchGet = %v, %v", cmds, err)
+	}
+	cmds, err := partial.BatchGet(ctx, keys)
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || len(batchErr.Errs) != 1 || !batchErr.Failed(1) || len(cmds) != 4 {
+		t.Fatalf("partial BatchGet = %v, %v", cmds, err)
+	}
+
+	foos := []*Foo{nil, {F: -1}, nil, {F: -1}}
+	if err = strict.GetObjects(ctx, keys, foos); err == nil {
+		t.Fatal("strict GetObjects succeeded")
+	}
+	foos = []*Foo{nil, {F: -1}, nil, {F: -1}}
+	if err = partial.GetObjects(ctx, keys, foos); !errors.As(err, &batchErr) || !batchErr.Failed(1) {
+		t.Fatalf("partial GetObjects = %v", err)
+	}
+	if foos[0].F != 1 || foos[1].F != -1 || foos[2].F != 3 || foos[3] != nil {
+		t.Fatalf("partial GetObjects = %v, %v, %v, %v", foos[0], foos[1], foos[2], foos[3])
+	}
+	objs, err := MGet[Foo](ctx, partial, keys)
+	if !errors.As(err, &batchErr) || objs[0].F != 1 || objs[1] != nil || objs[2].F != 3 || objs[3] != nil {
+		t.Fatalf("partial MGet = %v, %v", objs, err)
+	}
+}
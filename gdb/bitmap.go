@@ -0,0 +1,196 @@
+package gdb
+
+// Funcs handle the redis bitmaps, the bit fields and the sign-in calendars built on them
+
+import (
+	"context"
+	"math/bits"
+	"strconv"
+
+	"github.com/go-redis/redis/v8"
+)
+
+type Bitmap interface {
+	// SetBit return the old bit
+	SetBit(ctx context.Context, key string, offset int64, value bool) (bool, error)
+	GetBit(ctx context.Context, key string, offset int64) (bool, error)
+	BitCount(ctx context.Context, key string) (int64, error)
+	// BitCountRange count the set bits from the byte start to the byte end, the negative indexes count from the end
+	BitCountRange(ctx context.Context, key string, start, end int64) (int64, error)
+	// BitField run the operations in order and return the results of their GET, SET and INCRBY, a SET or INCRBY
+	// overflowing with OverflowFail does nothing and its result is 0, ErrBitFieldOverflow is returned with the
+	// results then
+	BitField(ctx context.Context, key string, ops *BitFieldOps) ([]int64, error)
+	// SignIn set the day from 1 to 31 in the sign-in calendar of the key, it returns whether the day is newly signed
+	SignIn(ctx context.Context, key string, day int) (bool, error)
+	// GetSignIn return the sign-in calendar of the key, a key per month is expected
+	GetSignIn(ctx context.Context, key string) (SignInCalendar, error)
+}
+
+// BitFieldType is the type of a bit field, i1 to i64 for the signed integers and u1 to u63 for the unsigned
+type BitFieldType string
+
+// BitFieldInt return the type of the signed integer of the bits
+func BitFieldInt(bits int) BitFieldType {
+	return BitFieldType("i" + strconv.Itoa(bits))
+}
+
+// BitFieldUint return the type of the unsigned integer of the bits
+func BitFieldUint(bits int) BitFieldType {
+	return BitFieldType("u" + strconv.Itoa(bits))
+}
+
+// BitFieldOverflow is the behavior of SET and INCRBY of BitField on overflow
+type BitFieldOverflow string
+
+const (
+	OverflowWrap BitFieldOverflow = "WRAP" // wrap around, the default
+	OverflowSat  BitFieldOverflow = "SAT"  // saturate at the min or max value
+	OverflowFail BitFieldOverflow = "FAIL" // do nothing
+)
+
+// BitFieldOps build the operations of BitField, the offsets are in bits, the *At ones take the index of the
+// field of the type instead, which is the offset of the width times the index
+//
+//	u8 := gdb.BitFieldUint(8)
+//	ops := gdb.NewBitFieldOps().Overflow(gdb.OverflowSat).IncrByAt(u8, 3, 1).GetAt(u8, 4)
+//	rets, err := client.BitField(ctx, key, ops)
+type BitFieldOps struct {
+	args []any
+}
+
+// NewBitFieldOps create an empty BitFieldOps
+func NewBitFieldOps() *BitFieldOps {
+	return &BitFieldOps{}
+}
+
+func (ops *BitFieldOps) add(args ...any) *BitFieldOps {
+	ops.args = append(ops.args, args...)
+	return ops
+}
+
+// Get read the field of the type at the bit offset
+func (ops *BitFieldOps) Get(t BitFieldType, offset int64) *BitFieldOps {
+	return ops.add("GET", string(t), offset)
+}
+
+// GetAt read the field of the type at the index
+func (ops *BitFieldOps) GetAt(t BitFieldType, index int64) *BitFieldOps {
+	return ops.add("GET", string(t), "#"+strconv.FormatInt(index, 10))
+}
+
+// Set write the field of the type at the bit offset, its result is the old value
+func (ops *BitFieldOps) Set(t BitFieldType, offset int64, value int64) *BitFieldOps {
+	return ops.add("SET", string(t), offset, value)
+}
+
+// SetAt write the field of the type at the index, its result is the old value
+func (ops *BitFieldOps) SetAt(t BitFieldType, index int64, value int64) *BitFieldOps {
+	return ops.add("SET", string(t), "#"+strconv.FormatInt(index, 10), value)
+}
+
+// IncrBy increase the field of the type at the bit offset, its result is the new value
+func (ops *BitFieldOps) IncrBy(t BitFieldType, offset int64, incr int64) *BitFieldOps {
+	return ops.add("INCRBY", string(t), offset, incr)
+}
+
+// IncrByAt increase the field of the type at the index, its result is the new value
+func (ops *BitFieldOps) IncrByAt(t BitFieldType, index int64, incr int64) *BitFieldOps {
+	return ops.add("INCRBY", string(t), "#"+strconv.FormatInt(index, 10), incr)
+}
+
+// Overflow set the overflow behavior of the following SET and INCRBY
+func (ops *BitFieldOps) Overflow(o BitFieldOverflow) *BitFieldOps {
+	return ops.add("OVERFLOW", string(o))
+}
+
+// SignInCalendar is the sign-in bits of the days of a month, bit 31 is day 1 like the first bit of the bitmap
+type SignInCalendar uint32
+
+const signInDays = 31
+
+// Signed return whether the day from 1 to 31 is signed
+func (c SignInCalendar) Signed(day int) bool {
+	return day >= 1 && day <= signInDays && c&(1<<(32-day)) != 0
+}
+
+// Days return the signed days in order
+func (c SignInCalendar) Days() []int {
+	var days []int
+	for day := 1; day <= signInDays; day++ {
+		if c.Signed(day) {
+			days = append(days, day)
+		}
+	}
+	return days
+}
+
+// Count return the number of the signed days
+func (c SignInCalendar) Count() int {
+	return bits.OnesCount32(uint32(c))
+}
+
+// Streak return the number of the consecutive signed days ending at the day
+func (c SignInCalendar) Streak(day int) int {
+	n := 0
+	for ; day >= 1 && c.Signed(day); day-- {
+		n++
+	}
+	return n
+}
+
+func (rc *redisClient) SetBit(ctx context.Context, key string, offset int64, value bool) (bool, error) {
+	v := 0
+	if value {
+		v = 1
+	}
+	old, err := rc.client.SetBit(ctx, key, offset, v).Result()
+	return old == 1, err
+}
+
+func (rc *redisClient) GetBit(ctx context.Context, key string, offset int64) (bool, error) {
+	v, err := rc.client.GetBit(ctx, key, offset).Result()
+	return v == 1, err
+}
+
+func (rc *redisClient) BitCount(ctx context.Context, key string) (int64, error) {
+	return rc.client.BitCount(ctx, key, nil).Result()
+}
+
+func (rc *redisClient) BitCountRange(ctx context.Context, key string, start, end int64) (int64, error) {
+	return rc.client.BitCount(ctx, key, &redis.BitCount{Start: start, End: end}).Result()
+}
+
+func (rc *redisClient) BitField(ctx context.Context, key string, ops *BitFieldOps) ([]int64, error) {
+	// the nil results of FAIL break redis.IntSliceCmd, so the replies are read one by one
+	vals, err := rc.client.Do(ctx, append([]any{"bitfield", key}, ops.args...)...).Slice()
+	if err != nil {
+		return nil, err
+	}
+	rets := make([]int64, len(vals))
+	for i, v := range vals {
+		n, ok := v.(int64)
+		if !ok {
+			err = ErrBitFieldOverflow
+		}
+		rets[i] = n
+	}
+	return rets, err
+}
+
+func (rc *redisClient) SignIn(ctx context.Context, key string, day int) (bool, error) {
+	if day < 1 || day > signInDays {
+		panic(PanicSignInDayOutOfRange)
+	}
+	old, err := rc.SetBit(ctx, key, int64(day-1), true)
+	return !old, err
+}
+
+func (rc *redisClient) GetSignIn(ctx context.Context, key string) (SignInCalendar, error) {
+	rets, err := rc.BitField(ctx, key, NewBitFieldOps().Get(BitFieldUint(signInDays), 0))
+	if err != nil {
+		return 0, err
+	}
+	// u31 is the days from the highest bit, it is shifted to bit 31
+	return SignInCalendar(rets[0] << 1), nil
+}
@@ -0,0 +1,101 @@
+package gdb
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestBitmap(t *testing.T) {
+	client, _, _ := newMemRedisClient(t)
+	ctx := context.Background()
+
+	if old, err := client.SetBit(ctx, "bits", 9, true); err != nil || old {
+		t.Fatalf("SetBit = %v, %v", old, err)
+	}
+	if old, _ := client.SetBit(ctx, "bits", 9, true); !old {
+		t.Fatal("SetBit old bit is not set")
+	}
+	_, _ = client.SetBit(ctx, "bits", 0, true)
+	if v, err := client.Get(ctx, "bits"); err != nil || v != "\x80\x40" {
+		t.Fatalf("bits = %q, %v", v, err)
+	}
+	if set, err := client.GetBit(ctx, "bits", 1); err != nil || set {
+		t.Fatalf("GetBit = %v, %v", set, err)
+	}
+	if n, err := client.BitCount(ctx, "bits"); err != nil || n != 2 {
+		t.Fatalf("BitCount = %d, %v", n, err)
+	}
+	if n, err := client.BitCountRange(ctx, "bits", -1, -1); err != nil || n != 1 {
+		t.Fatalf("BitCountRange = %d, %v", n, err)
+	}
+
+	u8, i4 := BitFieldUint(8), BitFieldInt(4)
+	rets, err := client.BitField(ctx, "fields", NewBitFieldOps().SetAt(u8, 1, 200).IncrByAt(u8, 1, 100).
+		Overflow(OverflowSat).IncrByAt(u8, 1, 250).Set(i4, 0, -3).Get(i4, 0).GetAt(u8, 1))
+	if want := []int64{0, 44, 255, 0, -3, 255}; err != nil || !reflect.DeepEqual(rets, want) {
+		t.Fatalf("BitField = %v, %v", rets, err)
+	}
+	rets, err = client.BitField(ctx, "fields", NewBitFieldOps().Overflow(OverflowFail).IncrByAt(u8, 1, 1).
+		IncrBy(i4, 0, -1))
+	if !errors.Is(err, ErrBitFieldOverflow) || !reflect.DeepEqual(rets, []int64{0, -4}) {
+		t.Fatalf("BitField overflow = %v, %v", rets, err)
+	}
+}
+
+func TestSignIn(t *testing.T) {
+	client, _, _ := newMemRedisClient(t)
+	ctx := context.Background()
+
+	for _, day := range []int{1, 3, 4, 5, 31} {
+		if first, err := client.SignIn(ctx, "signin:202405", day); err != nil || !first {
+			t.Fatalf("SignIn(%d) = %v, %v", day, first, err)
+		}
+	}
+	if first, _ := client.SignIn(ctx, "signin:202405", 4); first {
+		t.Fatal("SignIn twice is first")
+	}
+	c, err := client.GetSignIn(ctx, "signin:202405")
+	if err != nil || !reflect.DeepEqual(c.Days(), []int{1, 3, 4, 5, 31}) || c.Count() != 5 {
+		t.Fatalf("GetSignIn = %v, %v", c.Days(), err)
+	}
+	if !c.Signed(31) || c.Signed(2) || c.Streak(5) != 3 || c.Streak(6) != 0 || c.Streak(1) != 1 {
+		t.Fatalf("calendar = %032b", uint32(c))
+	}
+	if c, err = client.GetSignIn(ctx, "signin:202406"); err != nil || c != 0 {
+		t.Fatalf("empty GetSignIn = %v, %v", c, err)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("SignIn(32) not panicked")
+		}
+	}()
+	_, _ = client.SignIn(ctx, "signin:202405", 32)
+}
+
+func TestHyperLogLog(t *testing.T) {
+	client, _, _ := newMemRedisClient(t)
+	ctx := context.Background()
+
+	if changed, err := client.PFAdd(ctx, "dau:1", "a", "b", "c"); err != nil || !changed {
+		t.Fatalf("PFAdd = %v, %v", changed, err)
+	}
+	if changed, _ := client.PFAdd(ctx, "dau:1", "a"); changed {
+		t.Fatal("PFAdd of an added element changed")
+	}
+	_, _ = client.PFAdd(ctx, "dau:2", "c", "d")
+	if n, err := client.PFCount(ctx, "dau:1", "dau:2"); err != nil || n != 4 {
+		t.Fatalf("PFCount = %d, %v", n, err)
+	}
+	if err := client.PFMerge(ctx, "dau:week", "dau:1", "dau:2"); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := client.PFCount(ctx, "dau:week"); err != nil || n != 4 {
+		t.Fatalf("PFCount merged = %d, %v", n, err)
+	}
+	_ = client.Set(ctx, "str", "x")
+	if _, err := client.PFCount(ctx, "str"); err == nil {
+		t.Fatal("PFCount of a string succeeded")
+	}
+}
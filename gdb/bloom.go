@@ -0,0 +1,196 @@
+package gdb
+
+// Funcs handle the scalable bloom filters built on the redis bitmaps
+
+import (
+	"context"
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+)
+
+const (
+	defaultBloomCapacity  = 1000
+	defaultBloomErrorRate = 0.01
+	// the header of a bloom filter is 4 u32 fields: layers, items of the last layer, capacity and error rate
+	// in parts per billion of the first layer, the layers follow it
+	bloomHeaderBits = 128
+)
+
+type BloomFilter interface {
+	// BFReserve create the bloom filter of the key with the capacity and the error rate of its first layer,
+	// it returns ErrBloomFilterExists if the key exists. Every layer added when the last one is full doubles
+	// the capacity and halves the error rate, so the total error rate stays below twice the first one.
+	BFReserve(ctx context.Context, key string, capacity uint32, errorRate float64) error
+	// BFAdd return false if the item may have been added. A filter added without BFReserve has the capacity 1000
+	// and the error rate 0.01.
+	BFAdd(ctx context.Context, key string, item string) (bool, error)
+	BFMAdd(ctx context.Context, key string, items ...string) ([]bool, error)
+	// BFExists return false if the item is surely not added
+	BFExists(ctx context.Context, key string, item string) (bool, error)
+	BFMExists(ctx context.Context, key string, items ...string) ([]bool, error)
+	BFInfo(ctx context.Context, key string) (*BloomFilterInfo, error)
+}
+
+// BloomFilterInfo is the state of a bloom filter
+type BloomFilterInfo struct {
+	Layers    int
+	Items     int64   // the items added, the ones maybe added before are not counted
+	Capacity  int64   // the capacity of all the layers
+	ErrorRate float64 // the error rate of the first layer
+}
+
+// BloomScript add or check the items of the scalable bloom filter of KEYS[1]. ARGV are "1" to add or "0" to
+// check, the default capacity and error rate in parts per billion, then two 32 bit hashes of every item.
+// The bits of the item in a layer of m bits and k hashes are (h1 + i * h2) % m for i from 0 to k-1.
+// It returns 1 for every item added when adding, or for every item found when checking.
+const BloomScript = `
+local key, add = KEYS[1], ARGV[1] == '1'
+local h = redis.call('BITFIELD', key, 'GET', 'u32', 0, 'GET', 'u32', 32, 'GET', 'u32', 64, 'GET', 'u32', 96)
+local layers, count, cap0, ppb = h[1], h[2], h[3], h[4]
+if cap0 == 0 then
+	cap0, ppb = tonumber(ARGV[2]), tonumber(ARGV[3])
+end
+local ln2 = math.log(2)
+local function layer(j)
+	local c, p = cap0 * 2 ^ j, ppb / 1e9 / 2 ^ j
+	return c, math.ceil(-c * math.log(p) / ln2 ^ 2), math.ceil(-math.log(p) / ln2)
+end
+local function has(offset, m, k, h1, h2)
+	for i = 0, k - 1 do
+		if redis.call('GETBIT', key, offset + (h1 + i * h2) % m) == 0 then
+			return false
+		end
+	end
+	return true
+end
+local rets, added = {}, false
+for n = 4, #ARGV, 2 do
+	local h1, h2 = tonumber(ARGV[n]), tonumber(ARGV[n + 1])
+	local found, offset, c, m, k = false, 128, 0, 0, 0
+	for j = 0, layers - 1 do
+		c, m, k = layer(j)
+		if has(offset, m, k, h1, h2) then
+			found = true
+			break
+		end
+		offset = offset + m
+	end
+	if found or not add then
+		rets[#rets + 1] = (found and not add) and 1 or 0
+	else
+		if layers == 0 or count >= c then
+			layers, count = layers + 1, 0
+			c, m, k = layer(layers - 1)
+		else
+			offset = offset - m
+		end
+		for i = 0, k - 1 do
+			redis.call('SETBIT', key, offset + (h1 + i * h2) % m, 1)
+		end
+		count, added = count + 1, true
+		rets[#rets + 1] = 1
+	end
+end
+if added then
+	redis.call('BITFIELD', key, 'SET', 'u32', 0, layers, 'SET', 'u32', 32, count, 'SET', 'u32', 64, cap0,
+		'SET', 'u32', 96, ppb)
+end
+return rets
+`
+
+var bloomScript = newStaticScript(BloomScript)
+
+// bloomLayer return the capacity, the bits and the hashes of the layer j from 0, the same as the script
+func bloomLayer(cap0, ppb int64, j int) (int64, int64, int64) {
+	c, p := float64(cap0)*math.Pow(2, float64(j)), float64(ppb)/1e9/math.Pow(2, float64(j))
+	m, k := math.Ceil(-c*math.Log(p)/(math.Ln2*math.Ln2)), math.Ceil(-math.Log(p)/math.Ln2)
+	return int64(c), int64(m), int64(k)
+}
+
+// bloomHashes return the two 32 bit hashes of the item, h2 is odd so the k bits differ
+func bloomHashes(item string) (int64, int64) {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(item))
+	sum := h.Sum64()
+	return int64(sum & math.MaxUint32), int64(sum>>32 | 1)
+}
+
+func (rc *redisClient) BFReserve(ctx context.Context, key string, capacity uint32, errorRate float64) error {
+	ppb := math.Round(errorRate * 1e9)
+	if capacity == 0 || ppb < 1 || errorRate >= 1 {
+		return ErrBloomFilterOptions
+	}
+	header := make([]byte, bloomHeaderBits/8)
+	binary.BigEndian.PutUint32(header[8:], capacity)
+	binary.BigEndian.PutUint32(header[12:], uint32(ppb))
+	ok, err := rc.client.SetNX(ctx, key, header, 0).Result()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrBloomFilterExists
+	}
+	return nil
+}
+
+func (rc *redisClient) bloom(ctx context.Context, key string, add bool, items []string) ([]bool, error) {
+	if len(items) == 0 {
+		return nil, nil
+	}
+	mode := "0"
+	if add {
+		mode = "1"
+	}
+	args := make([]any, 0, 3+2*len(items))
+	args = append(args, mode, defaultBloomCapacity, int64(defaultBloomErrorRate*1e9))
+	for _, item := range items {
+		h1, h2 := bloomHashes(item)
+		args = append(args, h1, h2)
+	}
+	return rc.EvalSha(ctx, bloomScript, []string{key}, args...).BoolSlice()
+}
+
+func (rc *redisClient) BFAdd(ctx context.Context, key string, item string) (bool, error) {
+	rets, err := rc.bloom(ctx, key, true, []string{item})
+	if err != nil {
+		return false, err
+	}
+	return rets[0], nil
+}
+
+func (rc *redisClient) BFMAdd(ctx context.Context, key string, items ...string) ([]bool, error) {
+	return rc.bloom(ctx, key, true, items)
+}
+
+func (rc *redisClient) BFExists(ctx context.Context, key string, item string) (bool, error) {
+	rets, err := rc.bloom(ctx, key, false, []string{item})
+	if err != nil {
+		return false, err
+	}
+	return rets[0], nil
+}
+
+func (rc *redisClient) BFMExists(ctx context.Context, key string, items ...string) ([]bool, error) {
+	return rc.bloom(ctx, key, false, items)
+}
+
+func (rc *redisClient) BFInfo(ctx context.Context, key string) (*BloomFilterInfo, error) {
+	h, err := rc.client.BitField(ctx, key, "GET", "u32", 0, "GET", "u32", 32, "GET", "u32", 64,
+		"GET", "u32", 96).Result()
+	if err != nil {
+		return nil, err
+	}
+	info := &BloomFilterInfo{Layers: int(h[0]), ErrorRate: float64(h[3]) / 1e9}
+	for j := 0; j < info.Layers; j++ {
+		c, _, _ := bloomLayer(h[2], h[3], j)
+		info.Capacity += c
+		if j < info.Layers-1 {
+			info.Items += c
+		}
+	}
+	if info.Layers > 0 {
+		info.Items += h[1]
+	}
+	return info, nil
+}
@@ -0,0 +1,83 @@
+package gdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestBloomLayer(t *testing.T) {
+	c, m, k := bloomLayer(1000, 1e7, 0)
+	if c != 1000 || m != 9586 || k != 7 {
+		t.Fatalf("layer 0 = %d, %d, %d", c, m, k)
+	}
+	if c, _, k = bloomLayer(1000, 1e7, 2); c != 4000 || k != 9 {
+		t.Fatalf("layer 2 = %d, %d", c, k)
+	}
+}
+
+func TestBloomFilter(t *testing.T) {
+	client, _, _ := newMemRedisClient(t)
+	ctx := context.Background()
+
+	if err := client.BFReserve(ctx, "seen", 0, 0.01); !errors.Is(err, ErrBloomFilterOptions) {
+		t.Fatalf("BFReserve invalid = %v", err)
+	}
+	if err := client.BFReserve(ctx, "seen", 10, 0.01); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.BFReserve(ctx, "seen", 10, 0.01); !errors.Is(err, ErrBloomFilterExists) {
+		t.Fatalf("BFReserve twice = %v", err)
+	}
+	if added, err := client.BFAdd(ctx, "seen", "item:0"); err != nil || !added {
+		t.Fatalf("BFAdd = %v, %v", added, err)
+	}
+	if added, _ := client.BFAdd(ctx, "seen", "item:0"); added {
+		t.Fatal("BFAdd twice added")
+	}
+	items := make([]string, 0, 40)
+	for i := 1; i <= 40; i++ {
+		items = append(items, fmt.Sprintf("item:%d", i))
+	}
+	if _, err := client.BFMAdd(ctx, "seen", items...); err != nil {
+		t.Fatal(err)
+	}
+	// 10 + 20 + 40 after the layers grow
+	info, err := client.BFInfo(ctx, "seen")
+	if err != nil || info.Layers != 3 || info.Capacity != 70 || info.ErrorRate != 0.01 || info.Items < 35 {
+		t.Fatalf("BFInfo = %+v, %v", info, err)
+	}
+	founds, err := client.BFMExists(ctx, "seen", items...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, found := range founds {
+		if !found {
+			t.Fatalf("%s not found", items[i])
+		}
+	}
+	var falsePositives int
+	for i := 0; i < 1000; i++ {
+		if found, _ := client.BFExists(ctx, "seen", fmt.Sprintf("other:%d", i)); found {
+			falsePositives++
+		}
+	}
+	if falsePositives > 20 {
+		t.Fatalf("false positives = %d", falsePositives)
+	}
+
+	// a filter without BFReserve has the default options
+	rets, err := client.BFMAdd(ctx, "auto", "a", "b", "a")
+	if err != nil || !reflect.DeepEqual(rets, []bool{true, true, false}) {
+		t.Fatalf("BFMAdd = %v, %v", rets, err)
+	}
+	if info, err = client.BFInfo(ctx, "auto"); err != nil || *info != (BloomFilterInfo{Layers: 1, Items: 2,
+		Capacity: defaultBloomCapacity, ErrorRate: defaultBloomErrorRate}) {
+		t.Fatalf("BFInfo = %+v, %v", info, err)
+	}
+	if info, err = client.BFInfo(ctx, "missing"); err != nil || info.Layers != 0 {
+		t.Fatalf("BFInfo missing = %+v, %v", info, err)
+	}
+}
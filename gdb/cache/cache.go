@@ -0,0 +1,329 @@
+// Package gcache is a read-through object cache in front of gdb.DB, hot objects are kept in a local lru with ttl,
+// writes through the cache drop the local entries of all processes by publishing the keys on a redis channel.
+package gcache
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/oldjon/gutil/gdb"
+	gmarshaller "github.com/oldjon/gutil/marshaller"
+)
+
+const (
+	DefaultCapacity            = 10000
+	DefaultLocalTTL            = time.Minute
+	DefaultNegativeTTL         = 5 * time.Second
+	DefaultInvalidationChannel = "gdb:cache:invalidate"
+)
+
+// Loader load the object of the key from the source of truth when it is missing in redis,
+// it returns redis.Nil if the object does not exist.
+type Loader func(ctx context.Context, key string) (any, error)
+
+// Option cache option, zero values are replaced by the defaults
+type Option struct {
+	Capacity            int           // max entries in the local tier
+	LocalTTL            time.Duration // ttl of the local entries
+	NegativeTTL         time.Duration // ttl of the local not found entries, negative value disables negative caching
+	RedisTTL            time.Duration // expiration of the objects written back by the loader, 0 is the key schema DefaultTTL
+	Loader              Loader        // optional, called on redis miss
+	InvalidationChannel string        // redis channel the invalidations are broadcast on
+	// StaleTTL keep the expired local entries for the time, they are served when the circuit breaker of the db
+	// is open, 0 disables it. The invalidated entries are never served.
+	StaleTTL time.Duration
+}
+
+// Stats counters of the cache
+type Stats struct {
+	Hits          uint64 // served from the local tier, negative hits included
+	NegativeHits  uint64 // redis.Nil served from the local tier
+	Misses        uint64 // local misses
+	Loads         uint64 // loader calls
+	Shared        uint64 // misses served by the load of another caller
+	Evictions     uint64 // entries evicted by capacity
+	Invalidations uint64 // invalidation messages received
+	Stale         uint64 // stale entries served while the circuit breaker of the db is open
+	Size          int    // current entries of the local tier
+}
+
+type Cache struct {
+	db  *gdb.DB
+	opt Option
+	now func() time.Time
+
+	mu    sync.Mutex
+	local *lru
+	epoch uint64 // increased by Purge, a load started in an older epoch is not cached locally
+	// the keys being loaded, a load of a key invalidated since it started is not cached locally
+	loading map[string]*loading
+	group   group
+
+	hits, negativeHits, misses, loads, shared, evictions, invalidations, stale atomic.Uint64
+
+	sub  *gdb.Subscription
+	done chan struct{}
+}
+
+// loading is the generation of a key with loads in flight
+type loading struct {
+	gen   uint64 // increased by every invalidation of the key
+	loads int
+}
+
+// New create a cache in front of the db and subscribe the invalidation channel, Close should be called to release it
+func New(ctx context.Context, db *gdb.DB, opt *Option) (*Cache, error) {
+	c := &Cache{
+		db:      db,
+		now:     time.Now,
+		loading: make(map[string]*loading),
+		done:    make(chan struct{}),
+	}
+	if opt != nil {
+		c.opt = *opt
+	}
+	if c.opt.Capacity <= 0 {
+		c.opt.Capacity = DefaultCapacity
+	}
+	if c.opt.LocalTTL <= 0 {
+		c.opt.LocalTTL = DefaultLocalTTL
+	}
+	if c.opt.NegativeTTL == 0 {
+		c.opt.NegativeTTL = DefaultNegativeTTL
+	}
+	if c.opt.InvalidationChannel == "" {
+		c.opt.InvalidationChannel = DefaultInvalidationChannel
+	}
+	c.local = newLRU(c.opt.Capacity)
+
+	sub, err := db.Subscribe(ctx, c.opt.InvalidationChannel)
+	if err != nil {
+		return nil, err
+	}
+	c.sub = sub
+	go c.receive()
+	return c, nil
+}
+
+// receive drop the local entries of the invalidated keys until the cache is closed
+func (c *Cache) receive() {
+	defer close(c.done)
+	for msg := range c.sub.Channel() {
+		switch msg.Kind {
+		case gdb.MessageKindResubscribed:
+			// the invalidations during the failure are lost
+			c.Purge()
+		case gdb.MessageKindMessage:
+			c.invalidations.Add(1)
+			c.dropLocal(msg.Payload)
+		}
+	}
+}
+
+// GetObject get the object from the local tier, then the ObjectDB of the db, then the loader.
+// It returns redis.Nil if the object does not exist. The stale local entry is served if the circuit breaker of
+// the db is open and StaleTTL is set.
+func (c *Cache) GetObject(ctx context.Context, key string, obj any) error {
+	m, err := c.db.ObjMarshallerOf(key, obj)
+	if err != nil {
+		return err
+	}
+	data, err := c.get(ctx, key, m, reflect.TypeOf(obj).Elem())
+	if err != nil {
+		return err
+	}
+	return m.Unmarshal(data, obj)
+}
+
+// get return the marshalled object of the key, typ is the type of the object loaded on a local miss
+func (c *Cache) get(ctx context.Context, key string, m gmarshaller.Marshaller, typ reflect.Type) ([]byte, error) {
+	c.mu.Lock()
+	e, fresh := c.local.get(key, c.now())
+	c.mu.Unlock()
+	if fresh {
+		c.hits.Add(1)
+		if e.negative {
+			c.negativeHits.Add(1)
+			return nil, redis.Nil
+		}
+		return e.data, nil
+	}
+
+	c.misses.Add(1)
+	data, err, shared := c.group.do(ctx, key, func() ([]byte, error) {
+		gen, epoch := c.startLoad(key)
+		defer c.endLoad(key)
+		// the load is shared by all the waiters, it must not fail because the first caller is cancelled
+		data, err := c.load(context.WithoutCancel(ctx), key, m, typ)
+		if err == nil || errors.Is(err, redis.Nil) {
+			c.store(key, data, err != nil, gen, epoch)
+		}
+		return data, err
+	})
+	if shared {
+		c.shared.Add(1)
+	}
+	if e != nil && errors.Is(err, gdb.ErrCircuitOpen) {
+		c.stale.Add(1)
+		return e.data, nil
+	}
+	return data, err
+}
+
+// load read the object from the ObjectDB of the db, or from the loader and write it back, and return it marshalled
+func (c *Cache) load(ctx context.Context, key string, m gmarshaller.Marshaller, typ reflect.Type) ([]byte, error) {
+	obj := reflect.New(typ).Interface()
+	err := c.db.GetObject(ctx, key, obj)
+	if err == nil {
+		return m.Marshal(obj)
+	}
+	if !c.db.IsErrNil(err) || c.opt.Loader == nil {
+		return nil, err
+	}
+
+	c.loads.Add(1)
+	obj, err = c.opt.Loader(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if obj == nil {
+		return nil, redis.Nil
+	}
+	if c.opt.RedisTTL > 0 {
+		err = c.db.SetObjectEX(ctx, key, obj, c.opt.RedisTTL)
+	} else {
+		err = c.db.SetObject(ctx, key, obj)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return m.Marshal(obj)
+}
+
+// startLoad register a load of the key, it returns the generation of the key and the epoch at the start
+func (c *Cache) startLoad(key string) (gen, epoch uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	l := c.loading[key]
+	if l == nil {
+		l = &loading{}
+		c.loading[key] = l
+	}
+	l.loads++
+	return l.gen, c.epoch
+}
+
+// endLoad unregister a load of the key
+func (c *Cache) endLoad(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	l := c.loading[key]
+	if l.loads--; l.loads == 0 {
+		delete(c.loading, key)
+	}
+}
+
+// store cache the result of an unfinished load locally if neither the key is invalidated nor the cache is purged
+// since the load started
+func (c *Cache) store(key string, data []byte, negative bool, gen, epoch uint64) {
+	ttl := c.opt.LocalTTL
+	if negative {
+		if c.opt.NegativeTTL < 0 {
+			return
+		}
+		ttl = c.opt.NegativeTTL
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.loading[key].gen != gen || c.epoch != epoch {
+		return
+	}
+	e := &entry{key: key, data: data, negative: negative, expireAt: c.now().Add(ttl)}
+	if !negative {
+		e.staleUntil = e.expireAt.Add(c.opt.StaleTTL)
+	}
+	evicted := c.local.add(e)
+	c.evictions.Add(uint64(evicted))
+}
+
+func (c *Cache) SetObject(ctx context.Context, key string, obj any) error {
+	if err := c.db.SetObject(ctx, key, obj); err != nil {
+		return err
+	}
+	return c.Invalidate(ctx, key)
+}
+
+func (c *Cache) SetObjectEX(ctx context.Context, key string, obj any, expiration time.Duration) error {
+	if err := c.db.SetObjectEX(ctx, key, obj, expiration); err != nil {
+		return err
+	}
+	return c.Invalidate(ctx, key)
+}
+
+func (c *Cache) Del(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		if _, err := c.db.Del(ctx, key); err != nil {
+			return err
+		}
+	}
+	return c.Invalidate(ctx, keys...)
+}
+
+// Invalidate drop the local entries of the keys in this process and broadcast the invalidation to the others,
+// it should be called after the objects are modified without the cache
+func (c *Cache) Invalidate(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		c.dropLocal(key)
+		if _, err := c.db.Publish(ctx, c.opt.InvalidationChannel, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Cache) dropLocal(key string) {
+	c.mu.Lock()
+	if l := c.loading[key]; l != nil {
+		l.gen++
+	}
+	c.local.remove(key)
+	c.mu.Unlock()
+	c.group.forget(key)
+}
+
+// Purge drop all the local entries of this process
+func (c *Cache) Purge() {
+	c.mu.Lock()
+	c.epoch++
+	c.local.purge()
+	c.mu.Unlock()
+}
+
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	size := c.local.len()
+	c.mu.Unlock()
+	return Stats{
+		Hits:          c.hits.Load(),
+		NegativeHits:  c.negativeHits.Load(),
+		Misses:        c.misses.Load(),
+		Loads:         c.loads.Load(),
+		Shared:        c.shared.Load(),
+		Evictions:     c.evictions.Load(),
+		Invalidations: c.invalidations.Load(),
+		Stale:         c.stale.Load(),
+		Size:          size,
+	}
+}
+
+// Close stop receiving invalidations, the db is not closed
+func (c *Cache) Close() error {
+	err := c.sub.Close()
+	<-c.done
+	return err
+}
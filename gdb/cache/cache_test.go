@@ -0,0 +1,339 @@
+package gcache
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/oldjon/gutil/gdb"
+	"github.com/oldjon/gutil/gdb/memredis"
+	gmarshaller "github.com/oldjon/gutil/marshaller"
+)
+
+type Foo struct {
+	F int
+}
+
+func newServer(t *testing.T) *memredis.Server {
+	server := memredis.NewServer()
+	t.Cleanup(func() { _ = server.Close() })
+	return server
+}
+
+func newDB(t *testing.T, server *memredis.Server) *gdb.DB {
+	rc, err := gdb.NewMemRedisClient(server, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = rc.Close() })
+	return gdb.NewDB(rc, nil)
+}
+
+func newCache(t *testing.T, db *gdb.DB, opt *Option) *Cache {
+	c, err := New(context.Background(), db, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+func TestCacheReadThrough(t *testing.T) {
+	ctx := context.Background()
+	server := newServer(t)
+	db := newDB(t, server)
+
+	var loads atomic.Int32
+	release := make(chan struct{})
+	c := newCache(t, db, &Option{Loader: func(ctx context.Context, key string) (any, error) {
+		loads.Add(1)
+		<-release
+		if key == "missing" {
+			return nil, redis.Nil
+		}
+		return &Foo{F: 1}, nil
+	}})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var foo Foo
+			if err := c.GetObject(ctx, "foo", &foo); err != nil || foo.F != 1 {
+				t.Errorf("GetObject = %v, %v", foo, err)
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if loads.Load() != 1 {
+		t.Fatalf("loads = %d, want 1", loads.Load())
+	}
+	var foo Foo
+	if err := db.GetObject(ctx, "foo", &foo); err != nil || foo.F != 1 {
+		t.Fatalf("loaded object not written back, %v %v", foo, err)
+	}
+
+	// served locally even when redis changes without the cache
+	_ = db.SetObject(ctx, "foo", &Foo{F: 2})
+	if err := c.GetObject(ctx, "foo", &foo); err != nil || foo.F != 1 {
+		t.Fatalf("GetObject = %v, %v", foo, err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := c.GetObject(ctx, "missing", &foo); err != redis.Nil {
+			t.Fatalf("GetObject missing = %v", err)
+		}
+	}
+	if loads.Load() != 2 {
+		t.Fatalf("negative entry not cached, loads = %d", loads.Load())
+	}
+	if st := c.Stats(); st.NegativeHits != 1 || st.Size != 2 {
+		t.Fatalf("stats = %+v", st)
+	}
+}
+
+func TestCacheSharedLoad(t *testing.T) {
+	server := newServer(t)
+	db := newDB(t, server)
+
+	release := make(chan struct{})
+	c := newCache(t, db, &Option{Loader: func(ctx context.Context, key string) (any, error) {
+		<-release
+		if key == "panic" {
+			panic("loader")
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return &Foo{F: 1}, nil
+	}})
+
+	// the cancelled first caller returns at once and does not fail the waiters
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func(ctx context.Context, first bool) {
+			defer wg.Done()
+			var foo Foo
+			err := c.GetObject(ctx, "foo", &foo)
+			if first && !errors.Is(err, context.Canceled) || !first && (err != nil || foo.F != 1) {
+				t.Errorf("GetObject = %v, %v", foo, err)
+			}
+		}(ctx, i == 0)
+		time.Sleep(20 * time.Millisecond)
+		ctx = context.Background()
+	}
+	cancel()
+	time.Sleep(20 * time.Millisecond)
+	if st := c.Stats(); st.Loads != 1 {
+		t.Fatalf("stats = %+v", st)
+	}
+	close(release)
+	wg.Wait()
+
+	// a panic of the loader is returned to every caller and does not block the following ones
+	for i := 0; i < 2; i++ {
+		var foo Foo
+		if err := c.GetObject(context.Background(), "panic", &foo); err == nil {
+			t.Fatal("GetObject panic = nil")
+		}
+	}
+}
+
+func TestCacheInvalidation(t *testing.T) {
+	ctx := context.Background()
+	server := newServer(t)
+	c1 := newCache(t, newDB(t, server), nil)
+	c2 := newCache(t, newDB(t, server), nil)
+
+	if err := c1.SetObject(ctx, "foo", &Foo{F: 1}); err != nil {
+		t.Fatal(err)
+	}
+	var foo Foo
+	if err := c2.GetObject(ctx, "foo", &foo); err != nil || foo.F != 1 {
+		t.Fatalf("GetObject = %v, %v", foo, err)
+	}
+	if err := c1.SetObject(ctx, "foo", &Foo{F: 2}); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	// c2 receives the invalidations of both writes
+	for c2.Stats().Invalidations < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if err := c2.GetObject(ctx, "foo", &foo); err != nil || foo.F != 2 {
+		t.Fatalf("GetObject after invalidation = %v, %v", foo, err)
+	}
+}
+
+func TestCacheInvalidationDuringLoad(t *testing.T) {
+	ctx := context.Background()
+	server := newServer(t)
+	db := newDB(t, server)
+
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	c := newCache(t, db, &Option{Loader: func(ctx context.Context, key string) (any, error) {
+		started <- struct{}{}
+		<-release
+		return &Foo{F: 1}, nil
+	}})
+	load := func(key string) {
+		go func() {
+			<-started
+			// the invalidation of the other key does not drop the load, the one of the key does
+			_ = c.Invalidate(ctx, "other")
+			if key == "stale" {
+				_ = c.Invalidate(ctx, key)
+			}
+			release <- struct{}{}
+		}()
+		var foo Foo
+		if err := c.GetObject(ctx, key, &foo); err != nil || foo.F != 1 {
+			t.Fatalf("GetObject %s = %v, %v", key, foo, err)
+		}
+	}
+	load("hot")
+	load("stale")
+	if st := c.Stats(); st.Size != 1 || st.Loads != 2 {
+		t.Fatalf("stats = %+v", st)
+	}
+	var foo Foo
+	if err := c.GetObject(ctx, "hot", &foo); err != nil || c.Stats().Hits != 1 {
+		t.Fatalf("GetObject hot = %v, %+v", err, c.Stats())
+	}
+	if len(c.loading) != 0 {
+		t.Fatalf("loading = %v", c.loading)
+	}
+}
+
+func TestCacheObjectDB(t *testing.T) {
+	ctx := context.Background()
+	server := newServer(t)
+	rc, err := gdb.NewMemRedisClient(server, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = rc.Close() })
+	registry, err := gdb.NewKeySchemaRegistry(gdb.KeySchema{Prefix: "foo:", Type: reflect.TypeOf(Foo{}),
+		DefaultTTL: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	loader := func(ctx context.Context, key string) (any, error) {
+		return &Foo{F: 2}, nil
+	}
+
+	// the loaded objects are written back with the DefaultTTL of the schema
+	c := newCache(t, gdb.NewDB(rc, registry), &Option{Loader: loader})
+	var foo Foo
+	if err = c.GetObject(ctx, "foo:1", &foo); err != nil || foo.F != 2 {
+		t.Fatalf("GetObject = %v, %v", foo, err)
+	}
+	if ttl, err := rc.TTL(ctx, "foo:1"); err != nil || ttl != time.Hour {
+		t.Fatalf("TTL = %v, %v", ttl, err)
+	}
+
+	// the objects are read and written back by the ObjectDB, not by the redis client
+	store := gdb.NewMemDocStore()
+	docDB := gdb.NewDBWithObjectDB(gdb.NewDocObjectDB(store, nil), rc, registry)
+	_ = docDB.SetObject(ctx, "foo:3", &Foo{F: 3})
+	c = newCache(t, docDB, &Option{Loader: loader})
+	if err = c.GetObject(ctx, "foo:3", &foo); err != nil || foo.F != 3 {
+		t.Fatalf("GetObject of doc = %v, %v", foo, err)
+	}
+	if err = c.GetObject(ctx, "foo:2", &foo); err != nil || foo.F != 2 {
+		t.Fatalf("GetObject loaded = %v, %v", foo, err)
+	}
+	if doc, err := store.FindOne(ctx, "foo:2"); err != nil || doc.ExpireAt.IsZero() {
+		t.Fatalf("FindOne = %+v, %v", doc, err)
+	}
+	if ok, _ := rc.Exists(ctx, "foo:2"); ok {
+		t.Fatal("loaded object is written to redis")
+	}
+}
+
+func TestCacheServeStale(t *testing.T) {
+	ctx := context.Background()
+	server := newServer(t)
+	var down atomic.Bool
+	rc, err := gdb.NewRedisClient(&gdb.RedisClientOption{Mode: gdb.Single, Addr: "memredis", MaxRetries: -1,
+		Marshaller: &gmarshaller.JsonMarshaller{}, CircuitBreaker: &gdb.CircuitBreaker{MinCalls: 1, ErrorRate: 0.01,
+			OpenTimeout: time.Minute},
+		Dialer: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if down.Load() {
+				return nil, errors.New("connection refused")
+			}
+			return server.Dial(ctx, network, addr)
+		}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = rc.Close() })
+	db := gdb.NewDB(rc, nil)
+	c := newCache(t, db, &Option{LocalTTL: time.Second, StaleTTL: time.Minute})
+	now := time.Unix(1700000000, 0)
+	c.now = func() time.Time { return now }
+
+	_ = db.SetObject(ctx, "foo", &Foo{F: 1})
+	_ = db.SetObject(ctx, "bar", &Foo{F: 2})
+	var foo Foo
+	for _, key := range []string{"foo", "bar"} {
+		if err = c.GetObject(ctx, key, &foo); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now = now.Add(2 * time.Second)
+	down.Store(true)
+	server.CloseConnections()
+
+	// the failure opens the breaker, the stale entry is served only while it is open
+	if err = c.GetObject(ctx, "foo", &foo); err == nil || errors.Is(err, gdb.ErrCircuitOpen) {
+		t.Fatalf("GetObject while down = %v", err)
+	}
+	foo = Foo{}
+	if err = c.GetObject(ctx, "foo", &foo); err != nil || foo.F != 1 {
+		t.Fatalf("GetObject stale = %v, %v", foo, err)
+	}
+	_ = c.Invalidate(ctx, "bar")
+	if err = c.GetObject(ctx, "bar", &foo); !errors.Is(err, gdb.ErrCircuitOpen) {
+		t.Fatalf("GetObject invalidated = %v", err)
+	}
+	now = now.Add(time.Minute)
+	if err = c.GetObject(ctx, "foo", &foo); !errors.Is(err, gdb.ErrCircuitOpen) {
+		t.Fatalf("GetObject after stale ttl = %v", err)
+	}
+	if st := c.Stats(); st.Stale != 1 {
+		t.Fatalf("stats = %+v", st)
+	}
+}
+
+func TestLRU(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	l := newLRU(2)
+	l.add(&entry{key: "a", expireAt: now.Add(time.Second)})
+	l.add(&entry{key: "b", expireAt: now.Add(time.Minute)})
+	l.get("a", now)
+	if n := l.add(&entry{key: "c", expireAt: now.Add(time.Minute)}); n != 1 {
+		t.Fatalf("evicted %d", n)
+	}
+	if _, ok := l.get("b", now); ok {
+		t.Fatal("least recently used entry not evicted")
+	}
+	if _, ok := l.get("a", now.Add(time.Second)); ok {
+		t.Fatal("expired entry returned")
+	}
+	if l.len() != 1 {
+		t.Fatalf("len = %d", l.len())
+	}
+}
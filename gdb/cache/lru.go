@@ -0,0 +1,83 @@
+package gcache
+
+import (
+	"container/list"
+	"time"
+)
+
+// entry is a local cached value, data is the marshalled object, a negative entry caches a redis.Nil.
+// An expired entry is kept as stale until staleUntil if it is after expireAt.
+type entry struct {
+	key        string
+	data       []byte
+	negative   bool
+	expireAt   time.Time
+	staleUntil time.Time
+}
+
+// lru is a capacity bounded local store with per entry ttl, it is not goroutine safe
+type lru struct {
+	capacity int
+	ll       *list.List
+	items    map[string]*list.Element
+}
+
+func newLRU(capacity int) *lru {
+	return &lru{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// get return the entry of the key and whether it is fresh, a stale entry is returned as not fresh,
+// the entries neither fresh nor stale are removed
+func (l *lru) get(key string, now time.Time) (*entry, bool) {
+	el, ok := l.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*entry)
+	if !e.expireAt.After(now) && !e.staleUntil.After(now) {
+		l.removeElement(el)
+		return nil, false
+	}
+	l.ll.MoveToFront(el)
+	return e, e.expireAt.After(now)
+}
+
+// add insert or replace the entry, return the number of evicted entries
+func (l *lru) add(e *entry) int {
+	if el, ok := l.items[e.key]; ok {
+		el.Value = e
+		l.ll.MoveToFront(el)
+		return 0
+	}
+	l.items[e.key] = l.ll.PushFront(e)
+	var evicted int
+	for l.capacity > 0 && l.ll.Len() > l.capacity {
+		l.removeElement(l.ll.Back())
+		evicted++
+	}
+	return evicted
+}
+
+func (l *lru) remove(key string) {
+	if el, ok := l.items[key]; ok {
+		l.removeElement(el)
+	}
+}
+
+func (l *lru) purge() {
+	l.ll.Init()
+	l.items = make(map[string]*list.Element)
+}
+
+func (l *lru) len() int {
+	return l.ll.Len()
+}
+
+func (l *lru) removeElement(el *list.Element) {
+	l.ll.Remove(el)
+	delete(l.items, el.Value.(*entry).key)
+}
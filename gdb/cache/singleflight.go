@@ -0,0 +1,68 @@
+package gcache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// call is an in-flight or completed load
+type call struct {
+	done chan struct{}
+	data []byte
+	err  error
+}
+
+// group de-duplicate concurrent loads of the same key
+type group struct {
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+// do execute fn once for all the concurrent callers of the same key, shared is true for the callers that waited.
+// fn runs on its own goroutine, a caller whose ctx is done returns ctx.Err() without waiting it.
+// A panic of fn is returned as an error to all the callers.
+func (g *group) do(ctx context.Context, key string, fn func() ([]byte, error)) (data []byte, err error,
+	shared bool) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*call)
+	}
+	c, shared := g.calls[key]
+	if !shared {
+		c = &call{done: make(chan struct{})}
+		g.calls[key] = c
+		go g.run(key, c, fn)
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.data, c.err, shared
+	case <-ctx.Done():
+		return nil, ctx.Err(), shared
+	}
+}
+
+func (g *group) run(key string, c *call, fn func() ([]byte, error)) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.data, c.err = nil, fmt.Errorf("panic: %v", r)
+		}
+		close(c.done)
+
+		g.mu.Lock()
+		if g.calls[key] == c {
+			delete(g.calls, key)
+		}
+		g.mu.Unlock()
+	}()
+	c.data, c.err = fn()
+}
+
+// forget make the following callers of the key start a new load instead of waiting the in-flight one
+func (g *group) forget(key string) {
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+}
@@ -0,0 +1,298 @@
+package gdb
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	defaultBreakerErrorRate      = 0.5
+	defaultBreakerMinCalls       = 20
+	defaultBreakerWindow         = 10 * time.Second
+	defaultBreakerOpenTimeout    = 5 * time.Second
+	defaultBreakerHalfOpenProbes = 1
+	breakerWindowSlots           = 10 // the window rolls by a tenth
+)
+
+// CircuitState is the state of a CircuitBreaker
+type CircuitState int32
+
+const (
+	CircuitClosed   CircuitState = iota // the calls pass
+	CircuitOpen                         // the calls fail fast with ErrCircuitOpen
+	CircuitHalfOpen                     // the probe calls pass to check whether redis recovers
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// breakerBlockingCommands wait by design, their latency is not checked
+var breakerBlockingCommands = map[string]bool{
+	"blpop": true, "brpop": true, "brpoplpush": true, "blmove": true, "bzpopmin": true, "bzpopmax": true,
+	"xread": true, "xreadgroup": true,
+}
+
+// breakerFailedReplies are the error replies of redis telling it can not serve now
+var breakerFailedReplies = []string{"LOADING", "BUSY", "CLUSTERDOWN", "TRYAGAIN", "MASTERDOWN"}
+
+type breakerCallKey struct{}
+
+type breakerCall struct {
+	start time.Time
+	probe bool
+}
+
+// CircuitBreaker fail the commands fast with ErrCircuitOpen when redis degrades, instead of blocking them until
+// ReadTimeout. It opens when the failed calls reach ErrorRate of the calls in the last Window. A call fails with
+// a network error, a timeout, a reply such as LOADING or CLUSTERDOWN, or a latency over SlowThreshold, the other
+// error replies of redis are not failures. After OpenTimeout it is half open, HalfOpenProbes calls pass as the
+// probes, it closes when they all succeed, or opens again when one fails. A pipeline is a call.
+//
+// Set it to RedisClientOption.CircuitBreaker, the zero value is ready to use, use one breaker for each client.
+type CircuitBreaker struct {
+	ErrorRate      float64       // default 0.5
+	SlowThreshold  time.Duration // 0 means the latency is not checked
+	MinCalls       int           // the calls in the Window before it opens, default 20
+	Window         time.Duration // default 10s
+	OpenTimeout    time.Duration // default 5s
+	HalfOpenProbes int           // default 1
+	// OnStateChange is called on every state change with the lock of the breaker held, it should not block
+	OnStateChange func(from, to CircuitState)
+
+	now      func() time.Time
+	mu       sync.Mutex
+	state    CircuitState
+	openedAt time.Time
+	probes   int // the probes passed in the half open state
+	probeOKs int // the probes succeeded
+	calls    [breakerWindowSlots]uint64
+	failures [breakerWindowSlots]uint64
+	epochs   [breakerWindowSlots]int64
+}
+
+// validate return the invalid option of the breaker
+func (b *CircuitBreaker) validate() string {
+	switch {
+	case b.ErrorRate < 0 || b.ErrorRate > 1:
+		return "circuit_breaker_error_rate should be from 0 to 1"
+	case b.SlowThreshold < 0 || b.Window < 0 || b.OpenTimeout < 0:
+		return "negative circuit_breaker_slow_threshold_ms, circuit_breaker_window or circuit_breaker_open_timeout"
+	case b.MinCalls < 0 || b.HalfOpenProbes < 0:
+		return "negative circuit_breaker_min_calls or circuit_breaker_half_open_probes"
+	}
+	return ""
+}
+
+func (b *CircuitBreaker) clock() time.Time {
+	if b.now != nil {
+		return b.now()
+	}
+	return time.Now()
+}
+
+func (b *CircuitBreaker) window() time.Duration {
+	if b.Window > 0 {
+		return b.Window
+	}
+	return defaultBreakerWindow
+}
+
+func (b *CircuitBreaker) halfOpenProbes() int {
+	if b.HalfOpenProbes > 0 {
+		return b.HalfOpenProbes
+	}
+	return defaultBreakerHalfOpenProbes
+}
+
+// State return the current state, an open breaker is half open after OpenTimeout
+func (b *CircuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.checkOpenTimeout(b.clock())
+	return b.state
+}
+
+// setState change the state, b.mu must be held
+func (b *CircuitBreaker) setState(state CircuitState, now time.Time) {
+	from := b.state
+	b.state, b.probes, b.probeOKs = state, 0, 0
+	switch state {
+	case CircuitOpen:
+		b.openedAt = now
+	case CircuitClosed:
+		b.calls, b.failures, b.epochs = [breakerWindowSlots]uint64{}, [breakerWindowSlots]uint64{},
+			[breakerWindowSlots]int64{}
+	}
+	if b.OnStateChange != nil && from != state {
+		b.OnStateChange(from, state)
+	}
+}
+
+// checkOpenTimeout half open the breaker opened OpenTimeout ago, b.mu must be held
+func (b *CircuitBreaker) checkOpenTimeout(now time.Time) {
+	timeout := b.OpenTimeout
+	if timeout <= 0 {
+		timeout = defaultBreakerOpenTimeout
+	}
+	if b.state == CircuitOpen && now.Sub(b.openedAt) >= timeout {
+		b.setState(CircuitHalfOpen, now)
+	}
+}
+
+// allow return whether the call passes and whether it is a probe
+func (b *CircuitBreaker) allow() (bool, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.checkOpenTimeout(b.clock())
+	switch b.state {
+	case CircuitOpen:
+		return false, false
+	case CircuitHalfOpen:
+		if b.probes >= b.halfOpenProbes() {
+			return false, false
+		}
+		b.probes++
+		return true, true
+	}
+	return true, false
+}
+
+// cancel free the probe slot of a passed call cancelled by its caller, its result tells nothing of redis
+func (b *CircuitBreaker) cancel(probe bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if probe && b.state == CircuitHalfOpen && b.probes > 0 {
+		b.probes--
+	}
+}
+
+// record account the result of a passed call
+func (b *CircuitBreaker) record(probe, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.clock()
+	if probe {
+		if b.state != CircuitHalfOpen {
+			return
+		}
+		if failed {
+			b.setState(CircuitOpen, now)
+		} else if b.probeOKs++; b.probeOKs >= b.halfOpenProbes() {
+			b.setState(CircuitClosed, now)
+		}
+		return
+	}
+	if b.state != CircuitClosed {
+		return
+	}
+
+	epoch := now.UnixNano() / int64(b.window()/breakerWindowSlots)
+	i := epoch % breakerWindowSlots
+	if b.epochs[i] != epoch {
+		b.epochs[i], b.calls[i], b.failures[i] = epoch, 0, 0
+	}
+	b.calls[i]++
+	if !failed {
+		return
+	}
+	b.failures[i]++
+	var calls, failures uint64
+	for j := range b.epochs {
+		if epoch-b.epochs[j] < breakerWindowSlots {
+			calls += b.calls[j]
+			failures += b.failures[j]
+		}
+	}
+	minCalls, errorRate := b.MinCalls, b.ErrorRate
+	if minCalls <= 0 {
+		minCalls = defaultBreakerMinCalls
+	}
+	if errorRate <= 0 {
+		errorRate = defaultBreakerErrorRate
+	}
+	if calls >= uint64(minCalls) && float64(failures) >= errorRate*float64(calls) {
+		b.setState(CircuitOpen, now)
+	}
+}
+
+// failed return whether the error tells redis is degraded
+func (b *CircuitBreaker) failed(err error) bool {
+	if err == nil || errors.Is(err, redis.Nil) || errors.Is(err, ErrCircuitOpen) {
+		return false
+	}
+	var reply redis.Error
+	if errors.As(err, &reply) {
+		for _, prefix := range breakerFailedReplies {
+			if strings.HasPrefix(reply.Error(), prefix) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// slow return whether the call started at start is slower than SlowThreshold
+func (b *CircuitBreaker) slow(start time.Time) bool {
+	return b.SlowThreshold > 0 && time.Since(start) > b.SlowThreshold
+}
+
+func (b *CircuitBreaker) before(ctx context.Context) (context.Context, error) {
+	ok, probe := b.allow()
+	if !ok {
+		return ctx, ErrCircuitOpen
+	}
+	return context.WithValue(ctx, breakerCallKey{}, &breakerCall{start: time.Now(), probe: probe}), nil
+}
+
+func (b *CircuitBreaker) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return b.before(ctx)
+}
+
+func (b *CircuitBreaker) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	call, ok := ctx.Value(breakerCallKey{}).(*breakerCall)
+	if !ok { // rejected
+		return nil
+	}
+	if errors.Is(cmd.Err(), context.Canceled) {
+		b.cancel(call.probe)
+		return nil
+	}
+	b.record(call.probe, b.failed(cmd.Err()) || (!breakerBlockingCommands[cmd.Name()] && b.slow(call.start)))
+	return nil
+}
+
+func (b *CircuitBreaker) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return b.before(ctx)
+}
+
+func (b *CircuitBreaker) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	call, ok := ctx.Value(breakerCallKey{}).(*breakerCall)
+	if !ok {
+		return nil
+	}
+	failed := b.slow(call.start)
+	for _, cmd := range cmds {
+		if errors.Is(cmd.Err(), context.Canceled) {
+			b.cancel(call.probe)
+			return nil
+		}
+		failed = failed || b.failed(cmd.Err())
+	}
+	b.record(call.probe, failed)
+	return nil
+}
@@ -0,0 +1,204 @@
+package gdb
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/oldjon/gutil/gdb/memredis"
+	gmarshaller "github.com/oldjon/gutil/marshaller"
+)
+
+// newBreakerClient create a client of the breaker whose connections fail while down is set
+func newBreakerClient(t *testing.T, mode Mode, breaker *CircuitBreaker, hooks ...redis.Hook) (RedisClient,
+	*memredis.Server, *atomic.Bool, *fakeClock) {
+	server := memredis.NewServer()
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	breaker.now = clock.Now
+	down := &atomic.Bool{}
+	client, err := NewRedisClient(&RedisClientOption{Mode: mode, Addr: "memredis",
+		Addrs: map[string]string{"a": "memredis"}, MaxRetries: -1, Marshaller: &gmarshaller.JsonMarshaller{},
+		CircuitBreaker: breaker, Hooks: hooks,
+		Dialer: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if down.Load() {
+				return nil, errors.New("connection refused")
+			}
+			return server.Dial(ctx, network, addr)
+		}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = client.Close()
+		_ = server.Close()
+	})
+	return client, server, down, clock
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+	var changes []CircuitState
+	breaker := &CircuitBreaker{MinCalls: 4, OnStateChange: func(from, to CircuitState) {
+		changes = append(changes, to)
+	}}
+	client, server, down, clock := newBreakerClient(t, Single, breaker)
+
+	// redis error replies are not failures
+	_ = client.Set(ctx, "foo", "bar")
+	for i := 0; i < 3; i++ {
+		if _, err := client.HGet(ctx, "foo", "f"); err == nil {
+			t.Fatal("HGet on a string succeeded")
+		}
+	}
+	if state := breaker.State(); state != CircuitClosed {
+		t.Fatalf("state after error replies = %v", state)
+	}
+
+	down.Store(true)
+	server.CloseConnections()
+	for i := 0; i < 5; i++ {
+		_, _ = client.Get(ctx, "foo")
+	}
+	if state := breaker.State(); state != CircuitOpen {
+		t.Fatalf("state after failures = %v", state)
+	}
+	if _, err := client.Get(ctx, "foo"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Get while open = %v", err)
+	}
+	pipe := client.Pipeline()
+	get := pipe.Get(ctx, "foo")
+	if _, err := pipe.Exec(ctx); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("pipeline while open = %v", err)
+	}
+	if _, err := get.Result(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("pipelined Get while open = %v", err)
+	}
+
+	// the failed probe opens it again
+	clock.Add(5 * time.Second)
+	if state := breaker.State(); state != CircuitHalfOpen {
+		t.Fatalf("state after open timeout = %v", state)
+	}
+	if _, err := client.Get(ctx, "foo"); err == nil || errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("probe while down = %v", err)
+	}
+	if state := breaker.State(); state != CircuitOpen {
+		t.Fatalf("state after failed probe = %v", state)
+	}
+
+	down.Store(false)
+	clock.Add(5 * time.Second)
+	// the cancelled probe frees its slot without closing the breaker
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := client.Get(cancelled, "foo"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled probe = %v", err)
+	}
+	if state := breaker.State(); state != CircuitHalfOpen {
+		t.Fatalf("state after cancelled probe = %v", state)
+	}
+	if v, err := client.Get(ctx, "foo"); err != nil || v != "bar" {
+		t.Fatalf("probe = %v, %v", v, err)
+	}
+	if state := breaker.State(); state != CircuitClosed {
+		t.Fatalf("state after probe = %v", state)
+	}
+	want := []CircuitState{CircuitOpen, CircuitHalfOpen, CircuitOpen, CircuitHalfOpen, CircuitClosed}
+	if len(changes) != len(want) {
+		t.Fatalf("state changes = %v", changes)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Fatalf("state changes = %v", changes)
+		}
+	}
+}
+
+func TestCircuitBreakerSlowCalls(t *testing.T) {
+	ctx := context.Background()
+	breaker := &CircuitBreaker{SlowThreshold: time.Nanosecond, MinCalls: 3, ErrorRate: 1}
+	client, _, _, clock := newBreakerClient(t, Single, breaker)
+
+	// the PING of NewRedisClient is the first slow call
+	_, _ = client.Get(ctx, "foo")
+	if state := breaker.State(); state != CircuitClosed {
+		t.Fatalf("state under min calls = %v", state)
+	}
+	// the calls of the last window are counted
+	clock.Add(11 * time.Second)
+	_, _ = client.Get(ctx, "foo")
+	_, _ = client.Get(ctx, "foo")
+	if state := breaker.State(); state != CircuitClosed {
+		t.Fatalf("state after the window = %v", state)
+	}
+	_, _ = client.Get(ctx, "foo")
+	if state := breaker.State(); state != CircuitOpen {
+		t.Fatalf("state after slow calls = %v", state)
+	}
+}
+
+// errHook record the errors of the commands seen by the hooks of the client
+type errHook struct {
+	errs []error
+}
+
+func (h *errHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return ctx, nil
+}
+
+func (h *errHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	h.errs = append(h.errs, cmd.Err())
+	return nil
+}
+
+func (h *errHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return ctx, nil
+}
+
+func (h *errHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	for _, cmd := range cmds {
+		h.errs = append(h.errs, cmd.Err())
+	}
+	return nil
+}
+
+func TestCircuitBreakerRing(t *testing.T) {
+	ctx := context.Background()
+	hook := &errHook{}
+	breaker := &CircuitBreaker{MinCalls: 4}
+	client, server, down, _ := newBreakerClient(t, Ring, breaker, hook)
+
+	down.Store(true)
+	server.CloseConnections()
+	for i := 0; i < 5; i++ {
+		_, _ = client.Get(ctx, "foo")
+	}
+	if state := breaker.State(); state != CircuitOpen {
+		t.Fatalf("state after failures = %v", state)
+	}
+
+	// the hooks see the calls failed fast on the client, as in single mode
+	hook.errs = nil
+	if _, err := client.Get(ctx, "foo"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Get while open = %v", err)
+	}
+	if len(hook.errs) != 1 || !errors.Is(hook.errs[0], ErrCircuitOpen) {
+		t.Fatalf("hook errors = %v", hook.errs)
+	}
+
+	// the commands sent to the shards directly fail fast too
+	hook.errs = nil
+	err := client.(*redisClient).client.Watch(ctx, func(tx *redis.Tx) error {
+		return tx.Get(ctx, "foo").Err()
+	}, "foo")
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Watch while open = %v", err)
+	}
+	if len(hook.errs) == 0 || !errors.Is(hook.errs[0], ErrCircuitOpen) {
+		t.Fatalf("hook errors of Watch = %v", hook.errs)
+	}
+}
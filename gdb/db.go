@@ -2,20 +2,35 @@ package gdb
 
 import (
 	"context"
+	"errors"
 	"time"
+
+	"github.com/go-redis/redis/v8"
+	gmarshaller "github.com/oldjon/gutil/marshaller"
 )
 
 type DB struct {
-	ObjectDB // support redis db or mongo db(TODO)
+	ObjectDB // support redis db or document db like mongo
 	RedisClient
 }
 
+// the methods of ObjectDB are promoted by both embedded fields, so DB declares them all to select ObjectDB
+var _ ObjectDB = (*DB)(nil)
+
 func NewDB(redisClient RedisClient, koMapping KOMapping) *DB {
+	return NewDBWithObjectDB(redisClient, redisClient, koMapping)
+}
+
+// NewDBWithObjectDB create a DB whose objects are stored by objectDB, for example a document db created by
+// NewDocObjectDB, while the other redis commands go to redisClient. redisClient may be nil if they are not used.
+func NewDBWithObjectDB(objectDB ObjectDB, redisClient RedisClient, koMapping KOMapping) *DB {
 	db := &DB{
-		ObjectDB:    redisClient,
+		ObjectDB:    objectDB,
 		RedisClient: redisClient,
 	}
-	if koMapping != nil {
+	if registry, ok := koMapping.(*KeySchemaRegistry); ok {
+		db.ObjectDB.SetKeySchemas(registry)
+	} else if koMapping != nil {
 		db.ObjectDB.SetKOMapping(koMapping.Mapping())
 	} else {
 		db.ObjectDB.SetKOMapping(make(map[string]string))
@@ -35,6 +50,10 @@ func (db *DB) SetObjectEX(ctx context.Context, key string, obj any, expiration t
 	return db.ObjectDB.SetObjectEX(ctx, key, obj, expiration)
 }
 
+func (db *DB) UpdateObject(ctx context.Context, key string, obj any, fn UpdateFunc) error {
+	return db.ObjectDB.UpdateObject(ctx, key, obj, fn)
+}
+
 func (db *DB) GetObjects(ctx context.Context, keys []string, objs any) error {
 	return db.ObjectDB.GetObjects(ctx, keys, objs)
 }
@@ -115,6 +134,102 @@ func (db *DB) ZRemObjects(ctx context.Context, key string, values ...any) (int64
 	return db.ObjectDB.ZRemObjects(ctx, key, values...)
 }
 
+func (db *DB) GeoAddObjects(ctx context.Context, key string, values ...any) (int64, error) {
+	return db.ObjectDB.GeoAddObjects(ctx, key, values...)
+}
+
+func (db *DB) GeoPosObjects(ctx context.Context, key string, members ...any) ([]*redis.GeoPos, error) {
+	return db.ObjectDB.GeoPosObjects(ctx, key, members...)
+}
+
+func (db *DB) GeoDistObjects(ctx context.Context, key string, member1, member2 any, unit string) (float64, error) {
+	return db.ObjectDB.GeoDistObjects(ctx, key, member1, member2, unit)
+}
+
+func (db *DB) GeoSearchObjects(ctx context.Context, key string, q *redis.GeoSearchLocationQuery, objs any) (
+	[]redis.GeoLocation, error) {
+	return db.ObjectDB.GeoSearchObjects(ctx, key, q, objs)
+}
+
+func (db *DB) LPushObjects(ctx context.Context, key string, values ...any) (int64, error) {
+	return db.ObjectDB.LPushObjects(ctx, key, values...)
+}
+
+func (db *DB) RPushObjects(ctx context.Context, key string, values ...any) (int64, error) {
+	return db.ObjectDB.RPushObjects(ctx, key, values...)
+}
+
+func (db *DB) LPopObject(ctx context.Context, key string, obj any) error {
+	return db.ObjectDB.LPopObject(ctx, key, obj)
+}
+
+func (db *DB) RPopObject(ctx context.Context, key string, obj any) error {
+	return db.ObjectDB.RPopObject(ctx, key, obj)
+}
+
+func (db *DB) BLPopObject(ctx context.Context, timeout time.Duration, key string, obj any) error {
+	return db.ObjectDB.BLPopObject(ctx, timeout, key, obj)
+}
+
+func (db *DB) LRangeObjects(ctx context.Context, key string, start, stop int64, objs any) error {
+	return db.ObjectDB.LRangeObjects(ctx, key, start, stop, objs)
+}
+
+func (db *DB) SAddObjects(ctx context.Context, key string, members ...any) (int64, error) {
+	return db.ObjectDB.SAddObjects(ctx, key, members...)
+}
+
+func (db *DB) SRemObjects(ctx context.Context, key string, members ...any) (int64, error) {
+	return db.ObjectDB.SRemObjects(ctx, key, members...)
+}
+
+func (db *DB) SIsMemberObject(ctx context.Context, key string, member any) (bool, error) {
+	return db.ObjectDB.SIsMemberObject(ctx, key, member)
+}
+
+func (db *DB) SMembersObjects(ctx context.Context, key string, objs any) error {
+	return db.ObjectDB.SMembersObjects(ctx, key, objs)
+}
+
+func (db *DB) SPopObject(ctx context.Context, key string, obj any) error {
+	return db.ObjectDB.SPopObject(ctx, key, obj)
+}
+
+func (db *DB) XAddObject(ctx context.Context, stream string, obj any) (string, error) {
+	return db.ObjectDB.XAddObject(ctx, stream, obj)
+}
+
+func (db *DB) XMessageObject(msg redis.XMessage, obj any) error {
+	return db.ObjectDB.XMessageObject(msg, obj)
+}
+
 func (db *DB) IsErrNil(err error) bool {
+	if db.RedisClient == nil {
+		return errors.Is(err, redis.Nil)
+	}
 	return db.RedisClient.IsErrNil(err)
 }
+
+func (db *DB) ObjMarshaller() gmarshaller.Marshaller {
+	return db.ObjectDB.ObjMarshaller()
+}
+
+func (db *DB) ObjMarshallerOf(key string, obj any) (gmarshaller.Marshaller, error) {
+	return db.ObjectDB.ObjMarshallerOf(key, obj)
+}
+
+func (db *DB) LegacyReads() map[string]uint64 {
+	return db.ObjectDB.LegacyReads()
+}
+
+func (db *DB) CheckKeyObjMatch(key string, obj any) error {
+	return db.ObjectDB.CheckKeyObjMatch(key, obj)
+}
+
+func (db *DB) SetKOMapping(mapping map[string]string) {
+	db.ObjectDB.SetKOMapping(mapping)
+}
+
+func (db *DB) SetKeySchemas(registry *KeySchemaRegistry) {
+	db.ObjectDB.SetKeySchemas(registry)
+}
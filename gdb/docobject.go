@@ -0,0 +1,1093 @@
+package gdb
+
+// ObjectDB implemented on a DocStore, every db key is a Doc, so durable objects can live outside redis.
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	ggeohash "github.com/oldjon/gutil/gdb/geohash"
+	gmarshaller "github.com/oldjon/gutil/marshaller"
+)
+
+const (
+	docUpdateRetries   = 16
+	docBLPopPollPeriod = 20 * time.Millisecond
+)
+
+type docObjectDB struct {
+	store         DocStore
+	objMarshaller gmarshaller.Marshaller
+	now           func() time.Time
+	objectSchemas
+}
+
+// NewDocObjectDB create an ObjectDB on the document store, JsonMarshaller is used if marshaller is nil.
+// Missing objects are reported by redis.Nil as the redis backend does, so callers work with both backends.
+// Every key is updated atomically, but multi keys methods like SetObjects are not atomic across keys.
+func NewDocObjectDB(store DocStore, marshaller gmarshaller.Marshaller) ObjectDB {
+	if marshaller == nil {
+		marshaller = &gmarshaller.JsonMarshaller{}
+	}
+	return &docObjectDB{
+		store:         store,
+		objMarshaller: marshaller,
+		now:           time.Now,
+	}
+}
+
+// ObjMarshallerOf check obj is of the type of the key, and return the marshaller of the key
+func (d *docObjectDB) ObjMarshallerOf(key string, obj any) (gmarshaller.Marshaller, error) {
+	return d.marshallerOf(key, obj, d.objMarshaller)
+}
+
+func (d *docObjectDB) LegacyReads() map[string]uint64 {
+	return d.legacyReads(d.objMarshaller)
+}
+
+func (d *docObjectDB) ObjMarshaller() gmarshaller.Marshaller {
+	return d.objMarshaller
+}
+
+// read return the live document of the key, or nil if it does not exist
+func (d *docObjectDB) read(ctx context.Context, key string, kind string) (*Doc, error) {
+	doc, err := d.store.FindOne(ctx, key)
+	if errors.Is(err, ErrDocNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if doc.expired(d.now()) {
+		return nil, nil
+	}
+	if doc.Kind != kind {
+		return nil, ErrDocWrongKind
+	}
+	return doc, nil
+}
+
+// update modify the document of the key by fn and save it, fn is called again if the document is modified by others.
+// A document of another kind is replaced if overwrite is true, empty containers are deleted.
+func (d *docObjectDB) update(ctx context.Context, key string, kind string, overwrite bool, fn func(doc *Doc) error) error {
+	for i := 0; i < docUpdateRetries; i++ {
+		old, err := d.store.FindOne(ctx, key)
+		if err != nil && !errors.Is(err, ErrDocNotFound) {
+			return err
+		}
+		doc := &Doc{ID: key, Kind: kind}
+		if old != nil {
+			doc.Version = old.Version
+			if !old.expired(d.now()) {
+				if old.Kind == kind {
+					doc = old
+				} else if !overwrite {
+					return ErrDocWrongKind
+				}
+			}
+		}
+		version := doc.Version
+		if err = fn(doc); err != nil {
+			return err
+		}
+		doc.Version = version + 1
+		switch {
+		case old == nil:
+			if doc.empty() {
+				return nil
+			}
+			err = d.store.Insert(ctx, doc)
+		case doc.empty():
+			err = d.store.Delete(ctx, key, version)
+		default:
+			err = d.store.Replace(ctx, doc, version)
+		}
+		if !errors.Is(err, ErrDocConflict) {
+			return err
+		}
+	}
+	return ErrDocConflict
+}
+
+// marshal check and marshal the value of the key
+func (d *docObjectDB) marshal(key string, v any) ([]byte, error) {
+	m, err := d.ObjMarshallerOf(key, v)
+	if err != nil {
+		return nil, err
+	}
+	return m.Marshal(v)
+}
+
+// setSliceElem unmarshal data into the i-th element of objs, a nil data sets the element to zero
+func setSliceElem(m gmarshaller.Marshaller, objsValue reflect.Value, i int, data []byte, elemIsInterface bool) error {
+	objv := objsValue.Index(i)
+	if data == nil {
+		if !elemIsInterface {
+			objv.Set(reflect.Zero(objv.Type()))
+		} else {
+			objv.Set(reflect.Zero(objv.Elem().Type()))
+		}
+		return nil
+	}
+	if !elemIsInterface {
+		if objv.IsNil() {
+			objv.Set(reflect.New(objv.Type().Elem()))
+		}
+	} else {
+		if objv.Elem().IsNil() {
+			objv.Set(reflect.New(objv.Elem().Type().Elem()))
+		}
+	}
+	return m.Unmarshal(data, objv.Interface())
+}
+
+// checkDstSlice check objs is a slice of n struct points, it returns whether the elements are interfaces
+func checkDstSlice(objs any, n int, countPanic string) (reflect.Value, bool) {
+	objsValue := reflect.ValueOf(objs)
+	if objsValue.Kind() != reflect.Slice {
+		panic(PanicValueDstNeedBeSlice)
+	}
+	if n != objsValue.Len() {
+		panic(countPanic)
+	}
+	for i := 0; i < n; i++ {
+		if objsValue.Index(i).Kind() != reflect.Ptr && // input is slice of known struct type
+			objsValue.Index(i).Elem().Kind() != reflect.Ptr { // input is slice of interface
+			panic(PanicValueDstNeedBePointer)
+		}
+	}
+	return objsValue, objsValue.Index(0).Kind() != reflect.Ptr
+}
+
+func (d *docObjectDB) GetObject(ctx context.Context, key string, obj any) error {
+	m, err := d.ObjMarshallerOf(key, obj)
+	if err != nil {
+		return err
+	}
+	doc, err := d.read(ctx, key, DocKindString)
+	if err != nil {
+		return err
+	}
+	if doc == nil {
+		return redis.Nil
+	}
+	return m.Unmarshal(doc.Value, obj)
+}
+
+func (d *docObjectDB) SetObject(ctx context.Context, key string, obj any) error {
+	return d.SetObjectEX(ctx, key, obj, d.defaultTTL(key))
+}
+
+func (d *docObjectDB) SetObjectEX(ctx context.Context, key string, obj any, expiration time.Duration) error {
+	bys, err := d.marshal(key, obj)
+	if err != nil {
+		return err
+	}
+	return d.update(ctx, key, DocKindString, true, func(doc *Doc) error {
+		doc.Value = bys
+		doc.ExpireAt = time.Time{}
+		if expiration > 0 {
+			doc.ExpireAt = d.now().Add(expiration)
+		}
+		return nil
+	})
+}
+
+func (d *docObjectDB) UpdateObject(ctx context.Context, key string, obj any, fn UpdateFunc) error {
+	m, err := d.ObjMarshallerOf(key, obj)
+	if err != nil {
+		return err
+	}
+	err = d.update(ctx, key, DocKindString, false, func(doc *Doc) error {
+		resetObject(obj)
+		if doc.Value == nil {
+			// the created key gets the default ttl, the ttl of an existing key is kept
+			if ttl := d.defaultTTL(key); ttl > 0 {
+				doc.ExpireAt = d.now().Add(ttl)
+			}
+		} else {
+			if err := m.Unmarshal(doc.Value, obj); err != nil {
+				return err
+			}
+		}
+		if err := fn(obj); err != nil {
+			return err
+		}
+		bys, err := m.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		doc.Value = bys
+		return nil
+	})
+	if errors.Is(err, ErrDocConflict) {
+		return ErrUpdateConflict
+	}
+	return err
+}
+
+func (d *docObjectDB) GetObjects(ctx context.Context, keys []string, objs any) error {
+	if len(keys) == 0 {
+		panic(PanicKeyIsMissing)
+	}
+	objsValue, elemIsInterface := checkDstSlice(objs, len(keys), PanicKeyValueCountUnmatched)
+	ms := make([]gmarshaller.Marshaller, len(keys))
+	for i := range keys {
+		var err error
+		if ms[i], err = d.ObjMarshallerOf(keys[i], objsValue.Index(i).Interface()); err != nil {
+			return err
+		}
+	}
+
+	docs, err := d.store.FindMany(ctx, keys)
+	if err != nil {
+		return err
+	}
+	now := d.now()
+	values := make(map[string][]byte, len(docs))
+	for _, doc := range docs {
+		if doc.Kind == DocKindString && !doc.expired(now) {
+			values[doc.ID] = doc.Value
+		}
+	}
+	for i, key := range keys {
+		if err = setSliceElem(ms[i], objsValue, i, values[key], elemIsInterface); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *docObjectDB) SetObjects(ctx context.Context, keys []string, objs any) error {
+	return d.setObjects(ctx, keys, objs, d.defaultTTL)
+}
+
+func (d *docObjectDB) SetObjectsEX(ctx context.Context, keys []string, objs any, expiration time.Duration) error {
+	return d.setObjects(ctx, keys, objs, func(string) time.Duration { return expiration })
+}
+
+// setObjects set the objects of the keys with the expirations of the keys
+func (d *docObjectDB) setObjects(ctx context.Context, keys []string, objs any,
+	expiration func(key string) time.Duration) error {
+	if len(keys) == 0 {
+		panic(PanicKeyIsMissing)
+	}
+	objsValue := reflect.ValueOf(objs)
+	if objsValue.Kind() != reflect.Slice {
+		panic(PanicValueNeedBeSlice)
+	}
+	if len(keys) != objsValue.Len() {
+		panic(PanicKeyValueCountUnmatched)
+	}
+	for i, key := range keys {
+		if err := d.SetObjectEX(ctx, key, objsValue.Index(i).Interface(), expiration(key)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *docObjectDB) HSetObjects(ctx context.Context, key string, values ...any) error {
+	values = hsetPairs(values)
+
+	fields := make(map[string][]byte, len(values)/2)
+	for i := 0; i < len(values); i += 2 {
+		bys, err := d.marshal(key, values[i+1])
+		if err != nil {
+			return err
+		}
+		fields[fmt.Sprint(values[i])] = bys
+	}
+	return d.update(ctx, key, DocKindHash, false, func(doc *Doc) error {
+		if doc.Fields == nil {
+			doc.Fields = make(map[string][]byte, len(fields))
+		}
+		for f, v := range fields {
+			doc.Fields[f] = v
+		}
+		return nil
+	})
+}
+
+func (d *docObjectDB) HGetObject(ctx context.Context, key string, field string, obj any) error {
+	v := reflect.ValueOf(obj)
+	if v.Kind() != reflect.Ptr {
+		panic(PanicValueDstNeedBePointer)
+	}
+	m, err := d.ObjMarshallerOf(key, obj)
+	if err != nil {
+		return err
+	}
+	doc, err := d.read(ctx, key, DocKindHash)
+	if err != nil {
+		return err
+	}
+	if doc == nil {
+		return redis.Nil
+	}
+	data, ok := doc.Fields[field]
+	if !ok {
+		return redis.Nil
+	}
+	return m.Unmarshal(data, obj)
+}
+
+func (d *docObjectDB) HMGetObjects(ctx context.Context, key string, fields []string, objs any) error {
+	if len(fields) == 0 {
+		panic(PanicFieldsIsMissing)
+	}
+	objsValue, elemIsInterface := checkDstSlice(objs, len(fields), PanicFieldValueCountUnmatched)
+	m, err := d.ObjMarshallerOf(key, objs)
+	if err != nil {
+		return err
+	}
+
+	doc, err := d.read(ctx, key, DocKindHash)
+	if err != nil {
+		return err
+	}
+	for i, field := range fields {
+		var data []byte
+		if doc != nil {
+			data = doc.Fields[field]
+		}
+		if err = setSliceElem(m, objsValue, i, data, elemIsInterface); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *docObjectDB) HGetAllObjects(ctx context.Context, key string, fields *[]string, objs any) error {
+	if fields == nil {
+		panic(PanicFieldsIsNil)
+	}
+	m, err := d.ObjMarshallerOf(key, objs)
+	if err != nil {
+		return err
+	}
+	doc, err := d.read(ctx, key, DocKindHash)
+	if err != nil {
+		return err
+	}
+	var names, datas []string
+	if doc != nil {
+		names = make([]string, 0, len(doc.Fields))
+		for f := range doc.Fields {
+			names = append(names, f)
+		}
+		sort.Strings(names)
+		datas = make([]string, 0, len(names))
+		for _, f := range names {
+			datas = append(datas, string(doc.Fields[f]))
+		}
+	}
+	if err = unmarshalSliceBy(m, datas, objs); err != nil {
+		return err
+	}
+	*fields = append(*fields, names...)
+	return nil
+}
+
+// sortZMembers sort the zset members by score, then by member
+func sortZMembers(members []DocMember) {
+	sort.SliceStable(members, func(i, j int) bool {
+		if members[i].Score != members[j].Score {
+			return members[i].Score < members[j].Score
+		}
+		return bytes.Compare(members[i].Value, members[j].Value) < 0
+	})
+}
+
+func findMember(members []DocMember, value []byte) int {
+	for i := range members {
+		if bytes.Equal(members[i].Value, value) {
+			return i
+		}
+	}
+	return -1
+}
+
+// parseScoreBound parse the min or max of a score range, like "1.5", "(1.5", "-inf" and "+inf"
+func parseScoreBound(s string) (float64, bool, error) {
+	exclusive := strings.HasPrefix(s, "(")
+	if exclusive {
+		s = s[1:]
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("%w: score bound %q", ErrValue, s)
+	}
+	return v, exclusive, nil
+}
+
+// rankRange convert the redis style start and stop into the slice range [lo, hi)
+func rankRange(n int, start, stop int64) (int, int) {
+	l := int64(n)
+	if start < 0 {
+		start += l
+	}
+	if stop < 0 {
+		stop += l
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= l {
+		stop = l - 1
+	}
+	if start > stop {
+		return 0, 0
+	}
+	return int(start), int(stop + 1)
+}
+
+func reverseMembers(members []DocMember) []DocMember {
+	rets := make([]DocMember, len(members))
+	for i, m := range members {
+		rets[len(members)-1-i] = m
+	}
+	return rets
+}
+
+func (d *docObjectDB) ZAddObjects(ctx context.Context, key string, values ...any) (int64, error) {
+	if len(values)%2 != 0 {
+		panic(PanicScoreValueCountUnmatched)
+	}
+	members := make([]DocMember, 0, len(values)/2)
+	for i := 0; i < len(values); i += 2 {
+		score, err := toFloat64(values[i])
+		if err != nil {
+			return 0, err
+		}
+		bys, err := d.marshal(key, values[i+1])
+		if err != nil {
+			return 0, err
+		}
+		members = append(members, DocMember{Value: bys, Score: score})
+	}
+	var added int64
+	err := d.update(ctx, key, DocKindZSet, false, func(doc *Doc) error {
+		added = 0
+		for _, m := range members {
+			if i := findMember(doc.Members, m.Value); i >= 0 {
+				doc.Members[i].Score = m.Score
+				continue
+			}
+			doc.Members = append(doc.Members, m)
+			added++
+		}
+		sortZMembers(doc.Members)
+		return nil
+	})
+	return added, err
+}
+
+// zrange return the zset members of the key in the rank range
+func (d *docObjectDB) zrange(ctx context.Context, key string, start, stop int64, rev bool) ([]DocMember, error) {
+	doc, err := d.read(ctx, key, DocKindZSet)
+	if err != nil || doc == nil {
+		return nil, err
+	}
+	members := doc.Members
+	if rev {
+		members = reverseMembers(members)
+	}
+	lo, hi := rankRange(len(members), start, stop)
+	return members[lo:hi], nil
+}
+
+// zrangeByScore return the zset members of the key in the score range
+func (d *docObjectDB) zrangeByScore(ctx context.Context, key string, min, max string, rev bool) ([]DocMember, error) {
+	lo, loEx, err := parseScoreBound(min)
+	if err != nil {
+		return nil, err
+	}
+	hi, hiEx, err := parseScoreBound(max)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := d.read(ctx, key, DocKindZSet)
+	if err != nil || doc == nil {
+		return nil, err
+	}
+	members := make([]DocMember, 0)
+	for _, m := range doc.Members {
+		if m.Score < lo || loEx && m.Score == lo || m.Score > hi || hiEx && m.Score == hi {
+			continue
+		}
+		members = append(members, m)
+	}
+	if rev {
+		members = reverseMembers(members)
+	}
+	return members, nil
+}
+
+// zmembersInto unmarshal the members of the key into objs and return their scores
+func (d *docObjectDB) zmembersInto(key string, members []DocMember, objs any) ([]float64, error) {
+	m, err := d.ObjMarshallerOf(key, objs)
+	if err != nil {
+		return nil, err
+	}
+	datas := make([]string, 0, len(members))
+	scores := make([]float64, 0, len(members))
+	for _, m := range members {
+		datas = append(datas, string(m.Value))
+		scores = append(scores, m.Score)
+	}
+	if err = unmarshalSliceBy(m, datas, objs); err != nil {
+		return nil, err
+	}
+	return scores, nil
+}
+
+func (d *docObjectDB) ZRangeObjects(ctx context.Context, key string, start, stop int64, objs any) error {
+	_, err := d.ZRangeObjectsWithScores(ctx, key, start, stop, objs)
+	return err
+}
+
+func (d *docObjectDB) ZRangeObjectsByScore(ctx context.Context, key string, min, max string, objs any) error {
+	_, err := d.ZRangeObjectsByScoreWithScores(ctx, key, min, max, objs)
+	return err
+}
+
+func (d *docObjectDB) ZRangeObjectsWithScores(ctx context.Context, key string, start, stop int64, objs any) (
+	scores []float64, err error) {
+	members, err := d.zrange(ctx, key, start, stop, false)
+	if err != nil {
+		return nil, err
+	}
+	return d.zmembersInto(key, members, objs)
+}
+
+func (d *docObjectDB) ZRangeObjectsByScoreWithScores(ctx context.Context, key string, min, max string, objs any) (
+	scores []float64, err error) {
+	members, err := d.zrangeByScore(ctx, key, min, max, false)
+	if err != nil {
+		return nil, err
+	}
+	return d.zmembersInto(key, members, objs)
+}
+
+func (d *docObjectDB) ZRevRangeObjects(ctx context.Context, key string, start, stop int64, objs any) error {
+	_, err := d.ZRevRangeObjectsWithScores(ctx, key, start, stop, objs)
+	return err
+}
+
+func (d *docObjectDB) ZRevRangeObjectsByScore(ctx context.Context, key string, min, max string, objs any) error {
+	_, err := d.ZRevRangeObjectsByScoreWithScores(ctx, key, min, max, objs)
+	return err
+}
+
+func (d *docObjectDB) ZRevRangeObjectsWithScores(ctx context.Context, key string, start, stop int64, objs any) (
+	scores []float64, err error) {
+	members, err := d.zrange(ctx, key, start, stop, true)
+	if err != nil {
+		return nil, err
+	}
+	return d.zmembersInto(key, members, objs)
+}
+
+func (d *docObjectDB) ZRevRangeObjectsByScoreWithScores(ctx context.Context, key string, min, max string, objs any) (
+	scores []float64, err error) {
+	members, err := d.zrangeByScore(ctx, key, min, max, true)
+	if err != nil {
+		return nil, err
+	}
+	return d.zmembersInto(key, members, objs)
+}
+
+// zfind return the zset members of the key and the index of member, it returns redis.Nil if member does not exist
+func (d *docObjectDB) zfind(ctx context.Context, key string, member any) ([]DocMember, int, error) {
+	data, err := d.marshal(key, member)
+	if err != nil {
+		return nil, 0, err
+	}
+	doc, err := d.read(ctx, key, DocKindZSet)
+	if err != nil {
+		return nil, 0, err
+	}
+	if doc == nil {
+		return nil, 0, redis.Nil
+	}
+	i := findMember(doc.Members, data)
+	if i < 0 {
+		return nil, 0, redis.Nil
+	}
+	return doc.Members, i, nil
+}
+
+func (d *docObjectDB) ZRankObject(ctx context.Context, key string, member any) (int64, error) {
+	_, i, err := d.zfind(ctx, key, member)
+	if err != nil {
+		return 0, err
+	}
+	return int64(i), nil
+}
+
+func (d *docObjectDB) ZRevRankObject(ctx context.Context, key string, member any) (int64, error) {
+	members, i, err := d.zfind(ctx, key, member)
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(members) - 1 - i), nil
+}
+
+func (d *docObjectDB) ZScoreObject(ctx context.Context, key string, member any) (float64, error) {
+	members, i, err := d.zfind(ctx, key, member)
+	if err != nil {
+		return 0, err
+	}
+	return members[i].Score, nil
+}
+
+func (d *docObjectDB) ZRemObjects(ctx context.Context, key string, members ...any) (int64, error) {
+	return d.removeMembers(ctx, key, DocKindZSet, members)
+}
+
+// geoScoreOf return the geohash score of the position, it returns ErrValue for the positions redis rejects
+func geoScoreOf(lon, lat any) (float64, error) {
+	lo, err := toFloat64(lon)
+	if err != nil {
+		return 0, err
+	}
+	la, err := toFloat64(lat)
+	if err != nil {
+		return 0, err
+	}
+	if !ggeohash.Valid(lo, la) {
+		return 0, ErrValue
+	}
+	return float64(ggeohash.Encode(lo, la)), nil
+}
+
+// geoUnitOf return the meters of the unit, "" is km like go-redis
+func geoUnitOf(unit string) (float64, error) {
+	if unit == "" {
+		unit = "km"
+	}
+	u, ok := ggeohash.Unit(unit)
+	if !ok {
+		return 0, ErrValue
+	}
+	return u, nil
+}
+
+// geoRound round the distance to the 4 decimals of the redis replies
+func geoRound(dist float64) float64 {
+	return math.Round(dist*1e4) / 1e4
+}
+
+func (d *docObjectDB) GeoAddObjects(ctx context.Context, key string, values ...any) (int64, error) {
+	if len(values)%3 != 0 {
+		panic(PanicGeoValueCountUnmatched)
+	}
+	zvalues := make([]any, 0, len(values)/3*2)
+	for i := 0; i < len(values); i += 3 {
+		score, err := geoScoreOf(values[i], values[i+1])
+		if err != nil {
+			return 0, err
+		}
+		zvalues = append(zvalues, score, values[i+2])
+	}
+	return d.ZAddObjects(ctx, key, zvalues...)
+}
+
+func (d *docObjectDB) GeoPosObjects(ctx context.Context, key string, members ...any) ([]*redis.GeoPos, error) {
+	datas := make([][]byte, len(members))
+	for i, m := range members {
+		var err error
+		if datas[i], err = d.marshal(key, m); err != nil {
+			return nil, err
+		}
+	}
+	doc, err := d.read(ctx, key, DocKindZSet)
+	if err != nil {
+		return nil, err
+	}
+	poses := make([]*redis.GeoPos, len(members))
+	if doc == nil {
+		return poses, nil
+	}
+	for i, data := range datas {
+		if j := findMember(doc.Members, data); j >= 0 {
+			lon, lat := ggeohash.Decode(uint64(doc.Members[j].Score))
+			poses[i] = &redis.GeoPos{Longitude: lon, Latitude: lat}
+		}
+	}
+	return poses, nil
+}
+
+func (d *docObjectDB) GeoDistObjects(ctx context.Context, key string, member1, member2 any, unit string) (
+	float64, error) {
+	u, err := geoUnitOf(unit)
+	if err != nil {
+		return 0, err
+	}
+	members, i, err := d.zfind(ctx, key, member1)
+	if err != nil {
+		return 0, err
+	}
+	data, err := d.marshal(key, member2)
+	if err != nil {
+		return 0, err
+	}
+	j := findMember(members, data)
+	if j < 0 {
+		return 0, redis.Nil
+	}
+	lon1, lat1 := ggeohash.Decode(uint64(members[i].Score))
+	lon2, lat2 := ggeohash.Decode(uint64(members[j].Score))
+	return geoRound(ggeohash.Distance(lon1, lat1, lon2, lat2) / u), nil
+}
+
+func (d *docObjectDB) GeoSearchObjects(ctx context.Context, key string, q *redis.GeoSearchLocationQuery,
+	objs any) ([]redis.GeoLocation, error) {
+	m, err := d.ObjMarshallerOf(key, objs)
+	if err != nil {
+		return nil, err
+	}
+	unit := q.BoxUnit
+	if q.Radius > 0 {
+		unit = q.RadiusUnit
+	}
+	u, err := geoUnitOf(unit)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := d.read(ctx, key, DocKindZSet)
+	if err != nil {
+		return nil, err
+	}
+	var members []DocMember
+	if doc != nil {
+		members = doc.Members
+	}
+	area := &ggeohash.Area{Lon: q.Longitude, Lat: q.Latitude, Radius: q.Radius * u, Width: q.BoxWidth * u,
+		Height: q.BoxHeight * u}
+	if q.Member != "" {
+		i := findMember(members, []byte(q.Member))
+		if i < 0 {
+			return nil, ErrValue
+		}
+		area.Lon, area.Lat = ggeohash.Decode(uint64(members[i].Score))
+	}
+
+	type hit struct {
+		location redis.GeoLocation
+		dist     float64
+	}
+	var hits []hit
+	for _, member := range members {
+		lon, lat := ggeohash.Decode(uint64(member.Score))
+		dist, ok := area.Contains(lon, lat)
+		if !ok {
+			continue
+		}
+		l := redis.GeoLocation{Name: string(member.Value)}
+		if q.WithCoord {
+			l.Longitude, l.Latitude = lon, lat
+		}
+		if q.WithDist {
+			l.Dist = geoRound(dist / u)
+		}
+		if q.WithHash {
+			l.GeoHash = int64(member.Score)
+		}
+		hits = append(hits, hit{location: l, dist: dist})
+	}
+	// redis sorts by the distance when COUNT is given without the order
+	order := strings.ToLower(q.Sort)
+	if order == "" && q.Count > 0 {
+		order = "asc"
+	}
+	if order != "" {
+		sort.SliceStable(hits, func(i, j int) bool {
+			if order == "desc" {
+				return hits[i].dist > hits[j].dist
+			}
+			return hits[i].dist < hits[j].dist
+		})
+	}
+	if q.Count > 0 && len(hits) > q.Count {
+		hits = hits[:q.Count]
+	}
+	locations, datas := make([]redis.GeoLocation, len(hits)), make([]string, len(hits))
+	for i, h := range hits {
+		locations[i], datas[i] = h.location, h.location.Name
+	}
+	return locations, unmarshalSliceBy(m, datas, objs)
+}
+
+// removeMembers remove the members from the container of the key and return the removed count
+func (d *docObjectDB) removeMembers(ctx context.Context, key string, kind string, members []any) (int64, error) {
+	datas := make([][]byte, 0, len(members))
+	for _, m := range members {
+		bys, err := d.marshal(key, m)
+		if err != nil {
+			return 0, err
+		}
+		datas = append(datas, bys)
+	}
+	var removed int64
+	err := d.update(ctx, key, kind, false, func(doc *Doc) error {
+		removed = 0
+		for _, data := range datas {
+			if i := findMember(doc.Members, data); i >= 0 {
+				doc.Members = append(doc.Members[:i], doc.Members[i+1:]...)
+				removed++
+			}
+		}
+		return nil
+	})
+	return removed, err
+}
+
+// marshalDocMembers check and marshal every value of the key
+func (d *docObjectDB) marshalDocMembers(key string, values []any) ([]DocMember, error) {
+	members := make([]DocMember, 0, len(values))
+	for _, v := range values {
+		bys, err := d.marshal(key, v)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, DocMember{Value: bys})
+	}
+	return members, nil
+}
+
+func (d *docObjectDB) push(ctx context.Context, key string, values []any, left bool) (int64, error) {
+	members, err := d.marshalDocMembers(key, values)
+	if err != nil {
+		return 0, err
+	}
+	var n int64
+	err = d.update(ctx, key, DocKindList, false, func(doc *Doc) error {
+		for _, m := range members {
+			if left {
+				doc.Members = append([]DocMember{m}, doc.Members...)
+			} else {
+				doc.Members = append(doc.Members, m)
+			}
+		}
+		n = int64(len(doc.Members))
+		return nil
+	})
+	return n, err
+}
+
+func (d *docObjectDB) LPushObjects(ctx context.Context, key string, values ...any) (int64, error) {
+	return d.push(ctx, key, values, true)
+}
+
+func (d *docObjectDB) RPushObjects(ctx context.Context, key string, values ...any) (int64, error) {
+	return d.push(ctx, key, values, false)
+}
+
+func (d *docObjectDB) pop(ctx context.Context, key string, obj any, left bool) error {
+	m, err := d.ObjMarshallerOf(key, obj)
+	if err != nil {
+		return err
+	}
+	var data []byte
+	err = d.update(ctx, key, DocKindList, false, func(doc *Doc) error {
+		n := len(doc.Members)
+		if n == 0 {
+			return redis.Nil
+		}
+		if left {
+			data, doc.Members = doc.Members[0].Value, doc.Members[1:]
+		} else {
+			data, doc.Members = doc.Members[n-1].Value, doc.Members[:n-1]
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return m.Unmarshal(data, obj)
+}
+
+func (d *docObjectDB) LPopObject(ctx context.Context, key string, obj any) error {
+	return d.pop(ctx, key, obj, true)
+}
+
+func (d *docObjectDB) RPopObject(ctx context.Context, key string, obj any) error {
+	return d.pop(ctx, key, obj, false)
+}
+
+// BLPopObject poll the list since a document store can not block, timeout 0 means blocking until ctx is done
+func (d *docObjectDB) BLPopObject(ctx context.Context, timeout time.Duration, key string, obj any) error {
+	var deadline <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+	ticker := time.NewTicker(docBLPopPollPeriod)
+	defer ticker.Stop()
+	for {
+		err := d.LPopObject(ctx, key, obj)
+		if !errors.Is(err, redis.Nil) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-deadline:
+			return redis.Nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// membersOf return the values of the container of the key
+func (d *docObjectDB) membersOf(ctx context.Context, key string, kind string) ([]DocMember, error) {
+	doc, err := d.read(ctx, key, kind)
+	if err != nil || doc == nil {
+		return nil, err
+	}
+	return doc.Members, nil
+}
+
+func memberDatas(members []DocMember) []string {
+	datas := make([]string, 0, len(members))
+	for _, m := range members {
+		datas = append(datas, string(m.Value))
+	}
+	return datas
+}
+
+func (d *docObjectDB) LRangeObjects(ctx context.Context, key string, start, stop int64, objs any) error {
+	m, err := d.ObjMarshallerOf(key, objs)
+	if err != nil {
+		return err
+	}
+	members, err := d.membersOf(ctx, key, DocKindList)
+	if err != nil {
+		return err
+	}
+	lo, hi := rankRange(len(members), start, stop)
+	return unmarshalSliceBy(m, memberDatas(members[lo:hi]), objs)
+}
+
+func (d *docObjectDB) SAddObjects(ctx context.Context, key string, members ...any) (int64, error) {
+	ms, err := d.marshalDocMembers(key, members)
+	if err != nil {
+		return 0, err
+	}
+	var added int64
+	err = d.update(ctx, key, DocKindSet, false, func(doc *Doc) error {
+		added = 0
+		for _, m := range ms {
+			if findMember(doc.Members, m.Value) < 0 {
+				doc.Members = append(doc.Members, m)
+				added++
+			}
+		}
+		return nil
+	})
+	return added, err
+}
+
+func (d *docObjectDB) SRemObjects(ctx context.Context, key string, members ...any) (int64, error) {
+	return d.removeMembers(ctx, key, DocKindSet, members)
+}
+
+func (d *docObjectDB) SIsMemberObject(ctx context.Context, key string, member any) (bool, error) {
+	data, err := d.marshal(key, member)
+	if err != nil {
+		return false, err
+	}
+	members, err := d.membersOf(ctx, key, DocKindSet)
+	if err != nil {
+		return false, err
+	}
+	return findMember(members, data) >= 0, nil
+}
+
+func (d *docObjectDB) SMembersObjects(ctx context.Context, key string, objs any) error {
+	m, err := d.ObjMarshallerOf(key, objs)
+	if err != nil {
+		return err
+	}
+	members, err := d.membersOf(ctx, key, DocKindSet)
+	if err != nil {
+		return err
+	}
+	return unmarshalSliceBy(m, memberDatas(members), objs)
+}
+
+func (d *docObjectDB) SPopObject(ctx context.Context, key string, obj any) error {
+	m, err := d.ObjMarshallerOf(key, obj)
+	if err != nil {
+		return err
+	}
+	var data []byte
+	err = d.update(ctx, key, DocKindSet, false, func(doc *Doc) error {
+		if len(doc.Members) == 0 {
+			return redis.Nil
+		}
+		i := rand.Intn(len(doc.Members))
+		data = doc.Members[i].Value
+		doc.Members = append(doc.Members[:i], doc.Members[i+1:]...)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return m.Unmarshal(data, obj)
+}
+
+// nextStreamID generate a stream message id greater than last, in the redis format "<ms>-<seq>"
+func nextStreamID(last string, now time.Time) string {
+	ms, seq := now.UnixMilli(), int64(0)
+	if lms, lseq, ok := strings.Cut(last, "-"); ok {
+		lastMs, _ := strconv.ParseInt(lms, 10, 64)
+		lastSeq, _ := strconv.ParseInt(lseq, 10, 64)
+		if ms <= lastMs {
+			ms, seq = lastMs, lastSeq+1
+		}
+	}
+	return strconv.FormatInt(ms, 10) + "-" + strconv.FormatInt(seq, 10)
+}
+
+func (d *docObjectDB) XAddObject(ctx context.Context, stream string, obj any) (string, error) {
+	if err := d.CheckKeyObjMatch(stream, obj); err != nil {
+		return "", err
+	}
+	bys, err := d.objMarshaller.Marshal(obj)
+	if err != nil {
+		return "", err
+	}
+	var id string
+	err = d.update(ctx, stream, DocKindStream, false, func(doc *Doc) error {
+		var last string
+		if n := len(doc.Members); n > 0 {
+			last = doc.Members[n-1].ID
+		}
+		id = nextStreamID(last, d.now())
+		doc.Members = append(doc.Members, DocMember{ID: id, Value: bys})
+		return nil
+	})
+	return id, err
+}
+
+func (d *docObjectDB) XMessageObject(msg redis.XMessage, obj any) error {
+	v, ok := msg.Values[StreamObjectField].(string)
+	if !ok {
+		return ErrValueType
+	}
+	return d.objMarshaller.Unmarshal([]byte(v), obj)
+}
@@ -0,0 +1,193 @@
+package gdb
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+func newDocObjectDB() (*docObjectDB, *fakeClock) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	d := NewDocObjectDB(NewMemDocStore(), nil).(*docObjectDB)
+	d.now = clock.Now
+	return d, clock
+}
+
+func TestDocObjectDBString(t *testing.T) {
+	ctx := context.Background()
+	d, clock := newDocObjectDB()
+	d.SetKOMapping(map[string]string{"foo": "gdb.Foo"})
+
+	var foo Foo
+	if err := d.GetObject(ctx, "foo:1", &foo); !errors.Is(err, redis.Nil) {
+		t.Fatalf("GetObject missing = %v", err)
+	}
+	if err := d.SetObjectEX(ctx, "foo:1", &Foo{F: 1}, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetObjects(ctx, []string{"foo:2", "foo:3"}, []*Foo{{F: 2}, {F: 3}}); err != nil {
+		t.Fatal(err)
+	}
+	foos := make([]*Foo, 4)
+	if err := d.GetObjects(ctx, []string{"foo:1", "foo:2", "foo:4", "foo:3"}, foos); err != nil {
+		t.Fatal(err)
+	}
+	if foos[0].F != 1 || foos[1].F != 2 || foos[2] != nil || foos[3].F != 3 {
+		t.Fatalf("GetObjects = %v", foos)
+	}
+
+	clock.Add(time.Second)
+	if err := d.GetObject(ctx, "foo:1", &foo); !errors.Is(err, redis.Nil) {
+		t.Fatalf("GetObject expired = %v", err)
+	}
+	if err := d.SetObject(ctx, "foo:1", &Foo{F: 5}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.GetObject(ctx, "foo:1", &foo); err != nil || foo.F != 5 {
+		t.Fatalf("GetObject = %v, %v", foo, err)
+	}
+	if _, err := d.LPushObjects(ctx, "foo:1", &Foo{}); !errors.Is(err, ErrDocWrongKind) {
+		t.Fatalf("LPushObjects on string = %v", err)
+	}
+
+	if err := d.SetObject(ctx, "foo:1", &Bar{}); !errors.Is(err, ErrKeyObjMismatch) {
+		t.Fatalf("SetObject mismatched = %v", err)
+	}
+}
+
+func TestDocObjectDBHashAndZSet(t *testing.T) {
+	ctx := context.Background()
+	d, _ := newDocObjectDB()
+
+	if err := d.HSetObjects(ctx, "h", "f1", &Foo{F: 1}, "f2", &Foo{F: 2}); err != nil {
+		t.Fatal(err)
+	}
+	var foo Foo
+	if err := d.HGetObject(ctx, "h", "f2", &foo); err != nil || foo.F != 2 {
+		t.Fatalf("HGetObject = %v, %v", foo, err)
+	}
+	foos := make([]*Foo, 2)
+	if err := d.HMGetObjects(ctx, "h", []string{"f3", "f1"}, foos); err != nil || foos[0] != nil || foos[1].F != 1 {
+		t.Fatalf("HMGetObjects = %v, %v", foos, err)
+	}
+	var fields []string
+	var all []Foo
+	if err := d.HGetAllObjects(ctx, "h", &fields, &all); err != nil || len(all) != 2 || fields[1] != "f2" || all[1].F != 2 {
+		t.Fatalf("HGetAllObjects = %v %v, %v", fields, all, err)
+	}
+
+	if n, err := d.ZAddObjects(ctx, "z", 3, &Foo{F: 3}, 1, &Foo{F: 1}, 2.5, &Foo{F: 2}); err != nil || n != 3 {
+		t.Fatalf("ZAddObjects = %d, %v", n, err)
+	}
+	if n, _ := d.ZAddObjects(ctx, "z", 0, &Foo{F: 3}); n != 0 {
+		t.Fatalf("ZAddObjects update = %d", n)
+	}
+	// the scores are checked like the redis backend, numeric strings included
+	for _, score := range []any{"4", true} {
+		if _, err := d.ZAddObjects(ctx, "z", score, &Foo{F: 4}); !errors.Is(err, ErrValueType) {
+			t.Fatalf("ZAddObjects score %v = %v", score, err)
+		}
+	}
+	var zs []*Foo
+	scores, err := d.ZRangeObjectsByScoreWithScores(ctx, "z", "(0", "+inf", &zs)
+	if err != nil || len(zs) != 2 || zs[0].F != 1 || scores[1] != 2.5 {
+		t.Fatalf("ZRangeObjectsByScoreWithScores = %v %v, %v", zs, scores, err)
+	}
+	if err = d.ZRevRangeObjects(ctx, "z", 0, 0, &zs); err != nil || len(zs) != 1 || zs[0].F != 2 {
+		t.Fatalf("ZRevRangeObjects = %v, %v", zs, err)
+	}
+	if rank, err := d.ZRevRankObject(ctx, "z", &Foo{F: 3}); err != nil || rank != 2 {
+		t.Fatalf("ZRevRankObject = %d, %v", rank, err)
+	}
+	if _, err = d.ZScoreObject(ctx, "z", &Foo{F: 9}); !errors.Is(err, redis.Nil) {
+		t.Fatalf("ZScoreObject missing = %v", err)
+	}
+	if n, err := d.ZRemObjects(ctx, "z", &Foo{F: 1}, &Foo{F: 9}); err != nil || n != 1 {
+		t.Fatalf("ZRemObjects = %d, %v", n, err)
+	}
+}
+
+func TestDocObjectDBListSetStream(t *testing.T) {
+	ctx := context.Background()
+	d, _ := newDocObjectDB()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := d.RPushObjects(ctx, "l", &Foo{F: i}); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	var foos []Foo
+	if err := d.LRangeObjects(ctx, "l", 0, -1, &foos); err != nil || len(foos) != 20 {
+		t.Fatalf("LRangeObjects = %d, %v", len(foos), err)
+	}
+	for range foos {
+		var foo Foo
+		if err := d.LPopObject(ctx, "l", &foo); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var foo Foo
+	if err := d.BLPopObject(ctx, time.Millisecond, "l", &foo); !errors.Is(err, redis.Nil) {
+		t.Fatalf("BLPopObject timeout = %v", err)
+	}
+	if _, err := d.store.FindOne(ctx, "l"); !errors.Is(err, ErrDocNotFound) {
+		t.Fatalf("empty list not deleted, %v", err)
+	}
+
+	if n, err := d.SAddObjects(ctx, "s", &Foo{F: 1}, &Foo{F: 1}, &Foo{F: 2}); err != nil || n != 2 {
+		t.Fatalf("SAddObjects = %d, %v", n, err)
+	}
+	if ok, err := d.SIsMemberObject(ctx, "s", &Foo{F: 2}); err != nil || !ok {
+		t.Fatalf("SIsMemberObject = %v, %v", ok, err)
+	}
+	if err := d.SPopObject(ctx, "s", &foo); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SMembersObjects(ctx, "s", &foos); err != nil || len(foos) != 1 || foos[0].F == foo.F {
+		t.Fatalf("SMembersObjects = %v, %v", foos, err)
+	}
+
+	id1, _ := d.XAddObject(ctx, "x", &Foo{F: 1})
+	id2, err := d.XAddObject(ctx, "x", &Foo{F: 2})
+	if err != nil || id1 != "1700000000000-0" || id2 != "1700000000000-1" {
+		t.Fatalf("XAddObject = %s %s, %v", id1, id2, err)
+	}
+}
+
+func TestNewDBWithObjectDB(t *testing.T) {
+	ctx := context.Background()
+	rc, _, _ := newMemRedisClient(t)
+	db := NewDBWithObjectDB(NewDocObjectDB(NewMemDocStore(), nil), rc, nil)
+	if err := db.SetObject(ctx, "foo", &Foo{F: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := rc.Exists(ctx, "foo"); err != nil || ok {
+		t.Fatalf("object written into redis, %v %v", ok, err)
+	}
+	var foo Foo
+	if err := db.GetObject(ctx, "foo", &foo); err != nil || foo.F != 1 {
+		t.Fatalf("GetObject = %v, %v", foo, err)
+	}
+	if !db.IsErrNil(db.GetObject(ctx, "bar", &foo)) {
+		t.Fatal("missing object is not redis.Nil")
+	}
+	if _, err := db.GeoAddObjects(ctx, "geo", 13.361389, 38.115556, &Foo{F: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := rc.Exists(ctx, "geo"); err != nil || ok {
+		t.Fatalf("geo set written into redis, %v %v", ok, err)
+	}
+	if poses, err := db.GeoPosObjects(ctx, "geo", &Foo{F: 1}); err != nil || len(poses) != 1 || poses[0] == nil {
+		t.Fatalf("GeoPosObjects = %v, %v", poses, err)
+	}
+}
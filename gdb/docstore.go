@@ -0,0 +1,164 @@
+package gdb
+
+// DocStore is the storage of the document ObjectDB backend
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrDocNotFound is returned by DocStore when the document does not exist
+	ErrDocNotFound = errors.New("ERR_DOC_NOT_FOUND")
+	// ErrDocConflict is returned by DocStore when the document is modified by others since it is read
+	ErrDocConflict = errors.New("ERR_DOC_CONFLICT")
+	// ErrDocWrongKind is returned when the key holds a document of another kind, like WRONGTYPE of redis
+	ErrDocWrongKind = errors.New("ERR_DOC_WRONG_KIND")
+)
+
+// kinds of Doc, one for each redis data type supported by ObjectDB
+const (
+	DocKindString = "string"
+	DocKindHash   = "hash"
+	DocKindZSet   = "zset"
+	DocKindList   = "list"
+	DocKindSet    = "set"
+	DocKindStream = "stream"
+)
+
+// Doc is the document stored for a db key, the bson tags map it to a mongo collection document,
+// a ttl index on expire_at lets mongo remove the expired documents.
+type Doc struct {
+	ID       string            `bson:"_id"`
+	Version  int64             `bson:"v"`
+	Kind     string            `bson:"kind"`
+	Value    []byte            `bson:"value,omitempty"`     // string
+	Fields   map[string][]byte `bson:"fields,omitempty"`    // hash
+	Members  []DocMember       `bson:"members,omitempty"`   // zset in score order, list, set and stream in order
+	ExpireAt time.Time         `bson:"expire_at,omitempty"` // zero means no expiration
+}
+
+// DocMember is a member of a zset, list, set or stream document
+type DocMember struct {
+	ID    string  `bson:"id,omitempty"` // stream message id
+	Value []byte  `bson:"value"`
+	Score float64 `bson:"score,omitempty"` // zset score
+}
+
+func (d *Doc) expired(now time.Time) bool {
+	return !d.ExpireAt.IsZero() && !d.ExpireAt.After(now)
+}
+
+func (d *Doc) empty() bool {
+	switch d.Kind {
+	case DocKindHash:
+		return len(d.Fields) == 0
+	case DocKindZSet, DocKindList, DocKindSet:
+		return len(d.Members) == 0
+	}
+	return false
+}
+
+func (d *Doc) clone() *Doc {
+	c := *d
+	c.Value = bytes.Clone(d.Value)
+	if d.Fields != nil {
+		c.Fields = make(map[string][]byte, len(d.Fields))
+		for k, v := range d.Fields {
+			c.Fields[k] = bytes.Clone(v)
+		}
+	}
+	if d.Members != nil {
+		c.Members = make([]DocMember, len(d.Members))
+		for i, m := range d.Members {
+			c.Members[i] = m
+			c.Members[i].Value = bytes.Clone(m.Value)
+		}
+	}
+	return &c
+}
+
+// DocStore is a collection of Doc with optimistic concurrency on Doc.Version. NewMongoDocStore create it on a
+// mongo collection, NewMemDocStore create the in-process fake for unit tests.
+type DocStore interface {
+	// FindOne return the document of the id, or ErrDocNotFound
+	FindOne(ctx context.Context, id string) (*Doc, error)
+	// FindMany return the existing documents of the ids, in any order
+	FindMany(ctx context.Context, ids []string) ([]*Doc, error)
+	// Insert insert a new document, it returns ErrDocConflict if the id exists
+	Insert(ctx context.Context, doc *Doc) error
+	// Replace replace the document whose version is version, it returns ErrDocConflict if there is not such document
+	Replace(ctx context.Context, doc *Doc, version int64) error
+	// Delete delete the document whose version is version, it returns ErrDocConflict if there is not such document
+	Delete(ctx context.Context, id string, version int64) error
+}
+
+// memDocStore is an in-process DocStore, it is used in unit tests
+type memDocStore struct {
+	mu   sync.Mutex
+	docs map[string]*Doc
+}
+
+// NewMemDocStore create an in-process DocStore, documents are copied in and out so callers never share them
+func NewMemDocStore() DocStore {
+	return &memDocStore{docs: make(map[string]*Doc)}
+}
+
+func (s *memDocStore) FindOne(_ context.Context, id string) (*Doc, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	doc, ok := s.docs[id]
+	if !ok {
+		return nil, ErrDocNotFound
+	}
+	return doc.clone(), nil
+}
+
+func (s *memDocStore) FindMany(_ context.Context, ids []string) ([]*Doc, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	docs := make([]*Doc, 0, len(ids))
+	for _, id := range ids {
+		if doc, ok := s.docs[id]; ok {
+			docs = append(docs, doc.clone())
+		}
+	}
+	sort.Slice(docs, func(i, j int) bool { return docs[i].ID < docs[j].ID })
+	return docs, nil
+}
+
+func (s *memDocStore) Insert(_ context.Context, doc *Doc) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.docs[doc.ID]; ok {
+		return ErrDocConflict
+	}
+	s.docs[doc.ID] = doc.clone()
+	return nil
+}
+
+func (s *memDocStore) Replace(_ context.Context, doc *Doc, version int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, ok := s.docs[doc.ID]
+	if !ok || old.Version != version {
+		return ErrDocConflict
+	}
+	s.docs[doc.ID] = doc.clone()
+	return nil
+}
+
+func (s *memDocStore) Delete(_ context.Context, id string, version int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, ok := s.docs[id]
+	if !ok || old.Version != version {
+		return ErrDocConflict
+	}
+	delete(s.docs, id)
+	return nil
+}
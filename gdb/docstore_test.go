@@ -0,0 +1,74 @@
+package gdb
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemDocStore(t *testing.T) {
+	testDocStore(t, NewMemDocStore())
+}
+
+// testDocStore test the DocStore contract, it is shared by the stores
+func testDocStore(t *testing.T, store DocStore) {
+	ctx := context.Background()
+	if _, err := store.FindOne(ctx, "a"); !errors.Is(err, ErrDocNotFound) {
+		t.Fatalf("FindOne missing = %v", err)
+	}
+	expireAt := time.Unix(1700000000, 0).UTC()
+	a := &Doc{ID: "a", Version: 1, Kind: DocKindString, Value: []byte("1"), ExpireAt: expireAt}
+	if err := store.Insert(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Insert(ctx, a); !errors.Is(err, ErrDocConflict) {
+		t.Fatalf("Insert existing = %v", err)
+	}
+	b := &Doc{ID: "b", Version: 1, Kind: DocKindHash, Fields: map[string][]byte{"f": []byte("v")}}
+	if err := store.Insert(ctx, b); err != nil {
+		t.Fatal(err)
+	}
+
+	a2 := &Doc{ID: "a", Version: 2, Kind: DocKindString, Value: []byte("2")}
+	if err := store.Replace(ctx, a2, 2); !errors.Is(err, ErrDocConflict) {
+		t.Fatalf("Replace of another version = %v", err)
+	}
+	if err := store.Replace(ctx, a2, 1); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := store.FindOne(ctx, "a")
+	if err != nil || doc.Version != 2 || string(doc.Value) != "2" || !doc.ExpireAt.IsZero() {
+		t.Fatalf("FindOne = %+v, %v", doc, err)
+	}
+	if err = store.Replace(ctx, &Doc{ID: "c", Version: 1}, 0); !errors.Is(err, ErrDocConflict) {
+		t.Fatalf("Replace missing = %v", err)
+	}
+
+	docs, err := store.FindMany(ctx, []string{"b", "missing", "a"})
+	if err != nil || len(docs) != 2 {
+		t.Fatalf("FindMany = %v, %v", docs, err)
+	}
+	for _, doc := range docs {
+		if doc.ID == "b" && string(doc.Fields["f"]) != "v" {
+			t.Fatalf("FindMany b = %+v", doc)
+		}
+	}
+
+	if err = store.Delete(ctx, "a", 1); !errors.Is(err, ErrDocConflict) {
+		t.Fatalf("Delete of another version = %v", err)
+	}
+	if err = store.Delete(ctx, "a", 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = store.FindOne(ctx, "a"); !errors.Is(err, ErrDocNotFound) {
+		t.Fatalf("FindOne deleted = %v", err)
+	}
+	// a deleted id can be inserted again
+	if err = store.Insert(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+	if doc, err = store.FindOne(ctx, "a"); err != nil || !doc.ExpireAt.Equal(expireAt) {
+		t.Fatalf("FindOne inserted again = %+v, %v", doc, err)
+	}
+}
@@ -0,0 +1,72 @@
+package gdb
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	gmarshaller "github.com/oldjon/gutil/marshaller"
+)
+
+func TestEnvelopeMarshallerMigration(t *testing.T) {
+	ctx := context.Background()
+	legacy, server, _ := newMemRedisClient(t)
+	if err := legacy.SetObject(ctx, "foo:1", &Foo{F: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := legacy.HSetObjects(ctx, "bar:h", "b1", &Bar{B: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := gmarshaller.NewEnvelopeMarshaller("xml", ""); !errors.Is(err, gmarshaller.ErrUnknownMarshallerType) {
+		t.Fatalf("unknown type = %v", err)
+	}
+	em, err := gmarshaller.NewEnvelopeMarshaller(gmarshaller.MarshallerTypeJSON, gmarshaller.MarshallerTypeJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	strict, err := gmarshaller.NewEnvelopeMarshaller(gmarshaller.MarshallerTypeJSON, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := NewMemRedisClient(server, em)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	r, err := NewKeySchemaRegistry(KeySchema{Prefix: "bar:", Marshaller: strict})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetKeySchemas(r)
+
+	// the legacy value is read and rewritten with the format byte
+	var foo Foo
+	if err = client.UpdateObject(ctx, "foo:1", &foo, func(obj any) error {
+		obj.(*Foo).F++
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := client.Get(ctx, "foo:1")
+	if err != nil || raw[0] != gmarshaller.MarshallerTypeJSONNum {
+		t.Fatalf("raw = %q, %v", raw, err)
+	}
+	if err = client.GetObject(ctx, "foo:1", &foo); err != nil || foo.F != 2 {
+		t.Fatalf("GetObject = %v, %v", foo, err)
+	}
+	// the prefix without a legacy marshaller rejects the legacy values
+	var bar Bar
+	if err = client.HGetObject(ctx, "bar:h", "b1", &bar); !errors.Is(err, gmarshaller.ErrUnmarshalFailed) {
+		t.Fatalf("HGetObject legacy = %v", err)
+	}
+	if want := map[string]uint64{"": 1, "bar:": 0}; !reflect.DeepEqual(client.LegacyReads(), want) {
+		t.Fatalf("LegacyReads = %v, want %v", client.LegacyReads(), want)
+	}
+
+	// the legacy client can not read the new format
+	if err = legacy.GetObject(ctx, "foo:1", &foo); err == nil {
+		t.Fatal("legacy client read the envelope")
+	}
+}
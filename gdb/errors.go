@@ -8,6 +8,34 @@ var (
 	ErrValue       = errors.New("ERR_VALUE")
 	ErrValueType   = errors.New("ERR_VALUE_TYPE")
 	ErrScriptIsNil = errors.New("ERR_SCRIPT_IS_NIL")
+	// ErrScriptNotRegistered is returned when the script of the name is not registered in the ScriptRegistry
+	ErrScriptNotRegistered = errors.New("ERR_SCRIPT_NOT_REGISTERED")
+	// ErrScriptResultType is returned when the result of a script can not be decoded into the type
+	ErrScriptResultType = errors.New("ERR_SCRIPT_RESULT_TYPE")
+	// ErrUpdateConflict is returned when an update still conflicts with others after all the retries
+	ErrUpdateConflict = errors.New("ERR_UPDATE_CONFLICT")
+	// ErrVersionConflict is returned when the version of a versioned object is not the expected one
+	ErrVersionConflict = errors.New("ERR_VERSION_CONFLICT")
+	// ErrPipelineNotExecuted is returned by a Future whose pipeline is not executed yet
+	ErrPipelineNotExecuted = errors.New("ERR_PIPELINE_NOT_EXECUTED")
+	// ErrKeyObjMismatch is returned when the object is not of the type declared for the key
+	ErrKeyObjMismatch = errors.New("ERR_KEY_OBJ_MISMATCH")
+	// ErrKeySchemaInvalid is returned when a key schema can not be registered
+	ErrKeySchemaInvalid = errors.New("ERR_KEY_SCHEMA_INVALID")
+	// ErrBitFieldOverflow is returned by BitField when a SET or INCRBY overflows with OverflowFail
+	ErrBitFieldOverflow = errors.New("ERR_BIT_FIELD_OVERFLOW")
+	// ErrBloomFilterExists is returned by BFReserve when the key exists
+	ErrBloomFilterExists = errors.New("ERR_BLOOM_FILTER_EXISTS")
+	// ErrBloomFilterOptions is returned by BFReserve when the capacity or the error rate is invalid
+	ErrBloomFilterOptions = errors.New("ERR_BLOOM_FILTER_OPTIONS")
+	// ErrCircuitOpen is returned without sending the command when the circuit breaker of the client is open
+	ErrCircuitOpen = errors.New("ERR_CIRCUIT_OPEN")
+	// ErrObjectDBNotRedis is returned by the typed scans of a DB whose ObjectDB is not a RedisClient, since its
+	// objects are not in redis
+	ErrObjectDBNotRedis = errors.New("ERR_OBJECT_DB_NOT_REDIS")
+	// ErrNamespaceUnknownCommand is returned without sending the command when the client has a namespace and the keys
+	// of the command are unknown, so they can not be prefixed
+	ErrNamespaceUnknownCommand = errors.New("ERR_NAMESPACE_UNKNOWN_COMMAND")
 )
 
 var (
@@ -19,7 +47,11 @@ var (
 	PanicValueDstNeedBeAllocated  = "value dst object need be pointer allocated"
 	PanicFieldValueCountUnmatched = "field value count unmatched"
 	PanicFieldsIsMissing          = "field is missing"
+	PanicFieldsIsNil              = "fields is nil"
 	PanicScoreValueCountUnmatched = "score value count unmatched"
+	PanicGeoValueCountUnmatched   = "longitude latitude member count unmatched"
 	PanicHSetUnsupportedValueType = "hset unsupported value type"
 	PanicValueNotNum              = "value is not number"
+	PanicScriptRegistered         = "script name registered"
+	PanicSignInDayOutOfRange      = "sign in day out of range"
 )
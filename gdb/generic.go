@@ -8,6 +8,8 @@ import (
 type Generic interface {
 	IsErrNil(err error) bool
 	Exists(ctx context.Context, key string) (bool, error)
+	// Type return the type of the value of the key, such as string, hash or zset, it is none if the key is missing
+	Type(ctx context.Context, key string) (string, error)
 	TTL(ctx context.Context, key string) (time.Duration, error)
 	Del(ctx context.Context, key string) (uint32, error)
 	Expire(ctx context.Context, key string, expiration time.Duration) (bool, error)
@@ -51,6 +53,10 @@ func (rc *redisClient) Exists(ctx context.Context, key string) (bool, error) {
 	return n == 1, err
 }
 
+func (rc *redisClient) Type(ctx context.Context, key string) (string, error) {
+	return rc.client.Type(ctx, key).Result()
+}
+
 func (rc *redisClient) Expire(ctx context.Context, key string, expiration time.Duration) (bool, error) {
 	ret := rc.client.Expire(ctx, key, expiration)
 	return ret.Result()
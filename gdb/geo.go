@@ -0,0 +1,100 @@
+package gdb
+
+// Funcs handle the redis geo sets, which are zsets scored by the geohashes of the members
+
+import (
+	"context"
+
+	"github.com/go-redis/redis/v8"
+)
+
+type Geo interface {
+	GeoAdd(ctx context.Context, key string, locations ...*redis.GeoLocation) (int64, error)
+	// GeoPos return the positions of the members, nil for the missing ones
+	GeoPos(ctx context.Context, key string, members ...string) ([]*redis.GeoPos, error)
+	// GeoDist return the distance in the unit, m, km, ft or mi, "" is km. It returns redis.Nil if a member is
+	// missing.
+	GeoDist(ctx context.Context, key string, member1, member2, unit string) (float64, error)
+	GeoSearch(ctx context.Context, key string, q *redis.GeoSearchQuery) ([]string, error)
+	// GeoSearchLocation GeoSearch the members with the distances, the coordinates and the geohashes asked by q
+	GeoSearchLocation(ctx context.Context, key string, q *redis.GeoSearchLocationQuery) ([]redis.GeoLocation, error)
+}
+
+func (rc *redisClient) GeoAdd(ctx context.Context, key string, locations ...*redis.GeoLocation) (int64, error) {
+	return rc.client.GeoAdd(ctx, key, locations...).Result()
+}
+
+func (rc *redisClient) GeoPos(ctx context.Context, key string, members ...string) ([]*redis.GeoPos, error) {
+	return rc.client.GeoPos(ctx, key, members...).Result()
+}
+
+func (rc *redisClient) GeoDist(ctx context.Context, key string, member1, member2, unit string) (float64, error) {
+	return rc.client.GeoDist(ctx, key, member1, member2, unit).Result()
+}
+
+func (rc *redisClient) GeoSearch(ctx context.Context, key string, q *redis.GeoSearchQuery) ([]string, error) {
+	return rc.client.GeoSearch(ctx, key, q).Result()
+}
+
+func (rc *redisClient) GeoSearchLocation(ctx context.Context, key string, q *redis.GeoSearchLocationQuery) (
+	[]redis.GeoLocation, error) {
+	return rc.client.GeoSearchLocation(ctx, key, q).Result()
+}
+
+// NearbyQuery is the query of Nearby, the center is the object Member if it is not nil, otherwise the position
+type NearbyQuery struct {
+	Member    any
+	Longitude float64
+	Latitude  float64
+	Radius    float64 // must be positive
+	Unit      string  // m, km, ft or mi, default m
+	Count     int     // the nearest Count objects, 0 for all
+}
+
+// Located is an object found by Nearby, Dist is its distance from the center in the unit of the query
+type Located[PT any] struct {
+	Obj       PT
+	Longitude float64
+	Latitude  float64
+	Dist      float64
+}
+
+// Nearby search the objects added by GeoAddObjects within the radius of the center, the nearest first.
+// The center object is included if it is the Member of the query.
+func Nearby[T any, PT ObjPtr[T]](ctx context.Context, db ObjectDB, key string, q *NearbyQuery) (
+	[]Located[PT], error) {
+	if q.Radius <= 0 {
+		return nil, ErrValue
+	}
+	unit := q.Unit
+	if unit == "" {
+		unit = "m"
+	}
+	query := &redis.GeoSearchLocationQuery{
+		GeoSearchQuery: redis.GeoSearchQuery{Longitude: q.Longitude, Latitude: q.Latitude, Radius: q.Radius,
+			RadiusUnit: unit, Sort: "ASC", Count: q.Count},
+		WithCoord: true,
+		WithDist:  true,
+	}
+	if q.Member != nil {
+		m, err := db.ObjMarshallerOf(key, q.Member)
+		if err != nil {
+			return nil, err
+		}
+		data, err := m.Marshal(q.Member)
+		if err != nil {
+			return nil, err
+		}
+		query.Member = string(data)
+	}
+	var objs []PT
+	locations, err := db.GeoSearchObjects(ctx, key, query, &objs)
+	if err != nil {
+		return nil, err
+	}
+	rets := make([]Located[PT], len(locations))
+	for i, l := range locations {
+		rets[i] = Located[PT]{Obj: objs[i], Longitude: l.Longitude, Latitude: l.Latitude, Dist: l.Dist}
+	}
+	return rets, nil
+}
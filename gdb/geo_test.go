@@ -0,0 +1,148 @@
+package gdb
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+)
+
+func TestGeo(t *testing.T) {
+	client, _, _ := newMemRedisClient(t)
+	ctx := context.Background()
+
+	n, err := client.GeoAdd(ctx, "sicily", &redis.GeoLocation{Name: "Palermo", Longitude: 13.361389, Latitude: 38.115556},
+		&redis.GeoLocation{Name: "Catania", Longitude: 15.087269, Latitude: 37.502669})
+	if err != nil || n != 2 {
+		t.Fatalf("GeoAdd = %d, %v", n, err)
+	}
+	poses, err := client.GeoPos(ctx, "sicily", "Palermo", "Rome")
+	if err != nil || len(poses) != 2 || poses[1] != nil || math.Abs(poses[0].Longitude-13.361389) > 1e-5 {
+		t.Fatalf("GeoPos = %v, %v", poses, err)
+	}
+	if dist, err := client.GeoDist(ctx, "sicily", "Palermo", "Catania", "km"); err != nil || dist != 166.2742 {
+		t.Fatalf("GeoDist = %v, %v", dist, err)
+	}
+	if _, err = client.GeoDist(ctx, "sicily", "Palermo", "Rome", "km"); !errors.Is(err, redis.Nil) {
+		t.Fatalf("GeoDist missing = %v", err)
+	}
+	names, err := client.GeoSearch(ctx, "sicily", &redis.GeoSearchQuery{Longitude: 15, Latitude: 37, Radius: 100,
+		RadiusUnit: "km", Sort: "ASC"})
+	if err != nil || len(names) != 1 || names[0] != "Catania" {
+		t.Fatalf("GeoSearch = %v, %v", names, err)
+	}
+	locations, err := client.GeoSearchLocation(ctx, "sicily", &redis.GeoSearchLocationQuery{
+		GeoSearchQuery: redis.GeoSearchQuery{Member: "Palermo", BoxWidth: 400, BoxHeight: 400, BoxUnit: "km",
+			Sort: "DESC"},
+		WithDist: true,
+	})
+	if err != nil || len(locations) != 2 || locations[0].Name != "Catania" || locations[0].Dist != 166.2742 {
+		t.Fatalf("GeoSearchLocation = %v, %v", locations, err)
+	}
+}
+
+func testGeoObjects(t *testing.T, db ObjectDB) {
+	ctx := context.Background()
+	n, err := db.GeoAddObjects(ctx, "foo:geo", 13.361389, 38.115556, &Foo{F: 1}, 15.087269, 37.502669, &Foo{F: 2},
+		2.352222, 48.856613, &Foo{F: 3})
+	if err != nil || n != 3 {
+		t.Fatalf("GeoAddObjects = %d, %v", n, err)
+	}
+
+	located, err := Nearby[Foo](ctx, db, "foo:geo", &NearbyQuery{Member: &Foo{F: 2}, Radius: 200, Unit: "km"})
+	if err != nil || len(located) != 2 {
+		t.Fatalf("Nearby = %v, %v", located, err)
+	}
+	if located[0].Obj.F != 2 || located[0].Dist != 0 || located[1].Obj.F != 1 || located[1].Dist != 166.2742 ||
+		math.Abs(located[1].Latitude-38.115556) > 1e-5 {
+		t.Fatalf("Nearby = %+v, %+v", located[0], located[1])
+	}
+	located, err = Nearby[Foo](ctx, db, "foo:geo", &NearbyQuery{Longitude: 2.35, Latitude: 48.85, Radius: 5000,
+		Unit: "km", Count: 2})
+	if err != nil || len(located) != 2 || located[0].Obj.F != 3 || located[1].Obj.F != 1 {
+		t.Fatalf("Nearby count = %v, %v", located, err)
+	}
+	if _, err = Nearby[Foo](ctx, db, "foo:geo", &NearbyQuery{Radius: 0}); !errors.Is(err, ErrValue) {
+		t.Fatalf("Nearby zero radius = %v", err)
+	}
+
+	if dist, err := db.GeoDistObjects(ctx, "foo:geo", &Foo{F: 1}, &Foo{F: 2}, "m"); err != nil || dist != 166274.1516 {
+		t.Fatalf("GeoDistObjects = %v, %v", dist, err)
+	}
+	if _, err = db.GeoDistObjects(ctx, "foo:geo", &Foo{F: 1}, &Foo{F: 4}, ""); !errors.Is(err, redis.Nil) {
+		t.Fatalf("GeoDistObjects missing = %v", err)
+	}
+	poses, err := db.GeoPosObjects(ctx, "foo:geo", &Foo{F: 3}, &Foo{F: 4})
+	if err != nil || len(poses) != 2 || poses[1] != nil || math.Abs(poses[0].Latitude-48.856613) > 1e-5 {
+		t.Fatalf("GeoPosObjects = %v, %v", poses, err)
+	}
+
+	var foos []*Foo
+	locations, err := db.GeoSearchObjects(ctx, "foo:geo", &redis.GeoSearchLocationQuery{
+		GeoSearchQuery: redis.GeoSearchQuery{Longitude: 14, Latitude: 38, BoxWidth: 400, BoxHeight: 200,
+			BoxUnit: "km", Sort: "DESC"},
+	}, &foos)
+	if err != nil || len(locations) != 2 || len(foos) != 2 || foos[0].F != 2 || foos[1].F != 1 {
+		t.Fatalf("GeoSearchObjects = %v, %v, %v", locations, foos, err)
+	}
+}
+
+func TestGeoObjects(t *testing.T) {
+	client, _, _ := newMemRedisClient(t)
+	client.SetKOMapping(map[string]string{"foo": "gdb.Foo"})
+	testGeoObjects(t, client)
+}
+
+func TestDocObjectDBGeo(t *testing.T) {
+	d, _ := newDocObjectDB()
+	d.SetKOMapping(map[string]string{"foo": "gdb.Foo"})
+	testGeoObjects(t, d)
+}
+
+func TestPipelineGeoObjects(t *testing.T) {
+	ctx := context.Background()
+	client, _, _ := newMemRedisClient(t)
+	client.SetKOMapping(map[string]string{"foo": "gdb.Foo"})
+
+	pipe := client.Pipeline()
+	added := pipe.GeoAddObjects(ctx, "foo:geo", 13.361389, 38.115556, &Foo{F: 1}, 15.087269, 37.502669, &Foo{F: 2})
+	mismatch := pipe.GeoAddObjects(ctx, "foo:geo", 1, 1, &Bar{B: 1})
+	dist := pipe.GeoDistObjects(ctx, "foo:geo", &Foo{F: 1}, &Foo{F: 2}, "m")
+	poses := pipe.GeoPosObjects(ctx, "foo:geo", &Foo{F: 2}, &Foo{F: 3})
+	var foos, located []*Foo
+	search := pipe.GeoSearchObjects(ctx, "foo:geo", &redis.GeoSearchLocationQuery{
+		GeoSearchQuery: redis.GeoSearchQuery{Longitude: 14, Latitude: 38, BoxWidth: 400, BoxHeight: 200,
+			BoxUnit: "km", Sort: "DESC"},
+	}, &foos)
+	searchDist := pipe.GeoSearchObjects(ctx, "foo:geo", &redis.GeoSearchLocationQuery{
+		GeoSearchQuery: redis.GeoSearchQuery{Member: `{"F":1}`, Radius: 200, RadiusUnit: "km", Sort: "ASC"},
+		WithDist:       true,
+	}, &located)
+	if _, err := pipe.Exec(ctx); !errors.Is(err, ErrKeyObjMismatch) {
+		t.Fatalf("Exec = %v", err)
+	}
+
+	if n, err := added.Result(); err != nil || n != 2 {
+		t.Fatalf("GeoAddObjects = %d, %v", n, err)
+	}
+	if !errors.Is(mismatch.Err(), ErrKeyObjMismatch) {
+		t.Fatalf("GeoAddObjects mismatched = %v", mismatch.Err())
+	}
+	if d, err := dist.Result(); err != nil || d != 166274.1516 {
+		t.Fatalf("GeoDistObjects = %v, %v", d, err)
+	}
+	if p, err := poses.Result(); err != nil || len(p) != 2 || p[1] != nil ||
+		math.Abs(p[0].Latitude-37.502669) > 1e-5 {
+		t.Fatalf("GeoPosObjects = %v, %v", p, err)
+	}
+	if locations, err := search.Result(); err != nil || len(locations) != 2 || len(foos) != 2 || foos[0].F != 2 ||
+		foos[1].F != 1 {
+		t.Fatalf("GeoSearchObjects = %v, %v, %v", locations, foos, err)
+	}
+	if locations, err := searchDist.Result(); err != nil || len(locations) != 2 || locations[1].Dist != 166.2742 ||
+		located[0].F != 1 || located[1].F != 2 {
+		t.Fatalf("GeoSearchObjects with dist = %v, %v, %v", locations, located, err)
+	}
+}
@@ -0,0 +1,99 @@
+// Package ggeohash implements the 52 bit geohashes, the distances and the search areas of the redis geo
+// commands, so the backends of gdb without redis compute the same positions and distances as redis.
+package ggeohash
+
+import (
+	"math"
+	"strings"
+)
+
+const (
+	step        = 26
+	LatMax      = 85.05112878
+	LonMax      = 180.0
+	EarthRadius = 6372797.560856 // in meters
+)
+
+// Valid return whether the position can be encoded, the latitudes near the poles can not
+func Valid(lon, lat float64) bool {
+	return lon >= -LonMax && lon <= LonMax && lat >= -LatMax && lat <= LatMax
+}
+
+// interleave put the bits of x on the even bits and the bits of y on the odd bits
+func interleave(x, y uint32) uint64 {
+	var v uint64
+	for i := 0; i < 32; i++ {
+		v |= uint64(x>>i&1)<<(2*i) | uint64(y>>i&1)<<(2*i+1)
+	}
+	return v
+}
+
+func deinterleave(v uint64) (x, y uint32) {
+	for i := 0; i < 32; i++ {
+		x |= uint32(v>>(2*i)&1) << i
+		y |= uint32(v>>(2*i+1)&1) << i
+	}
+	return x, y
+}
+
+// Encode return the geohash of the valid position, which is the zset score of redis
+func Encode(lon, lat float64) uint64 {
+	latOffset := (lat + LatMax) / (2 * LatMax) * (1 << step)
+	lonOffset := (lon + LonMax) / (2 * LonMax) * (1 << step)
+	return interleave(uint32(latOffset), uint32(lonOffset))
+}
+
+// Decode return the center of the cell of the geohash
+func Decode(hash uint64) (lon, lat float64) {
+	ilat, ilon := deinterleave(hash)
+	latMin := -LatMax + float64(ilat)/(1<<step)*2*LatMax
+	latMax := -LatMax + float64(ilat+1)/(1<<step)*2*LatMax
+	lonMin := -LonMax + float64(ilon)/(1<<step)*2*LonMax
+	lonMax := -LonMax + float64(ilon+1)/(1<<step)*2*LonMax
+	lon = math.Max(-LonMax, math.Min(LonMax, (lonMin+lonMax)/2))
+	lat = math.Max(-LatMax, math.Min(LatMax, (latMin+latMax)/2))
+	return lon, lat
+}
+
+// Distance return the distance of the positions in meters
+func Distance(lon1, lat1, lon2, lat2 float64) float64 {
+	lat1r, lon1r := lat1*math.Pi/180, lon1*math.Pi/180
+	lat2r, lon2r := lat2*math.Pi/180, lon2*math.Pi/180
+	u, v := math.Sin((lat2r-lat1r)/2), math.Sin((lon2r-lon1r)/2)
+	return 2 * EarthRadius * math.Asin(math.Sqrt(u*u+math.Cos(lat1r)*math.Cos(lat2r)*v*v))
+}
+
+// Unit return the meters of the unit, m, km, ft or mi
+func Unit(unit string) (float64, bool) {
+	switch strings.ToLower(unit) {
+	case "m":
+		return 1, true
+	case "km":
+		return 1000, true
+	case "ft":
+		return 0.3048, true
+	case "mi":
+		return 1609.34, true
+	}
+	return 0, false
+}
+
+// Area is the search area of GEOSEARCH around the center, a circle of Radius or a box of Width and Height if
+// Radius is 0, in meters
+type Area struct {
+	Lon, Lat      float64
+	Radius        float64
+	Width, Height float64
+}
+
+// Contains return the distance of the position from the center and whether the position is in the area
+func (a *Area) Contains(lon, lat float64) (float64, bool) {
+	dist := Distance(a.Lon, a.Lat, lon, lat)
+	if a.Radius > 0 {
+		return dist, dist <= a.Radius
+	}
+	if EarthRadius*math.Abs(lat-a.Lat)*math.Pi/180 > a.Height/2 || Distance(a.Lon, lat, lon, lat) > a.Width/2 {
+		return dist, false
+	}
+	return dist, true
+}
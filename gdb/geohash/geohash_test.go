@@ -0,0 +1,40 @@
+package ggeohash
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	// the geohash and the position of Palermo replied by redis
+	if hash := Encode(13.361389, 38.115556); hash != 3479099956230698 {
+		t.Fatalf("Encode = %d", hash)
+	}
+	lon, lat := Decode(3479099956230698)
+	if math.Abs(lon-13.361389338970184) > 1e-12 || math.Abs(lat-38.1155563954963) > 1e-12 {
+		t.Fatalf("Decode = %v, %v", lon, lat)
+	}
+	if Valid(0, 86) || !Valid(-180, -85) {
+		t.Fatal("Valid")
+	}
+}
+
+func TestArea(t *testing.T) {
+	// GEODIST Sicily Palermo Catania is 166274.1516 m and Catania is 56.4413 km from 15,37 in redis
+	lon1, lat1 := Decode(Encode(13.361389, 38.115556))
+	lon2, lat2 := Decode(Encode(15.087269, 37.502669))
+	if d := Distance(lon1, lat1, lon2, lat2); math.Abs(d-166274.1516) > 0.0001 {
+		t.Fatalf("Distance = %v", d)
+	}
+	circle := &Area{Lon: 15, Lat: 37, Radius: 100e3}
+	if d, ok := circle.Contains(lon2, lat2); !ok || math.Abs(d/1000-56.4413) > 0.0001 {
+		t.Fatalf("circle Contains = %v, %v", d, ok)
+	}
+	if _, ok := circle.Contains(lon1, lat1); ok {
+		t.Fatal("circle contains Palermo")
+	}
+	box := &Area{Lon: 15, Lat: 37, Width: 400e3, Height: 400e3}
+	if _, ok := box.Contains(lon1, lat1); !ok {
+		t.Fatal("box does not contain Palermo")
+	}
+}
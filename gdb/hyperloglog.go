@@ -0,0 +1,28 @@
+package gdb
+
+// Funcs handle the redis data type hyperloglog
+
+import (
+	"context"
+)
+
+type HyperLogLog interface {
+	// PFAdd return whether the estimated cardinality is changed
+	PFAdd(ctx context.Context, key string, els ...any) (bool, error)
+	// PFCount return the estimated cardinality of the union of the keys
+	PFCount(ctx context.Context, keys ...string) (int64, error)
+	PFMerge(ctx context.Context, dest string, keys ...string) error
+}
+
+func (rc *redisClient) PFAdd(ctx context.Context, key string, els ...any) (bool, error) {
+	n, err := rc.client.PFAdd(ctx, key, els...).Result()
+	return n == 1, err
+}
+
+func (rc *redisClient) PFCount(ctx context.Context, keys ...string) (int64, error) {
+	return rc.client.PFCount(ctx, keys...).Result()
+}
+
+func (rc *redisClient) PFMerge(ctx context.Context, dest string, keys ...string) error {
+	return rc.client.PFMerge(ctx, dest, keys...).Err()
+}
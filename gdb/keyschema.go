@@ -0,0 +1,197 @@
+package gdb
+
+// Funcs declare the object types, ttls and marshallers of the keys by their prefixes
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	gmarshaller "github.com/oldjon/gutil/marshaller"
+)
+
+// KeySchema declare the objects stored in the keys with the prefix
+type KeySchema struct {
+	Prefix string // for example "player:", the longest registered prefix of a key is used
+	// Type is the object type, a struct or a pointer to struct, for example reflect.TypeOf(Player{}).
+	// The objects of the object methods must be of the type, or slices of it. nil means any type.
+	Type reflect.Type
+	// DefaultTTL is the expiration set by SetObject and SetObjects, 0 means no expiration
+	DefaultTTL time.Duration
+	// Marshaller marshal the objects of the keys, nil means the marshaller of the db. Use a
+	// gmarshaller.EnvelopeMarshaller to switch the format of the keys without rewriting them.
+	// The stream messages are always marshalled by the marshaller of the db.
+	Marshaller gmarshaller.Marshaller
+}
+
+// KeySchemaRegistry hold the key schemas, it is passed to NewDB as the KOMapping, or set by ObjectDB.SetKeySchemas
+type KeySchemaRegistry struct {
+	mu      sync.RWMutex
+	schemas []*KeySchema // sorted by prefix length desc
+}
+
+// NewKeySchemaRegistry create a registry with the schemas
+func NewKeySchemaRegistry(schemas ...KeySchema) (*KeySchemaRegistry, error) {
+	r := &KeySchemaRegistry{}
+	for _, s := range schemas {
+		if err := r.Register(s); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Register add the schema, it returns ErrKeySchemaInvalid if the prefix is empty or registered, Type is
+// not a struct, or neither Type nor Marshaller is set
+func (r *KeySchemaRegistry) Register(schema KeySchema) error {
+	if schema.Prefix == "" {
+		return fmt.Errorf("%w: empty prefix", ErrKeySchemaInvalid)
+	}
+	if schema.Type == nil && schema.Marshaller == nil {
+		return fmt.Errorf("%w: neither type nor marshaller of prefix %s is set", ErrKeySchemaInvalid, schema.Prefix)
+	}
+	if schema.Type != nil && objectType(schema.Type).Kind() != reflect.Struct {
+		return fmt.Errorf("%w: type of prefix %s is %v, not a struct", ErrKeySchemaInvalid, schema.Prefix, schema.Type)
+	}
+	schema.Type = objectType(schema.Type)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, s := range r.schemas {
+		if s.Prefix == schema.Prefix {
+			return fmt.Errorf("%w: prefix %s is registered", ErrKeySchemaInvalid, schema.Prefix)
+		}
+	}
+	r.schemas = append(r.schemas, &schema)
+	sort.SliceStable(r.schemas, func(i, j int) bool {
+		return len(r.schemas[i].Prefix) > len(r.schemas[j].Prefix)
+	})
+	return nil
+}
+
+// Lookup return the schema of the longest registered prefix of the key
+func (r *KeySchemaRegistry) Lookup(key string) (*KeySchema, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, s := range r.schemas {
+		if strings.HasPrefix(key, s.Prefix) {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// Mapping return the prefix -> type name mapping, so the registry is a KOMapping
+func (r *KeySchemaRegistry) Mapping() map[string]string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	mapping := make(map[string]string, len(r.schemas))
+	for _, s := range r.schemas {
+		if s.Type != nil {
+			mapping[s.Prefix] = s.Type.String()
+		}
+	}
+	return mapping
+}
+
+// objectType unwrap the pointers, slices and arrays of t
+func objectType(t reflect.Type) reflect.Type {
+	for t != nil {
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Array:
+			t = t.Elem()
+		default:
+			return t
+		}
+	}
+	return nil
+}
+
+// objectSchemas check the objects of the keys by the key schemas, or by the legacy KOMapping type names
+type objectSchemas struct {
+	koMapping map[string]string
+	schemas   *KeySchemaRegistry
+}
+
+// SetKOMapping set the legacy key prefix -> type name mapping, the prefix is the part of the key before ':'
+func (s *objectSchemas) SetKOMapping(mapping map[string]string) {
+	s.koMapping = buildKOMapping(mapping)
+}
+
+func (s *objectSchemas) SetKeySchemas(registry *KeySchemaRegistry) {
+	s.schemas = registry
+}
+
+// CheckKeyObjMatch return ErrKeyObjMismatch if obj is not of the type of the key
+func (s *objectSchemas) CheckKeyObjMatch(key string, obj any) error {
+	_, err := s.schemaOf(key, obj)
+	return err
+}
+
+// schemaOf check obj and return the schema of the key, it is nil if the key is not registered
+func (s *objectSchemas) schemaOf(key string, obj any) (*KeySchema, error) {
+	if s.schemas != nil {
+		schema, ok := s.schemas.Lookup(key)
+		if !ok {
+			return nil, nil
+		}
+		if schema.Type == nil {
+			return schema, nil
+		}
+		// the type of the elements of []any can not be told here
+		if t := objectType(reflect.TypeOf(obj)); t != nil && t.Kind() != reflect.Interface && t != schema.Type {
+			return nil, fmt.Errorf("%w: key %s of type %v, object of type %v",
+				ErrKeyObjMismatch, key, schema.Type, t)
+		}
+		return schema, nil
+	}
+	if err := checkKeyObjMatch(s.koMapping, key, obj); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// marshallerOf check obj and return the marshaller of the key, def if the schema has no marshaller
+func (s *objectSchemas) marshallerOf(key string, obj any, def gmarshaller.Marshaller) (gmarshaller.Marshaller, error) {
+	schema, err := s.schemaOf(key, obj)
+	if err != nil {
+		return nil, err
+	}
+	if schema != nil && schema.Marshaller != nil {
+		return schema.Marshaller, nil
+	}
+	return def, nil
+}
+
+// legacyReads report the values read without the envelope byte by the EnvelopeMarshallers, by the prefix of the
+// keys, "" is the marshaller of the db
+func (s *objectSchemas) legacyReads(def gmarshaller.Marshaller) map[string]uint64 {
+	reads := make(map[string]uint64)
+	if em, ok := def.(*gmarshaller.EnvelopeMarshaller); ok {
+		reads[""] = em.LegacyReads()
+	}
+	if s.schemas == nil {
+		return reads
+	}
+	s.schemas.mu.RLock()
+	defer s.schemas.mu.RUnlock()
+	for _, schema := range s.schemas.schemas {
+		if em, ok := schema.Marshaller.(*gmarshaller.EnvelopeMarshaller); ok {
+			reads[schema.Prefix] = em.LegacyReads()
+		}
+	}
+	return reads
+}
+
+// defaultTTL return the default ttl of the key, 0 if not set
+func (s *objectSchemas) defaultTTL(key string) time.Duration {
+	if s.schemas == nil {
+		return 0
+	}
+	if schema, ok := s.schemas.Lookup(key); ok {
+		return schema.DefaultTTL
+	}
+	return 0
+}
@@ -0,0 +1,115 @@
+package gdb
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	gmarshaller "github.com/oldjon/gutil/marshaller"
+)
+
+// prefixMarshaller mark the json data with a leading byte
+type prefixMarshaller struct {
+	gmarshaller.JsonMarshaller
+}
+
+func (m *prefixMarshaller) Marshal(v any) ([]byte, error) {
+	data, err := m.JsonMarshaller.Marshal(v)
+	return append([]byte{'#'}, data...), err
+}
+
+func (m *prefixMarshaller) Unmarshal(data []byte, v any) error {
+	if len(data) == 0 || data[0] != '#' {
+		return ErrValue
+	}
+	return m.JsonMarshaller.Unmarshal(data[1:], v)
+}
+
+func TestKeySchemaRegistry(t *testing.T) {
+	if _, err := NewKeySchemaRegistry(KeySchema{Prefix: "foo:", Type: reflect.TypeOf(0)}); !errors.Is(err,
+		ErrKeySchemaInvalid) {
+		t.Fatalf("non struct type = %v", err)
+	}
+	r, err := NewKeySchemaRegistry(
+		KeySchema{Prefix: "foo:", Type: reflect.TypeOf(&Foo{})},
+		KeySchema{Prefix: "foo:bar:", Type: reflect.TypeOf(Bar{})},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = r.Register(KeySchema{Prefix: "foo:", Type: reflect.TypeOf(Bar{})}); !errors.Is(err, ErrKeySchemaInvalid) {
+		t.Fatalf("duplicated prefix = %v", err)
+	}
+	if s, ok := r.Lookup("foo:bar:1"); !ok || s.Type != reflect.TypeOf(Bar{}) {
+		t.Fatalf("Lookup longest prefix = %v, %v", s, ok)
+	}
+	if s, ok := r.Lookup("foo:1"); !ok || s.Type != reflect.TypeOf(Foo{}) {
+		t.Fatalf("Lookup = %v, %v", s, ok)
+	}
+	if _, ok := r.Lookup("baz:1"); ok {
+		t.Fatal("Lookup unregistered prefix found")
+	}
+}
+
+func TestKeySchemaObjects(t *testing.T) {
+	client, _, _ := newMemRedisClient(t)
+	ctx := context.Background()
+	r, err := NewKeySchemaRegistry(
+		KeySchema{Prefix: "foo:", Type: reflect.TypeOf(Foo{}), DefaultTTL: time.Minute},
+		KeySchema{Prefix: "bar:", Type: reflect.TypeOf(Bar{}), Marshaller: &prefixMarshaller{}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetKeySchemas(r)
+
+	// a type of the same name is told apart by its reflect.Type
+	{
+		type Foo struct {
+			F int
+		}
+		if err = client.SetObject(ctx, "foo:1", &Foo{F: 1}); !errors.Is(err, ErrKeyObjMismatch) {
+			t.Fatalf("SetObject same named type = %v", err)
+		}
+	}
+	if _, err = client.LPushObjects(ctx, "foo:l", []*Bar{{B: 1}}); !errors.Is(err, ErrKeyObjMismatch) {
+		t.Fatalf("LPushObjects mismatched = %v", err)
+	}
+
+	// SetObject and SetObjects apply the default ttl
+	if err = client.SetObject(ctx, "foo:1", &Foo{F: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if ttl, err := client.TTL(ctx, "foo:1"); err != nil || ttl != time.Minute {
+		t.Fatalf("TTL = %v, %v", ttl, err)
+	}
+	if err = client.SetObjects(ctx, []string{"foo:2", "bar:2"}, []any{&Foo{F: 2}, &Bar{B: 2}}); err != nil {
+		t.Fatal(err)
+	}
+	if ttl, err := client.TTL(ctx, "foo:2"); err != nil || ttl != time.Minute {
+		t.Fatalf("TTL SetObjects = %v, %v", ttl, err)
+	}
+	if ttl, _ := client.TTL(ctx, "bar:2"); ttl > 0 {
+		t.Fatalf("TTL without default = %v", ttl)
+	}
+
+	// the objects of bar: are marshalled by the marshaller of the schema
+	if err = client.SetObject(ctx, "bar:1", &Bar{B: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if raw, err := client.Get(ctx, "bar:1"); err != nil || !strings.HasPrefix(raw, "#") {
+		t.Fatalf("raw = %q, %v", raw, err)
+	}
+	bar, err := Get[Bar](ctx, client, "bar:1")
+	if err != nil || bar.B != 3 {
+		t.Fatalf("Get = %v, %v", bar, err)
+	}
+	objs := []any{&Foo{}, &Bar{}}
+	if err = client.GetObjects(ctx, []string{"foo:2", "bar:2"}, objs); err != nil ||
+		objs[0].(*Foo).F != 2 || objs[1].(*Bar).B != 2 {
+		t.Fatalf("GetObjects = %v, %v", objs, err)
+	}
+}
@@ -0,0 +1,73 @@
+package gdb
+
+// Funcs handle the redis data type list
+
+import (
+	"context"
+	"time"
+)
+
+type List interface {
+	LPush(ctx context.Context, key string, values ...any) (int64, error)
+	RPush(ctx context.Context, key string, values ...any) (int64, error)
+	LPop(ctx context.Context, key string) (string, error)
+	RPop(ctx context.Context, key string) (string, error)
+	// BLPop returns the key and the value popped, it returns redis.Nil when timeout.
+	BLPop(ctx context.Context, timeout time.Duration, keys ...string) ([]string, error)
+	// BRPop returns the key and the value popped, it returns redis.Nil when timeout.
+	BRPop(ctx context.Context, timeout time.Duration, keys ...string) ([]string, error)
+	LRange(ctx context.Context, key string, start, stop int64) ([]string, error)
+	LLen(ctx context.Context, key string) (int64, error)
+	LIndex(ctx context.Context, key string, index int64) (string, error)
+	LRem(ctx context.Context, key string, count int64, value any) (int64, error)
+	LTrim(ctx context.Context, key string, start, stop int64) error
+	RPopLPush(ctx context.Context, source, destination string) (string, error)
+}
+
+func (rc *redisClient) LPush(ctx context.Context, key string, values ...any) (int64, error) {
+	return rc.client.LPush(ctx, key, values...).Result()
+}
+
+func (rc *redisClient) RPush(ctx context.Context, key string, values ...any) (int64, error) {
+	return rc.client.RPush(ctx, key, values...).Result()
+}
+
+func (rc *redisClient) LPop(ctx context.Context, key string) (string, error) {
+	return rc.client.LPop(ctx, key).Result()
+}
+
+func (rc *redisClient) RPop(ctx context.Context, key string) (string, error) {
+	return rc.client.RPop(ctx, key).Result()
+}
+
+func (rc *redisClient) BLPop(ctx context.Context, timeout time.Duration, keys ...string) ([]string, error) {
+	return rc.client.BLPop(ctx, timeout, keys...).Result()
+}
+
+func (rc *redisClient) BRPop(ctx context.Context, timeout time.Duration, keys ...string) ([]string, error) {
+	return rc.client.BRPop(ctx, timeout, keys...).Result()
+}
+
+func (rc *redisClient) LRange(ctx context.Context, key string, start, stop int64) ([]string, error) {
+	return rc.client.LRange(ctx, key, start, stop).Result()
+}
+
+func (rc *redisClient) LLen(ctx context.Context, key string) (int64, error) {
+	return rc.client.LLen(ctx, key).Result()
+}
+
+func (rc *redisClient) LIndex(ctx context.Context, key string, index int64) (string, error) {
+	return rc.client.LIndex(ctx, key, index).Result()
+}
+
+func (rc *redisClient) LRem(ctx context.Context, key string, count int64, value any) (int64, error) {
+	return rc.client.LRem(ctx, key, count, value).Result()
+}
+
+func (rc *redisClient) LTrim(ctx context.Context, key string, start, stop int64) error {
+	return rc.client.LTrim(ctx, key, start, stop).Err()
+}
+
+func (rc *redisClient) RPopLPush(ctx context.Context, source, destination string) (string, error) {
+	return rc.client.RPopLPush(ctx, source, destination).Result()
+}
@@ -0,0 +1,268 @@
+package memredis
+
+import (
+	"math"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+const maxBitOffset = 4*1024*1024*1024 - 1 // 512MB strings like redis
+
+var errBitOffset = errorReply("ERR bit offset is not an integer or out of range")
+
+func registerBitmapCommands() {
+	register("setbit", 4, cmdSetBit)
+	register("getbit", 3, cmdGetBit)
+	register("bitcount", -2, cmdBitCount)
+	register("bitfield", -2, cmdBitField)
+}
+
+func parseBitOffset(s string) (int64, errorReply) {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 || n > maxBitOffset {
+		return 0, errBitOffset
+	}
+	return n, ""
+}
+
+// getBits read width bits from the bit offset as an unsigned number, bit 0 is the highest bit of the first byte
+func getBits(buf []byte, offset int64, width int) uint64 {
+	var v uint64
+	for i := int64(0); i < int64(width); i++ {
+		v <<= 1
+		if byteIdx := (offset + i) / 8; byteIdx < int64(len(buf)) && buf[byteIdx]&(0x80>>((offset+i)%8)) != 0 {
+			v |= 1
+		}
+	}
+	return v
+}
+
+// setBits write the lowest width bits of v at the bit offset, the buffer grows with zero bytes as needed
+func setBits(buf []byte, offset int64, width int, v uint64) []byte {
+	if need := (offset + int64(width) + 7) / 8; need > int64(len(buf)) {
+		buf = append(buf, make([]byte, need-int64(len(buf)))...)
+	}
+	for i := int64(width) - 1; i >= 0; i-- {
+		mask := byte(0x80 >> ((offset + i) % 8))
+		if v&1 != 0 {
+			buf[(offset+i)/8] |= mask
+		} else {
+			buf[(offset+i)/8] &^= mask
+		}
+		v >>= 1
+	}
+	return buf
+}
+
+func cmdSetBit(ctx *cmdContext, args []string) any {
+	offset, errReply := parseBitOffset(args[2])
+	if errReply != "" {
+		return errReply
+	}
+	if args[3] != "0" && args[3] != "1" {
+		return errorReply("ERR bit is not an integer or out of range")
+	}
+	v, _, errType := ctx.getString(args[1])
+	if errType != "" {
+		return errType
+	}
+	buf := []byte(v)
+	old := getBits(buf, offset, 1)
+	ctx.setStringKeepTTL(args[1], string(setBits(buf, offset, 1, uint64(args[3][0]-'0'))))
+	return int64(old)
+}
+
+func cmdGetBit(ctx *cmdContext, args []string) any {
+	offset, errReply := parseBitOffset(args[2])
+	if errReply != "" {
+		return errReply
+	}
+	v, _, errType := ctx.getString(args[1])
+	if errType != "" {
+		return errType
+	}
+	return int64(getBits([]byte(v), offset, 1))
+}
+
+// cmdBitCount count the set bits of the string, the range is in bytes or in bits with BIT
+func cmdBitCount(ctx *cmdContext, args []string) any {
+	if len(args) != 2 && len(args) != 4 && len(args) != 5 {
+		return errSyntax
+	}
+	v, _, errType := ctx.getString(args[1])
+	if errType != "" {
+		return errType
+	}
+	buf := []byte(v)
+	if len(args) == 2 {
+		var n int64
+		for _, b := range buf {
+			n += int64(bits.OnesCount8(b))
+		}
+		return n
+	}
+	start, err1 := strconv.ParseInt(args[2], 10, 64)
+	end, err2 := strconv.ParseInt(args[3], 10, 64)
+	if err1 != nil || err2 != nil {
+		return errNotInt
+	}
+	unit := int64(8)
+	if len(args) == 5 {
+		switch strings.ToLower(args[4]) {
+		case "byte":
+		case "bit":
+			unit = 1
+		default:
+			return errSyntax
+		}
+	}
+	size := int64(len(buf)) * 8 / unit
+	if start < 0 {
+		start = max(start+size, 0)
+	}
+	if end < 0 {
+		end += size
+	}
+	end = min(end, size-1)
+	var n int64
+	for i := start * unit; i <= end*unit+unit-1; i++ {
+		n += int64(getBits(buf, i, 1))
+	}
+	return n
+}
+
+// bitFieldType is a type of BITFIELD such as i5 or u8
+type bitFieldType struct {
+	signed bool
+	width  int
+}
+
+func parseBitFieldType(s string) (bitFieldType, bool) {
+	if len(s) < 2 || (s[0] != 'i' && s[0] != 'u') {
+		return bitFieldType{}, false
+	}
+	width, err := strconv.Atoi(s[1:])
+	t := bitFieldType{signed: s[0] == 'i', width: width}
+	if err != nil || width < 1 || width > 64 || (!t.signed && width > 63) {
+		return bitFieldType{}, false
+	}
+	return t, true
+}
+
+// value convert the raw bits into the number of the type
+func (t bitFieldType) value(raw uint64) int64 {
+	if t.signed && t.width < 64 && raw&(1<<(t.width-1)) != 0 {
+		return int64(raw | ^uint64(0)<<t.width)
+	}
+	return int64(raw)
+}
+
+func (t bitFieldType) bounds() (int64, int64) {
+	if t.signed {
+		if t.width == 64 {
+			return math.MinInt64, math.MaxInt64
+		}
+		return -1 << (t.width - 1), 1<<(t.width-1) - 1
+	}
+	return 0, 1<<t.width - 1
+}
+
+// fit apply the overflow mode to the result of cur+incr, ok is false on overflow with FAIL
+func (t bitFieldType) fit(cur, incr int64, overflow string) (int64, bool) {
+	lo, hi := t.bounds()
+	sum := cur + incr
+	over := (incr > 0 && (sum > hi || sum < cur)) || (incr < 0 && (sum < lo || sum > cur))
+	if !over {
+		return sum, true
+	}
+	switch overflow {
+	case "sat":
+		if incr > 0 {
+			return hi, true
+		}
+		return lo, true
+	case "fail":
+		return 0, false
+	default:
+		mask := ^uint64(0)
+		if t.width < 64 {
+			mask = 1<<t.width - 1
+		}
+		return t.value(uint64(sum) & mask), true
+	}
+}
+
+// cmdBitField support GET, SET, INCRBY and OVERFLOW, the offsets can be prefixed with # to multiply by the width
+func cmdBitField(ctx *cmdContext, args []string) any {
+	v, _, errType := ctx.getString(args[1])
+	if errType != "" {
+		return errType
+	}
+	buf := []byte(v)
+	overflow, changed := "wrap", false
+	rets := make([]any, 0)
+	for i := 2; i < len(args); {
+		op := strings.ToLower(args[i])
+		if op == "overflow" {
+			if i+1 >= len(args) {
+				return errSyntax
+			}
+			overflow = strings.ToLower(args[i+1])
+			if overflow != "wrap" && overflow != "sat" && overflow != "fail" {
+				return errorReply("ERR Invalid OVERFLOW type specified")
+			}
+			i += 2
+			continue
+		}
+		n := map[string]int{"get": 3, "set": 4, "incrby": 4}[op]
+		if n == 0 || i+n > len(args) {
+			return errSyntax
+		}
+		t, ok := parseBitFieldType(args[i+1])
+		if !ok {
+			return errorReply("ERR Invalid bitfield type. Use something like i16 u8. " +
+				"Note that u64 is not supported but i64 is.")
+		}
+		offArg, mult := args[i+2], int64(1)
+		if strings.HasPrefix(offArg, "#") {
+			offArg, mult = offArg[1:], int64(t.width)
+		}
+		offset, errReply := parseBitOffset(offArg)
+		if errReply != "" || offset*mult+int64(t.width)-1 > maxBitOffset {
+			return errBitOffset
+		}
+		offset *= mult
+		cur := t.value(getBits(buf, offset, t.width))
+		switch op {
+		case "get":
+			rets = append(rets, cur)
+		default:
+			arg, err := strconv.ParseInt(args[i+3], 10, 64)
+			if err != nil {
+				return errNotInt
+			}
+			var next int64
+			if op == "set" {
+				next, ok = t.fit(0, arg, overflow)
+			} else {
+				next, ok = t.fit(cur, arg, overflow)
+			}
+			if !ok {
+				rets = append(rets, nil)
+				break
+			}
+			buf, changed = setBits(buf, offset, t.width, uint64(next)), true
+			if op == "set" {
+				rets = append(rets, cur)
+			} else {
+				rets = append(rets, next)
+			}
+		}
+		i += n
+	}
+	if changed {
+		ctx.setStringKeepTTL(args[1], string(buf))
+	}
+	return rets
+}
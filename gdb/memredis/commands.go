@@ -0,0 +1,334 @@
+package memredis
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+type cmdFunc func(ctx *cmdContext, args []string) any
+
+type cmdSpec struct {
+	fn     cmdFunc
+	arity  int  // same as redis, negative means at least -arity arguments
+	tx     bool // executed immediately even inside MULTI
+	pubsub bool // allowed in subscribed state
+}
+
+func (spec cmdSpec) checkArity(n int) bool {
+	if spec.arity >= 0 {
+		return n == spec.arity
+	}
+	return n >= -spec.arity
+}
+
+var commands map[string]cmdSpec
+
+func register(name string, arity int, fn cmdFunc) {
+	commands[name] = cmdSpec{fn: fn, arity: arity}
+}
+
+func init() {
+	commands = make(map[string]cmdSpec)
+
+	// connection
+	commands["ping"] = cmdSpec{fn: cmdPing, arity: -1, pubsub: true}
+	register("echo", 2, func(_ *cmdContext, args []string) any { return args[1] })
+	register("select", 2, cmdSelect)
+	register("auth", -2, func(*cmdContext, []string) any { return replyOK })
+	register("client", -2, func(*cmdContext, []string) any { return replyOK })
+	register("readonly", 1, func(*cmdContext, []string) any { return replyOK })
+	commands["quit"] = cmdSpec{fn: func(*cmdContext, []string) any { return replyOK }, arity: 1, pubsub: true}
+	register("flushdb", -1, cmdFlushDB)
+	register("flushall", -1, cmdFlushAll)
+	register("dbsize", 1, cmdDBSize)
+	register("time", 1, cmdTime)
+
+	// generic
+	register("del", -2, cmdDel)
+	register("unlink", -2, cmdDel)
+	register("exists", -2, cmdExists)
+	register("type", 2, cmdType)
+	register("keys", 2, cmdKeys)
+	register("ttl", 2, cmdTTL)
+	register("pttl", 2, cmdTTL)
+	register("expire", -3, cmdExpire)
+	register("pexpire", -3, cmdExpire)
+	register("expireat", -3, cmdExpire)
+	register("pexpireat", -3, cmdExpire)
+	register("persist", 2, cmdPersist)
+
+	registerStringCommands()
+	registerHashCommands()
+	registerZSetCommands()
+	registerListCommands()
+	registerSetCommands()
+	registerStreamCommands()
+	registerScriptCommands()
+	registerTxCommands()
+	registerPubSubCommands()
+	registerScanCommands()
+	registerBitmapCommands()
+	registerHyperLogLogCommands()
+	registerGeoCommands()
+	register("config", -2, cmdConfig)
+}
+
+func cmdPing(ctx *cmdContext, args []string) any {
+	if ctx.c.subscribed() {
+		msg := ""
+		if len(args) > 1 {
+			msg = args[1]
+		}
+		return []any{"pong", msg}
+	}
+	if len(args) > 1 {
+		return args[1]
+	}
+	return statusReply("PONG")
+}
+
+func cmdSelect(ctx *cmdContext, args []string) any {
+	n, err := strconv.Atoi(args[1])
+	if err != nil {
+		return errNotInt
+	}
+	if n < 0 || n >= len(ctx.s.dbs) {
+		return errorReply("ERR DB index is out of range")
+	}
+	ctx.c.db = n
+	return replyOK
+}
+
+func cmdFlushDB(ctx *cmdContext, _ []string) any {
+	ctx.db.flush()
+	return replyOK
+}
+
+func cmdFlushAll(ctx *cmdContext, _ []string) any {
+	for _, d := range ctx.s.dbs {
+		d.flush()
+	}
+	return replyOK
+}
+
+// cmdTime reply the clock of the server as redis does, the unix seconds and the microseconds in the second
+func cmdTime(ctx *cmdContext, _ []string) any {
+	us := ctx.now.UnixMicro()
+	return []any{strconv.FormatInt(us/1e6, 10), strconv.FormatInt(us%1e6, 10)}
+}
+
+func cmdDBSize(ctx *cmdContext, _ []string) any {
+	var n int64
+	for k := range ctx.db.data {
+		if ctx.lookup(k) != nil {
+			n++
+		}
+	}
+	return n
+}
+
+func cmdDel(ctx *cmdContext, args []string) any {
+	var n int64
+	for _, key := range args[1:] {
+		if ctx.lookup(key) != nil && ctx.db.del(key) {
+			ctx.s.notify(ctx.db.index, 'g', "del", key)
+			n++
+		}
+	}
+	return n
+}
+
+func cmdExists(ctx *cmdContext, args []string) any {
+	var n int64
+	for _, key := range args[1:] {
+		if ctx.lookup(key) != nil {
+			n++
+		}
+	}
+	return n
+}
+
+func cmdType(ctx *cmdContext, args []string) any {
+	e := ctx.lookup(args[1])
+	if e == nil {
+		return statusReply("none")
+	}
+	return statusReply(typeName(e.value))
+}
+
+func typeName(v any) string {
+	switch v.(type) {
+	case string:
+		return "string"
+	case hashValue:
+		return "hash"
+	case *zset:
+		return "zset"
+	case *listValue:
+		return "list"
+	case setValue:
+		return "set"
+	case *stream:
+		return "stream"
+	default:
+		return "none"
+	}
+}
+
+func cmdKeys(ctx *cmdContext, args []string) any {
+	keys := make([]string, 0)
+	for k := range ctx.db.data {
+		if ctx.lookup(k) != nil && matchPattern(args[1], k) {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+func cmdTTL(ctx *cmdContext, args []string) any {
+	e := ctx.lookup(args[1])
+	if e == nil {
+		return int64(-2)
+	}
+	if e.expireAt.IsZero() {
+		return int64(-1)
+	}
+	left := e.expireAt.Sub(ctx.now)
+	if strings.ToLower(args[0]) == "pttl" {
+		return left.Milliseconds()
+	}
+	return int64((left + 500*time.Millisecond) / time.Second)
+}
+
+func cmdExpire(ctx *cmdContext, args []string) any {
+	n, err := strconv.ParseInt(args[2], 10, 64)
+	if err != nil {
+		return errNotInt
+	}
+	var at time.Time
+	switch strings.ToLower(args[0]) {
+	case "expire":
+		at = ctx.now.Add(time.Duration(n) * time.Second)
+	case "pexpire":
+		at = ctx.now.Add(time.Duration(n) * time.Millisecond)
+	case "expireat":
+		at = time.Unix(n, 0)
+	case "pexpireat":
+		at = time.UnixMilli(n)
+	}
+
+	e := ctx.lookup(args[1])
+	if e == nil {
+		return int64(0)
+	}
+	for _, opt := range args[3:] {
+		switch strings.ToLower(opt) {
+		case "nx":
+			if !e.expireAt.IsZero() {
+				return int64(0)
+			}
+		case "xx":
+			if e.expireAt.IsZero() {
+				return int64(0)
+			}
+		case "gt":
+			if e.expireAt.IsZero() || !at.After(e.expireAt) {
+				return int64(0)
+			}
+		case "lt":
+			if !e.expireAt.IsZero() && !at.Before(e.expireAt) {
+				return int64(0)
+			}
+		default:
+			return errSyntax
+		}
+	}
+	if !ctx.now.Before(at) {
+		ctx.db.del(args[1])
+		ctx.s.notify(ctx.db.index, 'g', "del", args[1])
+		return int64(1)
+	}
+	e.expireAt = at
+	ctx.db.touch(args[1])
+	ctx.s.notify(ctx.db.index, 'g', "expire", args[1])
+	return int64(1)
+}
+
+func cmdPersist(ctx *cmdContext, args []string) any {
+	e := ctx.lookup(args[1])
+	if e == nil || e.expireAt.IsZero() {
+		return int64(0)
+	}
+	e.expireAt = time.Time{}
+	ctx.db.touch(args[1])
+	return int64(1)
+}
+
+// matchPattern report whether str matches the glob style pattern used by KEYS and SCAN
+func matchPattern(pattern, str string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(str); i++ {
+				if matchPattern(pattern[1:], str[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(str) == 0 {
+				return false
+			}
+			str = str[1:]
+			pattern = pattern[1:]
+		case '[':
+			if len(str) == 0 {
+				return false
+			}
+			end := strings.IndexByte(pattern[1:], ']')
+			if end < 0 {
+				return false
+			}
+			class := pattern[1 : end+1]
+			negate := len(class) > 0 && class[0] == '^'
+			if negate {
+				class = class[1:]
+			}
+			matched := false
+			for i := 0; i < len(class); i++ {
+				if i+2 < len(class) && class[i+1] == '-' {
+					if class[i] <= str[0] && str[0] <= class[i+2] {
+						matched = true
+					}
+					i += 2
+				} else if class[i] == str[0] {
+					matched = true
+				}
+			}
+			if matched == negate {
+				return false
+			}
+			str = str[1:]
+			pattern = pattern[end+2:]
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(str) == 0 || str[0] != pattern[0] {
+				return false
+			}
+			str = str[1:]
+			pattern = pattern[1:]
+		}
+	}
+	return len(str) == 0
+}
@@ -0,0 +1,224 @@
+package memredis
+
+import (
+	"time"
+)
+
+type entry struct {
+	value    any // string, hashValue, *zset, *listValue, setValue or *stream
+	expireAt time.Time
+}
+
+type hashValue map[string]string
+
+type db struct {
+	s        *Server
+	index    int
+	data     map[string]*entry
+	versions map[string]uint64 // bumped on every write of the key, used by WATCH
+}
+
+func newDB(s *Server, index int) *db {
+	return &db{
+		s:        s,
+		index:    index,
+		data:     make(map[string]*entry),
+		versions: make(map[string]uint64),
+	}
+}
+
+// lookup return the entry of the key, expired key is deleted and nil returned
+func (d *db) lookup(key string, now time.Time) *entry {
+	e, ok := d.data[key]
+	if !ok {
+		return nil
+	}
+	if !e.expireAt.IsZero() && !now.Before(e.expireAt) {
+		d.del(key)
+		d.s.notify(d.index, 'x', "expired", key)
+		return nil
+	}
+	return e
+}
+
+func (d *db) set(key string, e *entry) {
+	d.data[key] = e
+	d.touch(key)
+}
+
+func (d *db) del(key string) bool {
+	if _, ok := d.data[key]; !ok {
+		return false
+	}
+	delete(d.data, key)
+	d.touch(key)
+	return true
+}
+
+// touch mark the key as modified
+func (d *db) touch(key string) {
+	d.versions[key]++
+}
+
+func (d *db) flush() {
+	for k := range d.data {
+		d.touch(k)
+	}
+	d.data = make(map[string]*entry)
+}
+
+// cmdContext is passed to every command handler, the server lock is held during the handler call
+type cmdContext struct {
+	s        *Server
+	c        *conn
+	db       *db
+	now      time.Time
+	inScript bool // blocking commands never block inside scripts
+}
+
+func (ctx *cmdContext) lookup(key string) *entry {
+	return ctx.db.lookup(key, ctx.now)
+}
+
+// getString return the string value of the key, ok is false when the key does not exist
+func (ctx *cmdContext) getString(key string) (v string, ok bool, err errorReply) {
+	e := ctx.lookup(key)
+	if e == nil {
+		return "", false, ""
+	}
+	s, isStr := e.value.(string)
+	if !isStr {
+		return "", false, errWrongType
+	}
+	return s, true, ""
+}
+
+// getHash return the hash value of the key, create it when create is true
+func (ctx *cmdContext) getHash(key string, create bool) (hashValue, errorReply) {
+	e := ctx.lookup(key)
+	if e == nil {
+		if !create {
+			return nil, ""
+		}
+		h := hashValue{}
+		ctx.db.data[key] = &entry{value: h}
+		return h, ""
+	}
+	h, ok := e.value.(hashValue)
+	if !ok {
+		return nil, errWrongType
+	}
+	return h, ""
+}
+
+// getZSet return the sorted set value of the key, create it when create is true
+func (ctx *cmdContext) getZSet(key string, create bool) (*zset, errorReply) {
+	e := ctx.lookup(key)
+	if e == nil {
+		if !create {
+			return nil, ""
+		}
+		z := newZSet()
+		ctx.db.data[key] = &entry{value: z}
+		return z, ""
+	}
+	z, ok := e.value.(*zset)
+	if !ok {
+		return nil, errWrongType
+	}
+	return z, ""
+}
+
+// getList return the list value of the key, create it when create is true
+func (ctx *cmdContext) getList(key string, create bool) (*listValue, errorReply) {
+	e := ctx.lookup(key)
+	if e == nil {
+		if !create {
+			return nil, ""
+		}
+		l := &listValue{}
+		ctx.db.data[key] = &entry{value: l}
+		return l, ""
+	}
+	l, ok := e.value.(*listValue)
+	if !ok {
+		return nil, errWrongType
+	}
+	return l, ""
+}
+
+// getSet return the set value of the key, create it when create is true
+func (ctx *cmdContext) getSet(key string, create bool) (setValue, errorReply) {
+	e := ctx.lookup(key)
+	if e == nil {
+		if !create {
+			return nil, ""
+		}
+		s := setValue{}
+		ctx.db.data[key] = &entry{value: s}
+		return s, ""
+	}
+	s, ok := e.value.(setValue)
+	if !ok {
+		return nil, errWrongType
+	}
+	return s, ""
+}
+
+// getStream return the stream value of the key, create it when create is true
+func (ctx *cmdContext) getStream(key string, create bool) (*stream, errorReply) {
+	e := ctx.lookup(key)
+	if e == nil {
+		if !create {
+			return nil, ""
+		}
+		st := newStream()
+		ctx.db.data[key] = &entry{value: st}
+		return st, ""
+	}
+	st, ok := e.value.(*stream)
+	if !ok {
+		return nil, errWrongType
+	}
+	return st, ""
+}
+
+// block wait until another command changed the server or the timeout is reached,
+// zero timeout means waiting forever. It returns false when the timeout is reached.
+func (ctx *cmdContext) block(deadline time.Time) bool {
+	if ctx.s.closed || ctx.c.multi != nil || ctx.inScript {
+		return false
+	}
+	if !deadline.IsZero() {
+		if !time.Now().Before(deadline) {
+			return false
+		}
+		t := time.AfterFunc(time.Until(deadline), func() {
+			ctx.s.mu.Lock()
+			ctx.s.changed.Broadcast()
+			ctx.s.mu.Unlock()
+		})
+		defer t.Stop()
+	}
+	ctx.s.changed.Wait()
+	ctx.now = ctx.s.now()
+	return !ctx.s.closed
+}
+
+// container is implemented by the aggregate value types, a container key is removed once it is empty
+type container interface {
+	size() int
+}
+
+func (h hashValue) size() int { return len(h) }
+
+// removeIfEmpty delete the container key when it has no element
+func (ctx *cmdContext) removeIfEmpty(key string) {
+	e, ok := ctx.db.data[key]
+	if !ok {
+		return
+	}
+	if c, ok := e.value.(container); ok && c.size() == 0 {
+		delete(ctx.db.data, key)
+	}
+}
@@ -0,0 +1,245 @@
+package memredis
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	ggeohash "github.com/oldjon/gutil/gdb/geohash"
+)
+
+// the geo sets are zsets scored by the geohashes of ggeohash, which are the same as redis
+const geoDistFormat = 4 // the decimals of the distances in the replies
+
+func registerGeoCommands() {
+	register("geoadd", -5, cmdGeoAdd)
+	register("geopos", -2, cmdGeoPos)
+	register("geodist", -4, cmdGeoDist)
+	register("geosearch", -7, cmdGeoSearch)
+}
+
+var (
+	errGeoUnit   = errorReply("ERR unsupported unit provided. please use M, KM, FT, MI")
+	errGeoCoords = errorReply("ERR invalid longitude,latitude pair")
+)
+
+func parseLonLat(lonArg, latArg string) (float64, float64, errorReply) {
+	lon, err1 := strconv.ParseFloat(lonArg, 64)
+	lat, err2 := strconv.ParseFloat(latArg, 64)
+	if err1 != nil || err2 != nil {
+		return 0, 0, errNotFloat
+	}
+	if !ggeohash.Valid(lon, lat) {
+		return 0, 0, errGeoCoords
+	}
+	return lon, lat, ""
+}
+
+// cmdGeoAdd convert the positions into the geohash scores and add them by ZADD
+func cmdGeoAdd(ctx *cmdContext, args []string) any {
+	zargs := []string{"zadd", args[1]}
+	i := 2
+	for ; i < len(args); i++ {
+		opt := strings.ToLower(args[i])
+		if opt != "nx" && opt != "xx" && opt != "ch" {
+			break
+		}
+		zargs = append(zargs, opt)
+	}
+	if i == len(args) || (len(args)-i)%3 != 0 {
+		return errSyntax
+	}
+	for ; i < len(args); i += 3 {
+		lon, lat, errReply := parseLonLat(args[i], args[i+1])
+		if errReply != "" {
+			return errReply
+		}
+		zargs = append(zargs, strconv.FormatUint(ggeohash.Encode(lon, lat), 10), args[i+2])
+	}
+	return cmdZAdd(ctx, zargs)
+}
+
+func cmdGeoPos(ctx *cmdContext, args []string) any {
+	z, errReply := ctx.getZSet(args[1], false)
+	if errReply != "" {
+		return errReply
+	}
+	rets := make([]any, 0, len(args)-2)
+	for _, member := range args[2:] {
+		score, ok := 0.0, false
+		if z != nil {
+			score, ok = z.scores[member]
+		}
+		if !ok {
+			rets = append(rets, nilArray{})
+			continue
+		}
+		lon, lat := ggeohash.Decode(uint64(score))
+		rets = append(rets, []any{formatFloat(lon), formatFloat(lat)})
+	}
+	return rets
+}
+
+func cmdGeoDist(ctx *cmdContext, args []string) any {
+	unit := 1.0
+	if len(args) == 5 {
+		var ok bool
+		if unit, ok = ggeohash.Unit(args[4]); !ok {
+			return errGeoUnit
+		}
+	} else if len(args) != 4 {
+		return errSyntax
+	}
+	z, errReply := ctx.getZSet(args[1], false)
+	if errReply != "" || z == nil {
+		return nil
+	}
+	s1, ok1 := z.scores[args[2]]
+	s2, ok2 := z.scores[args[3]]
+	if !ok1 || !ok2 {
+		return nil
+	}
+	lon1, lat1 := ggeohash.Decode(uint64(s1))
+	lon2, lat2 := ggeohash.Decode(uint64(s2))
+	return strconv.FormatFloat(ggeohash.Distance(lon1, lat1, lon2, lat2)/unit, 'f', geoDistFormat, 64)
+}
+
+type geoResult struct {
+	member   string
+	dist     float64 // in meters
+	hash     uint64
+	lon, lat float64
+}
+
+// cmdGeoSearch support FROMMEMBER, FROMLONLAT, BYRADIUS, BYBOX, ASC, DESC, COUNT [ANY], WITHCOORD, WITHDIST and
+// WITHHASH, the members are scanned one by one
+func cmdGeoSearch(ctx *cmdContext, args []string) any {
+	var (
+		fromMember, lonLat, byRadius, byBox bool
+		lon, lat                            float64
+		area                                ggeohash.Area
+		unit                                = 1.0
+		sortOrder                           string
+		count                               int64
+		withCoord, withDist, withHash       bool
+		member                              string
+	)
+	z, errReply := ctx.getZSet(args[1], false)
+	if errReply != "" {
+		return errReply
+	}
+	for i := 2; i < len(args); i++ {
+		left := len(args) - i - 1
+		switch opt := strings.ToLower(args[i]); {
+		case opt == "frommember" && left >= 1:
+			fromMember, member = true, args[i+1]
+			i++
+		case opt == "fromlonlat" && left >= 2:
+			if lon, lat, errReply = parseLonLat(args[i+1], args[i+2]); errReply != "" {
+				return errReply
+			}
+			lonLat = true
+			i += 2
+		case opt == "byradius" && left >= 2:
+			r, err := strconv.ParseFloat(args[i+1], 64)
+			u, ok := ggeohash.Unit(args[i+2])
+			if err != nil || r < 0 {
+				return errorReply("ERR need numeric radius")
+			} else if !ok {
+				return errGeoUnit
+			}
+			byRadius, area.Radius, unit = true, r*u, u
+			i += 2
+		case opt == "bybox" && left >= 3:
+			w, err1 := strconv.ParseFloat(args[i+1], 64)
+			h, err2 := strconv.ParseFloat(args[i+2], 64)
+			u, ok := ggeohash.Unit(args[i+3])
+			if err1 != nil || err2 != nil || w < 0 || h < 0 {
+				return errorReply("ERR need numeric width and height")
+			} else if !ok {
+				return errGeoUnit
+			}
+			byBox, area.Width, area.Height, unit = true, w*u, h*u, u
+			i += 3
+		case opt == "asc" || opt == "desc":
+			sortOrder = opt
+		case opt == "count" && left >= 1:
+			n, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil || n <= 0 {
+				return errorReply("ERR COUNT must be > 0")
+			}
+			count = n
+			i++
+			if i+1 < len(args) && strings.ToLower(args[i+1]) == "any" {
+				i++
+			}
+		case opt == "withcoord":
+			withCoord = true
+		case opt == "withdist":
+			withDist = true
+		case opt == "withhash":
+			withHash = true
+		default:
+			return errSyntax
+		}
+	}
+	if fromMember == lonLat || byRadius == byBox {
+		return errorReply("ERR exactly one of FROMMEMBER or FROMLONLAT and one of BYRADIUS or BYBOX " +
+			"can be specified for geosearch")
+	}
+	if z == nil {
+		return []any{}
+	}
+	if fromMember {
+		score, ok := z.scores[member]
+		if !ok {
+			return errorReply("ERR could not decode requested zset member")
+		}
+		lon, lat = ggeohash.Decode(uint64(score))
+	}
+
+	area.Lon, area.Lat = lon, lat
+	var results []geoResult
+	for _, m := range z.sorted() {
+		r := geoResult{member: m.member, hash: uint64(m.score)}
+		r.lon, r.lat = ggeohash.Decode(r.hash)
+		var ok bool
+		if r.dist, ok = area.Contains(r.lon, r.lat); ok {
+			results = append(results, r)
+		}
+	}
+	if sortOrder == "" && count > 0 {
+		sortOrder = "asc"
+	}
+	if sortOrder != "" {
+		sort.SliceStable(results, func(i, j int) bool {
+			if sortOrder == "desc" {
+				return results[i].dist > results[j].dist
+			}
+			return results[i].dist < results[j].dist
+		})
+	}
+	if count > 0 && int64(len(results)) > count {
+		results = results[:count]
+	}
+
+	rets := make([]any, 0, len(results))
+	for _, r := range results {
+		if !withCoord && !withDist && !withHash {
+			rets = append(rets, r.member)
+			continue
+		}
+		item := []any{r.member}
+		if withDist {
+			item = append(item, strconv.FormatFloat(r.dist/unit, 'f', geoDistFormat, 64))
+		}
+		if withHash {
+			item = append(item, int64(r.hash))
+		}
+		if withCoord {
+			item = append(item, []any{formatFloat(r.lon), formatFloat(r.lat)})
+		}
+		rets = append(rets, item)
+	}
+	return rets
+}
@@ -0,0 +1,209 @@
+package memredis
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func registerHashCommands() {
+	register("hset", -4, cmdHSet)
+	register("hmset", -4, cmdHSet)
+	register("hsetnx", 4, cmdHSetNX)
+	register("hget", 3, cmdHGet)
+	register("hmget", -3, cmdHMGet)
+	register("hgetall", 2, cmdHGetAll)
+	register("hdel", -3, cmdHDel)
+	register("hlen", 2, cmdHLen)
+	register("hexists", 3, cmdHExists)
+	register("hkeys", 2, cmdHKeys)
+	register("hvals", 2, cmdHVals)
+	register("hincrby", 4, cmdHIncrBy)
+	register("hincrbyfloat", 4, cmdHIncrByFloat)
+}
+
+func cmdHSet(ctx *cmdContext, args []string) any {
+	if len(args)%2 != 0 {
+		return errWrongArgs(args[0])
+	}
+	h, err := ctx.getHash(args[1], true)
+	if err != "" {
+		return err
+	}
+	var n int64
+	for i := 2; i < len(args); i += 2 {
+		if _, ok := h[args[i]]; !ok {
+			n++
+		}
+		h[args[i]] = args[i+1]
+	}
+	ctx.db.touch(args[1])
+	if strings.EqualFold(args[0], "hmset") {
+		return replyOK
+	}
+	return n
+}
+
+func cmdHSetNX(ctx *cmdContext, args []string) any {
+	h, err := ctx.getHash(args[1], true)
+	if err != "" {
+		return err
+	}
+	if _, ok := h[args[2]]; ok {
+		return int64(0)
+	}
+	h[args[2]] = args[3]
+	ctx.db.touch(args[1])
+	return int64(1)
+}
+
+func cmdHGet(ctx *cmdContext, args []string) any {
+	h, err := ctx.getHash(args[1], false)
+	if err != "" {
+		return err
+	}
+	v, ok := h[args[2]]
+	if !ok {
+		return nil
+	}
+	return v
+}
+
+func cmdHMGet(ctx *cmdContext, args []string) any {
+	h, err := ctx.getHash(args[1], false)
+	if err != "" {
+		return err
+	}
+	rets := make([]any, 0, len(args)-2)
+	for _, field := range args[2:] {
+		v, ok := h[field]
+		if !ok {
+			rets = append(rets, nil)
+			continue
+		}
+		rets = append(rets, v)
+	}
+	return rets
+}
+
+func cmdHGetAll(ctx *cmdContext, args []string) any {
+	h, err := ctx.getHash(args[1], false)
+	if err != "" {
+		return err
+	}
+	rets := make([]string, 0, 2*len(h))
+	for _, field := range h.fields() {
+		rets = append(rets, field, h[field])
+	}
+	return rets
+}
+
+func cmdHDel(ctx *cmdContext, args []string) any {
+	h, err := ctx.getHash(args[1], false)
+	if err != "" {
+		return err
+	}
+	var n int64
+	for _, field := range args[2:] {
+		if _, ok := h[field]; ok {
+			delete(h, field)
+			n++
+		}
+	}
+	if n > 0 {
+		ctx.db.touch(args[1])
+		ctx.removeIfEmpty(args[1])
+	}
+	return n
+}
+
+func cmdHLen(ctx *cmdContext, args []string) any {
+	h, err := ctx.getHash(args[1], false)
+	if err != "" {
+		return err
+	}
+	return int64(len(h))
+}
+
+func cmdHExists(ctx *cmdContext, args []string) any {
+	h, err := ctx.getHash(args[1], false)
+	if err != "" {
+		return err
+	}
+	_, ok := h[args[2]]
+	return ok
+}
+
+func cmdHKeys(ctx *cmdContext, args []string) any {
+	h, err := ctx.getHash(args[1], false)
+	if err != "" {
+		return err
+	}
+	return h.fields()
+}
+
+func cmdHVals(ctx *cmdContext, args []string) any {
+	h, err := ctx.getHash(args[1], false)
+	if err != "" {
+		return err
+	}
+	rets := make([]string, 0, len(h))
+	for _, field := range h.fields() {
+		rets = append(rets, h[field])
+	}
+	return rets
+}
+
+func cmdHIncrBy(ctx *cmdContext, args []string) any {
+	delta, perr := strconv.ParseInt(args[3], 10, 64)
+	if perr != nil {
+		return errNotInt
+	}
+	h, err := ctx.getHash(args[1], true)
+	if err != "" {
+		return err
+	}
+	var cur int64
+	if v, ok := h[args[2]]; ok {
+		cur, perr = strconv.ParseInt(v, 10, 64)
+		if perr != nil {
+			return errorReply("ERR hash value is not an integer")
+		}
+	}
+	cur += delta
+	h[args[2]] = strconv.FormatInt(cur, 10)
+	ctx.db.touch(args[1])
+	return cur
+}
+
+func cmdHIncrByFloat(ctx *cmdContext, args []string) any {
+	delta, perr := strconv.ParseFloat(args[3], 64)
+	if perr != nil {
+		return errNotFloat
+	}
+	h, err := ctx.getHash(args[1], true)
+	if err != "" {
+		return err
+	}
+	var cur float64
+	if v, ok := h[args[2]]; ok {
+		cur, perr = strconv.ParseFloat(v, 64)
+		if perr != nil {
+			return errorReply("ERR hash value is not a float")
+		}
+	}
+	cur += delta
+	h[args[2]] = formatFloat(cur)
+	ctx.db.touch(args[1])
+	return h[args[2]]
+}
+
+// fields return the sorted field names, so replies are deterministic
+func (h hashValue) fields() []string {
+	fields := make([]string, 0, len(h))
+	for field := range h {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
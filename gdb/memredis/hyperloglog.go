@@ -0,0 +1,106 @@
+package memredis
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"sort"
+	"strings"
+)
+
+// the hyperloglogs are kept exact as the sorted 64 bit hashes of the elements after the "HYLL" magic of redis,
+// so they are strings to the other commands and PFCOUNT is exact unless the hashes collide
+const hllMagic = "HYLL"
+
+var errNotHLL = errorReply("WRONGTYPE Key is not a valid HyperLogLog string value.")
+
+func registerHyperLogLogCommands() {
+	register("pfadd", -2, cmdPFAdd)
+	register("pfcount", -2, cmdPFCount)
+	register("pfmerge", -2, cmdPFMerge)
+}
+
+// getHLL return the hashes of the hyperloglog of the key, nil if it does not exist
+func (ctx *cmdContext) getHLL(key string) (map[uint64]struct{}, errorReply) {
+	v, ok, errType := ctx.getString(key)
+	if errType != "" || !ok {
+		return nil, errType
+	}
+	if !strings.HasPrefix(v, hllMagic) || (len(v)-len(hllMagic))%8 != 0 {
+		return nil, errNotHLL
+	}
+	hashes := make(map[uint64]struct{})
+	for i := len(hllMagic); i < len(v); i += 8 {
+		hashes[binary.BigEndian.Uint64([]byte(v[i:i+8]))] = struct{}{}
+	}
+	return hashes, ""
+}
+
+func (ctx *cmdContext) setHLL(key string, hashes map[uint64]struct{}) {
+	sorted := make([]uint64, 0, len(hashes))
+	for h := range hashes {
+		sorted = append(sorted, h)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	buf := make([]byte, len(hllMagic), len(hllMagic)+8*len(sorted))
+	copy(buf, hllMagic)
+	for _, h := range sorted {
+		buf = binary.BigEndian.AppendUint64(buf, h)
+	}
+	ctx.setStringKeepTTL(key, string(buf))
+}
+
+func cmdPFAdd(ctx *cmdContext, args []string) any {
+	hashes, errReply := ctx.getHLL(args[1])
+	if errReply != "" {
+		return errReply
+	}
+	changed := hashes == nil
+	if changed {
+		hashes = make(map[uint64]struct{})
+	}
+	for _, el := range args[2:] {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(el))
+		if _, ok := hashes[h.Sum64()]; !ok {
+			hashes[h.Sum64()] = struct{}{}
+			changed = true
+		}
+	}
+	if !changed {
+		return int64(0)
+	}
+	ctx.setHLL(args[1], hashes)
+	return int64(1)
+}
+
+// unionHLL return the union of the hyperloglogs of the keys
+func (ctx *cmdContext) unionHLL(keys []string) (map[uint64]struct{}, errorReply) {
+	union := make(map[uint64]struct{})
+	for _, key := range keys {
+		hashes, errReply := ctx.getHLL(key)
+		if errReply != "" {
+			return nil, errReply
+		}
+		for h := range hashes {
+			union[h] = struct{}{}
+		}
+	}
+	return union, ""
+}
+
+func cmdPFCount(ctx *cmdContext, args []string) any {
+	union, errReply := ctx.unionHLL(args[1:])
+	if errReply != "" {
+		return errReply
+	}
+	return int64(len(union))
+}
+
+func cmdPFMerge(ctx *cmdContext, args []string) any {
+	union, errReply := ctx.unionHLL(args[1:])
+	if errReply != "" {
+		return errReply
+	}
+	ctx.setHLL(args[1], union)
+	return replyOK
+}
@@ -0,0 +1,261 @@
+package memredis
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+type listValue struct {
+	items []string
+}
+
+func (l *listValue) size() int { return len(l.items) }
+
+func registerListCommands() {
+	register("lpush", -3, cmdPush)
+	register("rpush", -3, cmdPush)
+	register("lpushx", -3, cmdPush)
+	register("rpushx", -3, cmdPush)
+	register("lpop", -2, cmdPop)
+	register("rpop", -2, cmdPop)
+	register("blpop", -3, cmdBPop)
+	register("brpop", -3, cmdBPop)
+	register("llen", 2, cmdLLen)
+	register("lrange", 4, cmdLRange)
+	register("lindex", 3, cmdLIndex)
+	register("lrem", 4, cmdLRem)
+	register("ltrim", 4, cmdLTrim)
+	register("rpoplpush", 3, cmdRPopLPush)
+	register("lmove", 5, cmdLMove)
+}
+
+func cmdPush(ctx *cmdContext, args []string) any {
+	name := strings.ToLower(args[0])
+	if strings.HasSuffix(name, "x") && ctx.lookup(args[1]) == nil {
+		return int64(0)
+	}
+	l, err := ctx.getList(args[1], true)
+	if err != "" {
+		return err
+	}
+	for _, v := range args[2:] {
+		if name[0] == 'l' {
+			l.items = append([]string{v}, l.items...)
+		} else {
+			l.items = append(l.items, v)
+		}
+	}
+	ctx.db.touch(args[1])
+	return int64(len(l.items))
+}
+
+func cmdPop(ctx *cmdContext, args []string) any {
+	count := -1
+	if len(args) > 2 {
+		n, err := strconv.Atoi(args[2])
+		if err != nil || n < 0 {
+			return errorReply("ERR value is out of range, must be positive")
+		}
+		count = n
+	}
+	l, err := ctx.getList(args[1], false)
+	if err != "" {
+		return err
+	}
+	if l == nil {
+		if count >= 0 {
+			return nilArray{}
+		}
+		return nil
+	}
+	left := strings.EqualFold(args[0], "lpop")
+	if count < 0 {
+		return ctx.popOne(args[1], l, left)
+	}
+	rets := make([]string, 0, count)
+	for i := 0; i < count && len(l.items) > 0; i++ {
+		rets = append(rets, ctx.popOne(args[1], l, left))
+	}
+	return rets
+}
+
+func (ctx *cmdContext) popOne(key string, l *listValue, left bool) string {
+	var v string
+	if left {
+		v, l.items = l.items[0], l.items[1:]
+	} else {
+		v, l.items = l.items[len(l.items)-1], l.items[:len(l.items)-1]
+	}
+	ctx.db.touch(key)
+	ctx.removeIfEmpty(key)
+	return v
+}
+
+func cmdBPop(ctx *cmdContext, args []string) any {
+	timeout, perr := strconv.ParseFloat(args[len(args)-1], 64)
+	if perr != nil || timeout < 0 {
+		return errorReply("ERR timeout is not a float or out of range")
+	}
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(time.Duration(timeout * float64(time.Second)))
+	}
+	keys := args[1 : len(args)-1]
+	left := strings.EqualFold(args[0], "blpop")
+	for {
+		for _, key := range keys {
+			l, err := ctx.getList(key, false)
+			if err != "" {
+				return err
+			}
+			if l != nil {
+				return []string{key, ctx.popOne(key, l, left)}
+			}
+		}
+		if !ctx.block(deadline) {
+			return nilArray{}
+		}
+	}
+}
+
+func cmdLLen(ctx *cmdContext, args []string) any {
+	l, err := ctx.getList(args[1], false)
+	if err != "" {
+		return err
+	}
+	if l == nil {
+		return int64(0)
+	}
+	return int64(len(l.items))
+}
+
+func cmdLRange(ctx *cmdContext, args []string) any {
+	start, e1 := strconv.ParseInt(args[2], 10, 64)
+	stop, e2 := strconv.ParseInt(args[3], 10, 64)
+	if e1 != nil || e2 != nil {
+		return errNotInt
+	}
+	l, err := ctx.getList(args[1], false)
+	if err != "" {
+		return err
+	}
+	if l == nil {
+		return []string{}
+	}
+	return append([]string{}, indexRange(l.items, start, stop)...)
+}
+
+func cmdLIndex(ctx *cmdContext, args []string) any {
+	i, perr := strconv.Atoi(args[2])
+	if perr != nil {
+		return errNotInt
+	}
+	l, err := ctx.getList(args[1], false)
+	if err != "" {
+		return err
+	}
+	if l == nil {
+		return nil
+	}
+	if i < 0 {
+		i += len(l.items)
+	}
+	if i < 0 || i >= len(l.items) {
+		return nil
+	}
+	return l.items[i]
+}
+
+func cmdLRem(ctx *cmdContext, args []string) any {
+	count, perr := strconv.Atoi(args[2])
+	if perr != nil {
+		return errNotInt
+	}
+	l, err := ctx.getList(args[1], false)
+	if err != "" {
+		return err
+	}
+	if l == nil {
+		return int64(0)
+	}
+	var removed int
+	items := l.items
+	if count >= 0 {
+		kept := make([]string, 0, len(items))
+		for _, v := range items {
+			if v == args[3] && (count == 0 || removed < count) {
+				removed++
+				continue
+			}
+			kept = append(kept, v)
+		}
+		l.items = kept
+	} else {
+		kept := make([]string, 0, len(items))
+		for i := len(items) - 1; i >= 0; i-- {
+			if items[i] == args[3] && removed < -count {
+				removed++
+				continue
+			}
+			kept = append([]string{items[i]}, kept...)
+		}
+		l.items = kept
+	}
+	if removed > 0 {
+		ctx.db.touch(args[1])
+		ctx.removeIfEmpty(args[1])
+	}
+	return int64(removed)
+}
+
+func cmdLTrim(ctx *cmdContext, args []string) any {
+	start, e1 := strconv.ParseInt(args[2], 10, 64)
+	stop, e2 := strconv.ParseInt(args[3], 10, 64)
+	if e1 != nil || e2 != nil {
+		return errNotInt
+	}
+	l, err := ctx.getList(args[1], false)
+	if err != "" {
+		return err
+	}
+	if l == nil {
+		return replyOK
+	}
+	l.items = append([]string{}, indexRange(l.items, start, stop)...)
+	ctx.db.touch(args[1])
+	ctx.removeIfEmpty(args[1])
+	return replyOK
+}
+
+func cmdRPopLPush(ctx *cmdContext, args []string) any {
+	return cmdLMove(ctx, []string{"lmove", args[1], args[2], "right", "left"})
+}
+
+func cmdLMove(ctx *cmdContext, args []string) any {
+	from, to := strings.ToLower(args[3]), strings.ToLower(args[4])
+	if (from != "left" && from != "right") || (to != "left" && to != "right") {
+		return errSyntax
+	}
+	src, err := ctx.getList(args[1], false)
+	if err != "" {
+		return err
+	}
+	if src == nil {
+		return nil
+	}
+	if e := ctx.lookup(args[2]); e != nil {
+		if _, ok := e.value.(*listValue); !ok {
+			return errWrongType
+		}
+	}
+	v := ctx.popOne(args[1], src, from == "left")
+	dst, _ := ctx.getList(args[2], true)
+	if to == "left" {
+		dst.items = append([]string{v}, dst.items...)
+	} else {
+		dst.items = append(dst.items, v)
+	}
+	ctx.db.touch(args[2])
+	return v
+}
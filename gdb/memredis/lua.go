@@ -0,0 +1,216 @@
+package memredis
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+)
+
+// The lua scripts run on gopher-lua, which is lua 5.1 like redis. The base, table, string and math libraries and
+// the redis library are available, cjson, cmsgpack, bit and struct are not.
+
+// luaProto return the compiled script of the sha, the scripts are compiled once
+func (s *Server) luaProto(sha, src string) (*lua.FunctionProto, error) {
+	if proto, ok := s.luaProtos[sha]; ok {
+		return proto, nil
+	}
+	chunk, err := parse.Parse(strings.NewReader(src), "@user_script")
+	if err != nil {
+		return nil, errorReplyf("ERR Error compiling script (new function): %s", oneLine(err.Error()))
+	}
+	proto, err := lua.Compile(chunk, "@user_script")
+	if err != nil {
+		return nil, errorReplyf("ERR Error compiling script (new function): %s", oneLine(err.Error()))
+	}
+	s.luaProtos[sha] = proto
+	return proto, nil
+}
+
+// runLua run the script in a new lua state, redis.call runs the commands against the db of ctx
+func (ctx *cmdContext) runLua(sha, src string, keys, argv []string) any {
+	proto, err := ctx.s.luaProto(sha, src)
+	if err != nil {
+		return err
+	}
+	L := lua.NewState(lua.Options{SkipOpenLibs: true})
+	defer L.Close()
+	for _, lib := range []struct {
+		name string
+		open lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		L.Push(L.NewFunction(lib.open))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+	L.SetGlobal("KEYS", luaStrings(L, keys))
+	L.SetGlobal("ARGV", luaStrings(L, argv))
+	L.SetGlobal("redis", ctx.luaRedisLib(L))
+
+	L.Push(L.NewFunctionFromProto(proto))
+	if err := L.PCall(0, 1, nil); err != nil {
+		if apiErr, ok := err.(*lua.ApiError); ok {
+			if tbl, ok := apiErr.Object.(*lua.LTable); ok {
+				if e, ok := tbl.RawGetString("err").(lua.LString); ok {
+					return errorReply(e)
+				}
+			}
+			return errorReplyf("ERR Error running script (call to f_%s): %s", sha, oneLine(apiErr.Object.String()))
+		}
+		return errorReply("ERR " + oneLine(err.Error()))
+	}
+	return fromLua(L.Get(-1))
+}
+
+// oneLine join the lines of the error, an error reply can not have line breaks
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func luaStrings(L *lua.LState, strs []string) *lua.LTable {
+	tbl := L.CreateTable(len(strs), 0)
+	for _, s := range strs {
+		tbl.Append(lua.LString(s))
+	}
+	return tbl
+}
+
+// luaRedisLib create the redis table of the scripts
+func (ctx *cmdContext) luaRedisLib(L *lua.LState) *lua.LTable {
+	lib := L.NewTable()
+	call := func(raise bool) lua.LGFunction {
+		return func(L *lua.LState) int {
+			args := make([]string, L.GetTop())
+			for i := range args {
+				switch v := L.Get(i + 1).(type) {
+				case lua.LString:
+					args[i] = string(v)
+				case lua.LNumber:
+					args[i] = fmt.Sprintf("%.14g", float64(v)) // lua_tolstring of redis
+				default:
+					L.Error(luaReply(L, errorReply("ERR Lua redis lib command arguments must be strings or integers")),
+						1)
+				}
+			}
+			ret := ctx.command(args)
+			if reply, ok := ret.(errorReply); ok && raise {
+				L.Error(luaReply(L, reply), 1)
+			}
+			L.Push(toLua(L, ret))
+			return 1
+		}
+	}
+	L.SetFuncs(lib, map[string]lua.LGFunction{
+		"call":  call(true),
+		"pcall": call(false),
+		"error_reply": func(L *lua.LState) int {
+			L.Push(luaReply(L, errorReply(L.CheckString(1))))
+			return 1
+		},
+		"status_reply": func(L *lua.LState) int {
+			L.Push(luaReply(L, statusReply(L.CheckString(1))))
+			return 1
+		},
+		"sha1hex": func(L *lua.LState) int {
+			h := sha1.Sum([]byte(L.CheckString(1)))
+			L.Push(lua.LString(hex.EncodeToString(h[:])))
+			return 1
+		},
+		"replicate_commands": func(L *lua.LState) int {
+			L.Push(lua.LTrue)
+			return 1
+		},
+		"set_repl": func(*lua.LState) int { return 0 },
+		"log":      func(*lua.LState) int { return 0 },
+	})
+	for name, v := range map[string]int{"LOG_DEBUG": 0, "LOG_VERBOSE": 1, "LOG_NOTICE": 2, "LOG_WARNING": 3,
+		"REPL_NONE": 0, "REPL_AOF": 1, "REPL_SLAVE": 2, "REPL_REPLICA": 2, "REPL_ALL": 3} {
+		lib.RawSetString(name, lua.LNumber(v))
+	}
+	return lib
+}
+
+// luaReply convert a status or error reply to the table of redis, {ok = status} or {err = error}
+func luaReply(L *lua.LState, reply any) *lua.LTable {
+	tbl := L.NewTable()
+	switch v := reply.(type) {
+	case statusReply:
+		tbl.RawSetString("ok", lua.LString(v))
+	case errorReply:
+		tbl.RawSetString("err", lua.LString(v))
+	}
+	return tbl
+}
+
+// toLua convert the reply of redis.call the same way redis converts the replies to lua values
+func toLua(L *lua.LState, v any) lua.LValue {
+	switch vv := v.(type) {
+	case nil, nilArray:
+		return lua.LFalse
+	case statusReply, errorReply:
+		return luaReply(L, vv)
+	case string:
+		return lua.LString(vv)
+	case int64:
+		return lua.LNumber(vv)
+	case int:
+		return lua.LNumber(vv)
+	case bool:
+		if vv {
+			return lua.LNumber(1)
+		}
+		return lua.LNumber(0)
+	case []string:
+		return luaStrings(L, vv)
+	case []any:
+		tbl := L.CreateTable(len(vv), 0)
+		for _, e := range vv {
+			tbl.Append(toLua(L, e))
+		}
+		return tbl
+	}
+	return lua.LString(fmt.Sprint(v))
+}
+
+// fromLua convert the value returned by the script the same way redis converts lua values to replies
+func fromLua(v lua.LValue) any {
+	switch vv := v.(type) {
+	case lua.LString:
+		return string(vv)
+	case lua.LNumber:
+		return int64(vv)
+	case lua.LBool:
+		if vv {
+			return int64(1)
+		}
+		return nil
+	case *lua.LTable:
+		if e, ok := vv.RawGetString("err").(lua.LString); ok {
+			return errorReply(e)
+		}
+		if s, ok := vv.RawGetString("ok").(lua.LString); ok {
+			return statusReply(s)
+		}
+		var l []any
+		for i := 1; ; i++ {
+			e := vv.RawGetInt(i)
+			if e == lua.LNil {
+				break
+			}
+			l = append(l, fromLua(e))
+		}
+		if l == nil {
+			return []any{}
+		}
+		return l
+	}
+	return nil
+}
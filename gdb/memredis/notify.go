@@ -0,0 +1,64 @@
+package memredis
+
+import (
+	"strconv"
+	"strings"
+)
+
+// notify publish the keyspace notification of the event if its class is enabled by notify-keyspace-events,
+// the server lock should be held
+func (s *Server) notify(dbIndex int, class byte, event, key string) {
+	flags := s.notifyFlags
+	if flags == "" || !strings.ContainsRune(flags, rune(class)) && !strings.ContainsRune(flags, 'A') {
+		return
+	}
+	db := strconv.Itoa(dbIndex)
+	if strings.ContainsRune(flags, 'K') {
+		s.publish("__keyspace@"+db+"__:"+key, event)
+	}
+	if strings.ContainsRune(flags, 'E') {
+		s.publish("__keyevent@"+db+"__:"+event, key)
+	}
+}
+
+// ActiveExpire delete all the expired keys now, like redis does in background, lazily expired keys are
+// only deleted when accessed. Tests call it after advancing the clock to receive the expired notifications.
+func (s *Server) ActiveExpire() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	for _, d := range s.dbs {
+		for key := range d.data {
+			d.lookup(key, now)
+		}
+	}
+	s.changed.Broadcast()
+}
+
+// cmdConfig support notify-keyspace-events, other parameters are accepted and ignored
+func cmdConfig(ctx *cmdContext, args []string) any {
+	switch strings.ToLower(args[1]) {
+	case "get":
+		if len(args) != 3 {
+			return errWrongArgs("config|get")
+		}
+		if matchPattern(strings.ToLower(args[2]), "notify-keyspace-events") {
+			return []string{"notify-keyspace-events", ctx.s.notifyFlags}
+		}
+		return []string{}
+	case "set":
+		if len(args) < 4 || len(args)%2 != 0 {
+			return errWrongArgs("config|set")
+		}
+		for i := 2; i < len(args); i += 2 {
+			if strings.EqualFold(args[i], "notify-keyspace-events") {
+				ctx.s.notifyFlags = args[i+1]
+			}
+		}
+		return replyOK
+	case "resetstat", "rewrite":
+		return replyOK
+	default:
+		return errorReplyf("ERR unknown subcommand '%s'", args[1])
+	}
+}
@@ -0,0 +1,90 @@
+package memredis
+
+import "strings"
+
+func registerPubSubCommands() {
+	registerPubSub("subscribe", -2, cmdSubscribe)
+	registerPubSub("psubscribe", -2, cmdSubscribe)
+	registerPubSub("unsubscribe", -1, cmdUnsubscribe)
+	registerPubSub("punsubscribe", -1, cmdUnsubscribe)
+	register("publish", 3, cmdPublish)
+}
+
+func registerPubSub(name string, arity int, fn cmdFunc) {
+	commands[name] = cmdSpec{fn: fn, arity: arity, pubsub: true}
+}
+
+func (c *conn) subscribed() bool {
+	return len(c.subs)+len(c.psubs) > 0
+}
+
+func (c *conn) subCount() int64 {
+	return int64(len(c.subs) + len(c.psubs))
+}
+
+func cmdSubscribe(ctx *cmdContext, args []string) any {
+	kind := strings.ToLower(args[0])
+	c := ctx.c
+	rets := make(multiReply, 0, len(args)-1)
+	for _, ch := range args[1:] {
+		if kind == "subscribe" {
+			if c.subs == nil {
+				c.subs = make(map[string]struct{})
+			}
+			c.subs[ch] = struct{}{}
+		} else {
+			if c.psubs == nil {
+				c.psubs = make(map[string]struct{})
+			}
+			c.psubs[ch] = struct{}{}
+		}
+		rets = append(rets, []any{kind, ch, c.subCount()})
+	}
+	return rets
+}
+
+func cmdUnsubscribe(ctx *cmdContext, args []string) any {
+	kind := strings.ToLower(args[0])
+	c := ctx.c
+	subs := c.subs
+	if kind == "punsubscribe" {
+		subs = c.psubs
+	}
+	channels := args[1:]
+	if len(channels) == 0 {
+		for ch := range subs {
+			channels = append(channels, ch)
+		}
+	}
+	if len(channels) == 0 {
+		return []any{kind, nil, c.subCount()}
+	}
+	rets := make(multiReply, 0, len(channels))
+	for _, ch := range channels {
+		delete(subs, ch)
+		rets = append(rets, []any{kind, ch, c.subCount()})
+	}
+	return rets
+}
+
+func cmdPublish(ctx *cmdContext, args []string) any {
+	return ctx.s.publish(args[1], args[2])
+}
+
+// publish send the message to all subscribers of the channel, the server lock should be held
+func (s *Server) publish(channel, message string) int64 {
+	var n int64
+	for c := range s.conns {
+		if _, ok := c.subs[channel]; ok {
+			c.push([]any{"message", channel, message})
+			n++
+		}
+		for pattern := range c.psubs {
+			if matchPattern(pattern, channel) {
+				c.push([]any{"pmessage", pattern, channel, message})
+				n++
+			}
+		}
+	}
+	return n
+}
@@ -0,0 +1,130 @@
+package memredis
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// reply types written back to the client, plain go values are written as:
+// string -> bulk string, int64/int -> integer, nil -> nil bulk string, []any -> array.
+type (
+	statusReply string
+	errorReply  string
+	nilArray    struct{}
+	multiReply  []any // several replies for one command, e.g. SUBSCRIBE with many channels
+)
+
+func errorReplyf(format string, args ...any) errorReply {
+	return errorReply(fmt.Sprintf(format, args...))
+}
+
+func (e errorReply) Error() string { return string(e) }
+
+const (
+	errSyntax      = errorReply("ERR syntax error")
+	errNotInt      = errorReply("ERR value is not an integer or out of range")
+	errNotFloat    = errorReply("ERR value is not a valid float")
+	errWrongType   = errorReply("WRONGTYPE Operation against a key holding the wrong kind of value")
+	errNoScript    = errorReply("NOSCRIPT No matching script. Please use EVAL.")
+	replyOK        = statusReply("OK")
+	errNestedMulti = errorReply("ERR MULTI calls can not be nested")
+)
+
+func errWrongArgs(name string) errorReply {
+	return errorReplyf("ERR wrong number of arguments for '%s' command", name)
+}
+
+var errProtocol = errors.New("memredis: protocol error")
+
+// readCommand read a command sent by the client, both multi bulk and inline commands are supported
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return []string{}, nil
+	}
+	if line[0] != '*' {
+		return strings.Fields(line), nil
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n < 0 {
+		return nil, errProtocol
+	}
+	args := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		line, err = readLine(r)
+		if err != nil {
+			return nil, err
+		}
+		if len(line) == 0 || line[0] != '$' {
+			return nil, errProtocol
+		}
+		size, err := strconv.Atoi(line[1:])
+		if err != nil || size < 0 {
+			return nil, errProtocol
+		}
+		buf := make([]byte, size+2)
+		if _, err = io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args = append(args, string(buf[:size]))
+	}
+	return args, nil
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func writeReply(w *bufio.Writer, v any) {
+	switch vv := v.(type) {
+	case nil:
+		_, _ = w.WriteString("$-1\r\n")
+	case nilArray:
+		_, _ = w.WriteString("*-1\r\n")
+	case multiReply:
+		for _, e := range vv {
+			writeReply(w, e)
+		}
+	case statusReply:
+		_, _ = w.WriteString("+" + string(vv) + "\r\n")
+	case errorReply:
+		_, _ = w.WriteString("-" + string(vv) + "\r\n")
+	case error:
+		_, _ = w.WriteString("-" + vv.Error() + "\r\n")
+	case int:
+		_, _ = w.WriteString(":" + strconv.Itoa(vv) + "\r\n")
+	case int64:
+		_, _ = w.WriteString(":" + strconv.FormatInt(vv, 10) + "\r\n")
+	case bool:
+		if vv {
+			_, _ = w.WriteString(":1\r\n")
+		} else {
+			_, _ = w.WriteString(":0\r\n")
+		}
+	case string:
+		_, _ = w.WriteString("$" + strconv.Itoa(len(vv)) + "\r\n" + vv + "\r\n")
+	case []string:
+		_, _ = w.WriteString("*" + strconv.Itoa(len(vv)) + "\r\n")
+		for _, s := range vv {
+			writeReply(w, s)
+		}
+	case []any:
+		_, _ = w.WriteString("*" + strconv.Itoa(len(vv)) + "\r\n")
+		for _, e := range vv {
+			writeReply(w, e)
+		}
+	default:
+		writeReply(w, fmt.Sprint(vv))
+	}
+}
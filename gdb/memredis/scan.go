@@ -0,0 +1,126 @@
+package memredis
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func registerScanCommands() {
+	register("scan", -2, cmdScan)
+	register("hscan", -3, cmdScan)
+	register("sscan", -3, cmdScan)
+	register("zscan", -3, cmdScan)
+}
+
+// cmdScan iterate the keys or members in name order, a cursor refers to the last returned name,
+// so every element existing during the whole iteration is returned even if others are deleted in between
+func cmdScan(ctx *cmdContext, args []string) any {
+	name := strings.ToLower(args[0])
+	optStart := 2
+	if name != "scan" {
+		optStart = 3
+	}
+	cursor, perr := strconv.ParseUint(args[optStart-1], 10, 64)
+	if perr != nil {
+		return errorReply("ERR invalid cursor")
+	}
+	after, ok := ctx.s.scanCursors[cursor]
+	if cursor != 0 && !ok {
+		return errorReply("ERR invalid cursor")
+	}
+	pattern, count, typ := "*", 10, ""
+	for i := optStart; i < len(args); i += 2 {
+		if i+1 >= len(args) {
+			return errSyntax
+		}
+		switch strings.ToLower(args[i]) {
+		case "match":
+			pattern = args[i+1]
+		case "count":
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil {
+				return errNotInt
+			}
+			if n < 1 {
+				return errSyntax
+			}
+			count = n
+		case "type":
+			if name != "scan" {
+				return errSyntax
+			}
+			typ = strings.ToLower(args[i+1])
+		default:
+			return errSyntax
+		}
+	}
+
+	// items are the scanned elements, each one is a key or member followed by its value if any
+	var items [][]string
+	switch name {
+	case "scan":
+		keys := make([]string, 0, len(ctx.db.data))
+		for k := range ctx.db.data {
+			if ctx.lookup(k) != nil {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			items = append(items, []string{k})
+		}
+	case "hscan":
+		h, err := ctx.getHash(args[1], false)
+		if err != "" {
+			return err
+		}
+		for _, f := range h.fields() {
+			items = append(items, []string{f, h[f]})
+		}
+	case "sscan":
+		s, err := ctx.getSet(args[1], false)
+		if err != "" {
+			return err
+		}
+		for _, m := range s.members() {
+			items = append(items, []string{m})
+		}
+	case "zscan":
+		z, err := ctx.getZSet(args[1], false)
+		if err != "" {
+			return err
+		}
+		if z != nil {
+			for _, m := range z.sorted() {
+				items = append(items, []string{m.member, formatFloat(m.score)})
+			}
+			sort.Slice(items, func(i, j int) bool { return items[i][0] < items[j][0] })
+		}
+	}
+
+	start := 0
+	if cursor != 0 {
+		start = sort.Search(len(items), func(i int) bool { return items[i][0] > after })
+	}
+	end := start + count
+	var next uint64
+	if end < len(items) {
+		ctx.s.lastCursor++
+		next = ctx.s.lastCursor
+		ctx.s.scanCursors[next] = items[end-1][0]
+	} else {
+		end = len(items)
+	}
+	rets := make([]string, 0)
+	for _, item := range items[start:end] {
+		if !matchPattern(pattern, item[0]) {
+			continue
+		}
+		if typ != "" && typeName(ctx.lookup(item[0]).value) != typ {
+			continue
+		}
+		rets = append(rets, item...)
+	}
+	return []any{strconv.FormatUint(next, 10), rets}
+}
@@ -0,0 +1,81 @@
+package memredis
+
+import (
+	"strconv"
+	"strings"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+func registerScriptCommands() {
+	register("eval", -3, cmdEval)
+	register("evalsha", -3, cmdEval)
+	register("script", -2, cmdScript)
+}
+
+func cmdEval(ctx *cmdContext, args []string) any {
+	var sha string
+	if strings.EqualFold(args[0], "evalsha") {
+		sha = strings.ToLower(args[1])
+		if _, ok := ctx.s.scripts[sha]; !ok {
+			return errNoScript
+		}
+	} else {
+		sha = scriptSha(args[1])
+		ctx.s.scripts[sha] = args[1]
+	}
+	numKeys, err := strconv.Atoi(args[2])
+	if err != nil {
+		return errNotInt
+	}
+	if numKeys < 0 || numKeys > len(args)-3 {
+		return errorReply("ERR Number of keys can't be greater than number of args")
+	}
+	keys := args[3 : 3+numKeys]
+	argv := args[3+numKeys:]
+	ctx.inScript = true
+	defer func() { ctx.inScript = false }()
+	return ctx.runLua(sha, ctx.s.scripts[sha], keys, argv)
+}
+
+// command run a command inside a script and return its reply
+func (ctx *cmdContext) command(args []string) any {
+	if len(args) == 0 {
+		return errorReply("ERR Please specify at least one argument for this redis lib call")
+	}
+	spec, ok := commands[strings.ToLower(args[0])]
+	if !ok || spec.tx {
+		return errorReply("ERR Unknown Redis command called from script")
+	}
+	if !spec.checkArity(len(args)) {
+		return errWrongArgs(args[0])
+	}
+	return spec.fn(ctx, args)
+}
+
+func cmdScript(ctx *cmdContext, args []string) any {
+	switch strings.ToLower(args[1]) {
+	case "load":
+		if len(args) != 3 {
+			return errWrongArgs("script|load")
+		}
+		sha := scriptSha(args[2])
+		ctx.s.scripts[sha] = args[2]
+		return sha
+	case "exists":
+		rets := make([]any, 0, len(args)-2)
+		for _, sha := range args[2:] {
+			_, ok := ctx.s.scripts[strings.ToLower(sha)]
+			rets = append(rets, ok)
+		}
+		return rets
+	case "flush":
+		ctx.s.scripts = make(map[string]string)
+		ctx.s.luaProtos = make(map[string]*lua.FunctionProto)
+		return replyOK
+	case "kill":
+		return errorReply("NOTBUSY No scripts in execution right now.")
+	default:
+		return errorReplyf("ERR unknown subcommand '%s'", args[1])
+	}
+}
@@ -0,0 +1,247 @@
+// Package memredis implements a small in-memory redis server which speaks RESP2 over in-process
+// connections. It is used to test code built on gdb.RedisClient without a live redis.
+package memredis
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+const defaultDBCount = 16
+
+// Server is an in-memory redis server
+type Server struct {
+	mu          sync.Mutex
+	changed     *sync.Cond // broadcast after every command, blocking commands wait on it
+	now         func() time.Time
+	dbs         []*db
+	scripts     map[string]string             // sha -> script source
+	luaProtos   map[string]*lua.FunctionProto // sha -> compiled script
+	scanCursors map[uint64]string             // scan cursor -> last returned name
+	lastCursor  uint64
+	notifyFlags string // notify-keyspace-events
+	conns       map[*conn]struct{}
+	closed      bool
+}
+
+// NewServer create an empty in-memory redis server using time.Now as its clock
+func NewServer() *Server {
+	s := &Server{
+		now:         time.Now,
+		dbs:         make([]*db, defaultDBCount),
+		scripts:     make(map[string]string),
+		luaProtos:   make(map[string]*lua.FunctionProto),
+		scanCursors: make(map[uint64]string),
+		conns:       make(map[*conn]struct{}),
+	}
+	s.changed = sync.NewCond(&s.mu)
+	for i := range s.dbs {
+		s.dbs[i] = newDB(s, i)
+	}
+	return s
+}
+
+// SetClock replace the clock used to evaluate key expiration
+func (s *Server) SetClock(now func() time.Time) {
+	s.mu.Lock()
+	s.now = now
+	s.mu.Unlock()
+}
+
+// FlushAll remove all keys of all dbs
+func (s *Server) FlushAll() {
+	s.mu.Lock()
+	for _, d := range s.dbs {
+		d.flush()
+	}
+	s.mu.Unlock()
+}
+
+// Keys return all the unexpired keys of the db
+func (s *Server) Keys(dbIndex int) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := s.dbs[dbIndex]
+	keys := make([]string, 0, len(d.data))
+	for k := range d.data {
+		if d.lookup(k, s.now()) != nil {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// Dial open an in-process connection to the server, its signature matches redis.Options.Dialer
+func (s *Server) Dial(_ context.Context, _, _ string) (net.Conn, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, errServerClosed
+	}
+	client, server := net.Pipe()
+	c := newConn(s, server)
+	s.conns[c] = struct{}{}
+	go c.serve()
+	return client, nil
+}
+
+// Close close all connections, the server can not be dialed anymore
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	s.CloseConnections()
+	return nil
+}
+
+// CloseConnections close all current connections like a network failure, clients can reconnect
+func (s *Server) CloseConnections() {
+	s.mu.Lock()
+	conns := s.conns
+	s.conns = make(map[*conn]struct{})
+	s.changed.Broadcast()
+	s.mu.Unlock()
+	for c := range conns {
+		_ = c.nc.Close()
+	}
+}
+
+var errServerClosed = errors.New("memredis: server closed")
+
+func scriptSha(src string) string {
+	h := sha1.Sum([]byte(src))
+	return hex.EncodeToString(h[:])
+}
+
+// conn is the server side of a client connection.
+// Commands are read by a separate goroutine, so pipelined clients never block on writing
+// while the server is writing replies.
+type conn struct {
+	s     *Server
+	nc    net.Conn
+	db    int
+	multi *[][]string         // queued commands after MULTI
+	dirty bool                // a queued command has error, EXEC will be aborted
+	watch map[string]uint64   // watched key -> version
+	subs  map[string]struct{} // subscribed channels
+	psubs map[string]struct{} // subscribed patterns
+
+	qmu    sync.Mutex
+	qcond  *sync.Cond
+	queue  [][]string
+	pushes []any // messages published to the connection
+	eof    bool
+}
+
+func newConn(s *Server, nc net.Conn) *conn {
+	c := &conn{s: s, nc: nc}
+	c.qcond = sync.NewCond(&c.qmu)
+	return c
+}
+
+func (c *conn) serve() {
+	go c.readLoop()
+	w := bufio.NewWriter(c.nc)
+	defer func() {
+		_ = c.nc.Close()
+		c.s.mu.Lock()
+		delete(c.s.conns, c)
+		c.s.mu.Unlock()
+	}()
+	for {
+		c.qmu.Lock()
+		for len(c.queue) == 0 && len(c.pushes) == 0 && !c.eof {
+			c.qcond.Wait()
+		}
+		if len(c.queue) == 0 && len(c.pushes) == 0 {
+			c.qmu.Unlock()
+			return
+		}
+		cmds, pushes := c.queue, c.pushes
+		c.queue, c.pushes = nil, nil
+		c.qmu.Unlock()
+
+		for _, args := range cmds {
+			writeReply(w, c.execute(args))
+		}
+		for _, msg := range pushes {
+			writeReply(w, msg)
+		}
+		if err := w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func (c *conn) readLoop() {
+	r := bufio.NewReader(c.nc)
+	for {
+		args, err := readCommand(r)
+		c.qmu.Lock()
+		if err != nil {
+			c.eof = true
+			if !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrClosedPipe) {
+				c.queue = append(c.queue, nil) // reply a protocol error before closing
+			}
+		} else if len(args) > 0 {
+			c.queue = append(c.queue, args)
+		}
+		c.qcond.Signal()
+		c.qmu.Unlock()
+		if err != nil {
+			return
+		}
+	}
+}
+
+// push queue a message to the connection, it is sent after the replies of the commands already received
+func (c *conn) push(msg any) {
+	c.qmu.Lock()
+	c.pushes = append(c.pushes, msg)
+	c.qcond.Signal()
+	c.qmu.Unlock()
+}
+
+// execute run a command received from the client
+func (c *conn) execute(args []string) any {
+	if args == nil {
+		return errorReply("ERR Protocol error")
+	}
+	name := strings.ToLower(args[0])
+	spec, ok := commands[name]
+	if !ok {
+		if c.multi != nil {
+			c.dirty = true
+		}
+		return errorReplyf("ERR unknown command '%s'", args[0])
+	}
+	if !spec.checkArity(len(args)) {
+		if c.multi != nil {
+			c.dirty = true
+		}
+		return errWrongArgs(name)
+	}
+
+	if c.subscribed() && !spec.pubsub {
+		return errorReplyf("ERR Can't execute '%s': only (P)SUBSCRIBE / (P)UNSUBSCRIBE / PING / QUIT are allowed in this context", name)
+	}
+	if c.multi != nil && !spec.tx {
+		*c.multi = append(*c.multi, args)
+		return statusReply("QUEUED")
+	}
+
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+	defer c.s.changed.Broadcast()
+	return spec.fn(&cmdContext{s: c.s, c: c, db: c.s.dbs[c.db], now: c.s.now()}, args)
+}
@@ -0,0 +1,36 @@
+package memredis
+
+import "testing"
+
+func TestMatchPattern(t *testing.T) {
+	cases := []struct {
+		pattern, str string
+		match        bool
+	}{
+		{"*", "anything", true},
+		{"user:*", "user:1", true},
+		{"user:*", "player:1", false},
+		{"user:?", "user:12", false},
+		{"user:[0-9]", "user:7", true},
+		{"user:[^0-9]", "user:7", false},
+		{`a\*b`, "a*b", true},
+	}
+	for _, c := range cases {
+		if got := matchPattern(c.pattern, c.str); got != c.match {
+			t.Errorf("matchPattern(%q, %q) = %v, want %v", c.pattern, c.str, got, c.match)
+		}
+	}
+}
+
+func TestIndexRange(t *testing.T) {
+	l := []int{0, 1, 2, 3, 4}
+	if got := indexRange(l, 1, -2); len(got) != 3 || got[0] != 1 || got[2] != 3 {
+		t.Errorf("indexRange(1, -2) = %v", got)
+	}
+	if got := indexRange(l, 3, 1); len(got) != 0 {
+		t.Errorf("indexRange(3, 1) = %v", got)
+	}
+	if got := indexRange(l, -100, 100); len(got) != 5 {
+		t.Errorf("indexRange(-100, 100) = %v", got)
+	}
+}
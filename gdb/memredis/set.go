@@ -0,0 +1,171 @@
+package memredis
+
+import (
+	"sort"
+	"strconv"
+)
+
+type setValue map[string]struct{}
+
+func (s setValue) size() int { return len(s) }
+
+// members return the sorted members, so replies are deterministic
+func (s setValue) members() []string {
+	members := make([]string, 0, len(s))
+	for m := range s {
+		members = append(members, m)
+	}
+	sort.Strings(members)
+	return members
+}
+
+func registerSetCommands() {
+	register("sadd", -3, cmdSAdd)
+	register("srem", -3, cmdSRem)
+	register("smembers", 2, cmdSMembers)
+	register("sismember", 3, cmdSIsMember)
+	register("smismember", -3, cmdSMIsMember)
+	register("scard", 2, cmdSCard)
+	register("spop", -2, cmdSPop)
+	register("srandmember", -2, cmdSRandMember)
+}
+
+func cmdSAdd(ctx *cmdContext, args []string) any {
+	s, err := ctx.getSet(args[1], true)
+	if err != "" {
+		return err
+	}
+	var n int64
+	for _, m := range args[2:] {
+		if _, ok := s[m]; !ok {
+			s[m] = struct{}{}
+			n++
+		}
+	}
+	ctx.db.touch(args[1])
+	return n
+}
+
+func cmdSRem(ctx *cmdContext, args []string) any {
+	s, err := ctx.getSet(args[1], false)
+	if err != "" {
+		return err
+	}
+	var n int64
+	for _, m := range args[2:] {
+		if _, ok := s[m]; ok {
+			delete(s, m)
+			n++
+		}
+	}
+	if n > 0 {
+		ctx.db.touch(args[1])
+		ctx.removeIfEmpty(args[1])
+	}
+	return n
+}
+
+func cmdSMembers(ctx *cmdContext, args []string) any {
+	s, err := ctx.getSet(args[1], false)
+	if err != "" {
+		return err
+	}
+	return s.members()
+}
+
+func cmdSIsMember(ctx *cmdContext, args []string) any {
+	s, err := ctx.getSet(args[1], false)
+	if err != "" {
+		return err
+	}
+	_, ok := s[args[2]]
+	return ok
+}
+
+func cmdSMIsMember(ctx *cmdContext, args []string) any {
+	s, err := ctx.getSet(args[1], false)
+	if err != "" {
+		return err
+	}
+	rets := make([]any, 0, len(args)-2)
+	for _, m := range args[2:] {
+		_, ok := s[m]
+		rets = append(rets, ok)
+	}
+	return rets
+}
+
+func cmdSCard(ctx *cmdContext, args []string) any {
+	s, err := ctx.getSet(args[1], false)
+	if err != "" {
+		return err
+	}
+	return int64(len(s))
+}
+
+// cmdSPop pop members in map iteration order, which is random enough for tests
+func cmdSPop(ctx *cmdContext, args []string) any {
+	count := -1
+	if len(args) > 2 {
+		n, perr := strconv.Atoi(args[2])
+		if perr != nil || n < 0 {
+			return errorReply("ERR value is out of range, must be positive")
+		}
+		count = n
+	}
+	s, err := ctx.getSet(args[1], false)
+	if err != "" {
+		return err
+	}
+	popped := make([]string, 0)
+	for m := range s {
+		if count < 0 && len(popped) == 1 || count >= 0 && len(popped) >= count {
+			break
+		}
+		popped = append(popped, m)
+		delete(s, m)
+	}
+	if len(popped) > 0 {
+		ctx.db.touch(args[1])
+		ctx.removeIfEmpty(args[1])
+	}
+	if count < 0 {
+		if len(popped) == 0 {
+			return nil
+		}
+		return popped[0]
+	}
+	return popped
+}
+
+func cmdSRandMember(ctx *cmdContext, args []string) any {
+	count := -1
+	if len(args) > 2 {
+		n, perr := strconv.Atoi(args[2])
+		if perr != nil {
+			return errNotInt
+		}
+		if n < 0 { // negative count allows repeated members in redis, distinct members are fine for tests
+			n = -n
+		}
+		count = n
+	}
+	s, err := ctx.getSet(args[1], false)
+	if err != "" {
+		return err
+	}
+	members := make([]string, 0)
+	for m := range s {
+		if count < 0 && len(members) == 1 || count >= 0 && len(members) >= count {
+			break
+		}
+		members = append(members, m)
+	}
+	if count < 0 {
+		if len(members) == 0 {
+			return nil
+		}
+		return members[0]
+	}
+	return members
+}
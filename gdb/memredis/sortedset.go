@@ -0,0 +1,480 @@
+package memredis
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type zmember struct {
+	member string
+	score  float64
+}
+
+type zset struct {
+	scores map[string]float64
+}
+
+func newZSet() *zset {
+	return &zset{scores: make(map[string]float64)}
+}
+
+func (z *zset) size() int { return len(z.scores) }
+
+// sorted return members ordered by score, then by member
+func (z *zset) sorted() []zmember {
+	members := make([]zmember, 0, len(z.scores))
+	for m, s := range z.scores {
+		members = append(members, zmember{member: m, score: s})
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].score != members[j].score {
+			return members[i].score < members[j].score
+		}
+		return members[i].member < members[j].member
+	})
+	return members
+}
+
+func (z *zset) rank(member string) (int, bool) {
+	if _, ok := z.scores[member]; !ok {
+		return 0, false
+	}
+	for i, m := range z.sorted() {
+		if m.member == member {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// scoreBound is a min or max argument of the by score commands
+type scoreBound struct {
+	value     float64
+	exclusive bool
+}
+
+func parseScoreBound(s string) (scoreBound, bool) {
+	var b scoreBound
+	if strings.HasPrefix(s, "(") {
+		b.exclusive = true
+		s = s[1:]
+	}
+	switch strings.ToLower(s) {
+	case "-inf":
+		b.value = math.Inf(-1)
+	case "+inf", "inf":
+		b.value = math.Inf(1)
+	default:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return b, false
+		}
+		b.value = f
+	}
+	return b, true
+}
+
+func (b scoreBound) aboveMin(score float64) bool {
+	if b.exclusive {
+		return score > b.value
+	}
+	return score >= b.value
+}
+
+func (b scoreBound) belowMax(score float64) bool {
+	if b.exclusive {
+		return score < b.value
+	}
+	return score <= b.value
+}
+
+func registerZSetCommands() {
+	register("zadd", -4, cmdZAdd)
+	register("zcard", 2, cmdZCard)
+	register("zcount", 4, cmdZCount)
+	register("zincrby", 4, cmdZIncrBy)
+	register("zrange", -4, cmdZRange)
+	register("zrevrange", -4, cmdZRange)
+	register("zrangebyscore", -4, cmdZRange)
+	register("zrevrangebyscore", -4, cmdZRange)
+	register("zrank", 3, cmdZRank)
+	register("zrevrank", 3, cmdZRank)
+	register("zrem", -3, cmdZRem)
+	register("zremrangebyrank", 4, cmdZRemRangeByRank)
+	register("zremrangebyscore", 4, cmdZRemRangeByScore)
+	register("zscore", 3, cmdZScore)
+	register("zmscore", -3, cmdZMScore)
+}
+
+func cmdZAdd(ctx *cmdContext, args []string) any {
+	var nx, xx, gt, lt, ch, incr bool
+	i := 2
+loop:
+	for ; i < len(args); i++ {
+		switch strings.ToLower(args[i]) {
+		case "nx":
+			nx = true
+		case "xx":
+			xx = true
+		case "gt":
+			gt = true
+		case "lt":
+			lt = true
+		case "ch":
+			ch = true
+		case "incr":
+			incr = true
+		default:
+			break loop
+		}
+	}
+	pairs := args[i:]
+	if len(pairs) == 0 || len(pairs)%2 != 0 || (nx && xx) || (incr && len(pairs) != 2) {
+		return errSyntax
+	}
+	scores := make([]float64, 0, len(pairs)/2)
+	for j := 0; j < len(pairs); j += 2 {
+		s, err := strconv.ParseFloat(pairs[j], 64)
+		if err != nil {
+			return errNotFloat
+		}
+		scores = append(scores, s)
+	}
+
+	z, err := ctx.getZSet(args[1], true)
+	if err != "" {
+		return err
+	}
+	var added, changed int64
+	var result any
+	for j := 0; j < len(pairs); j += 2 {
+		member, score := pairs[j+1], scores[j/2]
+		old, exists := z.scores[member]
+		if (nx && exists) || (xx && !exists) {
+			continue
+		}
+		if incr && exists {
+			score += old
+		}
+		if exists && ((gt && score <= old) || (lt && score >= old)) {
+			continue
+		}
+		z.scores[member] = score
+		result = formatFloat(score)
+		if !exists {
+			added++
+		} else if old != score {
+			changed++
+		}
+	}
+	ctx.db.touch(args[1])
+	ctx.removeIfEmpty(args[1])
+	if incr {
+		return result
+	}
+	if ch {
+		return added + changed
+	}
+	return added
+}
+
+func cmdZCard(ctx *cmdContext, args []string) any {
+	z, err := ctx.getZSet(args[1], false)
+	if err != "" {
+		return err
+	}
+	if z == nil {
+		return int64(0)
+	}
+	return int64(len(z.scores))
+}
+
+func cmdZCount(ctx *cmdContext, args []string) any {
+	min, ok1 := parseScoreBound(args[2])
+	max, ok2 := parseScoreBound(args[3])
+	if !ok1 || !ok2 {
+		return errorReply("ERR min or max is not a float")
+	}
+	z, err := ctx.getZSet(args[1], false)
+	if err != "" {
+		return err
+	}
+	if z == nil {
+		return int64(0)
+	}
+	var n int64
+	for _, s := range z.scores {
+		if min.aboveMin(s) && max.belowMax(s) {
+			n++
+		}
+	}
+	return n
+}
+
+func cmdZIncrBy(ctx *cmdContext, args []string) any {
+	delta, perr := strconv.ParseFloat(args[2], 64)
+	if perr != nil {
+		return errNotFloat
+	}
+	z, err := ctx.getZSet(args[1], true)
+	if err != "" {
+		return err
+	}
+	z.scores[args[3]] += delta
+	ctx.db.touch(args[1])
+	return formatFloat(z.scores[args[3]])
+}
+
+// cmdZRange handle ZRANGE with all its options and the legacy ZREVRANGE, ZRANGEBYSCORE and ZREVRANGEBYSCORE
+func cmdZRange(ctx *cmdContext, args []string) any {
+	name := strings.ToLower(args[0])
+	var (
+		byScore    = strings.HasSuffix(name, "byscore")
+		rev        = strings.HasPrefix(name, "zrev")
+		withScores bool
+		offset     int64
+		count      int64 = -1
+	)
+	start, stop := args[2], args[3]
+	if rev && byScore { // legacy ZREVRANGEBYSCORE takes max before min
+		start, stop = stop, start
+	}
+	for i := 4; i < len(args); i++ {
+		switch strings.ToLower(args[i]) {
+		case "withscores":
+			withScores = true
+		case "byscore":
+			byScore = true
+		case "rev":
+			rev = true
+		case "limit":
+			if i+2 >= len(args) {
+				return errSyntax
+			}
+			var err1, err2 error
+			offset, err1 = strconv.ParseInt(args[i+1], 10, 64)
+			count, err2 = strconv.ParseInt(args[i+2], 10, 64)
+			if err1 != nil || err2 != nil {
+				return errNotInt
+			}
+			i += 2
+		default:
+			return errSyntax
+		}
+	}
+	if name == "zrange" && rev && byScore { // ZRANGE key max min BYSCORE REV
+		start, stop = stop, start
+	}
+
+	z, err := ctx.getZSet(args[1], false)
+	if err != "" {
+		return err
+	}
+	var members []zmember
+	if z != nil {
+		members = z.sorted()
+	}
+	if byScore {
+		min, ok1 := parseScoreBound(start)
+		max, ok2 := parseScoreBound(stop)
+		if !ok1 || !ok2 {
+			return errorReply("ERR min or max is not a float")
+		}
+		selected := make([]zmember, 0, len(members))
+		for _, m := range members {
+			if min.aboveMin(m.score) && max.belowMax(m.score) {
+				selected = append(selected, m)
+			}
+		}
+		if rev {
+			reverse(selected)
+		}
+		members = limit(selected, offset, count)
+	} else {
+		b, e1 := strconv.ParseInt(start, 10, 64)
+		s, e2 := strconv.ParseInt(stop, 10, 64)
+		if e1 != nil || e2 != nil {
+			return errNotInt
+		}
+		if rev {
+			reverse(members)
+		}
+		members = indexRange(members, b, s)
+	}
+
+	rets := make([]string, 0, 2*len(members))
+	for _, m := range members {
+		rets = append(rets, m.member)
+		if withScores {
+			rets = append(rets, formatFloat(m.score))
+		}
+	}
+	return rets
+}
+
+func reverse(members []zmember) {
+	for i, j := 0, len(members)-1; i < j; i, j = i+1, j-1 {
+		members[i], members[j] = members[j], members[i]
+	}
+}
+
+func limit(members []zmember, offset, count int64) []zmember {
+	if offset < 0 || offset >= int64(len(members)) {
+		return nil
+	}
+	members = members[offset:]
+	if count >= 0 && count < int64(len(members)) {
+		members = members[:count]
+	}
+	return members
+}
+
+// indexRange apply redis style start and stop indexes, negative index counts from the end
+func indexRange[T any](l []T, start, stop int64) []T {
+	n := int64(len(l))
+	if start < 0 {
+		start += n
+	}
+	if stop < 0 {
+		stop += n
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= n {
+		stop = n - 1
+	}
+	if start > stop || start >= n {
+		return nil
+	}
+	return l[start : stop+1]
+}
+
+func cmdZRank(ctx *cmdContext, args []string) any {
+	z, err := ctx.getZSet(args[1], false)
+	if err != "" {
+		return err
+	}
+	if z == nil {
+		return nil
+	}
+	r, ok := z.rank(args[2])
+	if !ok {
+		return nil
+	}
+	if strings.EqualFold(args[0], "zrevrank") {
+		return int64(len(z.scores) - 1 - r)
+	}
+	return int64(r)
+}
+
+func cmdZRem(ctx *cmdContext, args []string) any {
+	z, err := ctx.getZSet(args[1], false)
+	if err != "" {
+		return err
+	}
+	if z == nil {
+		return int64(0)
+	}
+	var n int64
+	for _, m := range args[2:] {
+		if _, ok := z.scores[m]; ok {
+			delete(z.scores, m)
+			n++
+		}
+	}
+	if n > 0 {
+		ctx.db.touch(args[1])
+		ctx.removeIfEmpty(args[1])
+	}
+	return n
+}
+
+func cmdZRemRangeByRank(ctx *cmdContext, args []string) any {
+	start, e1 := strconv.ParseInt(args[2], 10, 64)
+	stop, e2 := strconv.ParseInt(args[3], 10, 64)
+	if e1 != nil || e2 != nil {
+		return errNotInt
+	}
+	z, err := ctx.getZSet(args[1], false)
+	if err != "" {
+		return err
+	}
+	if z == nil {
+		return int64(0)
+	}
+	removed := indexRange(z.sorted(), start, stop)
+	for _, m := range removed {
+		delete(z.scores, m.member)
+	}
+	if len(removed) > 0 {
+		ctx.db.touch(args[1])
+		ctx.removeIfEmpty(args[1])
+	}
+	return int64(len(removed))
+}
+
+func cmdZRemRangeByScore(ctx *cmdContext, args []string) any {
+	min, ok1 := parseScoreBound(args[2])
+	max, ok2 := parseScoreBound(args[3])
+	if !ok1 || !ok2 {
+		return errorReply("ERR min or max is not a float")
+	}
+	z, err := ctx.getZSet(args[1], false)
+	if err != "" {
+		return err
+	}
+	if z == nil {
+		return int64(0)
+	}
+	var n int64
+	for m, s := range z.scores {
+		if min.aboveMin(s) && max.belowMax(s) {
+			delete(z.scores, m)
+			n++
+		}
+	}
+	if n > 0 {
+		ctx.db.touch(args[1])
+		ctx.removeIfEmpty(args[1])
+	}
+	return n
+}
+
+func cmdZScore(ctx *cmdContext, args []string) any {
+	z, err := ctx.getZSet(args[1], false)
+	if err != "" {
+		return err
+	}
+	if z == nil {
+		return nil
+	}
+	s, ok := z.scores[args[2]]
+	if !ok {
+		return nil
+	}
+	return formatFloat(s)
+}
+
+func cmdZMScore(ctx *cmdContext, args []string) any {
+	z, err := ctx.getZSet(args[1], false)
+	if err != "" {
+		return err
+	}
+	rets := make([]any, 0, len(args)-2)
+	for _, m := range args[2:] {
+		if z == nil {
+			rets = append(rets, nil)
+			continue
+		}
+		s, ok := z.scores[m]
+		if !ok {
+			rets = append(rets, nil)
+			continue
+		}
+		rets = append(rets, formatFloat(s))
+	}
+	return rets
+}
@@ -0,0 +1,766 @@
+package memredis
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type streamID struct {
+	ms, seq uint64
+}
+
+func (id streamID) String() string {
+	return fmt.Sprintf("%d-%d", id.ms, id.seq)
+}
+
+func (id streamID) less(o streamID) bool {
+	return id.ms < o.ms || (id.ms == o.ms && id.seq < o.seq)
+}
+
+// parseStreamID parse a complete or partial id, a missing sequence is filled by defaultSeq
+func parseStreamID(s string, defaultSeq uint64) (streamID, bool) {
+	var id streamID
+	var err error
+	msStr, seqStr, hasSeq := strings.Cut(s, "-")
+	if id.ms, err = strconv.ParseUint(msStr, 10, 64); err != nil {
+		return id, false
+	}
+	if !hasSeq {
+		id.seq = defaultSeq
+		return id, true
+	}
+	if id.seq, err = strconv.ParseUint(seqStr, 10, 64); err != nil {
+		return id, false
+	}
+	return id, true
+}
+
+// parseRangeID parse the start or end argument of XRANGE and XPENDING
+func parseRangeID(s string, isStart bool) (streamID, bool) {
+	exclusive := strings.HasPrefix(s, "(")
+	if exclusive {
+		s = s[1:]
+	}
+	var id streamID
+	switch s {
+	case "-":
+		id = streamID{}
+	case "+":
+		id = streamID{ms: math.MaxUint64, seq: math.MaxUint64}
+	default:
+		var ok bool
+		if isStart {
+			id, ok = parseStreamID(s, 0)
+		} else {
+			id, ok = parseStreamID(s, math.MaxUint64)
+		}
+		if !ok {
+			return id, false
+		}
+	}
+	if exclusive {
+		if isStart {
+			if id.seq == math.MaxUint64 {
+				id.ms, id.seq = id.ms+1, 0
+			} else {
+				id.seq++
+			}
+		} else {
+			if id.seq == 0 {
+				id.ms, id.seq = id.ms-1, math.MaxUint64
+			} else {
+				id.seq--
+			}
+		}
+	}
+	return id, true
+}
+
+var errInvalidStreamID = errorReply("ERR Invalid stream ID specified as stream command argument")
+
+type streamEntry struct {
+	id     streamID
+	fields []string
+}
+
+type pendingEntry struct {
+	consumer    string
+	deliveredAt time.Time
+	deliveries  int64
+}
+
+type streamGroup struct {
+	lastID    streamID
+	pending   map[streamID]*pendingEntry
+	consumers map[string]struct{}
+}
+
+// stream is not a container, an empty stream key is kept like redis does
+type stream struct {
+	entries []streamEntry
+	lastID  streamID
+	groups  map[string]*streamGroup
+}
+
+func newStream() *stream {
+	return &stream{groups: make(map[string]*streamGroup)}
+}
+
+func (st *stream) find(id streamID) (streamEntry, bool) {
+	i := sort.Search(len(st.entries), func(i int) bool { return !st.entries[i].id.less(id) })
+	if i < len(st.entries) && st.entries[i].id == id {
+		return st.entries[i], true
+	}
+	return streamEntry{}, false
+}
+
+func (st *stream) rangeOf(start, end streamID) []streamEntry {
+	rets := make([]streamEntry, 0)
+	for _, e := range st.entries {
+		if !e.id.less(start) && !end.less(e.id) {
+			rets = append(rets, e)
+		}
+	}
+	return rets
+}
+
+// trim apply MAXLEN or MINID strategy, it returns the number of deleted entries
+func (st *stream) trim(strategy string, threshold string) (int64, errorReply) {
+	var removed int
+	switch strings.ToLower(strategy) {
+	case "maxlen":
+		n, err := strconv.Atoi(threshold)
+		if err != nil || n < 0 {
+			return 0, errNotInt
+		}
+		if len(st.entries) > n {
+			removed = len(st.entries) - n
+		}
+	case "minid":
+		id, ok := parseStreamID(threshold, 0)
+		if !ok {
+			return 0, errInvalidStreamID
+		}
+		for removed < len(st.entries) && st.entries[removed].id.less(id) {
+			removed++
+		}
+	default:
+		return 0, errSyntax
+	}
+	st.entries = st.entries[removed:]
+	return int64(removed), ""
+}
+
+func entryReply(e streamEntry) []any {
+	return []any{e.id.String(), e.fields}
+}
+
+func registerStreamCommands() {
+	register("xadd", -5, cmdXAdd)
+	register("xlen", 2, cmdXLen)
+	register("xrange", -4, cmdXRange)
+	register("xrevrange", -4, cmdXRange)
+	register("xdel", -3, cmdXDel)
+	register("xtrim", -4, cmdXTrim)
+	register("xgroup", -2, cmdXGroup)
+	register("xread", -4, cmdXRead)
+	register("xreadgroup", -7, cmdXRead)
+	register("xack", -4, cmdXAck)
+	register("xpending", -3, cmdXPending)
+	register("xclaim", -6, cmdXClaim)
+}
+
+func cmdXAdd(ctx *cmdContext, args []string) any {
+	i := 2
+	var noMkStream bool
+	var strategy, threshold string
+	for ; i < len(args); i++ {
+		switch opt := strings.ToLower(args[i]); opt {
+		case "nomkstream":
+			noMkStream = true
+			continue
+		case "maxlen", "minid":
+			strategy = opt
+			if i+1 < len(args) && (args[i+1] == "~" || args[i+1] == "=") {
+				i++
+			}
+			if i+1 >= len(args) {
+				return errSyntax
+			}
+			threshold = args[i+1]
+			i++
+			continue
+		case "limit":
+			i++
+			continue
+		}
+		break
+	}
+	if i >= len(args) || (len(args)-i-1)%2 != 0 || len(args)-i-1 == 0 {
+		return errWrongArgs("xadd")
+	}
+	if noMkStream && ctx.lookup(args[1]) == nil {
+		return nil
+	}
+	st, err := ctx.getStream(args[1], true)
+	if err != "" {
+		return err
+	}
+	var id streamID
+	if args[i] == "*" {
+		id = streamID{ms: uint64(ctx.now.UnixMilli())}
+		if !st.lastID.less(id) {
+			id = streamID{ms: st.lastID.ms, seq: st.lastID.seq + 1}
+		}
+	} else {
+		var ok bool
+		id, ok = parseStreamID(args[i], 0)
+		if !ok {
+			return errInvalidStreamID
+		}
+		if id == (streamID{}) || !st.lastID.less(id) {
+			return errorReply("ERR The ID specified in XADD is equal or smaller than the target stream top item")
+		}
+	}
+	st.entries = append(st.entries, streamEntry{id: id, fields: append([]string{}, args[i+1:]...)})
+	st.lastID = id
+	if strategy != "" {
+		if _, err = st.trim(strategy, threshold); err != "" {
+			return err
+		}
+	}
+	ctx.db.touch(args[1])
+	return id.String()
+}
+
+func cmdXLen(ctx *cmdContext, args []string) any {
+	st, err := ctx.getStream(args[1], false)
+	if err != "" {
+		return err
+	}
+	if st == nil {
+		return int64(0)
+	}
+	return int64(len(st.entries))
+}
+
+func cmdXRange(ctx *cmdContext, args []string) any {
+	rev := strings.EqualFold(args[0], "xrevrange")
+	startArg, endArg := args[2], args[3]
+	if rev {
+		startArg, endArg = endArg, startArg
+	}
+	start, ok1 := parseRangeID(startArg, true)
+	end, ok2 := parseRangeID(endArg, false)
+	if !ok1 || !ok2 {
+		return errInvalidStreamID
+	}
+	count := -1
+	if len(args) == 6 && strings.EqualFold(args[4], "count") {
+		n, perr := strconv.Atoi(args[5])
+		if perr != nil {
+			return errNotInt
+		}
+		count = n
+	} else if len(args) != 4 {
+		return errSyntax
+	}
+	st, err := ctx.getStream(args[1], false)
+	if err != "" {
+		return err
+	}
+	rets := make([]any, 0)
+	if st == nil {
+		return rets
+	}
+	entries := st.rangeOf(start, end)
+	if rev {
+		for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+			entries[i], entries[j] = entries[j], entries[i]
+		}
+	}
+	for _, e := range entries {
+		if count >= 0 && len(rets) >= count {
+			break
+		}
+		rets = append(rets, entryReply(e))
+	}
+	return rets
+}
+
+func cmdXDel(ctx *cmdContext, args []string) any {
+	st, err := ctx.getStream(args[1], false)
+	if err != "" {
+		return err
+	}
+	if st == nil {
+		return int64(0)
+	}
+	var n int64
+	for _, s := range args[2:] {
+		id, ok := parseStreamID(s, 0)
+		if !ok {
+			return errInvalidStreamID
+		}
+		for i, e := range st.entries {
+			if e.id == id {
+				st.entries = append(st.entries[:i], st.entries[i+1:]...)
+				n++
+				break
+			}
+		}
+	}
+	if n > 0 {
+		ctx.db.touch(args[1])
+	}
+	return n
+}
+
+func cmdXTrim(ctx *cmdContext, args []string) any {
+	threshold := args[3]
+	if (args[3] == "~" || args[3] == "=") && len(args) > 4 {
+		threshold = args[4]
+	}
+	st, err := ctx.getStream(args[1], false)
+	if err != "" {
+		return err
+	}
+	if st == nil {
+		return int64(0)
+	}
+	n, err := st.trim(args[2], threshold)
+	if err != "" {
+		return err
+	}
+	if n > 0 {
+		ctx.db.touch(args[1])
+	}
+	return n
+}
+
+func cmdXGroup(ctx *cmdContext, args []string) any {
+	sub := strings.ToLower(args[1])
+	if len(args) < 4 {
+		return errWrongArgs("xgroup|" + sub)
+	}
+	key, group := args[2], args[3]
+	switch sub {
+	case "create":
+		if len(args) < 5 {
+			return errWrongArgs("xgroup|create")
+		}
+		mkStream := len(args) > 5 && strings.EqualFold(args[5], "mkstream")
+		if !mkStream && ctx.lookup(key) == nil {
+			return errorReply("ERR The XGROUP subcommand requires the key to exist. " +
+				"Note that for CREATE you may want to use the MKSTREAM option to create an empty stream automatically.")
+		}
+		st, err := ctx.getStream(key, true)
+		if err != "" {
+			return err
+		}
+		if _, ok := st.groups[group]; ok {
+			return errorReply("BUSYGROUP Consumer Group name already exists")
+		}
+		g := &streamGroup{pending: make(map[streamID]*pendingEntry), consumers: make(map[string]struct{})}
+		if args[4] == "$" {
+			g.lastID = st.lastID
+		} else {
+			id, ok := parseStreamID(args[4], 0)
+			if !ok {
+				return errInvalidStreamID
+			}
+			g.lastID = id
+		}
+		st.groups[group] = g
+		ctx.db.touch(key)
+		return replyOK
+	case "destroy":
+		st, err := ctx.getStream(key, false)
+		if err != "" {
+			return err
+		}
+		if st == nil || st.groups[group] == nil {
+			return int64(0)
+		}
+		delete(st.groups, group)
+		ctx.db.touch(key)
+		return int64(1)
+	case "setid", "createconsumer", "delconsumer":
+		if len(args) < 5 {
+			return errWrongArgs("xgroup|" + sub)
+		}
+		st, err := ctx.getStream(key, false)
+		if err != "" {
+			return err
+		}
+		if st == nil || st.groups[group] == nil {
+			return errorReplyf("NOGROUP No such consumer group '%s' for key name '%s'", group, key)
+		}
+		g := st.groups[group]
+		ctx.db.touch(key)
+		switch sub {
+		case "setid":
+			if args[4] == "$" {
+				g.lastID = st.lastID
+				return replyOK
+			}
+			id, ok := parseStreamID(args[4], 0)
+			if !ok {
+				return errInvalidStreamID
+			}
+			g.lastID = id
+			return replyOK
+		case "createconsumer":
+			if _, ok := g.consumers[args[4]]; ok {
+				return int64(0)
+			}
+			g.consumers[args[4]] = struct{}{}
+			return int64(1)
+		default:
+			var n int64
+			for id, p := range g.pending {
+				if p.consumer == args[4] {
+					delete(g.pending, id)
+					n++
+				}
+			}
+			delete(g.consumers, args[4])
+			return n
+		}
+	default:
+		return errorReplyf("ERR unknown subcommand '%s'", args[1])
+	}
+}
+
+// cmdXRead handle both XREAD and XREADGROUP
+func cmdXRead(ctx *cmdContext, args []string) any {
+	var (
+		group, consumer string
+		count           = -1
+		block           = -1
+		noAck           bool
+		i               = 1
+	)
+	isGroup := strings.EqualFold(args[0], "xreadgroup")
+	if isGroup {
+		if !strings.EqualFold(args[1], "group") {
+			return errSyntax
+		}
+		group, consumer = args[2], args[3]
+		i = 4
+	}
+	for ; i < len(args); i++ {
+		opt := strings.ToLower(args[i])
+		if opt == "streams" {
+			i++
+			break
+		}
+		switch opt {
+		case "count", "block":
+			if i+1 >= len(args) {
+				return errSyntax
+			}
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil || n < 0 {
+				return errNotInt
+			}
+			if opt == "count" {
+				count = n
+			} else {
+				block = n
+			}
+			i++
+		case "noack":
+			noAck = true
+		default:
+			return errSyntax
+		}
+	}
+	rest := args[i:]
+	if len(rest) == 0 || len(rest)%2 != 0 {
+		return errorReply("ERR Unbalanced 'xread' list of streams: for each stream key an ID or '$' must be specified.")
+	}
+	keys, ids := rest[:len(rest)/2], rest[len(rest)/2:]
+
+	// resolve $ to the last id at the time the command is called
+	starts := make([]string, len(ids))
+	for j, id := range ids {
+		starts[j] = id
+		if id == "$" && !isGroup {
+			st, err := ctx.getStream(keys[j], false)
+			if err != "" {
+				return err
+			}
+			starts[j] = streamID{}.String()
+			if st != nil {
+				starts[j] = st.lastID.String()
+			}
+		}
+	}
+
+	var deadline time.Time
+	if block > 0 {
+		deadline = time.Now().Add(time.Duration(block) * time.Millisecond)
+	}
+	for {
+		rets := make([]any, 0, len(keys))
+		for j, key := range keys {
+			st, err := ctx.getStream(key, false)
+			if err != "" {
+				return err
+			}
+			var entries []any
+			var errReply errorReply
+			if isGroup {
+				entries, errReply = ctx.readGroup(key, st, group, consumer, starts[j], count, noAck)
+			} else {
+				entries, errReply = readStream(st, starts[j], count)
+			}
+			if errReply != "" {
+				return errReply
+			}
+			if entries != nil {
+				rets = append(rets, []any{key, entries})
+			}
+		}
+		if len(rets) > 0 {
+			return rets
+		}
+		if block < 0 || !ctx.block(deadline) {
+			return nilArray{}
+		}
+	}
+}
+
+func readStream(st *stream, start string, count int) ([]any, errorReply) {
+	after, ok := parseStreamID(start, 0)
+	if !ok {
+		return nil, errInvalidStreamID
+	}
+	if st == nil {
+		return nil, ""
+	}
+	var rets []any
+	for _, e := range st.entries {
+		if count > 0 && len(rets) >= count {
+			break
+		}
+		if after.less(e.id) {
+			rets = append(rets, entryReply(e))
+		}
+	}
+	return rets, ""
+}
+
+// readGroup read new messages with id ">", or the pending history of the consumer with other ids
+func (ctx *cmdContext) readGroup(key string, st *stream, group, consumer, start string, count int, noAck bool) (
+	[]any, errorReply) {
+	if st == nil || st.groups[group] == nil {
+		return nil, errorReplyf("NOGROUP No such key '%s' or consumer group '%s' in XREADGROUP with GROUP option",
+			key, group)
+	}
+	g := st.groups[group]
+	g.consumers[consumer] = struct{}{}
+	if start != ">" {
+		after, ok := parseStreamID(start, 0)
+		if !ok {
+			return nil, errInvalidStreamID
+		}
+		ids := make([]streamID, 0)
+		for id, p := range g.pending {
+			if p.consumer == consumer && after.less(id) {
+				ids = append(ids, id)
+			}
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i].less(ids[j]) })
+		rets := make([]any, 0, len(ids))
+		for _, id := range ids {
+			if count > 0 && len(rets) >= count {
+				break
+			}
+			p := g.pending[id]
+			p.deliveries++
+			p.deliveredAt = ctx.now
+			if e, ok := st.find(id); ok {
+				rets = append(rets, entryReply(e))
+			} else {
+				rets = append(rets, []any{id.String(), nilArray{}})
+			}
+		}
+		return rets, ""
+	}
+
+	var rets []any
+	for _, e := range st.entries {
+		if count > 0 && len(rets) >= count {
+			break
+		}
+		if !g.lastID.less(e.id) {
+			continue
+		}
+		g.lastID = e.id
+		if !noAck {
+			g.pending[e.id] = &pendingEntry{consumer: consumer, deliveredAt: ctx.now, deliveries: 1}
+		}
+		rets = append(rets, entryReply(e))
+	}
+	if len(rets) > 0 {
+		ctx.db.touch(key)
+	}
+	return rets, ""
+}
+
+func cmdXAck(ctx *cmdContext, args []string) any {
+	st, err := ctx.getStream(args[1], false)
+	if err != "" {
+		return err
+	}
+	if st == nil || st.groups[args[2]] == nil {
+		return int64(0)
+	}
+	g := st.groups[args[2]]
+	var n int64
+	for _, s := range args[3:] {
+		id, ok := parseStreamID(s, 0)
+		if !ok {
+			return errInvalidStreamID
+		}
+		if _, ok = g.pending[id]; ok {
+			delete(g.pending, id)
+			n++
+		}
+	}
+	if n > 0 {
+		ctx.db.touch(args[1])
+	}
+	return n
+}
+
+func cmdXPending(ctx *cmdContext, args []string) any {
+	st, err := ctx.getStream(args[1], false)
+	if err != "" {
+		return err
+	}
+	if st == nil || st.groups[args[2]] == nil {
+		return errorReplyf("NOGROUP No such key '%s' or consumer group '%s'", args[1], args[2])
+	}
+	g := st.groups[args[2]]
+	ids := make([]streamID, 0, len(g.pending))
+	for id := range g.pending {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i].less(ids[j]) })
+
+	if len(args) == 3 { // summary form
+		if len(ids) == 0 {
+			return []any{int64(0), nil, nil, nilArray{}}
+		}
+		perConsumer := make(map[string]int64)
+		for _, p := range g.pending {
+			perConsumer[p.consumer]++
+		}
+		names := make([]string, 0, len(perConsumer))
+		for name := range perConsumer {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		consumers := make([]any, 0, len(names))
+		for _, name := range names {
+			consumers = append(consumers, []any{name, strconv.FormatInt(perConsumer[name], 10)})
+		}
+		return []any{int64(len(ids)), ids[0].String(), ids[len(ids)-1].String(), consumers}
+	}
+
+	rest := args[3:]
+	var minIdle time.Duration
+	if strings.EqualFold(rest[0], "idle") {
+		if len(rest) < 2 {
+			return errSyntax
+		}
+		ms, perr := strconv.ParseInt(rest[1], 10, 64)
+		if perr != nil {
+			return errNotInt
+		}
+		minIdle = time.Duration(ms) * time.Millisecond
+		rest = rest[2:]
+	}
+	if len(rest) < 3 || len(rest) > 4 {
+		return errSyntax
+	}
+	start, ok1 := parseRangeID(rest[0], true)
+	end, ok2 := parseRangeID(rest[1], false)
+	if !ok1 || !ok2 {
+		return errInvalidStreamID
+	}
+	count, perr := strconv.Atoi(rest[2])
+	if perr != nil {
+		return errNotInt
+	}
+	rets := make([]any, 0)
+	for _, id := range ids {
+		if len(rets) >= count {
+			break
+		}
+		p := g.pending[id]
+		idle := ctx.now.Sub(p.deliveredAt)
+		if id.less(start) || end.less(id) || idle < minIdle || (len(rest) == 4 && p.consumer != rest[3]) {
+			continue
+		}
+		rets = append(rets, []any{id.String(), p.consumer, idle.Milliseconds(), p.deliveries})
+	}
+	return rets
+}
+
+// cmdXClaim support the basic form: XCLAIM key group consumer min-idle-time id [id ...] [JUSTID]
+func cmdXClaim(ctx *cmdContext, args []string) any {
+	st, err := ctx.getStream(args[1], false)
+	if err != "" {
+		return err
+	}
+	if st == nil || st.groups[args[2]] == nil {
+		return errorReplyf("NOGROUP No such key '%s' or consumer group '%s'", args[1], args[2])
+	}
+	g := st.groups[args[2]]
+	consumer := args[3]
+	ms, perr := strconv.ParseInt(args[4], 10, 64)
+	if perr != nil {
+		return errNotInt
+	}
+	minIdle := time.Duration(ms) * time.Millisecond
+	justID := false
+	rets := make([]any, 0)
+	for _, s := range args[5:] {
+		if strings.EqualFold(s, "justid") {
+			justID = true
+			continue
+		}
+		id, ok := parseStreamID(s, 0)
+		if !ok {
+			return errInvalidStreamID
+		}
+		p, ok := g.pending[id]
+		if !ok || ctx.now.Sub(p.deliveredAt) < minIdle {
+			continue
+		}
+		e, ok := st.find(id)
+		if !ok {
+			delete(g.pending, id)
+			continue
+		}
+		p.consumer = consumer
+		p.deliveredAt = ctx.now
+		if justID {
+			rets = append(rets, id.String())
+		} else {
+			p.deliveries++
+			rets = append(rets, entryReply(e))
+		}
+	}
+	g.consumers[consumer] = struct{}{}
+	ctx.db.touch(args[1])
+	return rets
+}
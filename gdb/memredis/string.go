@@ -0,0 +1,252 @@
+package memredis
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func registerStringCommands() {
+	register("get", 2, cmdGet)
+	register("set", -3, cmdSet)
+	register("setnx", 3, cmdSetNX)
+	register("setex", 4, cmdSetEX)
+	register("getset", 3, cmdGetSet)
+	register("mget", -2, cmdMGet)
+	register("mset", -3, cmdMSet)
+	register("incr", 2, cmdIncrBy)
+	register("decr", 2, cmdIncrBy)
+	register("incrby", 3, cmdIncrBy)
+	register("decrby", 3, cmdIncrBy)
+	register("incrbyfloat", 3, cmdIncrByFloat)
+	register("append", 3, cmdAppend)
+	register("strlen", 2, cmdStrLen)
+}
+
+func cmdGet(ctx *cmdContext, args []string) any {
+	v, ok, err := ctx.getString(args[1])
+	if err != "" {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+	return v
+}
+
+func cmdSet(ctx *cmdContext, args []string) any {
+	key, value := args[1], args[2]
+	var (
+		nx, xx, keepTTL, get bool
+		expireAt             time.Time
+	)
+	for i := 3; i < len(args); i++ {
+		switch opt := strings.ToLower(args[i]); opt {
+		case "nx":
+			nx = true
+		case "xx":
+			xx = true
+		case "keepttl":
+			keepTTL = true
+		case "get":
+			get = true
+		case "ex", "px", "exat", "pxat":
+			if i+1 >= len(args) {
+				return errSyntax
+			}
+			n, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil {
+				return errNotInt
+			}
+			if n <= 0 {
+				return errorReply("ERR invalid expire time in 'set' command")
+			}
+			i++
+			switch opt {
+			case "ex":
+				expireAt = ctx.now.Add(time.Duration(n) * time.Second)
+			case "px":
+				expireAt = ctx.now.Add(time.Duration(n) * time.Millisecond)
+			case "exat":
+				expireAt = time.Unix(n, 0)
+			case "pxat":
+				expireAt = time.UnixMilli(n)
+			}
+		default:
+			return errSyntax
+		}
+	}
+	if nx && xx {
+		return errSyntax
+	}
+
+	old, exists, errType := ctx.getString(key)
+	if get && errType != "" {
+		return errType
+	}
+	e := ctx.lookup(key)
+	if (nx && e != nil) || (xx && e == nil) {
+		if get && exists {
+			return old
+		}
+		return nil
+	}
+	if keepTTL && e != nil {
+		expireAt = e.expireAt
+	}
+	ctx.db.set(key, &entry{value: value, expireAt: expireAt})
+	ctx.s.notify(ctx.db.index, '$', "set", key)
+	if get {
+		if !exists {
+			return nil
+		}
+		return old
+	}
+	return replyOK
+}
+
+func cmdSetNX(ctx *cmdContext, args []string) any {
+	if ctx.lookup(args[1]) != nil {
+		return int64(0)
+	}
+	ctx.db.set(args[1], &entry{value: args[2]})
+	return int64(1)
+}
+
+func cmdSetEX(ctx *cmdContext, args []string) any {
+	n, err := strconv.ParseInt(args[2], 10, 64)
+	if err != nil {
+		return errNotInt
+	}
+	if n <= 0 {
+		return errorReply("ERR invalid expire time in 'setex' command")
+	}
+	ctx.db.set(args[1], &entry{value: args[3], expireAt: ctx.now.Add(time.Duration(n) * time.Second)})
+	return replyOK
+}
+
+func cmdGetSet(ctx *cmdContext, args []string) any {
+	old, exists, err := ctx.getString(args[1])
+	if err != "" {
+		return err
+	}
+	ctx.db.set(args[1], &entry{value: args[2]})
+	if !exists {
+		return nil
+	}
+	return old
+}
+
+func cmdMGet(ctx *cmdContext, args []string) any {
+	rets := make([]any, 0, len(args)-1)
+	for _, key := range args[1:] {
+		v, ok, err := ctx.getString(key)
+		if !ok || err != "" {
+			rets = append(rets, nil)
+			continue
+		}
+		rets = append(rets, v)
+	}
+	return rets
+}
+
+func cmdMSet(ctx *cmdContext, args []string) any {
+	if len(args)%2 != 1 {
+		return errWrongArgs("mset")
+	}
+	for i := 1; i < len(args); i += 2 {
+		ctx.db.set(args[i], &entry{value: args[i+1]})
+	}
+	return replyOK
+}
+
+func cmdIncrBy(ctx *cmdContext, args []string) any {
+	var delta int64 = 1
+	name := strings.ToLower(args[0])
+	if len(args) == 3 {
+		n, err := strconv.ParseInt(args[2], 10, 64)
+		if err != nil {
+			return errNotInt
+		}
+		delta = n
+	}
+	if strings.HasPrefix(name, "decr") {
+		delta = -delta
+	}
+	v, ok, errType := ctx.getString(args[1])
+	if errType != "" {
+		return errType
+	}
+	var cur int64
+	if ok {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return errNotInt
+		}
+		cur = n
+	}
+	cur += delta
+	ctx.setStringKeepTTL(args[1], strconv.FormatInt(cur, 10))
+	return cur
+}
+
+func cmdIncrByFloat(ctx *cmdContext, args []string) any {
+	delta, err := strconv.ParseFloat(args[2], 64)
+	if err != nil {
+		return errNotFloat
+	}
+	v, ok, errType := ctx.getString(args[1])
+	if errType != "" {
+		return errType
+	}
+	var cur float64
+	if ok {
+		cur, err = strconv.ParseFloat(v, 64)
+		if err != nil {
+			return errNotFloat
+		}
+	}
+	cur += delta
+	s := formatFloat(cur)
+	ctx.setStringKeepTTL(args[1], s)
+	return s
+}
+
+func cmdAppend(ctx *cmdContext, args []string) any {
+	v, _, errType := ctx.getString(args[1])
+	if errType != "" {
+		return errType
+	}
+	v += args[2]
+	ctx.setStringKeepTTL(args[1], v)
+	return int64(len(v))
+}
+
+func cmdStrLen(ctx *cmdContext, args []string) any {
+	v, _, errType := ctx.getString(args[1])
+	if errType != "" {
+		return errType
+	}
+	return int64(len(v))
+}
+
+// setStringKeepTTL update the string value of the key without touching its expiration
+func (ctx *cmdContext) setStringKeepTTL(key, value string) {
+	e := ctx.lookup(key)
+	if e == nil {
+		ctx.db.set(key, &entry{value: value})
+		return
+	}
+	e.value = value
+	ctx.db.touch(key)
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "inf"
+	} else if math.IsInf(f, -1) {
+		return "-inf"
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
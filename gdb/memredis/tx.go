@@ -0,0 +1,76 @@
+package memredis
+
+import "strings"
+
+func registerTxCommands() {
+	registerTx("multi", 1, cmdMulti)
+	registerTx("exec", 1, cmdExec)
+	registerTx("discard", 1, cmdDiscard)
+	registerTx("watch", -2, cmdWatch)
+	registerTx("unwatch", 1, cmdUnwatch)
+}
+
+func registerTx(name string, arity int, fn cmdFunc) {
+	commands[name] = cmdSpec{fn: fn, arity: arity, tx: true}
+}
+
+func cmdMulti(ctx *cmdContext, _ []string) any {
+	if ctx.c.multi != nil {
+		return errNestedMulti
+	}
+	ctx.c.multi = &[][]string{}
+	ctx.c.dirty = false
+	return replyOK
+}
+
+func cmdExec(ctx *cmdContext, _ []string) any {
+	c := ctx.c
+	if c.multi == nil {
+		return errorReply("ERR EXEC without MULTI")
+	}
+	queued, dirty, watch := *c.multi, c.dirty, c.watch
+	c.multi, c.dirty, c.watch = nil, false, nil
+	if dirty {
+		return errorReply("EXECABORT Transaction discarded because of previous errors.")
+	}
+	for key, version := range watch {
+		ctx.lookup(key) // an expired key counts as modified
+		if ctx.db.versions[key] != version {
+			return nilArray{}
+		}
+	}
+
+	rets := make([]any, 0, len(queued))
+	for _, args := range queued {
+		rets = append(rets, commands[strings.ToLower(args[0])].fn(ctx, args))
+		ctx.db = ctx.s.dbs[c.db]
+	}
+	return rets
+}
+
+func cmdDiscard(ctx *cmdContext, _ []string) any {
+	if ctx.c.multi == nil {
+		return errorReply("ERR DISCARD without MULTI")
+	}
+	ctx.c.multi, ctx.c.dirty, ctx.c.watch = nil, false, nil
+	return replyOK
+}
+
+func cmdWatch(ctx *cmdContext, args []string) any {
+	if ctx.c.multi != nil {
+		return errorReply("ERR WATCH inside MULTI is not allowed")
+	}
+	if ctx.c.watch == nil {
+		ctx.c.watch = make(map[string]uint64)
+	}
+	for _, key := range args[1:] {
+		ctx.lookup(key)
+		ctx.c.watch[key] = ctx.db.versions[key]
+	}
+	return replyOK
+}
+
+func cmdUnwatch(ctx *cmdContext, _ []string) any {
+	ctx.c.watch = nil
+	return replyOK
+}
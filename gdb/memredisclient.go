@@ -0,0 +1,22 @@
+package gdb
+
+import (
+	"github.com/go-redis/redis/v8"
+	"github.com/oldjon/gutil/gdb/memredis"
+	gmarshaller "github.com/oldjon/gutil/marshaller"
+)
+
+// NewMemRedisClient create a RedisClient object connected to the in-memory server, it is used in unit tests.
+// All redisClient features work the same as with a live redis, lua scripts run on the lua interpreter of the server.
+func NewMemRedisClient(server *memredis.Server, marshaller gmarshaller.Marshaller, hooks ...redis.Hook) (RedisClient, error) {
+	if marshaller == nil {
+		marshaller = &gmarshaller.JsonMarshaller{}
+	}
+	return NewRedisClient(&RedisClientOption{
+		Mode:       Single,
+		Addr:       "memredis",
+		Marshaller: marshaller,
+		Hooks:      hooks,
+		Dialer:     server.Dial,
+	})
+}
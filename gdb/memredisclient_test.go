@@ -0,0 +1,182 @@
+package gdb
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/oldjon/gutil/gdb/memredis"
+)
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Add(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+func newMemRedisClient(t *testing.T) (RedisClient, *memredis.Server, *fakeClock) {
+	server := memredis.NewServer()
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	server.SetClock(clock.Now)
+	client, err := NewMemRedisClient(server, nil)
+	if err != nil {
+		t.Fatalf("new mem redis client failed: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = client.Close()
+		_ = server.Close()
+	})
+	return client, server, clock
+}
+
+func TestMemRedisClientString(t *testing.T) {
+	client, _, clock := newMemRedisClient(t)
+	ctx := context.Background()
+
+	_, err := client.Get(ctx, "missing")
+	if !client.IsErrNil(err) {
+		t.Fatalf("get missing key should return redis.Nil, got %v", err)
+	}
+	if err = client.SetEX(ctx, "k1", "v1", 10*time.Second); err != nil {
+		t.Fatalf("setex failed: %v", err)
+	}
+	ttl, err := client.TTL(ctx, "k1")
+	if err != nil || ttl != 10*time.Second {
+		t.Fatalf("ttl = %v, %v", ttl, err)
+	}
+	ok, err := client.SetNX(ctx, "k1", "v2", 0)
+	if err != nil || ok {
+		t.Fatalf("setnx on existing key = %v, %v", ok, err)
+	}
+	clock.Add(11 * time.Second)
+	if _, err = client.Get(ctx, "k1"); !client.IsErrNil(err) {
+		t.Fatalf("key should be expired, got %v", err)
+	}
+	if _, err = client.TTL(ctx, "k1"); err != ErrTTLKeyNotExist {
+		t.Fatalf("ttl of expired key should return ErrTTLKeyNotExist, got %v", err)
+	}
+
+	n, err := client.IncrBy(ctx, "counter", 5)
+	if err != nil || n != 5 {
+		t.Fatalf("incrby = %d, %v", n, err)
+	}
+	cmds, err := client.BatchGet(ctx, []string{"counter", "missing"})
+	if err != nil || cmds[0].Val() != "5" || !client.IsErrNil(cmds[1].Err()) {
+		t.Fatalf("batchget failed: %v", err)
+	}
+}
+
+func TestMemRedisClientObjects(t *testing.T) {
+	client, _, _ := newMemRedisClient(t)
+	ctx := context.Background()
+
+	if err := client.SetObject(ctx, "foo:1", &Foo{F: 1}); err != nil {
+		t.Fatalf("setobject failed: %v", err)
+	}
+	var foo Foo
+	if err := client.GetObject(ctx, "foo:1", &foo); err != nil || foo.F != 1 {
+		t.Fatalf("getobject = %v, %v", foo, err)
+	}
+
+	if err := client.HSetObjects(ctx, "hash", "f1", &Foo{F: 1}, "f2", &Foo{F: 2}); err != nil {
+		t.Fatalf("hsetobjects failed: %v", err)
+	}
+	var fields []string
+	var foos []*Foo
+	if err := client.HGetAllObjects(ctx, "hash", &fields, &foos); err != nil || len(foos) != 2 {
+		t.Fatalf("hgetallobjects = %v, %v", foos, err)
+	}
+
+	if _, err := client.ZAddObjects(ctx, "zset", 2, Foo{F: 2}, 1, Foo{F: 1}); err != nil {
+		t.Fatalf("zaddobjects failed: %v", err)
+	}
+	var ranked []Foo
+	scores, err := client.ZRevRangeObjectsWithScores(ctx, "zset", 0, -1, &ranked)
+	if err != nil || len(ranked) != 2 || ranked[0].F != 2 || scores[1] != 1 {
+		t.Fatalf("zrevrangeobjectswithscores = %v, %v, %v", ranked, scores, err)
+	}
+	rank, err := client.ZRankObject(ctx, "zset", Foo{F: 2})
+	if err != nil || rank != 1 {
+		t.Fatalf("zrankobject = %d, %v", rank, err)
+	}
+	if _, err = client.ZRankObject(ctx, "zset", Foo{F: 3}); !client.IsErrNil(err) {
+		t.Fatalf("zrankobject of missing member should return redis.Nil, got %v", err)
+	}
+}
+
+func TestMemRedisClientPipelineAndScript(t *testing.T) {
+	client, _, _ := newMemRedisClient(t)
+	ctx := context.Background()
+
+	if err := client.SetObject(ctx, "foo:1", &Foo{F: 1}); err != nil {
+		t.Fatalf("setobject failed: %v", err)
+	}
+	var foo Foo
+	pipe := client.TxPipeline()
+	_ = pipe.GetObject(ctx, "foo:1", &foo)
+	_ = pipe.ZAddObjects(ctx, "zset", 1, &Foo{F: 1})
+	if _, err := pipe.Exec(ctx); err != nil || foo.F != 1 {
+		t.Fatalf("pipeline exec = %v, %v", foo, err)
+	}
+
+	s, err := NewScript(ctx, client, `return redis.call("get", KEYS[1])`)
+	if err != nil {
+		t.Fatalf("new script failed: %v", err)
+	}
+	v, err := client.EvalSha(ctx, s, []string{"foo:1"}).Text()
+	if err != nil || v != `{"F":1}` {
+		t.Fatalf("evalsha = %s, %v", v, err)
+	}
+}
+
+func TestMemRedisClientLua(t *testing.T) {
+	client, _, _ := newMemRedisClient(t)
+	ctx := context.Background()
+	_ = client.Set(ctx, "s", "v")
+	eval := func(src string, keys []string, args ...any) *redis.Cmd {
+		return client.Eval(ctx, &Script{src: src}, keys, args...)
+	}
+
+	// the nil reply of GET is false in lua, which is a nil reply again, an array reply stops at the first lua nil
+	v, err := eval(`return {redis.call('INCRBYFLOAT', KEYS[1], 1.5), redis.call('GET', KEYS[2]), 1, nil, 2}`,
+		[]string{"n", "missing"}).Slice()
+	if err != nil || len(v) != 3 || v[0] != "1.5" || v[1] != nil || v[2] != int64(1) {
+		t.Fatalf("eval = %v, %v", v, err)
+	}
+	v, err = eval(`return {redis.pcall('HGET', KEYS[1], 'f').err, tonumber(ARGV[1]) * 2.9,
+		redis.call('SET', KEYS[2], 0.25).ok, redis.call('GET', KEYS[2]), redis.call('EXISTS', KEYS[2]) == 1}`,
+		[]string{"s", "n"}, 7).Slice()
+	if err != nil || len(v) != 5 || !strings.HasPrefix(v[0].(string), "WRONGTYPE") || v[1] != int64(20) ||
+		v[2] != "OK" || v[3] != "0.25" || v[4] != int64(1) {
+		t.Fatalf("eval = %v, %v", v, err)
+	}
+
+	// the error replies of redis.call and redis.error_reply fail the script as they are
+	if err = eval(`return redis.call('HGET', KEYS[1], 'f')`, []string{"s"}).Err(); err == nil ||
+		!strings.HasPrefix(err.Error(), "WRONGTYPE") {
+		t.Fatalf("eval wrong type = %v", err)
+	}
+	if err = eval(`return redis.error_reply('MY failed')`, nil).Err(); err == nil || err.Error() != "MY failed" {
+		t.Fatalf("eval error reply = %v", err)
+	}
+	if err = eval(`return nil + 1`, nil).Err(); err == nil || !strings.HasPrefix(err.Error(), "ERR Error running") {
+		t.Fatalf("eval runtime error = %v", err)
+	}
+	if err = eval(`return (`, nil).Err(); err == nil || !strings.HasPrefix(err.Error(), "ERR Error compiling") {
+		t.Fatalf("eval compile error = %v", err)
+	}
+}
@@ -0,0 +1,54 @@
+// Package gmemtest create the gdb clients of the in-memory redis server for unit tests, the server and the code
+// under test share a manual clock.
+package gmemtest
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/oldjon/gutil/gdb"
+	"github.com/oldjon/gutil/gdb/memredis"
+)
+
+// Clock is a manual clock, it is safe for concurrent use
+type Clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewClock create a clock starting at now
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Add move the clock forward by d
+func (c *Clock) Add(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+// NewClient create a client of a new in-memory server whose clock starts at now, both are closed by the cleanup
+// of t
+func NewClient(t testing.TB, now time.Time) (gdb.RedisClient, *memredis.Server, *Clock) {
+	server := memredis.NewServer()
+	clock := NewClock(now)
+	server.SetClock(clock.Now)
+	client, err := gdb.NewMemRedisClient(server, nil)
+	if err != nil {
+		_ = server.Close()
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = client.Close()
+		_ = server.Close()
+	})
+	return client, server, clock
+}
@@ -0,0 +1,84 @@
+package gdb
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// mongoDocStore is a DocStore on a mongo collection, the documents are filtered by {_id, v}
+type mongoDocStore struct {
+	coll *mongo.Collection
+}
+
+// NewMongoDocStore create a DocStore on the collection, and create the ttl index on expire_at which lets mongo
+// remove the expired documents. The expired documents not removed yet are ignored by the document ObjectDB.
+func NewMongoDocStore(ctx context.Context, coll *mongo.Collection) (DocStore, error) {
+	_, err := coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expire_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &mongoDocStore{coll: coll}, nil
+}
+
+func (s *mongoDocStore) FindOne(ctx context.Context, id string) (*Doc, error) {
+	doc := &Doc{}
+	err := s.coll.FindOne(ctx, bson.D{{Key: "_id", Value: id}}).Decode(doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrDocNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+func (s *mongoDocStore) FindMany(ctx context.Context, ids []string) ([]*Doc, error) {
+	cur, err := s.coll.Find(ctx, bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: ids}}}})
+	if err != nil {
+		return nil, err
+	}
+	var docs []*Doc
+	if err = cur.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// Insert upsert the document with no version, the upsert fails with a duplicate key if the id exists
+func (s *mongoDocStore) Insert(ctx context.Context, doc *Doc) error {
+	filter := bson.D{{Key: "_id", Value: doc.ID}, {Key: "v", Value: bson.D{{Key: "$exists", Value: false}}}}
+	_, err := s.coll.ReplaceOne(ctx, filter, doc, options.Replace().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDocConflict
+	}
+	return err
+}
+
+func (s *mongoDocStore) Replace(ctx context.Context, doc *Doc, version int64) error {
+	ret, err := s.coll.ReplaceOne(ctx, bson.D{{Key: "_id", Value: doc.ID}, {Key: "v", Value: version}}, doc)
+	if err != nil {
+		return err
+	}
+	if ret.MatchedCount == 0 {
+		return ErrDocConflict
+	}
+	return nil
+}
+
+func (s *mongoDocStore) Delete(ctx context.Context, id string, version int64) error {
+	ret, err := s.coll.DeleteOne(ctx, bson.D{{Key: "_id", Value: id}, {Key: "v", Value: version}})
+	if err != nil {
+		return err
+	}
+	if ret.DeletedCount == 0 {
+		return ErrDocConflict
+	}
+	return nil
+}
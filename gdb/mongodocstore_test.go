@@ -0,0 +1,39 @@
+//go:build mongo
+
+package gdb
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TestMongoDocStore run against the mongo of $MONGO_URI, by go test -tags mongo
+func TestMongoDocStore(t *testing.T) {
+	uri := os.Getenv("MONGO_URI")
+	if uri == "" {
+		uri = "mongodb://localhost:27017"
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = client.Disconnect(context.Background()) }()
+	coll := client.Database("gdb_test").Collection(t.Name())
+	if err = coll.Drop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = coll.Drop(context.Background()) }()
+
+	store, err := NewMongoDocStore(ctx, coll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testDocStore(t, store)
+}
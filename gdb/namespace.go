@@ -0,0 +1,319 @@
+package gdb
+
+// Funcs prefix the keys of the commands with the namespace of the client
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// keySpec tell the positions of the keys in the args of a command, like the key specs of COMMAND INFO,
+// last is counted from the end if negative, -1 is the last arg
+type keySpec struct {
+	first, last, step int
+	numkeys           int      // the position of numkeys, the numkeys args after it are keys too, 0 if none
+	options           int      // the position of the first option, 0 if the command has no key options
+	keywords          []string // the options followed by a key
+}
+
+var (
+	specKey       = keySpec{first: 1, last: 1, step: 1}
+	specAllKeys   = keySpec{first: 1, last: -1, step: 1}
+	specTwoKeys   = keySpec{first: 1, last: 2, step: 1}
+	specSecondKey = keySpec{first: 2, last: 2, step: 1}
+	specPairKeys  = keySpec{first: 1, last: -1, step: 2}
+	specBlockKeys = keySpec{first: 1, last: -2, step: 1}
+	specNumkeys1  = keySpec{numkeys: 1}
+	specNumkeys2  = keySpec{numkeys: 2}
+	specStore     = keySpec{first: 1, last: 1, step: 1, numkeys: 2}
+	// the BY and GET patterns of SORT are prefixed like keys, except GET #
+	specSort        = keySpec{first: 1, last: 1, step: 1, options: 2, keywords: []string{"by", "get", "store"}}
+	specGeoRadius   = keySpec{first: 1, last: 1, step: 1, options: 6, keywords: []string{"store", "storedist"}}
+	specGeoRadiusBy = keySpec{first: 1, last: 1, step: 1, options: 5, keywords: []string{"store", "storedist"}}
+)
+
+// namespaceKeySpecs are the key specs of the commands whose keys are prefixed, the client with a namespace fails
+// the other commands with ErrNamespaceUnknownCommand, except the keyless commands
+var namespaceKeySpecs = map[string]keySpec{
+	// generic
+	"del": specAllKeys, "unlink": specAllKeys, "exists": specAllKeys, "touch": specAllKeys, "watch": specAllKeys,
+	"expire": specKey, "expireat": specKey, "pexpire": specKey, "pexpireat": specKey, "persist": specKey,
+	"expiretime": specKey, "pexpiretime": specKey, "ttl": specKey, "pttl": specKey, "type": specKey,
+	"dump": specKey, "restore": specKey, "move": specKey, "sort": specSort, "sort_ro": specSort,
+	"rename": specTwoKeys, "renamenx": specTwoKeys, "copy": specTwoKeys,
+	"object": specSecondKey, "memory": specSecondKey,
+	// scripting
+	"eval": specNumkeys2, "evalsha": specNumkeys2, "eval_ro": specNumkeys2, "evalsha_ro": specNumkeys2,
+	"fcall": specNumkeys2, "fcall_ro": specNumkeys2,
+	// string
+	"get": specKey, "set": specKey, "setnx": specKey, "setex": specKey, "psetex": specKey, "getset": specKey,
+	"getdel": specKey, "getex": specKey, "append": specKey, "strlen": specKey, "getrange": specKey,
+	"substr": specKey, "setrange": specKey, "incr": specKey, "incrby": specKey, "incrbyfloat": specKey,
+	"decr": specKey, "decrby": specKey, "lcs": specTwoKeys, "mget": specAllKeys, "mset": specPairKeys,
+	"msetnx": specPairKeys,
+	// bitmap and hyperloglog
+	"setbit": specKey, "getbit": specKey, "bitcount": specKey, "bitpos": specKey, "bitfield": specKey,
+	"bitfield_ro": specKey, "bitop": {first: 2, last: -1, step: 1}, "pfadd": specKey, "pfcount": specAllKeys,
+	"pfmerge": specAllKeys,
+	// hash
+	"hset": specKey, "hsetnx": specKey, "hget": specKey, "hmset": specKey, "hmget": specKey, "hgetall": specKey,
+	"hdel": specKey, "hexists": specKey, "hincrby": specKey, "hincrbyfloat": specKey, "hlen": specKey,
+	"hkeys": specKey, "hvals": specKey, "hstrlen": specKey, "hscan": specKey, "hrandfield": specKey,
+	// list
+	"lpush": specKey, "rpush": specKey, "lpushx": specKey, "rpushx": specKey, "lpop": specKey, "rpop": specKey,
+	"llen": specKey, "lrange": specKey, "ltrim": specKey, "lrem": specKey, "lindex": specKey, "lset": specKey,
+	"linsert": specKey, "lpos": specKey, "blpop": specBlockKeys, "brpop": specBlockKeys,
+	"rpoplpush": specTwoKeys, "brpoplpush": specTwoKeys, "lmove": specTwoKeys, "blmove": specTwoKeys,
+	"lmpop": specNumkeys1, "blmpop": specNumkeys2,
+	// set
+	"sadd": specKey, "srem": specKey, "smembers": specKey, "sismember": specKey, "smismember": specKey,
+	"scard": specKey, "spop": specKey, "srandmember": specKey, "sscan": specKey, "smove": specTwoKeys,
+	"sinter": specAllKeys, "sunion": specAllKeys, "sdiff": specAllKeys, "sintercard": specNumkeys1,
+	"sinterstore": specAllKeys, "sunionstore": specAllKeys, "sdiffstore": specAllKeys,
+	// sorted set
+	"zadd": specKey, "zrem": specKey, "zrange": specKey, "zrevrange": specKey, "zrangebyscore": specKey,
+	"zrevrangebyscore": specKey, "zrangebylex": specKey, "zrevrangebylex": specKey, "zrank": specKey,
+	"zrevrank": specKey, "zscore": specKey, "zmscore": specKey, "zincrby": specKey, "zcount": specKey,
+	"zlexcount": specKey, "zcard": specKey, "zremrangebyscore": specKey, "zremrangebyrank": specKey,
+	"zremrangebylex": specKey, "zscan": specKey, "zpopmin": specKey, "zpopmax": specKey,
+	"zrandmember": specKey, "bzpopmin": specBlockKeys, "bzpopmax": specBlockKeys, "zrangestore": specTwoKeys,
+	"zunion": specNumkeys1, "zinter": specNumkeys1, "zdiff": specNumkeys1, "zintercard": specNumkeys1,
+	"zunionstore": specStore, "zinterstore": specStore, "zdiffstore": specStore,
+	"zmpop": specNumkeys1, "bzmpop": specNumkeys2,
+	// geo
+	"geoadd": specKey, "geopos": specKey, "geodist": specKey, "geohash": specKey, "georadius": specGeoRadius,
+	"georadius_ro": specKey, "georadiusbymember": specGeoRadiusBy, "georadiusbymember_ro": specKey,
+	"geosearch": specKey, "geosearchstore": specTwoKeys,
+	// stream
+	"xadd": specKey, "xlen": specKey, "xrange": specKey, "xrevrange": specKey, "xdel": specKey, "xtrim": specKey,
+	"xack": specKey, "xpending": specKey, "xclaim": specKey, "xautoclaim": specKey, "xsetid": specKey,
+	"xgroup": specSecondKey, "xinfo": specSecondKey,
+}
+
+// keylessCommands are the commands without keys, which are sent as they are by the client with a namespace
+var keylessCommands = map[string]bool{
+	"ping": true, "echo": true, "select": true, "auth": true, "hello": true, "quit": true, "reset": true,
+	"info": true, "time": true, "dbsize": true, "flushdb": true, "flushall": true, "lastsave": true,
+	"save": true, "bgsave": true, "bgrewriteaof": true, "role": true, "config": true, "client": true,
+	"command": true, "cluster": true, "readonly": true, "readwrite": true, "asking": true, "wait": true,
+	"multi": true, "exec": true, "discard": true, "unwatch": true, "script": true, "function": true,
+	"slowlog": true, "latency": true, "acl": true, "publish": true, "spublish": true, "pubsub": true,
+	"subscribe": true, "unsubscribe": true, "psubscribe": true, "punsubscribe": true,
+}
+
+// keyIndexes return the positions of the keys in the args, ok is false when the keys of the command are unknown
+func keyIndexes(args []any) (idx []int, ok bool) {
+	name := strings.ToLower(argString(args[0]))
+	if keylessCommands[name] {
+		return nil, true
+	}
+	if name == "xread" || name == "xreadgroup" {
+		// xread [options] streams key [key ...] id [id ...]
+		for i := 1; i < len(args); i++ {
+			if strings.EqualFold(argString(args[i]), "streams") {
+				for j := i + 1; j <= i+(len(args)-i-1)/2; j++ {
+					idx = append(idx, j)
+				}
+				return idx, true
+			}
+		}
+		return nil, true
+	}
+	spec, ok := namespaceKeySpecs[name]
+	if !ok {
+		return nil, false
+	}
+	last := spec.last
+	if last < 0 {
+		last += len(args)
+	}
+	for i := spec.first; spec.step > 0 && i <= last && i < len(args); i += spec.step {
+		idx = append(idx, i)
+	}
+	if spec.numkeys > 0 && spec.numkeys < len(args) {
+		n, err := strconv.Atoi(argString(args[spec.numkeys]))
+		if err != nil {
+			return nil, false
+		}
+		for i := spec.numkeys + 1; i <= spec.numkeys+n && i < len(args); i++ {
+			idx = append(idx, i)
+		}
+	}
+	for i := spec.options; spec.options > 0 && i+1 < len(args); i++ {
+		for _, keyword := range spec.keywords {
+			if strings.EqualFold(argString(args[i]), keyword) {
+				if argString(args[i+1]) != "#" {
+					idx = append(idx, i+1)
+				}
+				i++
+				break
+			}
+		}
+	}
+	return idx, true
+}
+
+type rawKeysKey struct{}
+
+// withRawKeys mark the commands of the ctx as already prefixed, the namespace hook leaves their args and replies
+// as they are. It is used where the keys must be prefixed before the hooks run, for example the keys of Watch
+// which selects the node by them in cluster mode.
+func withRawKeys(ctx context.Context) context.Context {
+	return context.WithValue(ctx, rawKeysKey{}, true)
+}
+
+// nsKey return the key with the namespace prefix
+func (rc *redisClient) nsKey(key string) string {
+	return rc.namespace + key
+}
+
+// namespaceHook prefix the keys of the commands with the namespace, and trim the prefix of the keys in the replies
+type namespaceHook struct {
+	prefix string
+}
+
+func (h *namespaceHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	if ctx.Value(rawKeysKey{}) == nil {
+		return ctx, h.prefixArgs(cmd.Args())
+	}
+	return ctx, nil
+}
+
+func (h *namespaceHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	if ctx.Value(rawKeysKey{}) == nil {
+		h.trimReply(cmd)
+	}
+	return nil
+}
+
+func (h *namespaceHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	if ctx.Value(rawKeysKey{}) != nil {
+		return ctx, nil
+	}
+	for _, cmd := range cmds {
+		if err := h.prefixArgs(cmd.Args()); err != nil {
+			return ctx, err
+		}
+	}
+	return ctx, nil
+}
+
+func (h *namespaceHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	if ctx.Value(rawKeysKey{}) != nil {
+		return nil
+	}
+	for _, cmd := range cmds {
+		h.trimReply(cmd)
+	}
+	return nil
+}
+
+func (h *namespaceHook) prefixArg(args []any, i int) {
+	switch v := args[i].(type) {
+	case string:
+		args[i] = h.prefix + v
+	case []byte:
+		args[i] = append([]byte(h.prefix), v...)
+	}
+}
+
+// prefixArgs prefix the keys in the args in place, it fails with ErrNamespaceUnknownCommand instead of sending
+// the keys of an unknown command out of the namespace
+func (h *namespaceHook) prefixArgs(args []any) error {
+	if len(args) == 0 {
+		return nil
+	}
+	switch strings.ToLower(argString(args[0])) {
+	case "keys":
+		if len(args) > 1 {
+			args[1] = escapeGlob(h.prefix) + argString(args[1])
+		}
+		return nil
+	case "scan":
+		// scan cursor [match pattern] [count count] [type type]
+		for i := 2; i+1 < len(args); i++ {
+			if strings.EqualFold(argString(args[i]), "match") {
+				args[i+1] = escapeGlob(h.prefix) + argString(args[i+1])
+				return nil
+			}
+		}
+		return nil
+	}
+	idx, ok := keyIndexes(args)
+	if !ok {
+		return ErrNamespaceUnknownCommand
+	}
+	for _, i := range idx {
+		h.prefixArg(args, i)
+	}
+	return nil
+}
+
+// trimReply trim the prefix of the keys in the replies of the commands returning keys
+func (h *namespaceHook) trimReply(cmd redis.Cmder) {
+	if cmd.Err() != nil {
+		return
+	}
+	switch c := cmd.(type) {
+	case *redis.ScanCmd:
+		if c.Name() == "scan" {
+			keys, cursor := c.Val()
+			for i := range keys {
+				keys[i] = strings.TrimPrefix(keys[i], h.prefix)
+			}
+			c.SetVal(keys, cursor)
+		}
+	case *redis.StringSliceCmd:
+		switch c.Name() {
+		case "keys":
+			for i, key := range c.Val() {
+				c.Val()[i] = strings.TrimPrefix(key, h.prefix)
+			}
+		case "blpop", "brpop":
+			if val := c.Val(); len(val) > 0 {
+				val[0] = strings.TrimPrefix(val[0], h.prefix)
+			}
+		}
+	case *redis.ZWithKeyCmd:
+		if val := c.Val(); val != nil {
+			val.Key = strings.TrimPrefix(val.Key, h.prefix)
+		}
+	case *redis.XStreamSliceCmd:
+		streams := c.Val()
+		for i := range streams {
+			streams[i].Stream = strings.TrimPrefix(streams[i].Stream, h.prefix)
+		}
+	}
+}
+
+func argString(arg any) string {
+	switch v := arg.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	}
+	return ""
+}
+
+// escapeGlob escape the special characters of the glob style patterns in s
+func escapeGlob(s string) string {
+	if !strings.ContainsAny(s, `*?[]\`) {
+		return s
+	}
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`*?[]\`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
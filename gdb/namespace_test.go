@@ -0,0 +1,196 @@
+package gdb
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	gmarshaller "github.com/oldjon/gutil/marshaller"
+)
+
+func TestNamespace(t *testing.T) {
+	ctx := context.Background()
+	plain, server, clock := newMemRedisClient(t)
+	client, err := NewRedisClient(&RedisClientOption{
+		Addr:       "memredis",
+		Namespace:  "dev:",
+		Marshaller: &gmarshaller.JsonMarshaller{},
+		Dialer:     server.Dial,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	client.SetKOMapping(map[string]string{"foo:": "gdb.Foo"})
+
+	_ = plain.SetObject(ctx, "foo:0", &Foo{F: -1})
+	_ = client.SetObject(ctx, "foo:1", &Foo{F: 1})
+	pipe := client.Pipeline()
+	_ = pipe.HSetObjects(ctx, "hfoo", "f", &Foo{F: 2})
+	if _, err = pipe.Exec(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err = client.UpdateObject(ctx, "foo:1", &Foo{}, func(obj any) error {
+		obj.(*Foo).F++
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.SetVersionedObject(ctx, "vfoo", &Foo{F: 3}, 0); err != nil {
+		t.Fatal(err)
+	}
+	keys := server.Keys(0)
+	sort.Strings(keys)
+	if want := []string{"dev:foo:1", "dev:hfoo", "dev:vfoo", "foo:0"}; !equalStrings(keys, want) {
+		t.Fatalf("keys = %v, want %v", keys, want)
+	}
+	var foo Foo
+	if err = client.GetObject(ctx, "foo:1", &foo); err != nil || foo.F != 2 {
+		t.Fatalf("GetObject = %v, %v", foo, err)
+	}
+	// the ko mapping matches the logical key
+	if err = client.SetObject(ctx, "foo:2", &Bar{B: 1}); !errors.Is(err, ErrKeyObjMismatch) {
+		t.Fatalf("SetObject mismatched = %v", err)
+	}
+
+	var scanned []string
+	it := client.Scan(ctx, "", 0)
+	for it.Next(ctx) {
+		scanned = append(scanned, it.Key())
+	}
+	sort.Strings(scanned)
+	if want := []string{"foo:1", "hfoo", "vfoo"}; it.Err() != nil || !equalStrings(scanned, want) {
+		t.Fatalf("Scan = %v, %v", scanned, it.Err())
+	}
+
+	_, _ = client.RPush(ctx, "list", "a")
+	if kv, err := client.BLPop(ctx, time.Second, "list"); err != nil || kv[0] != "list" {
+		t.Fatalf("BLPop = %v, %v", kv, err)
+	}
+	_ = client.XGroupCreate(ctx, "stream", "g", "0")
+	_, _ = client.XAdd(ctx, &redis.XAddArgs{Stream: "stream", Values: []string{"k", "v"}})
+	streams, err := client.XReadGroup(ctx, &redis.XReadGroupArgs{Group: "g", Consumer: "c",
+		Streams: []string{"stream", ">"}, Count: 1, Block: -1})
+	if err != nil || len(streams) != 1 || streams[0].Stream != "stream" {
+		t.Fatalf("XReadGroup = %v, %v", streams, err)
+	}
+
+	if err = client.EnableKeyspaceEvents(ctx, "Ex"); err != nil {
+		t.Fatal(err)
+	}
+	sub, err := client.SubscribeKeyEvents(ctx, 0, "expired")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+	_ = plain.SetObjectEX(ctx, "foo:3", &Foo{F: 3}, time.Second)
+	_ = client.SetObjectEX(ctx, "foo:3", &Foo{F: 3}, time.Second)
+	clock.Add(time.Second)
+	server.ActiveExpire()
+	if msg := receiveMessage(t, sub); msg.Payload != "foo:3" {
+		t.Fatalf("expired event = %+v", msg)
+	}
+
+	if n, err := client.DelByPattern(ctx, "*", nil); err != nil || n != 4 {
+		t.Fatalf("DelByPattern = %d, %v", n, err)
+	}
+	if keys = server.Keys(0); !equalStrings(keys, []string{"foo:0"}) {
+		t.Fatalf("keys after DelByPattern = %v", keys)
+	}
+}
+
+func TestNamespacePrefixArgs(t *testing.T) {
+	h := &namespaceHook{prefix: "ns:"}
+	for _, c := range []struct {
+		args, want []any
+	}{
+		{[]any{"get", []byte("a")}, []any{"get", []byte("ns:a")}},
+		{[]any{"mset", "a", "1", "b", "2"}, []any{"mset", "ns:a", "1", "ns:b", "2"}},
+		{[]any{"evalsha", "sha", 2, "a", "b", "c"}, []any{"evalsha", "sha", 2, "ns:a", "ns:b", "c"}},
+		{[]any{"zunionstore", "d", "2", "a", "b", "weights", "1", "2"},
+			[]any{"zunionstore", "ns:d", "2", "ns:a", "ns:b", "weights", "1", "2"}},
+		{[]any{"zinter", 2, "a", "b", "withscores"}, []any{"zinter", 2, "ns:a", "ns:b", "withscores"}},
+		{[]any{"lmpop", "2", "a", "b", "left"}, []any{"lmpop", "2", "ns:a", "ns:b", "left"}},
+		{[]any{"bzmpop", "1", "1", "a", "min"}, []any{"bzmpop", "1", "1", "ns:a", "min"}},
+		{[]any{"sort", "a", "by", "w_*", "get", "#", "get", "o_*", "store", "d"},
+			[]any{"sort", "ns:a", "by", "ns:w_*", "get", "#", "get", "ns:o_*", "store", "ns:d"}},
+		{[]any{"georadius", "a", "1", "2", "3", "km", "store", "d"},
+			[]any{"georadius", "ns:a", "1", "2", "3", "km", "store", "ns:d"}},
+		{[]any{"georadiusbymember", "a", "store", "3", "km", "storedist", "d"},
+			[]any{"georadiusbymember", "ns:a", "store", "3", "km", "storedist", "ns:d"}},
+		{[]any{"xread", "count", "1", "streams", "a", "b", "0", "0"},
+			[]any{"xread", "count", "1", "streams", "ns:a", "ns:b", "0", "0"}},
+		{[]any{"ping", "a"}, []any{"ping", "a"}},
+	} {
+		if err := h.prefixArgs(c.args); err != nil || !reflect.DeepEqual(c.args, c.want) {
+			t.Fatalf("prefixArgs = %v, %v, want %v", c.args, err, c.want)
+		}
+	}
+	// the keys of the unknown commands are not sent out of the namespace
+	for _, args := range [][]any{{"migrate", "h", "6379", "a", "0", "1"}, {"zunionstore", "d", "n", "a"}} {
+		if err := h.prefixArgs(args); !errors.Is(err, ErrNamespaceUnknownCommand) {
+			t.Fatalf("prefixArgs %v = %v", args, err)
+		}
+	}
+}
+
+func TestNamespaceTrimReply(t *testing.T) {
+	ctx := context.Background()
+	h := &namespaceHook{prefix: "ns:"}
+	for _, name := range []string{"bzpopmin", "bzpopmax"} {
+		cmd := redis.NewZWithKeyCmd(ctx, name, "ns:a", 0)
+		cmd.SetVal(&redis.ZWithKey{Z: redis.Z{Score: 1, Member: "m"}, Key: "ns:a"})
+		h.trimReply(cmd)
+		if val := cmd.Val(); val.Key != "a" || val.Member != "m" {
+			t.Fatalf("%s reply = %+v", name, val)
+		}
+	}
+	blpop := redis.NewStringSliceCmd(ctx, "blpop", "ns:a", 0)
+	blpop.SetVal([]string{"ns:a", "ns:v"})
+	h.trimReply(blpop)
+	if val := blpop.Val(); !equalStrings(val, []string{"a", "ns:v"}) {
+		t.Fatalf("blpop reply = %v", val)
+	}
+}
+
+func TestNamespaceUnknownCommand(t *testing.T) {
+	ctx := context.Background()
+	_, server, _ := newMemRedisClient(t)
+	client, err := NewRedisClient(&RedisClientOption{
+		Addr:       "memredis",
+		Namespace:  "dev:",
+		Marshaller: &gmarshaller.JsonMarshaller{},
+		Dialer:     server.Dial,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	rc := client.(*redisClient)
+	if err = rc.client.Do(ctx, "randomkey").Err(); !errors.Is(err, ErrNamespaceUnknownCommand) {
+		t.Fatalf("randomkey = %v", err)
+	}
+	pipe := rc.client.Pipeline()
+	get := pipe.Get(ctx, "foo")
+	pipe.Do(ctx, "randomkey")
+	if _, err = pipe.Exec(ctx); !errors.Is(err, ErrNamespaceUnknownCommand) || !errors.Is(get.Err(),
+		ErrNamespaceUnknownCommand) {
+		t.Fatalf("pipeline with randomkey = %v, %v", err, get.Err())
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -3,20 +3,39 @@ package gdb
 import (
 	"context"
 	"time"
+
+	"github.com/go-redis/redis/v8"
+	gmarshaller "github.com/oldjon/gutil/marshaller"
 )
 
 type ObjectDB interface {
+	// SetKOMapping set the legacy key prefix -> type name mapping, SetKeySchemas is preferred
 	SetKOMapping(map[string]string)
-	CheckKeyObjMatch(key string, obj any)
+	// SetKeySchemas set the key schemas, which replace the KOMapping
+	SetKeySchemas(registry *KeySchemaRegistry)
+	// CheckKeyObjMatch return ErrKeyObjMismatch if obj (or the elements of the slice obj) is not of the type
+	// of the key
+	CheckKeyObjMatch(key string, obj any) error
+	// ObjMarshaller returns the marshaller used to marshal and unmarshal objects.
+	ObjMarshaller() gmarshaller.Marshaller
+	// ObjMarshallerOf check obj by CheckKeyObjMatch and return the marshaller of the key
+	ObjMarshallerOf(key string, obj any) (gmarshaller.Marshaller, error)
+	// LegacyReads report the values read without the format byte by the gmarshaller.EnvelopeMarshallers, by the
+	// key prefixes of the schemas, "" is the marshaller of the db
+	LegacyReads() map[string]uint64
 	// GetObject get data from db of the key, and unmarshal into obj.
 	// obj should be a struct point, and should be memory allocated
 	GetObject(ctx context.Context, key string, obj any) error
 	// SetObject set data into db by the key, the data is unmarshalled from obj.
-	// obj should be a struct point, and not nil.
+	// obj should be a struct point, and not nil. The DefaultTTL of the key schema is applied.
 	SetObject(ctx context.Context, key string, obj any) error
 	// SetObjectEX set data into db by the key with expiration, the data is unmarshalled from obj.
 	// obj should be a struct point, and not nil.
 	SetObjectEX(ctx context.Context, key string, obj any, expiration time.Duration) error
+	// UpdateObject get data from db of the key into obj, modify obj by fn, and set it back atomically.
+	// obj should be a struct point, and should be memory allocated, it is zero if the key does not exist.
+	// fn may be called more than once when the object is modified by others concurrently.
+	UpdateObject(ctx context.Context, key string, obj any, fn UpdateFunc) error
 	// GetObjects get datas from db of all keys, and unmarshal into objs.
 	// objs should be a slice of struct points, and slice should be memory allocated.
 	GetObjects(ctx context.Context, keys []string, objs any) error
@@ -70,8 +89,57 @@ type ObjectDB interface {
 	ZRevRankObject(ctx context.Context, key string, member any) (int64, error)
 	ZScoreObject(ctx context.Context, key string, member any) (float64, error)
 	ZRemObjects(ctx context.Context, key string, members ...any) (int64, error)
+
+	// GeoAddObjects add longitude, latitude and member triples into the geo set of the key,
+	// member will be marshalled before add. The members are removed by ZRemObjects.
+	GeoAddObjects(ctx context.Context, key string, values ...any) (int64, error)
+	// GeoPosObjects return the positions of the members, nil for the missing ones
+	GeoPosObjects(ctx context.Context, key string, members ...any) ([]*redis.GeoPos, error)
+	// GeoDistObjects return the distance of the members in the unit, m, km, ft or mi, "" is km.
+	// It returns redis.Nil if a member is missing.
+	GeoDistObjects(ctx context.Context, key string, member1, member2 any, unit string) (float64, error)
+	// GeoSearchObjects GeoSearch members with the locations asked by q from the geo set of the key, and unmarshall
+	// members into objs. objs should be a point of a slice of struct or struct points. See Nearby for an object
+	// center.
+	GeoSearchObjects(ctx context.Context, key string, q *redis.GeoSearchLocationQuery, objs any) (
+		[]redis.GeoLocation, error)
+
+	// LPushObjects push values into the head of the list of the key, values will be marshalled before push.
+	LPushObjects(ctx context.Context, key string, values ...any) (int64, error)
+	// RPushObjects push values into the tail of the list of the key, values will be marshalled before push.
+	RPushObjects(ctx context.Context, key string, values ...any) (int64, error)
+	// LPopObject pop the head of the list of the key, and unmarshal into obj.
+	// obj should be a struct point, and should be memory allocated
+	LPopObject(ctx context.Context, key string, obj any) error
+	// RPopObject pop the tail of the list of the key, and unmarshal into obj.
+	// obj should be a struct point, and should be memory allocated
+	RPopObject(ctx context.Context, key string, obj any) error
+	// BLPopObject block until the head of the list of the key popped or timeout, and unmarshal into obj.
+	// obj should be a struct point, and should be memory allocated. It returns redis.Nil when timeout.
+	BLPopObject(ctx context.Context, timeout time.Duration, key string, obj any) error
+	// LRangeObjects LRange values from list of the key, and unmarshall values into objs.
+	// objs should be a point of a slice of struct or struct points.
+	LRangeObjects(ctx context.Context, key string, start, stop int64, objs any) error
+
+	// SAddObjects add members into set of the key, members will be marshalled before add.
+	SAddObjects(ctx context.Context, key string, members ...any) (int64, error)
+	SRemObjects(ctx context.Context, key string, members ...any) (int64, error)
+	SIsMemberObject(ctx context.Context, key string, member any) (bool, error)
+	// SMembersObjects get all members from set of the key, and unmarshall members into objs.
+	// objs should be a point of a slice of struct or struct points.
+	SMembersObjects(ctx context.Context, key string, objs any) error
+	// SPopObject pop a random member from set of the key, and unmarshal into obj.
+	// obj should be a struct point, and should be memory allocated
+	SPopObject(ctx context.Context, key string, obj any) error
+
+	// XAddObject append obj into the stream, obj will be marshalled into the field StreamObjectField of the message.
+	XAddObject(ctx context.Context, stream string, obj any) (string, error)
+	// XMessageObject unmarshal the message added by XAddObject into obj.
+	// obj should be a struct point, and should be memory allocated
+	XMessageObject(msg redis.XMessage, obj any) error
 }
 
+// KOMapping map the key prefixes to the object type names, a *KeySchemaRegistry is also a KOMapping
 type KOMapping interface {
 	Mapping() map[string]string
 }
@@ -2,16 +2,129 @@ package gdb
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/go-redis/redis/v8"
 )
 
+// Pipeliner queue the calls and send them by Exec, every call returns a Future resolved at Exec, except the calls
+// kept from the first version which return the errors before sending, their Future variants are named XxxFuture
 type Pipeliner interface {
+	PipelinerGeneric
+	PipelinerString
+	PipelinerHash
+	PipelinerSortedSet
 	PipelinerObject
+	// EvalSha run the script by EVALSHA, the script is not loaded on NOSCRIPT, see ScriptRegistry.Load.
+	// The result can be decoded by ScriptResult.
+	EvalSha(ctx context.Context, s *Script, keys []string, args ...any) *Future[any]
+	// Exec send the queued calls and resolve their futures. It returns a *PipelineError holding the error of
+	// every failed call, redis.Nil replies are not failures and are only reported by the futures.
 	Exec(ctx context.Context) ([]redis.Cmder, error)
-	// pipeline zset
+}
+
+type PipelinerGeneric interface {
+	Exists(ctx context.Context, key string) *Future[bool]
+	Type(ctx context.Context, key string) *Future[string]
+	TTL(ctx context.Context, key string) *Future[time.Duration]
+	Del(ctx context.Context, key string) *Future[uint32]
+	Expire(ctx context.Context, key string, expiration time.Duration) *Future[bool]
+	ExpireAt(ctx context.Context, key string, t time.Time) *Future[bool]
+	ExpireAtTS(ctx context.Context, key string, ts int64) *Future[bool]
+}
+
+type PipelinerString interface {
+	SetNX(ctx context.Context, key string, value any, expiration time.Duration) *Future[bool]
+	Set(ctx context.Context, key string, value any) *Future[struct{}]
+	SetEX(ctx context.Context, key string, value any, expiration time.Duration) *Future[struct{}]
+	Get(ctx context.Context, key string) *Future[string]
+	Incr(ctx context.Context, key string) *Future[int64]
+	IncrBy(ctx context.Context, key string, value int64) *Future[int64]
+	BatchGet(ctx context.Context, keys []string) *Future[[]*redis.StringCmd]
+	BatchSet(ctx context.Context, keys []string, values []any, expiration time.Duration) *Future[struct{}]
+	BatchDel(ctx context.Context, keys []string) *Future[struct{}]
+}
+
+type PipelinerHash interface {
+	HGet(ctx context.Context, key, field string) *Future[string]
+	HSet(ctx context.Context, key string, values ...any) *Future[struct{}]
+	HMSet(ctx context.Context, key string, values ...any) *Future[bool]
+	HMGet(ctx context.Context, key string, fields ...string) *Future[[]any]
+	HGetAll(ctx context.Context, key string) *Future[map[string]string]
+	HDel(ctx context.Context, key string, fields ...string) *Future[int64]
+	HLen(ctx context.Context, key string) *Future[int64]
+}
+
+type PipelinerSortedSet interface {
+	ZAdd(ctx context.Context, key string, values ...any) *Future[int64]
+	ZCard(ctx context.Context, key string) *Future[int64]
+	ZCount(ctx context.Context, key string, min, max string) *Future[int64]
+	ZIncrBy(ctx context.Context, key string, increment float64, member any) *Future[float64]
+	ZRange(ctx context.Context, key string, start, stop int64) *Future[[]string]
+	ZRangeByScore(ctx context.Context, key string, min, max string) *Future[[]string]
+	ZRangeWithScores(ctx context.Context, key string, start, stop int64) *Future[[]redis.Z]
+	ZRangeByScoreWithScores(ctx context.Context, key string, min, max string) *Future[[]redis.Z]
+	ZRevRange(ctx context.Context, key string, start, stop int64) *Future[[]string]
+	ZRevRangeByScore(ctx context.Context, key string, min, max string) *Future[[]string]
+	ZRevRangeWithScores(ctx context.Context, key string, start, stop int64) *Future[[]redis.Z]
+	ZRevRangeByScoreWithScores(ctx context.Context, key string, min, max string) *Future[[]redis.Z]
+	ZRank(ctx context.Context, key string, member any) *Future[int64]
+	ZRevRank(ctx context.Context, key string, member any) *Future[int64]
+	ZRem(ctx context.Context, key string, members ...any) *Future[int64]
+	ZRemRangeByRank(ctx context.Context, key string, start, stop int64) *Future[int64]
 	ZRemRangeByScore(ctx context.Context, key string, min, max float64) error
+	ZRemRangeByScoreFuture(ctx context.Context, key string, min, max string) *Future[int64]
+	ZScore(ctx context.Context, key string, member any) *Future[float64]
+}
+
+// Future is the result of a pipelined call, it is resolved by Exec of the pipeline
+type Future[T any] struct {
+	val      T
+	err      error
+	done     bool
+	queueErr error // the error before sending
+}
+
+// Result return the value and the error of the call, it returns ErrPipelineNotExecuted before Exec
+func (f *Future[T]) Result() (T, error) {
+	if !f.done {
+		var zero T
+		return zero, ErrPipelineNotExecuted
+	}
+	return f.val, f.err
+}
+
+func (f *Future[T]) Val() T {
+	v, _ := f.Result()
+	return v
+}
+
+func (f *Future[T]) Err() error {
+	_, err := f.Result()
+	return err
+}
+
+// PipelineError is returned by Exec if some calls of the pipeline failed
+type PipelineError struct {
+	Errs map[int]error // errors by the indexes of the calls in the order they were queued
+}
+
+func (e *PipelineError) Error() string {
+	first := sortedIndexes(e.Errs)[0]
+	return fmt.Sprintf("%d pipeline calls failed, call %d: %v", len(e.Errs), first, e.Errs[first])
+}
+
+// Unwrap return the errors of the calls, so errors.Is and errors.As match any of them
+func (e *PipelineError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errs))
+	for _, i := range sortedIndexes(e.Errs) {
+		errs = append(errs, e.Errs[i])
+	}
+	return errs
 }
 
 func (rc *redisClient) Pipeline() Pipeliner {
@@ -22,47 +135,356 @@ func (rc *redisClient) TxPipeline() Pipeliner {
 	return &pipeline{Pipe: rc.client.TxPipeline(), rc: rc}
 }
 
-func doNothing(_ redis.Cmder, _ any) error { return nil }
+// pipelineCall is a call queued in the pipeline, the replies of its n commands are passed to resolve at Exec
+type pipelineCall struct {
+	n       int
+	resolve func(cmds []redis.Cmder) error
+}
 
 type pipeline struct {
-	Pipe        redis.Pipeliner
-	rc          *redisClient
-	mux         sync.RWMutex
-	resHandlers []func(cmd redis.Cmder, obj any) error
-	objects     []any
+	Pipe  redis.Pipeliner
+	rc    *redisClient
+	mux   sync.Mutex
+	calls []pipelineCall
+}
+
+// queue queue the commands of a call by fn, the future is resolved by their replies at Exec
+func queue[T any](pipe *pipeline, fn func(p redis.Pipeliner), resolve func(cmds []redis.Cmder) (T, error)) *Future[T] {
+	f := &Future[T]{}
+	pipe.mux.Lock()
+	defer pipe.mux.Unlock()
+	n := pipe.Pipe.Len()
+	fn(pipe.Pipe)
+	pipe.calls = append(pipe.calls, pipelineCall{n: pipe.Pipe.Len() - n, resolve: func(cmds []redis.Cmder) error {
+		f.val, f.err = resolve(cmds)
+		f.done = true
+		return f.err
+	}})
+	return f
+}
+
+// failed queue a call failed before sending, for example by marshalling, its future is resolved with err at Exec
+func failed[T any](pipe *pipeline, err error) *Future[T] {
+	f := queue(pipe, func(redis.Pipeliner) {}, func([]redis.Cmder) (T, error) {
+		var zero T
+		return zero, err
+	})
+	f.queueErr = err
+	return f
+}
+
+type resultCmd[T any] interface {
+	redis.Cmder
+	Result() (T, error)
+}
+
+// queueCmd queue the command of fn, the future is resolved by its result
+func queueCmd[T any, C resultCmd[T]](pipe *pipeline, fn func(p redis.Pipeliner) C) *Future[T] {
+	return queue(pipe, func(p redis.Pipeliner) { fn(p) }, func(cmds []redis.Cmder) (T, error) {
+		return cmds[0].(C).Result()
+	})
+}
+
+// queueErr queue the commands of fn, the future is resolved by the first error of them
+func queueErr(pipe *pipeline, fn func(p redis.Pipeliner)) *Future[struct{}] {
+	return queue(pipe, fn, func(cmds []redis.Cmder) (struct{}, error) {
+		for _, cmd := range cmds {
+			if err := cmd.Err(); err != nil {
+				return struct{}{}, err
+			}
+		}
+		return struct{}{}, nil
+	})
 }
 
 func (pipe *pipeline) Exec(ctx context.Context) ([]redis.Cmder, error) {
+	pipe.mux.Lock()
+	defer pipe.mux.Unlock()
+	calls := pipe.calls
+	pipe.calls = nil
 	cmds, err := pipe.Pipe.Exec(ctx)
-	if err != nil {
-		return nil, err
+
+	errs := make(map[int]error)
+	var offset int
+	for i, call := range calls {
+		if callErr := call.resolve(cmds[offset : offset+call.n]); callErr != nil && !errors.Is(callErr, redis.Nil) {
+			errs[i] = callErr
+		}
+		offset += call.n
 	}
-	if len(pipe.resHandlers) == 0 {
-		return cmds, nil
+	if len(errs) > 0 {
+		return cmds, &PipelineError{Errs: errs}
 	}
-	for i, f := range pipe.resHandlers {
-		err = f(cmds[i], pipe.objects[i])
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return cmds, err
+	}
+	return cmds, nil
+}
+
+// pipeline generic
+func (pipe *pipeline) Exists(ctx context.Context, key string) *Future[bool] {
+	return queue(pipe, func(p redis.Pipeliner) { p.Exists(ctx, key) }, func(cmds []redis.Cmder) (bool, error) {
+		n, err := cmds[0].(*redis.IntCmd).Result()
+		return n == 1, err
+	})
+}
+
+func (pipe *pipeline) Type(ctx context.Context, key string) *Future[string] {
+	return queueCmd[string](pipe, func(p redis.Pipeliner) *redis.StatusCmd { return p.Type(ctx, key) })
+}
+
+func (pipe *pipeline) TTL(ctx context.Context, key string) *Future[time.Duration] {
+	return queue(pipe, func(p redis.Pipeliner) { p.TTL(ctx, key) }, func(cmds []redis.Cmder) (time.Duration, error) {
+		duration, err := cmds[0].(*redis.DurationCmd).Result()
 		if err != nil {
-			return nil, err
+			return 0, err
+		}
+		if duration == ttlKeyNotExpireSet {
+			return 0, ErrTTLKeyNotExpireSet
+		} else if duration == ttlKeyNotExists {
+			return 0, ErrTTLKeyNotExist
 		}
+		return duration, nil
+	})
+}
+
+func (pipe *pipeline) Del(ctx context.Context, key string) *Future[uint32] {
+	return queue(pipe, func(p redis.Pipeliner) { p.Del(ctx, key) }, func(cmds []redis.Cmder) (uint32, error) {
+		n, err := cmds[0].(*redis.IntCmd).Result()
+		return uint32(n), err
+	})
+}
+
+func (pipe *pipeline) Expire(ctx context.Context, key string, expiration time.Duration) *Future[bool] {
+	return queueCmd[bool](pipe, func(p redis.Pipeliner) *redis.BoolCmd { return p.Expire(ctx, key, expiration) })
+}
+
+func (pipe *pipeline) ExpireAt(ctx context.Context, key string, t time.Time) *Future[bool] {
+	return queueCmd[bool](pipe, func(p redis.Pipeliner) *redis.BoolCmd { return p.ExpireAt(ctx, key, t) })
+}
+
+func (pipe *pipeline) ExpireAtTS(ctx context.Context, key string, ts int64) *Future[bool] {
+	return pipe.ExpireAt(ctx, key, time.Unix(ts, 0))
+}
+
+// pipeline string
+func (pipe *pipeline) SetNX(ctx context.Context, key string, value any, expiration time.Duration) *Future[bool] {
+	return queueCmd[bool](pipe, func(p redis.Pipeliner) *redis.BoolCmd { return p.SetNX(ctx, key, value, expiration) })
+}
+
+func (pipe *pipeline) Set(ctx context.Context, key string, value any) *Future[struct{}] {
+	return queueErr(pipe, func(p redis.Pipeliner) { p.Set(ctx, key, value, -1) })
+}
+
+func (pipe *pipeline) SetEX(ctx context.Context, key string, value any, expiration time.Duration) *Future[struct{}] {
+	return queueErr(pipe, func(p redis.Pipeliner) { p.Set(ctx, key, value, expiration) })
+}
+
+func (pipe *pipeline) Get(ctx context.Context, key string) *Future[string] {
+	return queueCmd[string](pipe, func(p redis.Pipeliner) *redis.StringCmd { return p.Get(ctx, key) })
+}
+
+func (pipe *pipeline) Incr(ctx context.Context, key string) *Future[int64] {
+	return queueCmd[int64](pipe, func(p redis.Pipeliner) *redis.IntCmd { return p.Incr(ctx, key) })
+}
+
+func (pipe *pipeline) IncrBy(ctx context.Context, key string, value int64) *Future[int64] {
+	return queueCmd[int64](pipe, func(p redis.Pipeliner) *redis.IntCmd { return p.IncrBy(ctx, key, value) })
+}
+
+// BatchGet resolve the get commands of the keys, the misses are reported by the commands
+func (pipe *pipeline) BatchGet(ctx context.Context, keys []string) *Future[[]*redis.StringCmd] {
+	return queue(pipe, func(p redis.Pipeliner) {
+		for _, key := range keys {
+			p.Get(ctx, key)
+		}
+	}, func(cmds []redis.Cmder) ([]*redis.StringCmd, error) {
+		rets := make([]*redis.StringCmd, len(cmds))
+		for i, cmd := range cmds {
+			if err := cmd.Err(); err != nil && !errors.Is(err, redis.Nil) {
+				return nil, err
+			}
+			rets[i] = cmd.(*redis.StringCmd)
+		}
+		return rets, nil
+	})
+}
+
+func (pipe *pipeline) BatchSet(ctx context.Context, keys []string, values []any,
+	expiration time.Duration) *Future[struct{}] {
+	if len(keys) != len(values) {
+		panic(PanicKeyValueCountUnmatched)
 	}
-	return cmds, nil
+	return queueErr(pipe, func(p redis.Pipeliner) {
+		for i, key := range keys {
+			p.Set(ctx, key, values[i], expiration)
+		}
+	})
+}
+
+func (pipe *pipeline) BatchDel(ctx context.Context, keys []string) *Future[struct{}] {
+	return queueErr(pipe, func(p redis.Pipeliner) {
+		for _, key := range keys {
+			p.Del(ctx, key)
+		}
+	})
+}
+
+// pipeline hash
+func (pipe *pipeline) HGet(ctx context.Context, key, field string) *Future[string] {
+	return queueCmd[string](pipe, func(p redis.Pipeliner) *redis.StringCmd { return p.HGet(ctx, key, field) })
+}
+
+func (pipe *pipeline) HSet(ctx context.Context, key string, values ...any) *Future[struct{}] {
+	return queueErr(pipe, func(p redis.Pipeliner) { p.HSet(ctx, key, values...) })
+}
+
+func (pipe *pipeline) HMSet(ctx context.Context, key string, values ...any) *Future[bool] {
+	return queueCmd[bool](pipe, func(p redis.Pipeliner) *redis.BoolCmd { return p.HMSet(ctx, key, values...) })
+}
+
+func (pipe *pipeline) HMGet(ctx context.Context, key string, fields ...string) *Future[[]any] {
+	return queueCmd[[]any](pipe, func(p redis.Pipeliner) *redis.SliceCmd { return p.HMGet(ctx, key, fields...) })
+}
+
+func (pipe *pipeline) HGetAll(ctx context.Context, key string) *Future[map[string]string] {
+	return queueCmd[map[string]string](pipe, func(p redis.Pipeliner) *redis.StringStringMapCmd {
+		return p.HGetAll(ctx, key)
+	})
+}
+
+func (pipe *pipeline) HDel(ctx context.Context, key string, fields ...string) *Future[int64] {
+	return queueCmd[int64](pipe, func(p redis.Pipeliner) *redis.IntCmd { return p.HDel(ctx, key, fields...) })
+}
+
+func (pipe *pipeline) HLen(ctx context.Context, key string) *Future[int64] {
+	return queueCmd[int64](pipe, func(p redis.Pipeliner) *redis.IntCmd { return p.HLen(ctx, key) })
 }
 
 // pipeline zset
-func (pipe *pipeline) ZRemRangeByScore(ctx context.Context, key string, min, max float64) error {
-	minStr, err := toString(min)
+func (pipe *pipeline) ZAdd(ctx context.Context, key string, values ...any) *Future[int64] {
+	members, err := zMembers(values, func(v any) (any, error) { return v, nil })
 	if err != nil {
-		return err
+		return failed[int64](pipe, err)
 	}
-	maxStr, err := toString(max)
+	return queueCmd[int64](pipe, func(p redis.Pipeliner) *redis.IntCmd { return p.ZAdd(ctx, key, members...) })
+}
+
+func (pipe *pipeline) ZCard(ctx context.Context, key string) *Future[int64] {
+	return queueCmd[int64](pipe, func(p redis.Pipeliner) *redis.IntCmd { return p.ZCard(ctx, key) })
+}
+
+func (pipe *pipeline) ZCount(ctx context.Context, key string, min, max string) *Future[int64] {
+	return queueCmd[int64](pipe, func(p redis.Pipeliner) *redis.IntCmd { return p.ZCount(ctx, key, min, max) })
+}
+
+func (pipe *pipeline) ZIncrBy(ctx context.Context, key string, increment float64, member any) *Future[float64] {
+	memStr, err := toString(member)
 	if err != nil {
-		return err
+		return failed[float64](pipe, err)
 	}
-	pipe.mux.Lock()
-	result := pipe.Pipe.ZRemRangeByScore(ctx, key, minStr, maxStr)
-	pipe.resHandlers = append(pipe.resHandlers, doNothing)
-	pipe.objects = append(pipe.objects, nil)
-	pipe.mux.Unlock()
-	return result.Err()
+	return queueCmd[float64](pipe, func(p redis.Pipeliner) *redis.FloatCmd {
+		return p.ZIncrBy(ctx, key, increment, memStr)
+	})
+}
+
+func (pipe *pipeline) ZRange(ctx context.Context, key string, start, stop int64) *Future[[]string] {
+	return queueCmd[[]string](pipe, func(p redis.Pipeliner) *redis.StringSliceCmd {
+		return p.ZRange(ctx, key, start, stop)
+	})
+}
+
+func (pipe *pipeline) ZRangeByScore(ctx context.Context, key string, min, max string) *Future[[]string] {
+	return queueCmd[[]string](pipe, func(p redis.Pipeliner) *redis.StringSliceCmd {
+		return p.ZRangeByScore(ctx, key, &redis.ZRangeBy{Min: min, Max: max})
+	})
+}
+
+func (pipe *pipeline) ZRangeWithScores(ctx context.Context, key string, start, stop int64) *Future[[]redis.Z] {
+	return queueCmd[[]redis.Z](pipe, func(p redis.Pipeliner) *redis.ZSliceCmd {
+		return p.ZRangeWithScores(ctx, key, start, stop)
+	})
+}
+
+func (pipe *pipeline) ZRangeByScoreWithScores(ctx context.Context, key string, min, max string) *Future[[]redis.Z] {
+	return queueCmd[[]redis.Z](pipe, func(p redis.Pipeliner) *redis.ZSliceCmd {
+		return p.ZRangeByScoreWithScores(ctx, key, &redis.ZRangeBy{Min: min, Max: max})
+	})
+}
+
+func (pipe *pipeline) ZRevRange(ctx context.Context, key string, start, stop int64) *Future[[]string] {
+	return queueCmd[[]string](pipe, func(p redis.Pipeliner) *redis.StringSliceCmd {
+		return p.ZRevRange(ctx, key, start, stop)
+	})
+}
+
+func (pipe *pipeline) ZRevRangeByScore(ctx context.Context, key string, min, max string) *Future[[]string] {
+	return queueCmd[[]string](pipe, func(p redis.Pipeliner) *redis.StringSliceCmd {
+		return p.ZRevRangeByScore(ctx, key, &redis.ZRangeBy{Min: min, Max: max})
+	})
+}
+
+func (pipe *pipeline) ZRevRangeWithScores(ctx context.Context, key string, start, stop int64) *Future[[]redis.Z] {
+	return queueCmd[[]redis.Z](pipe, func(p redis.Pipeliner) *redis.ZSliceCmd {
+		return p.ZRevRangeWithScores(ctx, key, start, stop)
+	})
+}
+
+func (pipe *pipeline) ZRevRangeByScoreWithScores(ctx context.Context, key string, min, max string) *Future[[]redis.Z] {
+	return queueCmd[[]redis.Z](pipe, func(p redis.Pipeliner) *redis.ZSliceCmd {
+		return p.ZRevRangeByScoreWithScores(ctx, key, &redis.ZRangeBy{Min: min, Max: max})
+	})
+}
+
+func (pipe *pipeline) ZRank(ctx context.Context, key string, member any) *Future[int64] {
+	memStr, err := toString(member)
+	if err != nil {
+		return failed[int64](pipe, err)
+	}
+	return queueCmd[int64](pipe, func(p redis.Pipeliner) *redis.IntCmd { return p.ZRank(ctx, key, memStr) })
+}
+
+func (pipe *pipeline) ZRevRank(ctx context.Context, key string, member any) *Future[int64] {
+	memStr, err := toString(member)
+	if err != nil {
+		return failed[int64](pipe, err)
+	}
+	return queueCmd[int64](pipe, func(p redis.Pipeliner) *redis.IntCmd { return p.ZRevRank(ctx, key, memStr) })
+}
+
+func (pipe *pipeline) ZRem(ctx context.Context, key string, members ...any) *Future[int64] {
+	return queueCmd[int64](pipe, func(p redis.Pipeliner) *redis.IntCmd { return p.ZRem(ctx, key, members...) })
+}
+
+func (pipe *pipeline) ZRemRangeByRank(ctx context.Context, key string, start, stop int64) *Future[int64] {
+	return queueCmd[int64](pipe, func(p redis.Pipeliner) *redis.IntCmd {
+		return p.ZRemRangeByRank(ctx, key, start, stop)
+	})
+}
+
+func (pipe *pipeline) ZRemRangeByScore(ctx context.Context, key string, min, max float64) error {
+	minStr, maxStr := strconv.FormatFloat(min, 'g', -1, 64), strconv.FormatFloat(max, 'g', -1, 64)
+	return pipe.ZRemRangeByScoreFuture(ctx, key, minStr, maxStr).queueErr
+}
+
+func (pipe *pipeline) ZRemRangeByScoreFuture(ctx context.Context, key string, min, max string) *Future[int64] {
+	return queueCmd[int64](pipe, func(p redis.Pipeliner) *redis.IntCmd {
+		return p.ZRemRangeByScore(ctx, key, min, max)
+	})
+}
+
+func (pipe *pipeline) ZScore(ctx context.Context, key string, member any) *Future[float64] {
+	memStr, err := toString(member)
+	if err != nil {
+		return failed[float64](pipe, err)
+	}
+	return queueCmd[float64](pipe, func(p redis.Pipeliner) *redis.FloatCmd { return p.ZScore(ctx, key, memStr) })
+}
+
+// pipeline script
+func (pipe *pipeline) EvalSha(ctx context.Context, s *Script, keys []string, args ...any) *Future[any] {
+	if s == nil {
+		return failed[any](pipe, ErrScriptIsNil)
+	}
+	return queueCmd[any](pipe, func(p redis.Pipeliner) *redis.Cmd { return p.EvalSha(ctx, s.hash, keys, args...) })
 }
@@ -0,0 +1,140 @@
+package gdb
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+func TestPipelineFutures(t *testing.T) {
+	ctx := context.Background()
+	client, _, _ := newMemRedisClient(t)
+	if err := client.SetObjectEX(ctx, "foo:1", &Foo{F: 1}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	pipe := client.Pipeline()
+	incr := pipe.IncrBy(ctx, "counter", 3)
+	ttl := pipe.TTL(ctx, "foo:1")
+	exists := pipe.Exists(ctx, "nokey")
+	batch := pipe.BatchGet(ctx, []string{"counter", "nokey"})
+	foos := make([]*Foo, 2)
+	getObjs := pipe.GetObjects(ctx, []string{"foo:1", "foo:2"}, foos)
+	zadd := pipe.ZAddObjectsFuture(ctx, "zfoo", 1.5, &Foo{F: 1}, 2.5, &Foo{F: 2})
+	var zfoos []*Foo
+	zrange := pipe.ZRangeObjectsWithScores(ctx, "zfoo", 0, -1, &zfoos)
+	if _, err := incr.Result(); !errors.Is(err, ErrPipelineNotExecuted) {
+		t.Fatalf("before Exec = %v", err)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := incr.Result(); err != nil || n != 3 {
+		t.Fatalf("IncrBy = %d, %v", n, err)
+	}
+	if d, err := ttl.Result(); err != nil || d != time.Minute {
+		t.Fatalf("TTL = %v, %v", d, err)
+	}
+	if ok, err := exists.Result(); err != nil || ok {
+		t.Fatalf("Exists = %v, %v", ok, err)
+	}
+	if cmds := batch.Val(); len(cmds) != 2 || cmds[0].Val() != "3" || !errors.Is(cmds[1].Err(), redis.Nil) {
+		t.Fatalf("BatchGet = %v", cmds)
+	}
+	if err := getObjs.Err(); err != nil || len(foos) != 2 || foos[0].F != 1 || foos[1] != nil {
+		t.Fatalf("GetObjects = %v, %v", foos, err)
+	}
+	if n := zadd.Val(); n != 2 {
+		t.Fatalf("ZAddObjects = %d", n)
+	}
+	scores, err := zrange.Result()
+	if err != nil || len(zfoos) != 2 || zfoos[1].F != 2 || len(scores) != 2 || scores[1] != 2.5 {
+		t.Fatalf("ZRangeObjectsWithScores = %v, %v, %v", zfoos, scores, err)
+	}
+}
+
+func TestPipelineErrors(t *testing.T) {
+	ctx := context.Background()
+	client, _, _ := newMemRedisClient(t)
+	client.SetKOMapping(map[string]string{"foo:": "gdb.Foo"})
+	if err := client.Set(ctx, "str", "v"); err != nil {
+		t.Fatal(err)
+	}
+
+	pipe := client.TxPipeline()
+	set := pipe.SetObject(ctx, "foo:1", &Foo{F: 1})
+	mismatch := pipe.SetObject(ctx, "foo:2", &Bar{B: 1})
+	wrongType := pipe.HLen(ctx, "str")
+	var foo Foo
+	get := pipe.GetObjectFuture(ctx, "foo:1", &foo)
+	miss := pipe.Get(ctx, "nokey")
+	incr := pipe.ZIncrBy(ctx, "z", 1, 1.5)
+	_, err := pipe.Exec(ctx)
+
+	var pErr *PipelineError
+	if !errors.As(err, &pErr) || len(pErr.Errs) != 3 || pErr.Errs[1] == nil || pErr.Errs[2] == nil {
+		t.Fatalf("Exec = %v", err)
+	}
+	if !errors.Is(err, ErrKeyObjMismatch) || !errors.Is(mismatch.Err(), ErrKeyObjMismatch) {
+		t.Fatalf("mismatch = %v", mismatch.Err())
+	}
+	if wrongType.Err() == nil {
+		t.Fatal("HLen on a string succeeded")
+	}
+	if set.Err() != nil || get.Err() != nil || foo.F != 1 {
+		t.Fatalf("GetObject = %v, %v", foo, get.Err())
+	}
+	if !errors.Is(miss.Err(), redis.Nil) {
+		t.Fatalf("Get miss = %v", miss.Err())
+	}
+	// the member of an unsupported type is not sent
+	if !errors.Is(incr.Err(), ErrValueType) {
+		t.Fatalf("ZIncrBy float member = %v", incr.Err())
+	}
+	if n, err := client.ZCard(ctx, "z"); err != nil || n != 0 {
+		t.Fatalf("ZCard = %d, %v", n, err)
+	}
+}
+
+func TestPipelineErrorCalls(t *testing.T) {
+	ctx := context.Background()
+	client, _, _ := newMemRedisClient(t)
+	client.SetKOMapping(map[string]string{"foo:": "gdb.Foo"})
+
+	pipe := client.Pipeline()
+	if err := pipe.HSetObjects(ctx, "foo:h", "b", &Foo{F: 2}, "a", &Foo{F: 1}, "c", &Foo{F: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if err := pipe.ZAddObjects(ctx, "foo:z", 1, &Foo{F: 1}, 2, &Foo{F: 2}, 3, &Foo{F: 3}); err != nil {
+		t.Fatal(err)
+	}
+	// the errors before sending are returned by the calls
+	if err := pipe.ZAddObjects(ctx, "foo:z", 4, &Bar{B: 4}); !errors.Is(err, ErrKeyObjMismatch) {
+		t.Fatalf("ZAddObjects mismatched = %v", err)
+	}
+	if err := pipe.ZRemRangeByScore(ctx, "foo:z", 1, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := pipe.ZRemObjects(ctx, "foo:z", &Foo{F: 3}); err != nil {
+		t.Fatal(err)
+	}
+	var fields []string
+	var foos []*Foo
+	hgetall := pipe.HGetAllObjects(ctx, "foo:h", &fields, &foos)
+	zcard := pipe.ZCard(ctx, "foo:z")
+	if _, err := pipe.Exec(ctx); !errors.Is(err, ErrKeyObjMismatch) {
+		t.Fatalf("Exec = %v", err)
+	}
+	if n := zcard.Val(); n != 0 {
+		t.Fatalf("ZCard = %d", n)
+	}
+	// the fields are sorted
+	if err := hgetall.Err(); err != nil || !equalStrings(fields, []string{"a", "b", "c"}) || foos[0].F != 1 ||
+		foos[2].F != 3 {
+		t.Fatalf("HGetAllObjects = %v, %v, %v", fields, foos, err)
+	}
+}
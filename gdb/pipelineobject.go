@@ -2,98 +2,566 @@ package gdb
 
 import (
 	"context"
+	"errors"
+	"sort"
+	"time"
 
 	"github.com/go-redis/redis/v8"
+	gmarshaller "github.com/oldjon/gutil/marshaller"
 )
 
+// PipelinerObject is the ObjectDB calls of a pipeline, the objects are unmarshalled into the destinations at Exec.
+// UpdateObject is not supported since the object must be read before it is written back, XMessageObject is not
+// a command.
 type PipelinerObject interface {
 	GetObject(ctx context.Context, key string, obj any) error
+	GetObjectFuture(ctx context.Context, key string, obj any) *Future[struct{}]
+	SetObject(ctx context.Context, key string, obj any) *Future[struct{}]
+	SetObjectEX(ctx context.Context, key string, obj any, expiration time.Duration) *Future[struct{}]
+	GetObjects(ctx context.Context, keys []string, objs any) *Future[struct{}]
+	SetObjects(ctx context.Context, keys []string, objs any) *Future[struct{}]
+	SetObjectsEX(ctx context.Context, keys []string, objs any, expiration time.Duration) *Future[struct{}]
+
 	HSetObjects(ctx context.Context, key string, values ...any) error
+	HSetObjectsFuture(ctx context.Context, key string, values ...any) *Future[struct{}]
+	HGetObject(ctx context.Context, key string, field string, obj any) *Future[struct{}]
+	HMGetObjects(ctx context.Context, key string, fields []string, objs any) *Future[struct{}]
+	HGetAllObjects(ctx context.Context, key string, fields *[]string, objs any) *Future[struct{}]
+
 	ZAddObjects(ctx context.Context, key string, values ...any) error
-	ZRemObjects(ctx context.Context, key string, values ...any) error
+	ZAddObjectsFuture(ctx context.Context, key string, values ...any) *Future[int64]
+	ZRangeObjects(ctx context.Context, key string, start, stop int64, objs any) *Future[struct{}]
+	ZRangeObjectsByScore(ctx context.Context, key string, min, max string, objs any) *Future[struct{}]
+	ZRangeObjectsWithScores(ctx context.Context, key string, start, stop int64, objs any) *Future[[]float64]
+	ZRangeObjectsByScoreWithScores(ctx context.Context, key string, min, max string, objs any) *Future[[]float64]
+	ZRevRangeObjects(ctx context.Context, key string, start, stop int64, objs any) *Future[struct{}]
+	ZRevRangeObjectsByScore(ctx context.Context, key string, min, max string, objs any) *Future[struct{}]
+	ZRevRangeObjectsWithScores(ctx context.Context, key string, start, stop int64, objs any) *Future[[]float64]
+	ZRevRangeObjectsByScoreWithScores(ctx context.Context, key string, min, max string, objs any) *Future[[]float64]
+	ZRankObject(ctx context.Context, key string, member any) *Future[int64]
+	ZRevRankObject(ctx context.Context, key string, member any) *Future[int64]
+	ZScoreObject(ctx context.Context, key string, member any) *Future[float64]
+	ZRemObjects(ctx context.Context, key string, members ...any) error
+	ZRemObjectsFuture(ctx context.Context, key string, members ...any) *Future[int64]
+
+	GeoAddObjects(ctx context.Context, key string, values ...any) *Future[int64]
+	GeoPosObjects(ctx context.Context, key string, members ...any) *Future[[]*redis.GeoPos]
+	GeoDistObjects(ctx context.Context, key string, member1, member2 any, unit string) *Future[float64]
+	GeoSearchObjects(ctx context.Context, key string, q *redis.GeoSearchLocationQuery,
+		objs any) *Future[[]redis.GeoLocation]
+
+	LPushObjects(ctx context.Context, key string, values ...any) *Future[int64]
+	RPushObjects(ctx context.Context, key string, values ...any) *Future[int64]
+	LPopObject(ctx context.Context, key string, obj any) *Future[struct{}]
+	RPopObject(ctx context.Context, key string, obj any) *Future[struct{}]
+	BLPopObject(ctx context.Context, timeout time.Duration, key string, obj any) *Future[struct{}]
+	LRangeObjects(ctx context.Context, key string, start, stop int64, objs any) *Future[struct{}]
+
+	SAddObjects(ctx context.Context, key string, members ...any) *Future[int64]
+	SRemObjects(ctx context.Context, key string, members ...any) *Future[int64]
+	SIsMemberObject(ctx context.Context, key string, member any) *Future[bool]
+	SMembersObjects(ctx context.Context, key string, objs any) *Future[struct{}]
+	SPopObject(ctx context.Context, key string, obj any) *Future[struct{}]
+
+	XAddObject(ctx context.Context, stream string, obj any) *Future[string]
+}
+
+// marshal check obj and marshal it by the marshaller of the key
+func (pipe *pipeline) marshal(key string, obj any) ([]byte, error) {
+	m, err := pipe.rc.ObjMarshallerOf(key, obj)
+	if err != nil {
+		return nil, err
+	}
+	return m.Marshal(obj)
+}
+
+// readObject queue the command of fn reading a marshalled object of the key, and unmarshal it into obj at Exec
+func (pipe *pipeline) readObject(key string, obj any, fn func(p redis.Pipeliner) *redis.StringCmd) *Future[struct{}] {
+	m, err := pipe.rc.ObjMarshallerOf(key, obj)
+	if err != nil {
+		return failed[struct{}](pipe, err)
+	}
+	return queue(pipe, func(p redis.Pipeliner) { fn(p) }, func(cmds []redis.Cmder) (struct{}, error) {
+		v, err := cmds[0].(*redis.StringCmd).Result()
+		if err != nil {
+			return struct{}{}, err
+		}
+		return struct{}{}, m.Unmarshal([]byte(v), obj)
+	})
+}
+
+// readObjects queue the command of fn reading marshalled objects of the key, and unmarshal them into objs at Exec
+func (pipe *pipeline) readObjects(key string, objs any,
+	fn func(p redis.Pipeliner) *redis.StringSliceCmd) *Future[struct{}] {
+	m, err := pipe.rc.ObjMarshallerOf(key, objs)
+	if err != nil {
+		return failed[struct{}](pipe, err)
+	}
+	return queue(pipe, func(p redis.Pipeliner) { fn(p) }, func(cmds []redis.Cmder) (struct{}, error) {
+		rets, err := cmds[0].(*redis.StringSliceCmd).Result()
+		if err != nil {
+			return struct{}{}, err
+		}
+		return struct{}{}, unmarshalSliceBy(m, rets, objs)
+	})
+}
+
+// readZObjects queue the command of fn reading zset members of the key, and unmarshal them into objs at Exec
+func (pipe *pipeline) readZObjects(key string, objs any,
+	fn func(p redis.Pipeliner) *redis.ZSliceCmd) *Future[[]float64] {
+	m, err := pipe.rc.ObjMarshallerOf(key, objs)
+	if err != nil {
+		return failed[[]float64](pipe, err)
+	}
+	return queue(pipe, func(p redis.Pipeliner) { fn(p) }, func(cmds []redis.Cmder) ([]float64, error) {
+		rets, err := cmds[0].(*redis.ZSliceCmd).Result()
+		if err != nil {
+			return nil, err
+		}
+		datas := make([]string, 0, len(rets))
+		scores := make([]float64, 0, len(rets))
+		for _, z := range rets {
+			data, ok := z.Member.(string)
+			if !ok {
+				return nil, ErrValueType
+			}
+			datas = append(datas, data)
+			scores = append(scores, z.Score)
+		}
+		if err = unmarshalSliceBy(m, datas, objs); err != nil {
+			return nil, err
+		}
+		return scores, nil
+	})
 }
 
 // pipeline string object
 func (pipe *pipeline) GetObject(ctx context.Context, key string, obj any) error {
+	return pipe.GetObjectFuture(ctx, key, obj).queueErr
+}
+
+func (pipe *pipeline) GetObjectFuture(ctx context.Context, key string, obj any) *Future[struct{}] {
 	if obj == nil {
 		panic(PanicValueDstNeedBeAllocated)
 	}
-	pipe.mux.Lock()
-	result := pipe.Pipe.Get(ctx, key)
-	pipe.resHandlers = append(pipe.resHandlers, func(cmd redis.Cmder, obj any) error {
-		c := cmd.(*redis.StringCmd) // let it panic
-		if c.Err() != nil {
-			return nil
+	return pipe.readObject(key, obj, func(p redis.Pipeliner) *redis.StringCmd { return p.Get(ctx, key) })
+}
+
+func (pipe *pipeline) SetObject(ctx context.Context, key string, obj any) *Future[struct{}] {
+	bys, err := pipe.marshal(key, obj)
+	if err != nil {
+		return failed[struct{}](pipe, err)
+	}
+	var expiration time.Duration = redis.KeepTTL // keep the ttl like Set
+	if ttl := pipe.rc.defaultTTL(key); ttl > 0 {
+		expiration = ttl
+	}
+	return queueErr(pipe, func(p redis.Pipeliner) { p.Set(ctx, key, bys, expiration) })
+}
+
+func (pipe *pipeline) SetObjectEX(ctx context.Context, key string, obj any,
+	expiration time.Duration) *Future[struct{}] {
+	bys, err := pipe.marshal(key, obj)
+	if err != nil {
+		return failed[struct{}](pipe, err)
+	}
+	return queueErr(pipe, func(p redis.Pipeliner) { p.Set(ctx, key, bys, expiration) })
+}
+
+func (pipe *pipeline) GetObjects(ctx context.Context, keys []string, objs any) *Future[struct{}] {
+	if len(keys) == 0 {
+		panic(PanicKeyIsMissing)
+	}
+	objsValue, elemIsInterface := checkDstSlice(objs, len(keys), PanicKeyValueCountUnmatched)
+	ms := make([]gmarshaller.Marshaller, len(keys))
+	for i, key := range keys {
+		var err error
+		if ms[i], err = pipe.rc.ObjMarshallerOf(key, objsValue.Index(i).Interface()); err != nil {
+			return failed[struct{}](pipe, err)
+		}
+	}
+	return queue(pipe, func(p redis.Pipeliner) {
+		for _, key := range keys {
+			p.Get(ctx, key)
+		}
+	}, func(cmds []redis.Cmder) (struct{}, error) {
+		for i, cmd := range cmds {
+			v, err := cmd.(*redis.StringCmd).Bytes()
+			if errors.Is(err, redis.Nil) {
+				v = nil
+			} else if err != nil {
+				return struct{}{}, err
+			}
+			if err = setSliceElem(ms[i], objsValue, i, v, elemIsInterface); err != nil {
+				return struct{}{}, err
+			}
+		}
+		return struct{}{}, nil
+	})
+}
+
+func (pipe *pipeline) SetObjects(ctx context.Context, keys []string, objs any) *Future[struct{}] {
+	return pipe.setObjects(ctx, keys, objs, pipe.rc.defaultTTL)
+}
+
+func (pipe *pipeline) SetObjectsEX(ctx context.Context, keys []string, objs any,
+	expiration time.Duration) *Future[struct{}] {
+	return pipe.setObjects(ctx, keys, objs, func(string) time.Duration { return expiration })
+}
+
+// setObjects set the objects with the expiration of every key
+func (pipe *pipeline) setObjects(ctx context.Context, keys []string, objs any,
+	expiration func(key string) time.Duration) *Future[struct{}] {
+	if len(keys) == 0 {
+		panic(PanicKeyIsMissing)
+	}
+	datas, err := pipe.rc.marshalObjects(keys, objs)
+	if err != nil {
+		return failed[struct{}](pipe, err)
+	}
+	return queueErr(pipe, func(p redis.Pipeliner) {
+		for i, key := range keys {
+			p.Set(ctx, key, datas[i], expiration(key))
 		}
-		return pipe.rc.objMarshaller.Unmarshal([]byte(c.Val()), obj)
 	})
-	pipe.objects = append(pipe.objects, obj)
-	pipe.mux.Unlock()
-	return result.Err()
 }
 
 // pipeline hash object
 func (pipe *pipeline) HSetObjects(ctx context.Context, key string, values ...any) error {
+	return pipe.HSetObjectsFuture(ctx, key, values...).queueErr
+}
+
+func (pipe *pipeline) HSetObjectsFuture(ctx context.Context, key string, values ...any) *Future[struct{}] {
+	values = hsetPairs(values)
 	var l = make([]any, 0, len(values))
-	if len(values)%2 != 0 {
-		panic(PanicFieldValueCountUnmatched)
-	}
 	for i := 0; i < len(values); i += 2 {
-		l = append(l, values[i]) // key
-		bys, err := pipe.rc.objMarshaller.Marshal(values[i+1])
+		bys, err := pipe.marshal(key, values[i+1])
 		if err != nil {
-			return err
+			return failed[struct{}](pipe, err)
 		}
-		l = append(l, bys) // value
+		l = append(l, values[i], bys)
 	}
-	pipe.mux.Lock()
-	result := pipe.Pipe.HSet(ctx, key, l...)
-	pipe.resHandlers = append(pipe.resHandlers, doNothing)
-	pipe.objects = append(pipe.objects, nil)
-	pipe.mux.Unlock()
-	return result.Err()
+	return queueErr(pipe, func(p redis.Pipeliner) { p.HSet(ctx, key, l...) })
+}
+
+func (pipe *pipeline) HGetObject(ctx context.Context, key string, field string, obj any) *Future[struct{}] {
+	return pipe.readObject(key, obj, func(p redis.Pipeliner) *redis.StringCmd { return p.HGet(ctx, key, field) })
+}
+
+func (pipe *pipeline) HMGetObjects(ctx context.Context, key string, fields []string, objs any) *Future[struct{}] {
+	if len(fields) == 0 {
+		panic(PanicFieldsIsMissing)
+	}
+	objsValue, elemIsInterface := checkDstSlice(objs, len(fields), PanicFieldValueCountUnmatched)
+	m, err := pipe.rc.ObjMarshallerOf(key, objs)
+	if err != nil {
+		return failed[struct{}](pipe, err)
+	}
+	return queue(pipe, func(p redis.Pipeliner) { p.HMGet(ctx, key, fields...) },
+		func(cmds []redis.Cmder) (struct{}, error) {
+			rets, err := cmds[0].(*redis.SliceCmd).Result()
+			if err != nil {
+				return struct{}{}, err
+			}
+			for i, v := range rets {
+				var data []byte
+				if str, ok := v.(string); ok && str != "" {
+					data = []byte(str)
+				}
+				if err = setSliceElem(m, objsValue, i, data, elemIsInterface); err != nil {
+					return struct{}{}, err
+				}
+			}
+			return struct{}{}, nil
+		})
+}
+
+func (pipe *pipeline) HGetAllObjects(ctx context.Context, key string, fields *[]string, objs any) *Future[struct{}] {
+	if fields == nil {
+		panic(PanicFieldsIsNil)
+	}
+	m, err := pipe.rc.ObjMarshallerOf(key, objs)
+	if err != nil {
+		return failed[struct{}](pipe, err)
+	}
+	return queue(pipe, func(p redis.Pipeliner) { p.HGetAll(ctx, key) }, func(cmds []redis.Cmder) (struct{}, error) {
+		rets, err := cmds[0].(*redis.StringStringMapCmd).Result()
+		if err != nil {
+			return struct{}{}, err
+		}
+		names := make([]string, 0, len(rets))
+		for k := range rets {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		datas := make([]string, 0, len(rets))
+		for _, name := range names {
+			datas = append(datas, rets[name])
+		}
+		if err = unmarshalSliceBy(m, datas, objs); err != nil {
+			return struct{}{}, err
+		}
+		*fields = append(*fields, names...)
+		return struct{}{}, nil
+	})
 }
 
 // pipeline zset object
 func (pipe *pipeline) ZAddObjects(ctx context.Context, key string, values ...any) error {
-	if len(values)%2 != 0 {
-		panic(PanicScoreValueCountUnmatched)
+	return pipe.ZAddObjectsFuture(ctx, key, values...).queueErr
+}
+
+func (pipe *pipeline) ZAddObjectsFuture(ctx context.Context, key string, values ...any) *Future[int64] {
+	members, err := zMembers(values, func(v any) (any, error) {
+		bys, err := pipe.marshal(key, v)
+		return string(bys), err
+	})
+	if err != nil {
+		return failed[int64](pipe, err)
 	}
-	var members = make([]*redis.Z, 0, len(values))
-	for i := 0; i < len(values); i += 2 {
-		s, err := toFloat64(values[i])
-		if err != nil {
-			return err
+	return queueCmd[int64](pipe, func(p redis.Pipeliner) *redis.IntCmd { return p.ZAdd(ctx, key, members...) })
+}
+
+func (pipe *pipeline) ZRangeObjects(ctx context.Context, key string, start, stop int64, objs any) *Future[struct{}] {
+	return pipe.readObjects(key, objs, func(p redis.Pipeliner) *redis.StringSliceCmd {
+		return p.ZRange(ctx, key, start, stop)
+	})
+}
+
+func (pipe *pipeline) ZRangeObjectsByScore(ctx context.Context, key string, min, max string,
+	objs any) *Future[struct{}] {
+	return pipe.readObjects(key, objs, func(p redis.Pipeliner) *redis.StringSliceCmd {
+		return p.ZRangeByScore(ctx, key, &redis.ZRangeBy{Min: min, Max: max})
+	})
+}
+
+func (pipe *pipeline) ZRangeObjectsWithScores(ctx context.Context, key string, start, stop int64,
+	objs any) *Future[[]float64] {
+	return pipe.readZObjects(key, objs, func(p redis.Pipeliner) *redis.ZSliceCmd {
+		return p.ZRangeWithScores(ctx, key, start, stop)
+	})
+}
+
+func (pipe *pipeline) ZRangeObjectsByScoreWithScores(ctx context.Context, key string, min, max string,
+	objs any) *Future[[]float64] {
+	return pipe.readZObjects(key, objs, func(p redis.Pipeliner) *redis.ZSliceCmd {
+		return p.ZRangeByScoreWithScores(ctx, key, &redis.ZRangeBy{Min: min, Max: max})
+	})
+}
+
+func (pipe *pipeline) ZRevRangeObjects(ctx context.Context, key string, start, stop int64, objs any) *Future[struct{}] {
+	return pipe.readObjects(key, objs, func(p redis.Pipeliner) *redis.StringSliceCmd {
+		return p.ZRevRange(ctx, key, start, stop)
+	})
+}
+
+func (pipe *pipeline) ZRevRangeObjectsByScore(ctx context.Context, key string, min, max string,
+	objs any) *Future[struct{}] {
+	return pipe.readObjects(key, objs, func(p redis.Pipeliner) *redis.StringSliceCmd {
+		return p.ZRevRangeByScore(ctx, key, &redis.ZRangeBy{Min: min, Max: max})
+	})
+}
+
+func (pipe *pipeline) ZRevRangeObjectsWithScores(ctx context.Context, key string, start, stop int64,
+	objs any) *Future[[]float64] {
+	return pipe.readZObjects(key, objs, func(p redis.Pipeliner) *redis.ZSliceCmd {
+		return p.ZRevRangeWithScores(ctx, key, start, stop)
+	})
+}
+
+func (pipe *pipeline) ZRevRangeObjectsByScoreWithScores(ctx context.Context, key string, min, max string,
+	objs any) *Future[[]float64] {
+	return pipe.readZObjects(key, objs, func(p redis.Pipeliner) *redis.ZSliceCmd {
+		return p.ZRevRangeByScoreWithScores(ctx, key, &redis.ZRangeBy{Min: min, Max: max})
+	})
+}
+
+func (pipe *pipeline) ZRankObject(ctx context.Context, key string, member any) *Future[int64] {
+	data, err := pipe.marshal(key, member)
+	if err != nil {
+		return failed[int64](pipe, err)
+	}
+	return queueCmd[int64](pipe, func(p redis.Pipeliner) *redis.IntCmd { return p.ZRank(ctx, key, string(data)) })
+}
+
+func (pipe *pipeline) ZRevRankObject(ctx context.Context, key string, member any) *Future[int64] {
+	data, err := pipe.marshal(key, member)
+	if err != nil {
+		return failed[int64](pipe, err)
+	}
+	return queueCmd[int64](pipe, func(p redis.Pipeliner) *redis.IntCmd { return p.ZRevRank(ctx, key, string(data)) })
+}
+
+func (pipe *pipeline) ZScoreObject(ctx context.Context, key string, member any) *Future[float64] {
+	data, err := pipe.marshal(key, member)
+	if err != nil {
+		return failed[float64](pipe, err)
+	}
+	return queueCmd[float64](pipe, func(p redis.Pipeliner) *redis.FloatCmd { return p.ZScore(ctx, key, string(data)) })
+}
+
+func (pipe *pipeline) ZRemObjects(ctx context.Context, key string, members ...any) error {
+	return pipe.ZRemObjectsFuture(ctx, key, members...).queueErr
+}
+
+func (pipe *pipeline) ZRemObjectsFuture(ctx context.Context, key string, members ...any) *Future[int64] {
+	l, err := pipe.rc.marshalMembers(key, members)
+	if err != nil {
+		return failed[int64](pipe, err)
+	}
+	return queueCmd[int64](pipe, func(p redis.Pipeliner) *redis.IntCmd { return p.ZRem(ctx, key, l...) })
+}
+
+// pipeline geo object
+func (pipe *pipeline) GeoAddObjects(ctx context.Context, key string, values ...any) *Future[int64] {
+	locations, err := pipe.rc.geoLocations(key, values)
+	if err != nil {
+		return failed[int64](pipe, err)
+	}
+	return queueCmd[int64](pipe, func(p redis.Pipeliner) *redis.IntCmd { return p.GeoAdd(ctx, key, locations...) })
+}
+
+func (pipe *pipeline) GeoPosObjects(ctx context.Context, key string, members ...any) *Future[[]*redis.GeoPos] {
+	names, err := pipe.rc.geoMemberNames(key, members)
+	if err != nil {
+		return failed[[]*redis.GeoPos](pipe, err)
+	}
+	return queueCmd[[]*redis.GeoPos](pipe, func(p redis.Pipeliner) *redis.GeoPosCmd {
+		return p.GeoPos(ctx, key, names...)
+	})
+}
+
+func (pipe *pipeline) GeoDistObjects(ctx context.Context, key string, member1, member2 any,
+	unit string) *Future[float64] {
+	names, err := pipe.rc.geoMemberNames(key, []any{member1, member2})
+	if err != nil {
+		return failed[float64](pipe, err)
+	}
+	return queueCmd[float64](pipe, func(p redis.Pipeliner) *redis.FloatCmd {
+		return p.GeoDist(ctx, key, names[0], names[1], unit)
+	})
+}
+
+func (pipe *pipeline) GeoSearchObjects(ctx context.Context, key string, q *redis.GeoSearchLocationQuery,
+	objs any) *Future[[]redis.GeoLocation] {
+	m, err := pipe.rc.ObjMarshallerOf(key, objs)
+	if err != nil {
+		return failed[[]redis.GeoLocation](pipe, err)
+	}
+	withLocation := geoSearchWithLocation(q)
+	return queue(pipe, func(p redis.Pipeliner) {
+		if withLocation {
+			p.GeoSearchLocation(ctx, key, q)
+		} else {
+			p.GeoSearch(ctx, key, &q.GeoSearchQuery)
+		}
+	}, func(cmds []redis.Cmder) ([]redis.GeoLocation, error) {
+		var locations []redis.GeoLocation
+		var err error
+		if withLocation {
+			locations, err = cmds[0].(*redis.GeoSearchLocationCmd).Result()
+		} else {
+			var names []string
+			names, err = cmds[0].(*redis.StringSliceCmd).Result()
+			locations = geoNameLocations(names)
 		}
-		bys, err := pipe.rc.objMarshaller.Marshal(values[i+1])
 		if err != nil {
-			return err
+			return nil, err
 		}
-		members = append(members, &redis.Z{
-			Score:  s,
-			Member: string(bys),
-		})
+		return locations, unmarshalLocations(m, locations, objs)
+	})
+}
+
+// pipeline list object
+func (pipe *pipeline) LPushObjects(ctx context.Context, key string, values ...any) *Future[int64] {
+	l, err := pipe.rc.marshalMembers(key, values)
+	if err != nil {
+		return failed[int64](pipe, err)
 	}
-	pipe.mux.Lock()
-	result := pipe.Pipe.ZAdd(ctx, key, members...)
-	pipe.resHandlers = append(pipe.resHandlers, doNothing)
-	pipe.objects = append(pipe.objects, nil)
-	pipe.mux.Unlock()
-	return result.Err()
+	return queueCmd[int64](pipe, func(p redis.Pipeliner) *redis.IntCmd { return p.LPush(ctx, key, l...) })
+}
+
+func (pipe *pipeline) RPushObjects(ctx context.Context, key string, values ...any) *Future[int64] {
+	l, err := pipe.rc.marshalMembers(key, values)
+	if err != nil {
+		return failed[int64](pipe, err)
+	}
+	return queueCmd[int64](pipe, func(p redis.Pipeliner) *redis.IntCmd { return p.RPush(ctx, key, l...) })
+}
+
+func (pipe *pipeline) LPopObject(ctx context.Context, key string, obj any) *Future[struct{}] {
+	return pipe.readObject(key, obj, func(p redis.Pipeliner) *redis.StringCmd { return p.LPop(ctx, key) })
+}
+
+func (pipe *pipeline) RPopObject(ctx context.Context, key string, obj any) *Future[struct{}] {
+	return pipe.readObject(key, obj, func(p redis.Pipeliner) *redis.StringCmd { return p.RPop(ctx, key) })
 }
 
-func (pipe *pipeline) ZRemObjects(ctx context.Context, key string, values ...any) error {
-	for i, v := range values {
-		bys, err := pipe.rc.objMarshaller.Marshal(v)
+// BLPopObject block the connection of the pipeline until the list has a value or timeout, in a TxPipeline it
+// does not block and returns redis.Nil if the list is empty
+func (pipe *pipeline) BLPopObject(ctx context.Context, timeout time.Duration, key string, obj any) *Future[struct{}] {
+	m, err := pipe.rc.ObjMarshallerOf(key, obj)
+	if err != nil {
+		return failed[struct{}](pipe, err)
+	}
+	return queue(pipe, func(p redis.Pipeliner) { p.BLPop(ctx, timeout, key) }, func(cmds []redis.Cmder) (struct{}, error) {
+		rets, err := cmds[0].(*redis.StringSliceCmd).Result()
 		if err != nil {
-			return err
+			return struct{}{}, err
 		}
-		values[i] = string(bys)
+		return struct{}{}, m.Unmarshal([]byte(rets[1]), obj)
+	})
+}
+
+func (pipe *pipeline) LRangeObjects(ctx context.Context, key string, start, stop int64, objs any) *Future[struct{}] {
+	return pipe.readObjects(key, objs, func(p redis.Pipeliner) *redis.StringSliceCmd {
+		return p.LRange(ctx, key, start, stop)
+	})
+}
+
+// pipeline set object
+func (pipe *pipeline) SAddObjects(ctx context.Context, key string, members ...any) *Future[int64] {
+	l, err := pipe.rc.marshalMembers(key, members)
+	if err != nil {
+		return failed[int64](pipe, err)
+	}
+	return queueCmd[int64](pipe, func(p redis.Pipeliner) *redis.IntCmd { return p.SAdd(ctx, key, l...) })
+}
+
+func (pipe *pipeline) SRemObjects(ctx context.Context, key string, members ...any) *Future[int64] {
+	l, err := pipe.rc.marshalMembers(key, members)
+	if err != nil {
+		return failed[int64](pipe, err)
+	}
+	return queueCmd[int64](pipe, func(p redis.Pipeliner) *redis.IntCmd { return p.SRem(ctx, key, l...) })
+}
+
+func (pipe *pipeline) SIsMemberObject(ctx context.Context, key string, member any) *Future[bool] {
+	data, err := pipe.marshal(key, member)
+	if err != nil {
+		return failed[bool](pipe, err)
+	}
+	return queueCmd[bool](pipe, func(p redis.Pipeliner) *redis.BoolCmd { return p.SIsMember(ctx, key, string(data)) })
+}
+
+func (pipe *pipeline) SMembersObjects(ctx context.Context, key string, objs any) *Future[struct{}] {
+	return pipe.readObjects(key, objs, func(p redis.Pipeliner) *redis.StringSliceCmd { return p.SMembers(ctx, key) })
+}
+
+func (pipe *pipeline) SPopObject(ctx context.Context, key string, obj any) *Future[struct{}] {
+	return pipe.readObject(key, obj, func(p redis.Pipeliner) *redis.StringCmd { return p.SPop(ctx, key) })
+}
+
+// pipeline stream object
+func (pipe *pipeline) XAddObject(ctx context.Context, stream string, obj any) *Future[string] {
+	if err := pipe.rc.CheckKeyObjMatch(stream, obj); err != nil {
+		return failed[string](pipe, err)
 	}
-	pipe.mux.Lock()
-	result := pipe.Pipe.ZRem(ctx, key, values...)
-	pipe.resHandlers = append(pipe.resHandlers, doNothing)
-	pipe.objects = append(pipe.objects, nil)
-	pipe.mux.Unlock()
-	return result.Err()
+	bys, err := pipe.rc.objMarshaller.Marshal(obj)
+	if err != nil {
+		return failed[string](pipe, err)
+	}
+	return queueCmd[string](pipe, func(p redis.Pipeliner) *redis.StringCmd {
+		return p.XAdd(ctx, &redis.XAddArgs{Stream: stream, Values: []any{StreamObjectField, string(bys)}})
+	})
 }
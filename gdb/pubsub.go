@@ -0,0 +1,247 @@
+package gdb
+
+// Funcs handle the redis publish/subscribe and keyspace notifications
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	gmarshaller "github.com/oldjon/gutil/marshaller"
+)
+
+const (
+	pubsubChannelSize   = 100
+	pubsubHealthCheck   = 30 * time.Second
+	pubsubMinRetryDelay = 50 * time.Millisecond
+	pubsubMaxRetryDelay = 2 * time.Second
+)
+
+type PubSub interface {
+	Publish(ctx context.Context, channel string, message any) (int64, error)
+	// PublishObject publish the marshalled obj, subscribers decode it by Message.Object
+	PublishObject(ctx context.Context, channel string, obj any) (int64, error)
+	// Subscribe subscribe the channels, the subscription reconnects and resubscribes after failures until closed
+	Subscribe(ctx context.Context, channels ...string) (*Subscription, error)
+	// PSubscribe subscribe the channels matching the patterns, it reconnects like Subscribe
+	PSubscribe(ctx context.Context, patterns ...string) (*Subscription, error)
+	// EnableKeyspaceEvents set notify-keyspace-events of the server (of every master in cluster mode, every shard in ring mode),
+	// for example "Ex" for expired key events
+	EnableKeyspaceEvents(ctx context.Context, flags string) error
+	// SubscribeKeyEvents subscribe the keyevent notifications of the db, for example "expired",
+	// the payload of the messages is the key. In cluster and ring mode the notifications of every node are subscribed.
+	// With a namespace only the events of the keys in the namespace are received, and the prefix is trimmed.
+	SubscribeKeyEvents(ctx context.Context, db int, events ...string) (*Subscription, error)
+}
+
+// KeyEventChannel return the channel of the keyevent notifications of the event in the db
+func KeyEventChannel(db int, event string) string {
+	return "__keyevent@" + strconv.Itoa(db) + "__:" + event
+}
+
+// KeySpaceChannel return the channel of the keyspace notifications of the key in the db
+func KeySpaceChannel(db int, key string) string {
+	return "__keyspace@" + strconv.Itoa(db) + "__:" + key
+}
+
+type MessageKind uint8
+
+const (
+	// MessageKindMessage is a published message
+	MessageKindMessage MessageKind = iota
+	// MessageKindResubscribed is sent after the subscription recovered from a failure,
+	// the messages published in between are lost
+	MessageKindResubscribed
+)
+
+// Message is received from a Subscription
+type Message struct {
+	Kind    MessageKind
+	Channel string
+	Pattern string // the matched pattern of PSubscribe
+	Payload string
+
+	marshaller gmarshaller.Marshaller
+}
+
+// Object unmarshal the payload published by PublishObject into obj
+func (m *Message) Object(obj any) error {
+	return m.marshaller.Unmarshal([]byte(m.Payload), obj)
+}
+
+// Subscription deliver the messages of one or more redis subscriptions into its channel
+type Subscription struct {
+	subs       []*redis.PubSub
+	ch         chan *Message
+	marshaller gmarshaller.Marshaller
+	keyPrefix  string // the payloads are keys, the prefix is trimmed and other keys are dropped
+	cancel     context.CancelFunc
+	wg         sync.WaitGroup
+	closeOnce  sync.Once
+}
+
+// newSubscription wait the first confirmation of every subscription, then start receiving
+func newSubscription(ctx context.Context, subs []*redis.PubSub, marshaller gmarshaller.Marshaller,
+	keyPrefix string) (*Subscription, error) {
+	for _, ps := range subs {
+		if _, err := ps.Receive(ctx); err != nil {
+			for _, ps := range subs {
+				_ = ps.Close()
+			}
+			return nil, err
+		}
+	}
+	recvCtx, cancel := context.WithCancel(context.Background())
+	s := &Subscription{
+		subs:       subs,
+		ch:         make(chan *Message, pubsubChannelSize),
+		marshaller: marshaller,
+		keyPrefix:  keyPrefix,
+		cancel:     cancel,
+	}
+	for _, ps := range subs {
+		s.wg.Add(1)
+		go s.receive(recvCtx, ps)
+	}
+	go func() {
+		s.wg.Wait()
+		close(s.ch)
+	}()
+	return s, nil
+}
+
+// Channel return the channel of the messages, it is closed after the subscription is closed
+func (s *Subscription) Channel() <-chan *Message {
+	return s.ch
+}
+
+// Close unsubscribe and stop receiving
+func (s *Subscription) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		s.cancel()
+		for _, ps := range s.subs {
+			if e := ps.Close(); e != nil && err == nil {
+				err = e
+			}
+		}
+	})
+	return err
+}
+
+func (s *Subscription) deliver(ctx context.Context, msg *Message) bool {
+	msg.marshaller = s.marshaller
+	select {
+	case s.ch <- msg:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// receive read the messages of ps until ctx is done. go-redis reconnects and resubscribes on the next read after
+// a failure, a ping is sent when nothing is received for a while to detect a dead connection.
+func (s *Subscription) receive(ctx context.Context, ps *redis.PubSub) {
+	defer s.wg.Done()
+	var broken bool
+	delay := pubsubMinRetryDelay
+	for {
+		msg, err := ps.ReceiveTimeout(ctx, pubsubHealthCheck)
+		if ctx.Err() != nil {
+			return
+		}
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			if err = ps.Ping(ctx); err == nil {
+				continue
+			}
+		}
+		if err != nil {
+			broken = true
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+			if delay *= 2; delay > pubsubMaxRetryDelay {
+				delay = pubsubMaxRetryDelay
+			}
+			continue
+		}
+		delay = pubsubMinRetryDelay
+
+		switch m := msg.(type) {
+		case *redis.Subscription:
+			if broken && (m.Kind == "subscribe" || m.Kind == "psubscribe") {
+				broken = false
+				if !s.deliver(ctx, &Message{Kind: MessageKindResubscribed, Channel: m.Channel}) {
+					return
+				}
+			}
+		case *redis.Message:
+			if s.keyPrefix != "" {
+				if !strings.HasPrefix(m.Payload, s.keyPrefix) {
+					continue
+				}
+				m.Payload = m.Payload[len(s.keyPrefix):]
+			}
+			if !s.deliver(ctx, &Message{Channel: m.Channel, Pattern: m.Pattern, Payload: m.Payload}) {
+				return
+			}
+		}
+	}
+}
+
+func (rc *redisClient) Publish(ctx context.Context, channel string, message any) (int64, error) {
+	return rc.client.Publish(ctx, channel, message).Result()
+}
+
+func (rc *redisClient) PublishObject(ctx context.Context, channel string, obj any) (int64, error) {
+	bys, err := rc.objMarshaller.Marshal(obj)
+	if err != nil {
+		return 0, err
+	}
+	return rc.client.Publish(ctx, channel, bys).Result()
+}
+
+func (rc *redisClient) Subscribe(ctx context.Context, channels ...string) (*Subscription, error) {
+	return newSubscription(ctx, []*redis.PubSub{rc.client.Subscribe(ctx, channels...)}, rc.objMarshaller, "")
+}
+
+func (rc *redisClient) PSubscribe(ctx context.Context, patterns ...string) (*Subscription, error) {
+	return newSubscription(ctx, []*redis.PubSub{rc.client.PSubscribe(ctx, patterns...)}, rc.objMarshaller, "")
+}
+
+func (rc *redisClient) EnableKeyspaceEvents(ctx context.Context, flags string) error {
+	nodes, err := rc.masters(ctx)
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		if err = node.ConfigSet(ctx, "notify-keyspace-events", flags).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (rc *redisClient) SubscribeKeyEvents(ctx context.Context, db int, events ...string) (*Subscription, error) {
+	nodes, err := rc.masters(ctx)
+	if err != nil {
+		return nil, err
+	}
+	channels := make([]string, 0, len(events))
+	for _, event := range events {
+		channels = append(channels, KeyEventChannel(db, event))
+	}
+	subs := make([]*redis.PubSub, 0, len(nodes))
+	for _, node := range nodes {
+		subs = append(subs, node.Subscribe(ctx, channels...))
+	}
+	return newSubscription(ctx, subs, rc.objMarshaller, rc.namespace)
+}
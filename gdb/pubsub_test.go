@@ -0,0 +1,93 @@
+package gdb
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// receiveMessage wait a message of the subscription
+func receiveMessage(t *testing.T, sub *Subscription) *Message {
+	t.Helper()
+	select {
+	case msg, ok := <-sub.Channel():
+		if !ok {
+			t.Fatal("subscription closed")
+		}
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received")
+	}
+	return nil
+}
+
+func TestPubSub(t *testing.T) {
+	ctx := context.Background()
+	client, server, _ := newMemRedisClient(t)
+
+	sub, err := client.Subscribe(ctx, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+	psub, err := client.PSubscribe(ctx, "bar:*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer psub.Close()
+
+	if n, err := client.PublishObject(ctx, "foo", &Foo{F: 1}); err != nil || n != 1 {
+		t.Fatalf("PublishObject = %d, %v", n, err)
+	}
+	foo, err := MessageObject[Foo](receiveMessage(t, sub))
+	if err != nil || foo.F != 1 {
+		t.Fatalf("MessageObject = %v, %v", foo, err)
+	}
+	_, _ = client.Publish(ctx, "bar:1", "hello")
+	if msg := receiveMessage(t, psub); msg.Pattern != "bar:*" || msg.Channel != "bar:1" || msg.Payload != "hello" {
+		t.Fatalf("pmessage = %+v", msg)
+	}
+
+	// the subscription recovers after the connection is broken
+	server.CloseConnections()
+	if msg := receiveMessage(t, sub); msg.Kind != MessageKindResubscribed {
+		t.Fatalf("message after reconnection = %+v", msg)
+	}
+	// the broken pooled connections of the client fail once before reconnecting
+	var n int64
+	for i := 0; i < 3 && n == 0; i++ {
+		n, err = client.Publish(ctx, "foo", "after")
+	}
+	if err != nil || n != 1 {
+		t.Fatalf("Publish after reconnection = %d, %v", n, err)
+	}
+	if msg := receiveMessage(t, sub); msg.Payload != "after" {
+		t.Fatalf("message after resubscription = %+v", msg)
+	}
+
+	_ = sub.Close()
+	if _, ok := <-sub.Channel(); ok {
+		t.Fatal("channel not closed")
+	}
+}
+
+func TestKeyEvents(t *testing.T) {
+	ctx := context.Background()
+	client, server, clock := newMemRedisClient(t)
+
+	if err := client.EnableKeyspaceEvents(ctx, "Ex"); err != nil {
+		t.Fatal(err)
+	}
+	sub, err := client.SubscribeKeyEvents(ctx, 0, "expired")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	_ = client.SetObjectEX(ctx, "foo", &Foo{F: 1}, time.Second)
+	clock.Add(time.Second)
+	server.ActiveExpire()
+	if msg := receiveMessage(t, sub); msg.Channel != KeyEventChannel(0, "expired") || msg.Payload != "foo" {
+		t.Fatalf("expired event = %+v", msg)
+	}
+}
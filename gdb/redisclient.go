@@ -2,15 +2,15 @@ package gdb
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
-	"fmt"
 	"io"
+	"net"
+	"strconv"
 	"time"
 
 	"github.com/go-redis/redis/v8"
-	"github.com/oldjon/gutil/env"
 	"github.com/oldjon/gutil/marshaller"
-	"github.com/opentracing/opentracing-go"
 )
 
 type Mode uint8
@@ -18,8 +18,24 @@ type Mode uint8
 const (
 	Single Mode = iota
 	Cluster
+	Sentinel // a master and its replicas monitored by redis sentinels
+	Ring     // consistent-hashed shards of single instances
 )
 
+func (m Mode) String() string {
+	switch m {
+	case Single:
+		return "single"
+	case Cluster:
+		return "cluster"
+	case Sentinel:
+		return "sentinel"
+	case Ring:
+		return "ring"
+	}
+	return "mode(" + strconv.Itoa(int(m)) + ")"
+}
+
 var (
 	// ErrConfigNotFound is returned when gredis config can not be found
 	ErrConfigNotFound = errors.New("can not find gredis config")
@@ -31,6 +47,9 @@ var (
 	ErrTTLKeyNotExist = errors.New("ttl key not exist")
 
 	ErrRedisConfigNotFound = errors.New("can not find gredis config")
+
+	// ErrInvalidRedisConfig is returned when the gredis config or option is invalid
+	ErrInvalidRedisConfig = errors.New("invalid gredis config")
 )
 
 const (
@@ -42,18 +61,48 @@ const (
 type RedisClientOption struct {
 	Mode                Mode
 	Addr                string
+	Username            string // ACL user, password only auth if empty
 	Password            string
 	Db                  int
 	PoolSize            int
-	Addrs               map[string]string
+	MinIdleConns        int
+	PoolTimeout         time.Duration
+	Addrs               map[string]string // shard name => addr in ring mode
 	ClusterAddrs        []string
 	ClusterMaxRedirects int
 	ClusterReadOnly     bool
-	DialTimeout         time.Duration
-	ReadTimeout         time.Duration
-	WriteTimeout        time.Duration
-	Marshaller          gmarshaller.Marshaller
-	Hooks               []redis.Hook
+	SentinelMasterName  string
+	SentinelAddrs       []string
+	SentinelUsername    string
+	SentinelPassword    string
+	// SentinelReplicaReads route the read-only commands to the master or the replicas randomly
+	SentinelReplicaReads bool
+	DialTimeout          time.Duration
+	ReadTimeout          time.Duration
+	WriteTimeout         time.Duration
+	// MaxRetries of a failed command, 0 means the default 3 and -1 disables the retries
+	MaxRetries      int
+	MinRetryBackoff time.Duration
+	MaxRetryBackoff time.Duration
+	// Namespace is prefixed to the keys of every command, pipeline, script KEYS and SCAN pattern, and trimmed
+	// from the keys in the replies, for example "dev:". The pubsub channels are not prefixed.
+	Namespace string
+	// BatchFanout is the max number of the node pipelines run concurrently by the batch commands in cluster mode,
+	// 0 means the default 4
+	BatchFanout int
+	// BatchPartial make the batch commands return the results of the succeeded keys with a *BatchError,
+	// instead of only the error of the first failed key
+	BatchPartial bool
+	// TLSConfig enables TLS of the connections to the servers (and the sentinels)
+	TLSConfig  *tls.Config
+	Marshaller gmarshaller.Marshaller
+	// Hooks are attached to the client in every mode after the namespace hook, so they see the prefixed keys.
+	// In cluster and ring mode they also run for the commands sent to the nodes directly.
+	Hooks []redis.Hook
+	// CircuitBreaker fail the commands fast when redis degrades, nil disables it
+	CircuitBreaker *CircuitBreaker
+	// Dialer creates new network connection, it is used to connect to an in-memory server
+	Dialer func(ctx context.Context, network, addr string) (net.Conn, error)
 }
 
 // RedisClient introduce all the gredis method we need for gredis client and also with context support
@@ -63,6 +112,16 @@ type RedisClient interface {
 	String
 	Hash
 	SortedSet
+	List
+	Set
+	Stream
+	HyperLogLog
+	Bitmap
+	BloomFilter
+	Geo
+	PubSub
+	VersionedObject
+	Scanner
 	ObjectDB
 	Scripter
 	Pipeline() Pipeliner
@@ -77,30 +136,18 @@ func NewRedisClient(option *RedisClientOption) (RedisClient, error) {
 	var client *redisClient
 	var err error
 
+	if err = option.validate(); err != nil {
+		return nil, err
+	}
 	switch option.Mode {
 	case Single:
-		client, err = newRedisClientSingle(
-			option.Addr,
-			option.Password,
-			option.Db,
-			option.PoolSize,
-			option.DialTimeout,
-			option.ReadTimeout,
-			option.WriteTimeout,
-			option.Hooks,
-		)
+		client, err = newRedisClientSingle(option)
 	case Cluster:
-		client, err = newRedisClientCluster(
-			option.ClusterAddrs,
-			option.Password,
-			option.PoolSize,
-			option.ClusterMaxRedirects,
-			option.ClusterReadOnly,
-			option.DialTimeout,
-			option.ReadTimeout,
-			option.WriteTimeout,
-			option.Hooks,
-		)
+		client, err = newRedisClientCluster(option)
+	case Sentinel:
+		client, err = newRedisClientSentinel(option)
+	case Ring:
+		client, err = newRedisClientRing(option)
 	default:
 		return nil, ErrConfigNotFound
 	}
@@ -110,6 +157,29 @@ func NewRedisClient(option *RedisClientOption) (RedisClient, error) {
 	}
 
 	client.objMarshaller = option.Marshaller
+	client.batchFanout = option.BatchFanout
+	client.batchPartial = option.BatchPartial
+	// the hooks are attached to the client itself in every mode, the namespace hook is the first so that the
+	// cluster slots are computed with the prefixed keys, and the other hooks see the keys sent to redis
+	if option.Namespace != "" {
+		client.namespace = option.Namespace
+		client.client.AddHook(&namespaceHook{prefix: option.Namespace})
+	}
+	for _, hook := range option.Hooks {
+		client.client.AddHook(hook)
+	}
+	if option.CircuitBreaker != nil {
+		// after the other hooks, so they see the calls failed fast in every mode
+		client.client.AddHook(option.CircuitBreaker)
+	}
+	for _, hook := range option.Hooks {
+		switch h := hook.(type) {
+		case *MetricsHook:
+			h.addPool(client.client.PoolStats)
+		case *SlowHook:
+			h.setNamespace(option.Namespace)
+		}
+	}
 
 	// run a ping test?
 	if _, err = client.client.Ping(context.TODO()).Result(); err != nil {
@@ -122,129 +192,212 @@ func NewRedisClient(option *RedisClientOption) (RedisClient, error) {
 type redisClient struct {
 	client redis.UniversalClient // client would be a universal client to support single or ring or cluster
 	mode   string
+	// namespace is the prefix of the keys, see RedisClientOption.Namespace
+	namespace string
+	// batchFanout and batchPartial, see RedisClientOption.BatchFanout and RedisClientOption.BatchPartial
+	batchFanout  int
+	batchPartial bool
 	// object
 	objMarshaller gmarshaller.Marshaller
-	koMapping     map[string]string
-}
-
-func getRedisMode(configReader env.ModuleConfig) Mode {
-	mode := configReader.GetString("mode")
-	if mode == "single" {
-		return Single
-	}
-	if mode == "cluster" {
-		return Cluster
-	}
-	return Single
+	objectSchemas
 }
 
 // newRedisClientSingle create a RedisClient object using gredis v8 client in single instance mode
-func newRedisClientSingle(addr string, password string, db int, poolSize int, dialTimeOut time.Duration,
-	readTimeOut time.Duration, writeTimeOut time.Duration, hooks []redis.Hook) (*redisClient, error) {
+func newRedisClientSingle(option *RedisClientOption) (*redisClient, error) {
 	rc := redisClient{}
 	rc.client = redis.NewClient(&redis.Options{
-		Addr:         addr,
-		Password:     password,
-		DB:           db,
-		PoolSize:     poolSize,
-		DialTimeout:  dialTimeOut,
-		ReadTimeout:  readTimeOut,
-		WriteTimeout: writeTimeOut,
+		Addr:            option.Addr,
+		Username:        option.Username,
+		Password:        option.Password,
+		DB:              option.Db,
+		PoolSize:        option.PoolSize,
+		MinIdleConns:    option.MinIdleConns,
+		PoolTimeout:     option.PoolTimeout,
+		DialTimeout:     option.DialTimeout,
+		ReadTimeout:     option.ReadTimeout,
+		WriteTimeout:    option.WriteTimeout,
+		MaxRetries:      option.MaxRetries,
+		MinRetryBackoff: option.MinRetryBackoff,
+		MaxRetryBackoff: option.MaxRetryBackoff,
+		TLSConfig:       option.TLSConfig,
+		Dialer:          option.Dialer,
 	})
-	for _, hook := range hooks {
-		rc.client.AddHook(hook)
-	}
-	rc.mode = "single"
+	rc.mode = Single.String()
 
 	return &rc, nil
 }
 
-// newRedisClientSingle create a RedisClient object using gredis v8 client in cluster instance mode
-func newRedisClientCluster(addrs []string, password string, poolSize int,
-	maxRedirects int, readOnly bool, dialTimeOut time.Duration,
-	readTimeOut time.Duration, writeTimeOut time.Duration, hooks []redis.Hook) (*redisClient, error) {
+// newRedisClientCluster create a RedisClient object using gredis v8 client in cluster instance mode
+func newRedisClientCluster(option *RedisClientOption) (*redisClient, error) {
 	rc := redisClient{}
 	rc.client = redis.NewClusterClient(&redis.ClusterOptions{
 		NewClient: func(opt *redis.Options) *redis.Client {
 			node := redis.NewClient(opt)
-			for _, hook := range hooks {
-				node.AddHook(hook)
-			}
+			addNodeHooks(node, option.nodeHooks())
 			return node
 		},
-		Addrs:        addrs,
-		Password:     password,
-		PoolSize:     poolSize,
-		MaxRedirects: maxRedirects,
-		ReadOnly:     readOnly,
-		DialTimeout:  dialTimeOut,
-		ReadTimeout:  readTimeOut,
-		WriteTimeout: writeTimeOut,
+		Addrs:           option.ClusterAddrs,
+		Username:        option.Username,
+		Password:        option.Password,
+		PoolSize:        option.PoolSize,
+		MinIdleConns:    option.MinIdleConns,
+		PoolTimeout:     option.PoolTimeout,
+		MaxRedirects:    option.ClusterMaxRedirects,
+		ReadOnly:        option.ClusterReadOnly,
+		DialTimeout:     option.DialTimeout,
+		ReadTimeout:     option.ReadTimeout,
+		WriteTimeout:    option.WriteTimeout,
+		MaxRetries:      option.MaxRetries,
+		MinRetryBackoff: option.MinRetryBackoff,
+		MaxRetryBackoff: option.MaxRetryBackoff,
+		TLSConfig:       option.TLSConfig,
+		Dialer:          option.Dialer,
 	})
+	rc.client.AddHook(clientHooksMarker{})
+	rc.mode = Cluster.String()
+	return &rc, nil
+}
 
-	rc.mode = "cluster"
+// newRedisClientSentinel create a RedisClient object using gredis v8 client in sentinel mode,
+// with SentinelReplicaReads the read-only commands are routed to the master or the replicas randomly
+func newRedisClientSentinel(option *RedisClientOption) (*redisClient, error) {
+	opt := &redis.FailoverOptions{
+		MasterName:       option.SentinelMasterName,
+		SentinelAddrs:    option.SentinelAddrs,
+		SentinelUsername: option.SentinelUsername,
+		SentinelPassword: option.SentinelPassword,
+		RouteRandomly:    option.SentinelReplicaReads,
+		Username:         option.Username,
+		Password:         option.Password,
+		DB:               option.Db,
+		PoolSize:         option.PoolSize,
+		MinIdleConns:     option.MinIdleConns,
+		PoolTimeout:      option.PoolTimeout,
+		DialTimeout:      option.DialTimeout,
+		ReadTimeout:      option.ReadTimeout,
+		WriteTimeout:     option.WriteTimeout,
+		MaxRetries:       option.MaxRetries,
+		MinRetryBackoff:  option.MinRetryBackoff,
+		MaxRetryBackoff:  option.MaxRetryBackoff,
+		TLSConfig:        option.TLSConfig,
+		Dialer:           option.Dialer,
+	}
+	rc := redisClient{}
+	if option.SentinelReplicaReads {
+		// the failover cluster client creates its node clients internally, the commands sent to the nodes directly
+		// do not run the hooks
+		rc.client = redis.NewFailoverClusterClient(opt)
+	} else {
+		rc.client = redis.NewFailoverClient(opt)
+	}
+	rc.mode = Sentinel.String()
 	return &rc, nil
 }
 
-// Close gredis connection
-func (rc *redisClient) Close() error {
-	return rc.client.Close()
+// newRedisClientRing create a RedisClient object using gredis v8 client in ring mode,
+// the keys are distributed to the shards of Addrs by consistent hashing of the shard names
+func newRedisClientRing(option *RedisClientOption) (*redisClient, error) {
+	rc := redisClient{}
+	rc.client = redis.NewRing(&redis.RingOptions{
+		NewClient: func(name string, opt *redis.Options) *redis.Client {
+			shard := redis.NewClient(opt)
+			addNodeHooks(shard, option.nodeHooks())
+			return shard
+		},
+		Addrs:           option.Addrs,
+		Username:        option.Username,
+		Password:        option.Password,
+		DB:              option.Db,
+		PoolSize:        option.PoolSize,
+		MinIdleConns:    option.MinIdleConns,
+		PoolTimeout:     option.PoolTimeout,
+		DialTimeout:     option.DialTimeout,
+		ReadTimeout:     option.ReadTimeout,
+		WriteTimeout:    option.WriteTimeout,
+		MaxRetries:      option.MaxRetries,
+		MinRetryBackoff: option.MinRetryBackoff,
+		MaxRetryBackoff: option.MaxRetryBackoff,
+		TLSConfig:       option.TLSConfig,
+		Dialer:          option.Dialer,
+	})
+	rc.client.AddHook(clientHooksMarker{})
+	rc.mode = Ring.String()
+	return &rc, nil
 }
 
-func NewRedisClientByConfig(cfg env.ModuleConfig, marshaller string, tracer opentracing.Tracer) (RedisClient, error) {
-	var redisConfig *RedisClientOption
+type clientHooksKey struct{}
 
-	redisMode := getRedisMode(cfg)
-	switch redisMode {
-	case Single:
-		redisConfig = &RedisClientOption{
-			Mode: Single,
-			Addr: cfg.GetString("addr"),
-			Db:   cfg.GetInt("db"),
-		}
-	case Cluster:
-		redisConfig = &RedisClientOption{
-			Mode:                Cluster,
-			ClusterAddrs:        cfg.GetStringSlice("addrs"),
-			ClusterMaxRedirects: cfg.GetInt("maxredirects"),
-			ClusterReadOnly:     cfg.GetBool("readonly"),
-		}
-	default:
-		return nil, fmt.Errorf("%w: mode[%d]", ErrRedisConfigNotFound, redisMode)
-	}
-
-	// set common config
-	redisConfig.PoolSize = cfg.GetInt("pool_size")
-	redisConfig.Password = cfg.GetString("password")
-	redisConfig.ReadTimeout = time.Duration(cfg.GetInt("readtimeout")) * time.Second
-	if cfg.GetString("db_marshaller") != "" {
-		marshaller = cfg.GetString("db_marshaller")
-	}
-	if marshaller == gmarshaller.MarshallerTypeJSON {
-		redisConfig.Marshaller = &gmarshaller.JsonMarshaller{}
-	} else if marshaller == gmarshaller.MarshallerTypeProtoBuf {
-		redisConfig.Marshaller = &gmarshaller.ProtoMarshaller{}
-	} else if marshaller == gmarshaller.MarshallerTypeProtoBufComp {
-		redisConfig.Marshaller = &gmarshaller.ProtoCompressMarshaller{}
-	} else { // default marshal by json
-		marshaller = gmarshaller.MarshallerTypeJSON
-		redisConfig.Marshaller = &gmarshaller.JsonMarshaller{}
-	}
-
-	if tracer != nil {
-		// 增加 tracer hook
-		redisConfig.Hooks = append(redisConfig.Hooks, &TraceHook{
-			Tracer:     tracer,
-			Instance:   redisConfig.Addr,
-			RedisMode:  cfg.GetString("mode"),
-			Marshaller: marshaller,
-		})
-	}
-
-	client, err := NewRedisClient(redisConfig)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create gredis client: %w, %s", err, redisConfig.Addr)
+// clientHooksMarker is the first hook of the cluster and ring clients, it marks the commands which run the hooks of
+// the client, so the node hooks skip them
+type clientHooksMarker struct{}
+
+func (clientHooksMarker) BeforeProcess(ctx context.Context, _ redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, clientHooksKey{}, true), nil
+}
+
+func (clientHooksMarker) AfterProcess(context.Context, redis.Cmder) error {
+	return nil
+}
+
+func (clientHooksMarker) BeforeProcessPipeline(ctx context.Context, _ []redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, clientHooksKey{}, true), nil
+}
+
+func (clientHooksMarker) AfterProcessPipeline(context.Context, []redis.Cmder) error {
+	return nil
+}
+
+// nodeHook run the hook on a node of the cluster and ring clients only for the commands sent to the node directly,
+// like the transactions of Watch and the SCAN of every node, the other commands run the hook on the client itself
+type nodeHook struct {
+	redis.Hook
+}
+
+// nodeHooks return the hooks of the commands sent to the nodes directly, in the same order as the client
+func (option *RedisClientOption) nodeHooks() []redis.Hook {
+	hooks := option.Hooks
+	if option.CircuitBreaker != nil {
+		hooks = append(hooks[:len(hooks):len(hooks)], option.CircuitBreaker)
 	}
+	return hooks
+}
 
-	return client, nil
+// addNodeHooks attach the hooks to a node client
+func addNodeHooks(node *redis.Client, hooks []redis.Hook) {
+	for _, hook := range hooks {
+		node.AddHook(nodeHook{hook})
+	}
+}
+
+func (h nodeHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	if ctx.Value(clientHooksKey{}) != nil {
+		return ctx, nil
+	}
+	return h.Hook.BeforeProcess(ctx, cmd)
+}
+
+func (h nodeHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	if ctx.Value(clientHooksKey{}) != nil {
+		return nil
+	}
+	return h.Hook.AfterProcess(ctx, cmd)
+}
+
+func (h nodeHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	if ctx.Value(clientHooksKey{}) != nil {
+		return ctx, nil
+	}
+	return h.Hook.BeforeProcessPipeline(ctx, cmds)
+}
+
+func (h nodeHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	if ctx.Value(clientHooksKey{}) != nil {
+		return nil
+	}
+	return h.Hook.AfterProcessPipeline(ctx, cmds)
+}
+
+// Close gredis connection
+func (rc *redisClient) Close() error {
+	return rc.client.Close()
 }
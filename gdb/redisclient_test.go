@@ -0,0 +1,163 @@
+package gdb
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/oldjon/gutil/gdb/memredis"
+	gmarshaller "github.com/oldjon/gutil/marshaller"
+)
+
+// countHook count the processed commands of a node
+type countHook struct {
+	n atomic.Int64
+}
+
+func (h *countHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	h.n.Add(1)
+	return ctx, nil
+}
+
+func (h *countHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	return nil
+}
+
+func (h *countHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	h.n.Add(int64(len(cmds)))
+	return ctx, nil
+}
+
+func (h *countHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	return nil
+}
+
+func TestRingClient(t *testing.T) {
+	ctx := context.Background()
+	servers := map[string]*memredis.Server{"a:6379": memredis.NewServer(), "b:6379": memredis.NewServer()}
+	hook := &countHook{}
+	client, err := NewRedisClient(&RedisClientOption{
+		Mode:       Ring,
+		Addrs:      map[string]string{"a": "a:6379", "b": "b:6379"},
+		Marshaller: &gmarshaller.JsonMarshaller{},
+		Hooks:      []redis.Hook{hook},
+		Dialer: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return servers[addr].Dial(ctx, network, addr)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = client.Close()
+		for _, s := range servers {
+			_ = s.Close()
+		}
+	})
+
+	hook.n.Store(0) // the ping of NewRedisClient
+	for i := 0; i < 20; i++ {
+		if err = client.SetObject(ctx, "foo:"+strconv.Itoa(i), &Foo{F: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for addr, s := range servers {
+		if len(s.Keys(0)) == 0 {
+			t.Fatalf("no key on shard %s", addr)
+		}
+	}
+	// the hook sees every command once
+	if hook.n.Load() != 20 {
+		t.Fatalf("hook processed %d commands", hook.n.Load())
+	}
+
+	var keys int
+	it := client.Scan(ctx, "foo:*", 0)
+	for it.Next(ctx) {
+		keys++
+	}
+	if it.Err() != nil || keys != 20 {
+		t.Fatalf("Scan = %d keys, %v", keys, it.Err())
+	}
+}
+
+// argsHook record the commands processed by the hooks of the client
+type argsHook struct {
+	mu   sync.Mutex
+	cmds []string
+}
+
+func (h *argsHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var args []string
+	for _, arg := range cmd.Args() {
+		args = append(args, fmt.Sprint(arg))
+	}
+	h.cmds = append(h.cmds, strings.Join(args, " "))
+	return ctx, nil
+}
+
+func (h *argsHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	return nil
+}
+
+func (h *argsHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	for _, cmd := range cmds {
+		_, _ = h.BeforeProcess(ctx, cmd)
+	}
+	return ctx, nil
+}
+
+func (h *argsHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	return nil
+}
+
+func TestClientHooks(t *testing.T) {
+	ctx := context.Background()
+	for _, mode := range []Mode{Single, Ring} {
+		t.Run(mode.String(), func(t *testing.T) {
+			server := memredis.NewServer()
+			t.Cleanup(func() { _ = server.Close() })
+			hook := &argsHook{}
+			client, err := NewRedisClient(&RedisClientOption{
+				Mode:       mode,
+				Addr:       "memredis",
+				Addrs:      map[string]string{"a": "memredis"},
+				Namespace:  "ns:",
+				Marshaller: &gmarshaller.JsonMarshaller{},
+				Hooks:      []redis.Hook{hook},
+				Dialer:     server.Dial,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { _ = client.Close() })
+			hook.cmds = nil // the ping of NewRedisClient
+
+			if err = client.Set(ctx, "k", "v"); err != nil {
+				t.Fatal(err)
+			}
+			var keys []string
+			it := client.Scan(ctx, "k*", 0)
+			for it.Next(ctx) {
+				keys = append(keys, it.Key())
+			}
+			if it.Err() != nil || !reflect.DeepEqual(keys, []string{"k"}) {
+				t.Fatalf("Scan = %v, %v", keys, it.Err())
+			}
+			// the hook sees the prefixed keys, and every command once
+			want := []string{"set ns:k v keepttl", "scan 0 match ns:k*"}
+			if !reflect.DeepEqual(hook.cmds, want) {
+				t.Fatalf("hook processed %q, want %q", hook.cmds, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,330 @@
+package gdb
+
+// Funcs read and validate the gredis client config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/oldjon/gutil/env"
+	gmarshaller "github.com/oldjon/gutil/marshaller"
+	"github.com/opentracing/opentracing-go"
+)
+
+// getRedisMode read the mode of the config, single is the default
+func getRedisMode(configReader env.ModuleConfig) (Mode, error) {
+	switch mode := configReader.GetString("mode"); mode {
+	case "", "single":
+		return Single, nil
+	case "cluster":
+		return Cluster, nil
+	case "sentinel":
+		return Sentinel, nil
+	case "ring":
+		return Ring, nil
+	default:
+		return Single, fmt.Errorf("%w: unknown mode %q", ErrInvalidRedisConfig, mode)
+	}
+}
+
+// getMarshaller return the marshaller of the type, json is the default
+func getMarshaller(marshaller string) (gmarshaller.Marshaller, string, error) {
+	switch marshaller {
+	case "", gmarshaller.MarshallerTypeJSON:
+		return &gmarshaller.JsonMarshaller{}, gmarshaller.MarshallerTypeJSON, nil
+	case gmarshaller.MarshallerTypeProtoBuf:
+		return &gmarshaller.ProtoMarshaller{}, marshaller, nil
+	case gmarshaller.MarshallerTypeProtoBufComp:
+		return &gmarshaller.ProtoCompressMarshaller{}, marshaller, nil
+	default:
+		return nil, "", fmt.Errorf("%w: unknown marshaller %q", ErrInvalidRedisConfig, marshaller)
+	}
+}
+
+// getEnvelopeMarshaller wrap the marshaller of the type in an EnvelopeMarshaller if db_marshaller_envelope is set
+func getEnvelopeMarshaller(cfg env.ModuleConfig, marshaller string, m gmarshaller.Marshaller) (
+	gmarshaller.Marshaller, error) {
+	if !cfg.GetBool("db_marshaller_envelope") {
+		if cfg.IsSet("db_legacy_marshaller") {
+			return nil, fmt.Errorf("%w: db_legacy_marshaller is set but db_marshaller_envelope is false",
+				ErrInvalidRedisConfig)
+		}
+		return m, nil
+	}
+	legacy := gmarshaller.MarshallerTypeJSON
+	if cfg.IsSet("db_legacy_marshaller") {
+		legacy = cfg.GetString("db_legacy_marshaller")
+	}
+	em, err := gmarshaller.NewEnvelopeMarshaller(marshaller, legacy)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unknown db_legacy_marshaller %q", ErrInvalidRedisConfig, legacy)
+	}
+	return em, nil
+}
+
+// getTLSConfig read the tls config, it returns nil if tls is not enabled
+func getTLSConfig(cfg env.ModuleConfig) (*tls.Config, error) {
+	caFile, certFile, keyFile := cfg.GetString("tls_ca_file"), cfg.GetString("tls_cert_file"), cfg.GetString("tls_key_file")
+	serverName, insecure := cfg.GetString("tls_server_name"), cfg.GetBool("tls_insecure_skip_verify")
+	if !cfg.GetBool("tls_enable") {
+		if caFile != "" || certFile != "" || keyFile != "" || serverName != "" || insecure {
+			return nil, fmt.Errorf("%w: tls options are set but tls_enable is false", ErrInvalidRedisConfig)
+		}
+		return nil, nil
+	}
+	if (certFile == "") != (keyFile == "") {
+		return nil, fmt.Errorf("%w: tls_cert_file and tls_key_file must be set together", ErrInvalidRedisConfig)
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         serverName,
+		InsecureSkipVerify: insecure, // nolint:gosec
+	}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("%w: read tls_ca_file: %v", ErrInvalidRedisConfig, err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%w: no certificate found in tls_ca_file %s", ErrInvalidRedisConfig, caFile)
+		}
+	}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("%w: load tls client certificate: %v", ErrInvalidRedisConfig, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// validate check the invalid combinations of the option
+func (option *RedisClientOption) validate() error {
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("%w: "+format, append([]any{ErrInvalidRedisConfig}, args...)...)
+	}
+	switch option.Mode {
+	case Single:
+		if option.Addr == "" {
+			return invalid("addr is required in single mode")
+		}
+	case Cluster:
+		if len(option.ClusterAddrs) == 0 {
+			return invalid("addrs are required in cluster mode")
+		}
+		if option.Db != 0 {
+			return invalid("db %d is not supported in cluster mode", option.Db)
+		}
+	case Sentinel:
+		if option.SentinelMasterName == "" || len(option.SentinelAddrs) == 0 {
+			return invalid("master_name and sentinel_addrs are required in sentinel mode")
+		}
+		if option.SentinelReplicaReads && option.Db != 0 {
+			return invalid("db %d is not supported with replica_reads", option.Db)
+		}
+	case Ring:
+		if len(option.Addrs) == 0 {
+			return invalid("shards are required in ring mode")
+		}
+	default:
+		return invalid("unknown mode %s", option.Mode)
+	}
+	if option.Mode != Cluster && (option.ClusterReadOnly || option.ClusterMaxRedirects != 0) {
+		return invalid("readonly and maxredirects are only supported in cluster mode")
+	}
+	if option.Mode != Sentinel && option.SentinelReplicaReads {
+		return invalid("replica_reads is only supported in sentinel mode")
+	}
+	if option.Password == "" && option.Username != "" {
+		return invalid("password is required with username")
+	}
+	if option.SentinelPassword == "" && option.SentinelUsername != "" {
+		return invalid("sentinel_password is required with sentinel_username")
+	}
+	if option.PoolSize < 0 || option.MinIdleConns < 0 {
+		return invalid("negative pool_size %d or min_idle_conns %d", option.PoolSize, option.MinIdleConns)
+	}
+	if option.PoolSize > 0 && option.MinIdleConns > option.PoolSize {
+		return invalid("min_idle_conns %d exceeds pool_size %d", option.MinIdleConns, option.PoolSize)
+	}
+	if option.DialTimeout < 0 || option.PoolTimeout < 0 {
+		return invalid("negative dialtimeout %v or pool_timeout %v", option.DialTimeout, option.PoolTimeout)
+	}
+	if option.MaxRetries < -1 {
+		return invalid("max_retries %d, -1 disables the retries", option.MaxRetries)
+	}
+	if option.MinRetryBackoff > 0 && option.MaxRetryBackoff > 0 && option.MinRetryBackoff > option.MaxRetryBackoff {
+		return invalid("min_retry_backoff %v exceeds max_retry_backoff %v",
+			option.MinRetryBackoff, option.MaxRetryBackoff)
+	}
+	if option.BatchFanout < 0 {
+		return invalid("negative batch_fanout %d", option.BatchFanout)
+	}
+	if option.Marshaller == nil {
+		return invalid("marshaller is required")
+	}
+	if option.CircuitBreaker != nil {
+		if msg := option.CircuitBreaker.validate(); msg != "" {
+			return invalid(msg)
+		}
+	}
+	return nil
+}
+
+// instance return the addrs of the option for tracing
+func (option *RedisClientOption) instance() string {
+	switch option.Mode {
+	case Cluster:
+		return strings.Join(option.ClusterAddrs, ",")
+	case Sentinel:
+		return option.SentinelMasterName + "@" + strings.Join(option.SentinelAddrs, ",")
+	case Ring:
+		addrs := make([]string, 0, len(option.Addrs))
+		for _, addr := range option.Addrs {
+			addrs = append(addrs, addr)
+		}
+		sort.Strings(addrs)
+		return strings.Join(addrs, ",")
+	default:
+		return option.Addr
+	}
+}
+
+// NewRedisClientByConfig create a RedisClient object from the config, the keys are
+//
+//	mode: single(default), cluster, sentinel or ring
+//	single:   addr, db
+//	cluster:  addrs, maxredirects, readonly
+//	sentinel: master_name, sentinel_addrs, sentinel_username, sentinel_password, replica_reads, db
+//	ring:     shards (name => addr), db
+//	namespace, username, password, pool_size, min_idle_conns, pool_timeout,
+//	dialtimeout, readtimeout, writetimeout (in seconds),
+//	max_retries, min_retry_backoff_ms, max_retry_backoff_ms,
+//	tls_enable, tls_ca_file, tls_cert_file, tls_key_file, tls_server_name, tls_insecure_skip_verify,
+//	circuit_breaker_enable, circuit_breaker_error_rate, circuit_breaker_slow_threshold_ms, circuit_breaker_min_calls,
+//	circuit_breaker_window, circuit_breaker_open_timeout (in seconds), circuit_breaker_half_open_probes,
+//	db_marshaller: overwrite the marshaller argument
+//	db_marshaller_envelope: write the format byte before the values, db_legacy_marshaller (default json) reads
+//	the values written without it
+//
+// Invalid combinations of the config return ErrInvalidRedisConfig.
+// The hooks are attached after the tracer hook, the instance and mode labels of a MetricsHook are set if empty.
+func NewRedisClientByConfig(cfg env.ModuleConfig, marshaller string, tracer opentracing.Tracer,
+	hooks ...redis.Hook) (RedisClient, error) {
+	var redisConfig *RedisClientOption
+
+	redisMode, err := getRedisMode(cfg)
+	if err != nil {
+		return nil, err
+	}
+	switch redisMode {
+	case Single:
+		redisConfig = &RedisClientOption{
+			Mode: Single,
+			Addr: cfg.GetString("addr"),
+			Db:   cfg.GetInt("db"),
+		}
+	case Cluster:
+		redisConfig = &RedisClientOption{
+			Mode:                Cluster,
+			ClusterAddrs:        cfg.GetStringSlice("addrs"),
+			ClusterMaxRedirects: cfg.GetInt("maxredirects"),
+			ClusterReadOnly:     cfg.GetBool("readonly"),
+			Db:                  cfg.GetInt("db"),
+		}
+	case Sentinel:
+		redisConfig = &RedisClientOption{
+			Mode:                 Sentinel,
+			Db:                   cfg.GetInt("db"),
+			SentinelMasterName:   cfg.GetString("master_name"),
+			SentinelAddrs:        cfg.GetStringSlice("sentinel_addrs"),
+			SentinelUsername:     cfg.GetString("sentinel_username"),
+			SentinelPassword:     cfg.GetString("sentinel_password"),
+			SentinelReplicaReads: cfg.GetBool("replica_reads"),
+		}
+	case Ring:
+		redisConfig = &RedisClientOption{
+			Mode:  Ring,
+			Addrs: cfg.GetStringMapString("shards"),
+			Db:    cfg.GetInt("db"),
+		}
+	}
+
+	// set common config
+	redisConfig.Namespace = cfg.GetString("namespace")
+	redisConfig.Username = cfg.GetString("username")
+	redisConfig.Password = cfg.GetString("password")
+	redisConfig.PoolSize = cfg.GetInt("pool_size")
+	redisConfig.MinIdleConns = cfg.GetInt("min_idle_conns")
+	redisConfig.PoolTimeout = time.Duration(cfg.GetInt("pool_timeout")) * time.Second
+	redisConfig.DialTimeout = time.Duration(cfg.GetInt("dialtimeout")) * time.Second
+	redisConfig.ReadTimeout = time.Duration(cfg.GetInt("readtimeout")) * time.Second
+	redisConfig.WriteTimeout = time.Duration(cfg.GetInt("writetimeout")) * time.Second
+	redisConfig.MaxRetries = cfg.GetInt("max_retries")
+	redisConfig.MinRetryBackoff = time.Duration(cfg.GetInt("min_retry_backoff_ms")) * time.Millisecond
+	redisConfig.MaxRetryBackoff = time.Duration(cfg.GetInt("max_retry_backoff_ms")) * time.Millisecond
+	redisConfig.BatchFanout = cfg.GetInt("batch_fanout")
+	redisConfig.BatchPartial = cfg.GetBool("batch_partial")
+	if cfg.GetBool("circuit_breaker_enable") {
+		redisConfig.CircuitBreaker = &CircuitBreaker{
+			ErrorRate:      cfg.GetFloat64("circuit_breaker_error_rate"),
+			SlowThreshold:  time.Duration(cfg.GetInt("circuit_breaker_slow_threshold_ms")) * time.Millisecond,
+			MinCalls:       cfg.GetInt("circuit_breaker_min_calls"),
+			Window:         time.Duration(cfg.GetInt("circuit_breaker_window")) * time.Second,
+			OpenTimeout:    time.Duration(cfg.GetInt("circuit_breaker_open_timeout")) * time.Second,
+			HalfOpenProbes: cfg.GetInt("circuit_breaker_half_open_probes"),
+		}
+	}
+	if redisConfig.TLSConfig, err = getTLSConfig(cfg); err != nil {
+		return nil, err
+	}
+	if cfg.GetString("db_marshaller") != "" {
+		marshaller = cfg.GetString("db_marshaller")
+	}
+	if redisConfig.Marshaller, marshaller, err = getMarshaller(marshaller); err != nil {
+		return nil, err
+	}
+	if redisConfig.Marshaller, err = getEnvelopeMarshaller(cfg, marshaller, redisConfig.Marshaller); err != nil {
+		return nil, err
+	}
+	if err = redisConfig.validate(); err != nil {
+		return nil, err
+	}
+
+	if tracer != nil {
+		// 增加 tracer hook
+		redisConfig.Hooks = append(redisConfig.Hooks, &TraceHook{
+			Tracer:     tracer,
+			Instance:   redisConfig.instance(),
+			RedisMode:  redisMode.String(),
+			Marshaller: marshaller,
+		})
+	}
+	for _, hook := range hooks {
+		if m, ok := hook.(*MetricsHook); ok {
+			if m.Instance == "" {
+				m.Instance = redisConfig.instance()
+			}
+			if m.RedisMode == "" {
+				m.RedisMode = redisMode.String()
+			}
+		}
+		redisConfig.Hooks = append(redisConfig.Hooks, hook)
+	}
+
+	client, err := NewRedisClient(redisConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gredis client: %w, %s", err, redisConfig.instance())
+	}
+
+	return client, nil
+}
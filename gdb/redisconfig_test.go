@@ -0,0 +1,54 @@
+package gdb
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/oldjon/gutil/env"
+	"github.com/spf13/viper"
+)
+
+func TestNewRedisClientByConfigInvalid(t *testing.T) {
+	cases := map[string]map[string]any{
+		"unknown mode":          {"mode": "sharded", "addr": "localhost:6379"},
+		"no addr":               {"mode": "single"},
+		"cluster db":            {"mode": "cluster", "addrs": []string{"localhost:7000"}, "db": 1},
+		"no sentinel master":    {"mode": "sentinel", "sentinel_addrs": []string{"localhost:26379"}},
+		"replica reads db":      {"mode": "sentinel", "master_name": "m", "sentinel_addrs": []string{"s:26379"}, "replica_reads": true, "db": 2},
+		"no ring shards":        {"mode": "ring"},
+		"username only":         {"addr": "localhost:6379", "username": "app"},
+		"min idle over pool":    {"addr": "localhost:6379", "pool_size": 2, "min_idle_conns": 3},
+		"backoff range":         {"addr": "localhost:6379", "min_retry_backoff_ms": 100, "max_retry_backoff_ms": 10},
+		"max retries":           {"addr": "localhost:6379", "max_retries": -2},
+		"negative batch fanout": {"addr": "localhost:6379", "batch_fanout": -1},
+		"tls not enabled":       {"addr": "localhost:6379", "tls_ca_file": "ca.pem"},
+		"tls cert without key":  {"addr": "localhost:6379", "tls_enable": true, "tls_cert_file": "cert.pem"},
+		"tls missing ca file":   {"addr": "localhost:6379", "tls_enable": true, "tls_ca_file": "/nonexistent/ca.pem"},
+		"unknown db marshaller": {"addr": "localhost:6379", "db_marshaller": "xml"},
+		"legacy no envelope":    {"addr": "localhost:6379", "db_legacy_marshaller": "json"},
+		"unknown legacy":        {"addr": "localhost:6379", "db_marshaller_envelope": true, "db_legacy_marshaller": "xml"},
+		"breaker error rate":    {"addr": "localhost:6379", "circuit_breaker_enable": true, "circuit_breaker_error_rate": 2},
+		"breaker min calls":     {"addr": "localhost:6379", "circuit_breaker_enable": true, "circuit_breaker_min_calls": -1},
+	}
+	for name, values := range cases {
+		v := viper.New()
+		for k, val := range values {
+			v.Set(k, val)
+		}
+		_, err := NewRedisClientByConfig(env.NewModuleConfig(v, nil), "", nil)
+		if !errors.Is(err, ErrInvalidRedisConfig) {
+			t.Errorf("%s: err = %v", name, err)
+		}
+	}
+}
+
+func TestRedisClientOptionInstance(t *testing.T) {
+	option := &RedisClientOption{Mode: Ring, Addrs: map[string]string{"b": "b:6379", "a": "a:6379"}}
+	if s := option.instance(); s != "a:6379,b:6379" {
+		t.Fatalf("ring instance = %s", s)
+	}
+	option = &RedisClientOption{Mode: Sentinel, SentinelMasterName: "m", SentinelAddrs: []string{"s1", "s2"}}
+	if s := option.instance(); s != "m@s1,s2" {
+		t.Fatalf("sentinel instance = %s", s)
+	}
+}
@@ -0,0 +1,242 @@
+package gdb
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// DefaultLatencyBuckets are the upper bounds of the latency histograms in seconds
+var DefaultLatencyBuckets = []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1}
+
+// pipelineSizeBuckets are the upper bounds of the pipeline size histogram
+var pipelineSizeBuckets = []float64{1, 2, 5, 10, 20, 50, 100, 200, 500, 1000}
+
+type metricsStartKey struct{}
+
+// MetricsHook record the metrics of the commands and the connection pools, and expose them in the prometheus
+// text format by Handler. The zero value is ready to use, the pool stats of the clients created by NewRedisClient
+// with the hook are collected, use one hook for each client to tell the clients apart by Instance.
+type MetricsHook struct {
+	Namespace string    // prefix of the metric names, default "gdb_redis"
+	Instance  string    // value of the instance label
+	RedisMode string    // value of the mode label
+	Buckets   []float64 // latency buckets, default DefaultLatencyBuckets
+
+	mu        sync.Mutex
+	commands  map[string]*commandMetrics
+	pipelines *histogram // latency of the pipelines
+	sizes     *histogram // commands of the pipelines
+	pools     []func() *redis.PoolStats
+}
+
+type commandMetrics struct {
+	total    uint64
+	errors   uint64
+	misses   uint64     // redis.Nil replies
+	duration *histogram // latency of the commands not in pipelines
+}
+
+type histogram struct {
+	buckets []float64
+	counts  []uint64 // count of each bucket, not cumulative
+	sum     float64
+	count   uint64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+}
+
+func (h *histogram) observe(v float64) {
+	if i := sort.SearchFloat64s(h.buckets, v); i < len(h.buckets) {
+		h.counts[i]++
+	}
+	h.sum += v
+	h.count++
+}
+
+// addPool collect the pool stats of a client
+func (h *MetricsHook) addPool(stats func() *redis.PoolStats) {
+	h.mu.Lock()
+	h.pools = append(h.pools, stats)
+	h.mu.Unlock()
+}
+
+// command return the metrics of the command, h.mu must be held
+func (h *MetricsHook) command(name string) *commandMetrics {
+	if h.commands == nil {
+		h.commands = make(map[string]*commandMetrics)
+	}
+	m, ok := h.commands[name]
+	if !ok {
+		buckets := h.Buckets
+		if len(buckets) == 0 {
+			buckets = DefaultLatencyBuckets
+		}
+		m = &commandMetrics{duration: newHistogram(buckets)}
+		h.commands[name] = m
+	}
+	return m
+}
+
+// count account the result of the cmd, h.mu must be held
+func (h *MetricsHook) count(cmd redis.Cmder) *commandMetrics {
+	m := h.command(cmd.Name())
+	m.total++
+	if err := cmd.Err(); errors.Is(err, redis.Nil) {
+		m.misses++
+	} else if err != nil {
+		m.errors++
+	}
+	return m
+}
+
+func (h *MetricsHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, metricsStartKey{}, time.Now()), nil
+}
+
+func (h *MetricsHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	start, ok := ctx.Value(metricsStartKey{}).(time.Time)
+	h.mu.Lock()
+	m := h.count(cmd)
+	if ok {
+		m.duration.observe(time.Since(start).Seconds())
+	}
+	h.mu.Unlock()
+	return nil
+}
+
+func (h *MetricsHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, metricsStartKey{}, time.Now()), nil
+}
+
+func (h *MetricsHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	start, ok := ctx.Value(metricsStartKey{}).(time.Time)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, cmd := range cmds {
+		h.count(cmd)
+	}
+	if h.pipelines == nil {
+		buckets := h.Buckets
+		if len(buckets) == 0 {
+			buckets = DefaultLatencyBuckets
+		}
+		h.pipelines = newHistogram(buckets)
+		h.sizes = newHistogram(pipelineSizeBuckets)
+	}
+	if ok {
+		h.pipelines.observe(time.Since(start).Seconds())
+	}
+	h.sizes.observe(float64(len(cmds)))
+	return nil
+}
+
+// Handler return a http handler writing the metrics in the prometheus text exposition format
+func (h *MetricsHook) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = h.WriteMetrics(w)
+	})
+}
+
+// WriteMetrics write the metrics in the prometheus text exposition format
+func (h *MetricsHook) WriteMetrics(writer io.Writer) error {
+	w := bufio.NewWriter(writer)
+	ns := h.Namespace
+	if ns == "" {
+		ns = "gdb_redis"
+	}
+	base := `instance="` + escapeLabel(h.Instance) + `",mode="` + escapeLabel(h.RedisMode) + `"`
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	names := make([]string, 0, len(h.commands))
+	for name := range h.commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	counter := func(metric, help string, value func(m *commandMetrics) uint64) {
+		writeHeader(w, ns+metric, help, "counter")
+		for _, name := range names {
+			fmt.Fprintf(w, "%s%s{%s,command=\"%s\"} %d\n", ns, metric, base, escapeLabel(name), value(h.commands[name]))
+		}
+	}
+	counter("_commands_total", "Commands processed, including the pipelined ones.",
+		func(m *commandMetrics) uint64 { return m.total })
+	counter("_command_errors_total", "Commands failed, redis nil replies excluded.",
+		func(m *commandMetrics) uint64 { return m.errors })
+	counter("_command_misses_total", "Commands replied redis nil.",
+		func(m *commandMetrics) uint64 { return m.misses })
+
+	writeHeader(w, ns+"_command_duration_seconds", "Latency of the commands not in pipelines.", "histogram")
+	for _, name := range names {
+		writeHistogram(w, ns+"_command_duration_seconds", base+`,command="`+escapeLabel(name)+`"`,
+			h.commands[name].duration)
+	}
+	if h.pipelines != nil {
+		writeHeader(w, ns+"_pipeline_duration_seconds", "Latency of the pipelines.", "histogram")
+		writeHistogram(w, ns+"_pipeline_duration_seconds", base, h.pipelines)
+		writeHeader(w, ns+"_pipeline_size", "Commands of the pipelines.", "histogram")
+		writeHistogram(w, ns+"_pipeline_size", base, h.sizes)
+	}
+
+	if len(h.pools) == 0 {
+		return w.Flush()
+	}
+	var stats redis.PoolStats
+	for _, pool := range h.pools {
+		s := pool()
+		stats.Hits += s.Hits
+		stats.Misses += s.Misses
+		stats.Timeouts += s.Timeouts
+		stats.TotalConns += s.TotalConns
+		stats.IdleConns += s.IdleConns
+		stats.StaleConns += s.StaleConns
+	}
+	pool := func(metric, help, typ string, value uint32) {
+		writeHeader(w, ns+metric, help, typ)
+		fmt.Fprintf(w, "%s%s{%s} %d\n", ns, metric, base, value)
+	}
+	pool("_pool_hits_total", "Free connections found in the pool.", "counter", stats.Hits)
+	pool("_pool_misses_total", "Free connections not found in the pool.", "counter", stats.Misses)
+	pool("_pool_timeouts_total", "Waits for a connection timed out.", "counter", stats.Timeouts)
+	pool("_pool_stale_conns_total", "Stale connections removed from the pool.", "counter", stats.StaleConns)
+	pool("_pool_total_conns", "Connections in the pool.", "gauge", stats.TotalConns)
+	pool("_pool_idle_conns", "Idle connections in the pool.", "gauge", stats.IdleConns)
+	return w.Flush()
+}
+
+func writeHeader(w *bufio.Writer, name, help, typ string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+func writeHistogram(w *bufio.Writer, name, labels string, h *histogram) {
+	var cumulative uint64
+	for i, le := range h.buckets {
+		cumulative += h.counts[i]
+		fmt.Fprintf(w, "%s_bucket{%s,le=\"%s\"} %d\n", name, labels,
+			strconv.FormatFloat(le, 'g', -1, 64), cumulative)
+	}
+	fmt.Fprintf(w, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, h.count)
+	fmt.Fprintf(w, "%s_sum{%s} %s\n", name, labels, strconv.FormatFloat(h.sum, 'g', -1, 64))
+	fmt.Fprintf(w, "%s_count{%s} %d\n", name, labels, h.count)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
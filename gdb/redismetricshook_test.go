@@ -0,0 +1,48 @@
+package gdb
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/oldjon/gutil/gdb/memredis"
+)
+
+func TestMetricsHook(t *testing.T) {
+	ctx := context.Background()
+	server := memredis.NewServer()
+	hook := &MetricsHook{Instance: "memredis", RedisMode: "single"}
+	client, err := NewMemRedisClient(server, nil, hook)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = client.Close()
+		_ = server.Close()
+	})
+
+	_, _ = client.Get(ctx, "missing")
+	_ = client.Set(ctx, "foo", "bar")
+	_, _ = client.Incr(ctx, "foo")
+	pipe := client.Pipeline()
+	_ = pipe.GetObject(ctx, "obj1", &Foo{})
+	_ = pipe.GetObject(ctx, "obj2", &Foo{})
+	_, _ = pipe.Exec(ctx)
+
+	rec := httptest.NewRecorder()
+	hook.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, line := range []string{
+		`gdb_redis_commands_total{instance="memredis",mode="single",command="get"} 3`,
+		`gdb_redis_command_misses_total{instance="memredis",mode="single",command="get"} 3`,
+		`gdb_redis_command_errors_total{instance="memredis",mode="single",command="incr"} 1`,
+		`gdb_redis_command_duration_seconds_count{instance="memredis",mode="single",command="get"} 1`,
+		`gdb_redis_pipeline_size_bucket{instance="memredis",mode="single",le="2"} 1`,
+		`# TYPE gdb_redis_pool_total_conns gauge`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("metric %q not found in\n%s", line, body)
+		}
+	}
+}
@@ -3,36 +3,66 @@ package gdb
 import (
 	"context"
 	"errors"
+	"fmt"
 	"reflect"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-redis/redis/v8"
+	gmarshaller "github.com/oldjon/gutil/marshaller"
 )
 
-func (rc *redisClient) SetKOMapping(mapping map[string]string) {
-	rc.koMapping = make(map[string]string)
+// ObjMarshallerOf check obj is of the type of the key, and return the marshaller of the key
+func (rc *redisClient) ObjMarshallerOf(key string, obj any) (gmarshaller.Marshaller, error) {
+	return rc.marshallerOf(key, obj, rc.objMarshaller)
+}
+
+func (rc *redisClient) LegacyReads() map[string]uint64 {
+	return rc.legacyReads(rc.objMarshaller)
+}
+
+// buildKOMapping convert the key prefix -> full type name mapping into key prefix -> short type name
+func buildKOMapping(mapping map[string]string) map[string]string {
+	koMapping := make(map[string]string)
 	for k, v := range mapping {
 		if k == "" {
 			continue
 		}
 		k = strings.Split(k, ":")[0]
 		strs := strings.Split(v, ".")
-		rc.koMapping[k] = strs[len(strs)-1]
+		koMapping[k] = strs[len(strs)-1]
 	}
+	return koMapping
 }
 
-func (rc *redisClient) CheckKeyObjMatch(key string, obj any) { // panic if not match
-	key = strings.Split(key, ":")[0]
-	t, ok := rc.koMapping[key]
+func checkKeyObjMatch(koMapping map[string]string, key string, obj any) error {
+	prefix := strings.Split(key, ":")[0]
+	t, ok := koMapping[prefix]
 	if !ok {
-		return
+		return nil
+	}
+	if name := typeShortName(reflect.TypeOf(obj)); t != name {
+		return fmt.Errorf("%w: key %s of type %s, object of type %s", ErrKeyObjMismatch, key, t, name)
 	}
-	ot := reflect.TypeOf(obj).String()
-	strs := strings.Split(ot, ".")
-	if t != strs[len(strs)-1] {
-		panic("db key and db object type is not match") // if type is not match, means there is a bug in code
+	return nil
+}
+
+var typeShortNames sync.Map // reflect.Type -> string
+
+// typeShortName returns the type name without package path, it is cached because CheckKeyObjMatch is on the hot path.
+func typeShortName(t reflect.Type) string {
+	if name, ok := typeShortNames.Load(t); ok {
+		return name.(string)
 	}
+	strs := strings.Split(t.String(), ".")
+	name := strs[len(strs)-1]
+	typeShortNames.Store(t, name)
+	return name
+}
+
+func (rc *redisClient) ObjMarshaller() gmarshaller.Marshaller {
+	return rc.objMarshaller
 }
 
 func (rc *redisClient) IsErrNil(err error) bool {
@@ -40,26 +70,38 @@ func (rc *redisClient) IsErrNil(err error) bool {
 }
 
 func (rc *redisClient) GetObject(ctx context.Context, key string, obj any) error {
-	rc.CheckKeyObjMatch(key, obj)
+	m, err := rc.ObjMarshallerOf(key, obj)
+	if err != nil {
+		return err
+	}
 	v, err := rc.Get(ctx, key)
 	if err != nil {
 		return err
 	}
-	return rc.objMarshaller.Unmarshal([]byte(v), obj)
+	return m.Unmarshal([]byte(v), obj)
 }
 
 func (rc *redisClient) SetObject(ctx context.Context, key string, obj any) error {
-	rc.CheckKeyObjMatch(key, obj)
-	bys, err := rc.objMarshaller.Marshal(obj) //测试是否可以传nil指针
+	m, err := rc.ObjMarshallerOf(key, obj)
+	if err != nil {
+		return err
+	}
+	bys, err := m.Marshal(obj) //测试是否可以传nil指针
 	if err != nil {
 		return err
 	}
+	if ttl := rc.defaultTTL(key); ttl > 0 {
+		return rc.SetEX(ctx, key, bys, ttl)
+	}
 	return rc.Set(ctx, key, bys)
 }
 
 func (rc *redisClient) SetObjectEX(ctx context.Context, key string, obj any, expiration time.Duration) error {
-	rc.CheckKeyObjMatch(key, obj)
-	bys, err := rc.objMarshaller.Marshal(obj)
+	m, err := rc.ObjMarshallerOf(key, obj)
+	if err != nil {
+		return err
+	}
+	bys, err := m.Marshal(obj)
 	if err != nil {
 		return err
 	}
@@ -79,8 +121,12 @@ func (rc *redisClient) GetObjects(ctx context.Context, keys []string, objs any)
 	}
 
 	var elemIsInterface bool
+	ms := make([]gmarshaller.Marshaller, len(keys))
 	for i := 0; i < len(keys); i++ {
-		rc.CheckKeyObjMatch(keys[i], objsValue.Index(i).Interface())
+		var err error
+		if ms[i], err = rc.ObjMarshallerOf(keys[i], objsValue.Index(i).Interface()); err != nil {
+			return err
+		}
 		if objsValue.Index(i).Kind() != reflect.Ptr && // input is slice of known struct type
 			objsValue.Index(i).Elem().Kind() != reflect.Ptr { // input is slice of interface
 			panic(PanicValueDstNeedBePointer)
@@ -91,7 +137,7 @@ func (rc *redisClient) GetObjects(ctx context.Context, keys []string, objs any)
 	}
 
 	cmds, err := rc.BatchGet(ctx, keys)
-	if err != nil {
+	if cmds == nil {
 		return err
 	}
 	for i, v := range cmds {
@@ -104,7 +150,10 @@ func (rc *redisClient) GetObjects(ctx context.Context, keys []string, objs any)
 			}
 			continue
 		} else if v.Err() != nil {
-			return err
+			if err != nil { // the objects of the failed keys are left as they are with a *BatchError
+				continue
+			}
+			return v.Err()
 		}
 		if !elemIsInterface {
 			if objv.IsNil() {
@@ -116,22 +165,23 @@ func (rc *redisClient) GetObjects(ctx context.Context, keys []string, objs any)
 			}
 		}
 
-		err = rc.objMarshaller.Unmarshal([]byte(v.Val()), objv.Interface())
-		if err != nil {
-			return err
+		if uErr := ms[i].Unmarshal([]byte(v.Val()), objv.Interface()); uErr != nil {
+			return uErr
 		}
 	}
-	return nil
+	return err
 }
 
 func (rc *redisClient) SetObjects(ctx context.Context, keys []string, objs any) error {
-	return rc.SetObjectsEX(ctx, keys, objs, 0)
+	return rc.setObjects(ctx, keys, objs, rc.defaultTTL)
 }
 
 func (rc *redisClient) SetObjectsEX(ctx context.Context, keys []string, objs any, expiration time.Duration) error {
-	if len(keys) == 0 {
-		panic(PanicKeyIsMissing)
-	}
+	return rc.setObjects(ctx, keys, objs, func(string) time.Duration { return expiration })
+}
+
+// marshalObjects marshal the slice objs by the marshallers of the keys
+func (rc *redisClient) marshalObjects(keys []string, objs any) ([]any, error) {
 	objsValue := reflect.ValueOf(objs)
 	if objsValue.Kind() != reflect.Slice {
 		panic(PanicValueNeedBeSlice)
@@ -139,20 +189,37 @@ func (rc *redisClient) SetObjectsEX(ctx context.Context, keys []string, objs any
 	if len(keys) != objsValue.Len() {
 		panic(PanicKeyValueCountUnmatched)
 	}
-	var err error
 	datas := make([]any, objsValue.Len())
 	for i := 0; i < objsValue.Len(); i++ {
 		objv := objsValue.Index(i).Interface()
-		rc.CheckKeyObjMatch(keys[i], objv)
-		datas[i], err = rc.objMarshaller.Marshal(objv)
+		m, err := rc.ObjMarshallerOf(keys[i], objv)
 		if err != nil {
-			return err
+			return nil, err
+		}
+		if datas[i], err = m.Marshal(objv); err != nil {
+			return nil, err
 		}
 	}
-	return rc.BatchSet(ctx, keys, datas, expiration)
+	return datas, nil
 }
 
-func (rc *redisClient) HSetObjects(ctx context.Context, key string, values ...any) error {
+// setObjects set the objects with the expiration of every key
+func (rc *redisClient) setObjects(ctx context.Context, keys []string, objs any,
+	expiration func(key string) time.Duration) error {
+	if len(keys) == 0 {
+		panic(PanicKeyIsMissing)
+	}
+	datas, err := rc.marshalObjects(keys, objs)
+	if err != nil {
+		return err
+	}
+	return rc.batch(ctx, keys, func(pipeliner redis.Pipeliner, i int) redis.Cmder {
+		return pipeliner.Set(ctx, keys[i], datas[i], expiration(keys[i]))
+	})
+}
+
+// hsetPairs flatten the values of HSetObjects, a single []any or map[string]any, into field and value pairs
+func hsetPairs(values []any) []any {
 	if len(values) == 1 {
 		value := values[0]
 		switch v := value.(type) {
@@ -167,14 +234,22 @@ func (rc *redisClient) HSetObjects(ctx context.Context, key string, values ...an
 			panic(PanicHSetUnsupportedValueType)
 		}
 	}
-
-	var l = make([]any, 0, len(values))
 	if len(values)%2 != 0 {
 		panic(PanicFieldValueCountUnmatched)
 	}
+	return values
+}
+
+func (rc *redisClient) HSetObjects(ctx context.Context, key string, values ...any) error {
+	values = hsetPairs(values)
+	var l = make([]any, 0, len(values))
 	for i := 0; i < len(values); i += 2 {
 		l = append(l, values[i]) // key
-		bys, err := rc.objMarshaller.Marshal(values[i+1])
+		m, err := rc.ObjMarshallerOf(key, values[i+1])
+		if err != nil {
+			return err
+		}
+		bys, err := m.Marshal(values[i+1])
 		if err != nil {
 			return err
 		}
@@ -185,6 +260,10 @@ func (rc *redisClient) HSetObjects(ctx context.Context, key string, values ...an
 }
 
 func (rc *redisClient) HGetObject(ctx context.Context, key string, field string, obj any) error {
+	m, err := rc.ObjMarshallerOf(key, obj)
+	if err != nil {
+		return err
+	}
 	v := reflect.ValueOf(obj)
 	if v.Kind() != reflect.Ptr {
 		panic(PanicValueDstNeedBePointer)
@@ -193,11 +272,15 @@ func (rc *redisClient) HGetObject(ctx context.Context, key string, field string,
 	if err != nil {
 		return err
 	}
-	err = rc.objMarshaller.Unmarshal([]byte(data), obj)
+	err = m.Unmarshal([]byte(data), obj)
 	return err
 }
 
 func (rc *redisClient) HMGetObjects(ctx context.Context, key string, fields []string, objs any) error {
+	m, err := rc.ObjMarshallerOf(key, objs)
+	if err != nil {
+		return err
+	}
 	if len(fields) == 0 {
 		panic(PanicFieldsIsMissing)
 	}
@@ -248,7 +331,7 @@ func (rc *redisClient) HMGetObjects(ctx context.Context, key string, fields []st
 			}
 		}
 		//start := time.Now().UnixMilli()
-		err = rc.objMarshaller.Unmarshal([]byte(str), objv.Interface())
+		err = m.Unmarshal([]byte(str), objv.Interface())
 		if err != nil {
 			return err
 		}
@@ -260,6 +343,10 @@ func (rc *redisClient) HMGetObjects(ctx context.Context, key string, fields []st
 }
 
 func (rc *redisClient) HGetAllObjects(ctx context.Context, key string, fields *[]string, objs any) error {
+	m, err := rc.ObjMarshallerOf(key, objs)
+	if err != nil {
+		return err
+	}
 	if fields == nil {
 		panic("HGetAllObjects fields is nil")
 	}
@@ -286,7 +373,7 @@ func (rc *redisClient) HGetAllObjects(ctx context.Context, key string, fields *[
 			t = objType.Elem()
 		}
 		obj := reflect.New(t)
-		err = rc.objMarshaller.Unmarshal([]byte(v), obj.Interface())
+		err = m.Unmarshal([]byte(v), obj.Interface())
 		if err != nil {
 			return err
 		}
@@ -308,7 +395,11 @@ func (rc *redisClient) ZAddObjects(ctx context.Context, key string, values ...an
 	}
 	for i := 0; i < len(values); i += 2 {
 		l = append(l, values[i]) // key
-		bys, err := rc.objMarshaller.Marshal(values[i+1])
+		m, err := rc.ObjMarshallerOf(key, values[i+1])
+		if err != nil {
+			return 0, err
+		}
+		bys, err := m.Marshal(values[i+1])
 		if err != nil {
 			return 0, err
 		}
@@ -318,6 +409,10 @@ func (rc *redisClient) ZAddObjects(ctx context.Context, key string, values ...an
 }
 
 func (rc *redisClient) ZRangeObjects(ctx context.Context, key string, start, stop int64, objs any) error {
+	m, err := rc.ObjMarshallerOf(key, objs)
+	if err != nil {
+		return err
+	}
 	// check type of objs
 	objsType := reflect.TypeOf(objs)
 	if objsType.Kind() != reflect.Ptr {
@@ -340,7 +435,7 @@ func (rc *redisClient) ZRangeObjects(ctx context.Context, key string, start, sto
 			t = objType.Elem()
 		}
 		obj := reflect.New(t)
-		err = rc.objMarshaller.Unmarshal([]byte(v), obj.Interface())
+		err = m.Unmarshal([]byte(v), obj.Interface())
 		if err != nil {
 			return err
 		}
@@ -354,6 +449,10 @@ func (rc *redisClient) ZRangeObjects(ctx context.Context, key string, start, sto
 }
 
 func (rc *redisClient) ZRangeObjectsByScore(ctx context.Context, key string, min, max string, objs any) error {
+	m, err := rc.ObjMarshallerOf(key, objs)
+	if err != nil {
+		return err
+	}
 	// check type of objs
 	objsType := reflect.TypeOf(objs)
 	if objsType.Kind() != reflect.Ptr {
@@ -376,7 +475,7 @@ func (rc *redisClient) ZRangeObjectsByScore(ctx context.Context, key string, min
 			t = objType.Elem()
 		}
 		obj := reflect.New(t)
-		err = rc.objMarshaller.Unmarshal([]byte(v), obj.Interface())
+		err = m.Unmarshal([]byte(v), obj.Interface())
 		if err != nil {
 			return err
 		}
@@ -391,6 +490,10 @@ func (rc *redisClient) ZRangeObjectsByScore(ctx context.Context, key string, min
 
 func (rc *redisClient) ZRangeObjectsWithScores(ctx context.Context, key string, start, stop int64, objs any) (
 	scores []float64, err error) {
+	m, err := rc.ObjMarshallerOf(key, objs)
+	if err != nil {
+		return nil, err
+	}
 	// check type of objs
 	objsType := reflect.TypeOf(objs)
 	if objsType.Kind() != reflect.Ptr {
@@ -414,7 +517,7 @@ func (rc *redisClient) ZRangeObjectsWithScores(ctx context.Context, key string,
 			t = objType.Elem()
 		}
 		obj := reflect.New(t)
-		err = rc.objMarshaller.Unmarshal([]byte(v.Member.(string)), obj.Interface())
+		err = m.Unmarshal([]byte(v.Member.(string)), obj.Interface())
 		if err != nil {
 			return nil, err
 		}
@@ -430,6 +533,10 @@ func (rc *redisClient) ZRangeObjectsWithScores(ctx context.Context, key string,
 
 func (rc *redisClient) ZRangeObjectsByScoreWithScores(ctx context.Context, key string, min, max string, objs any) (
 	scores []float64, err error) {
+	m, err := rc.ObjMarshallerOf(key, objs)
+	if err != nil {
+		return nil, err
+	}
 	// check type of objs
 	objsType := reflect.TypeOf(objs)
 	if objsType.Kind() != reflect.Ptr {
@@ -453,7 +560,7 @@ func (rc *redisClient) ZRangeObjectsByScoreWithScores(ctx context.Context, key s
 			t = objType.Elem()
 		}
 		obj := reflect.New(t)
-		err = rc.objMarshaller.Unmarshal([]byte(v.Member.(string)), obj.Interface())
+		err = m.Unmarshal([]byte(v.Member.(string)), obj.Interface())
 		if err != nil {
 			return nil, err
 		}
@@ -468,6 +575,10 @@ func (rc *redisClient) ZRangeObjectsByScoreWithScores(ctx context.Context, key s
 }
 
 func (rc *redisClient) ZRevRangeObjects(ctx context.Context, key string, start, stop int64, objs any) error {
+	m, err := rc.ObjMarshallerOf(key, objs)
+	if err != nil {
+		return err
+	}
 	// check type of objs
 	objsType := reflect.TypeOf(objs)
 	if objsType.Kind() != reflect.Ptr {
@@ -490,7 +601,7 @@ func (rc *redisClient) ZRevRangeObjects(ctx context.Context, key string, start,
 			t = objType.Elem()
 		}
 		obj := reflect.New(t)
-		err = rc.objMarshaller.Unmarshal([]byte(v), obj.Interface())
+		err = m.Unmarshal([]byte(v), obj.Interface())
 		if err != nil {
 			return err
 		}
@@ -504,6 +615,10 @@ func (rc *redisClient) ZRevRangeObjects(ctx context.Context, key string, start,
 }
 
 func (rc *redisClient) ZRevRangeObjectsByScore(ctx context.Context, key string, min, max string, objs any) error {
+	m, err := rc.ObjMarshallerOf(key, objs)
+	if err != nil {
+		return err
+	}
 	// check type of objs
 	objsType := reflect.TypeOf(objs)
 	if objsType.Kind() != reflect.Ptr {
@@ -526,7 +641,7 @@ func (rc *redisClient) ZRevRangeObjectsByScore(ctx context.Context, key string,
 			t = objType.Elem()
 		}
 		obj := reflect.New(t)
-		err = rc.objMarshaller.Unmarshal([]byte(v), obj.Interface())
+		err = m.Unmarshal([]byte(v), obj.Interface())
 		if err != nil {
 			return err
 		}
@@ -541,6 +656,10 @@ func (rc *redisClient) ZRevRangeObjectsByScore(ctx context.Context, key string,
 
 func (rc *redisClient) ZRevRangeObjectsWithScores(ctx context.Context, key string, start, stop int64, objs any) (
 	scores []float64, err error) {
+	m, err := rc.ObjMarshallerOf(key, objs)
+	if err != nil {
+		return nil, err
+	}
 	// check type of objs
 	objsType := reflect.TypeOf(objs)
 	if objsType.Kind() != reflect.Ptr {
@@ -564,7 +683,7 @@ func (rc *redisClient) ZRevRangeObjectsWithScores(ctx context.Context, key strin
 			t = objType.Elem()
 		}
 		obj := reflect.New(t)
-		err = rc.objMarshaller.Unmarshal([]byte(v.Member.(string)), obj.Interface())
+		err = m.Unmarshal([]byte(v.Member.(string)), obj.Interface())
 		if err != nil {
 			return nil, err
 		}
@@ -580,6 +699,10 @@ func (rc *redisClient) ZRevRangeObjectsWithScores(ctx context.Context, key strin
 
 func (rc *redisClient) ZRevRangeObjectsByScoreWithScores(ctx context.Context, key string, min, max string, objs any) (
 	scores []float64, err error) {
+	m, err := rc.ObjMarshallerOf(key, objs)
+	if err != nil {
+		return nil, err
+	}
 	// check type of objs
 	objsType := reflect.TypeOf(objs)
 	if objsType.Kind() != reflect.Ptr {
@@ -603,7 +726,7 @@ func (rc *redisClient) ZRevRangeObjectsByScoreWithScores(ctx context.Context, ke
 			t = objType.Elem()
 		}
 		obj := reflect.New(t)
-		err = rc.objMarshaller.Unmarshal([]byte(v.Member.(string)), obj.Interface())
+		err = m.Unmarshal([]byte(v.Member.(string)), obj.Interface())
 		if err != nil {
 			return nil, err
 		}
@@ -618,7 +741,11 @@ func (rc *redisClient) ZRevRangeObjectsByScoreWithScores(ctx context.Context, ke
 }
 
 func (rc *redisClient) ZRankObject(ctx context.Context, key string, member any) (int64, error) {
-	data, err := rc.objMarshaller.Marshal(member)
+	m, err := rc.ObjMarshallerOf(key, member)
+	if err != nil {
+		return 0, err
+	}
+	data, err := m.Marshal(member)
 	if err != nil {
 		return 0, err
 	}
@@ -626,7 +753,11 @@ func (rc *redisClient) ZRankObject(ctx context.Context, key string, member any)
 }
 
 func (rc *redisClient) ZRevRankObject(ctx context.Context, key string, member any) (int64, error) {
-	data, err := rc.objMarshaller.Marshal(member)
+	m, err := rc.ObjMarshallerOf(key, member)
+	if err != nil {
+		return 0, err
+	}
+	data, err := m.Marshal(member)
 	if err != nil {
 		return 0, err
 	}
@@ -634,7 +765,11 @@ func (rc *redisClient) ZRevRankObject(ctx context.Context, key string, member an
 }
 
 func (rc *redisClient) ZScoreObject(ctx context.Context, key string, member any) (float64, error) {
-	data, err := rc.objMarshaller.Marshal(member)
+	m, err := rc.ObjMarshallerOf(key, member)
+	if err != nil {
+		return 0, err
+	}
+	data, err := m.Marshal(member)
 	if err != nil {
 		return 0, err
 	}
@@ -643,7 +778,11 @@ func (rc *redisClient) ZScoreObject(ctx context.Context, key string, member any)
 
 func (rc *redisClient) ZRemObjects(ctx context.Context, key string, members ...any) (int64, error) {
 	for i, v := range members {
-		bys, err := rc.objMarshaller.Marshal(v)
+		m, err := rc.ObjMarshallerOf(key, v)
+		if err != nil {
+			return 0, err
+		}
+		bys, err := m.Marshal(v)
 		if err != nil {
 			return 0, err
 		}
@@ -651,3 +790,304 @@ func (rc *redisClient) ZRemObjects(ctx context.Context, key string, members ...a
 	}
 	return rc.ZRem(ctx, key, members...)
 }
+
+// geoLocations check and marshal the longitude, latitude and member triples of GeoAddObjects
+func (rc *redisClient) geoLocations(key string, values []any) ([]*redis.GeoLocation, error) {
+	if len(values)%3 != 0 {
+		panic(PanicGeoValueCountUnmatched)
+	}
+	locations := make([]*redis.GeoLocation, 0, len(values)/3)
+	for i := 0; i < len(values); i += 3 {
+		lon, err := toFloat64(values[i])
+		if err != nil {
+			return nil, err
+		}
+		lat, err := toFloat64(values[i+1])
+		if err != nil {
+			return nil, err
+		}
+		m, err := rc.ObjMarshallerOf(key, values[i+2])
+		if err != nil {
+			return nil, err
+		}
+		bys, err := m.Marshal(values[i+2])
+		if err != nil {
+			return nil, err
+		}
+		locations = append(locations, &redis.GeoLocation{Name: string(bys), Longitude: lon, Latitude: lat})
+	}
+	return locations, nil
+}
+
+func (rc *redisClient) GeoAddObjects(ctx context.Context, key string, values ...any) (int64, error) {
+	locations, err := rc.geoLocations(key, values)
+	if err != nil {
+		return 0, err
+	}
+	return rc.GeoAdd(ctx, key, locations...)
+}
+
+// geoMemberNames marshal the geo members into their names
+func (rc *redisClient) geoMemberNames(key string, members []any) ([]string, error) {
+	datas, err := rc.marshalMembers(key, members)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(datas))
+	for i, data := range datas {
+		names[i] = data.(string)
+	}
+	return names, nil
+}
+
+func (rc *redisClient) GeoPosObjects(ctx context.Context, key string, members ...any) ([]*redis.GeoPos, error) {
+	names, err := rc.geoMemberNames(key, members)
+	if err != nil {
+		return nil, err
+	}
+	return rc.GeoPos(ctx, key, names...)
+}
+
+func (rc *redisClient) GeoDistObjects(ctx context.Context, key string, member1, member2 any, unit string) (
+	float64, error) {
+	names, err := rc.geoMemberNames(key, []any{member1, member2})
+	if err != nil {
+		return 0, err
+	}
+	return rc.GeoDist(ctx, key, names[0], names[1], unit)
+}
+
+func (rc *redisClient) GeoSearchObjects(ctx context.Context, key string, q *redis.GeoSearchLocationQuery,
+	objs any) ([]redis.GeoLocation, error) {
+	m, err := rc.ObjMarshallerOf(key, objs)
+	if err != nil {
+		return nil, err
+	}
+	var locations []redis.GeoLocation
+	if geoSearchWithLocation(q) {
+		locations, err = rc.GeoSearchLocation(ctx, key, q)
+	} else {
+		var names []string
+		names, err = rc.GeoSearch(ctx, key, &q.GeoSearchQuery)
+		locations = geoNameLocations(names)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return locations, unmarshalLocations(m, locations, objs)
+}
+
+// geoSearchWithLocation return whether the replies of q have the locations, the replies without WITH are the plain
+// members, which GeoSearchLocationCmd cannot parse, so they are searched by GeoSearch
+func geoSearchWithLocation(q *redis.GeoSearchLocationQuery) bool {
+	return q.WithCoord || q.WithDist || q.WithHash
+}
+
+// geoNameLocations return the locations of only the names
+func geoNameLocations(names []string) []redis.GeoLocation {
+	locations := make([]redis.GeoLocation, len(names))
+	for i, name := range names {
+		locations[i].Name = name
+	}
+	return locations
+}
+
+// unmarshalLocations unmarshal the names of the locations into objs
+func unmarshalLocations(m gmarshaller.Marshaller, locations []redis.GeoLocation, objs any) error {
+	datas := make([]string, len(locations))
+	for i, l := range locations {
+		datas[i] = l.Name
+	}
+	return unmarshalSliceBy(m, datas, objs)
+}
+
+// unmarshalSliceBy unmarshal datas into objs, objs should be a point of a slice of struct or struct points.
+func unmarshalSliceBy(m gmarshaller.Marshaller, datas []string, objs any) error {
+	// check type of objs
+	objsType := reflect.TypeOf(objs)
+	if objsType.Kind() != reflect.Ptr {
+		panic(PanicValueDstNeedBePointer)
+	}
+	// get the elem type
+	objsValue := reflect.ValueOf(objs)
+	objType := objsValue.Elem().Type().Elem()
+	var objPtr = objType.Kind() == reflect.Ptr
+
+	objsValue.Elem().Set(reflect.MakeSlice(objsValue.Elem().Type(), len(datas), len(datas)))
+	for i, v := range datas {
+		t := objType
+		if objPtr {
+			t = objType.Elem()
+		}
+		obj := reflect.New(t)
+		err := m.Unmarshal([]byte(v), obj.Interface())
+		if err != nil {
+			return err
+		}
+		if objPtr {
+			objsValue.Elem().Index(i).Set(obj)
+		} else {
+			objsValue.Elem().Index(i).Set(obj.Elem())
+		}
+	}
+	return nil
+}
+
+// marshalMembers check and marshal every value of the key
+func (rc *redisClient) marshalMembers(key string, values []any) ([]any, error) {
+	l := make([]any, 0, len(values))
+	for _, v := range values {
+		m, err := rc.ObjMarshallerOf(key, v)
+		if err != nil {
+			return nil, err
+		}
+		bys, err := m.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		l = append(l, string(bys))
+	}
+	return l, nil
+}
+
+func (rc *redisClient) LPushObjects(ctx context.Context, key string, values ...any) (int64, error) {
+	l, err := rc.marshalMembers(key, values)
+	if err != nil {
+		return 0, err
+	}
+	return rc.LPush(ctx, key, l...)
+}
+
+func (rc *redisClient) RPushObjects(ctx context.Context, key string, values ...any) (int64, error) {
+	l, err := rc.marshalMembers(key, values)
+	if err != nil {
+		return 0, err
+	}
+	return rc.RPush(ctx, key, l...)
+}
+
+func (rc *redisClient) LPopObject(ctx context.Context, key string, obj any) error {
+	m, err := rc.ObjMarshallerOf(key, obj)
+	if err != nil {
+		return err
+	}
+	v, err := rc.LPop(ctx, key)
+	if err != nil {
+		return err
+	}
+	return m.Unmarshal([]byte(v), obj)
+}
+
+func (rc *redisClient) RPopObject(ctx context.Context, key string, obj any) error {
+	m, err := rc.ObjMarshallerOf(key, obj)
+	if err != nil {
+		return err
+	}
+	v, err := rc.RPop(ctx, key)
+	if err != nil {
+		return err
+	}
+	return m.Unmarshal([]byte(v), obj)
+}
+
+func (rc *redisClient) BLPopObject(ctx context.Context, timeout time.Duration, key string, obj any) error {
+	m, err := rc.ObjMarshallerOf(key, obj)
+	if err != nil {
+		return err
+	}
+	rets, err := rc.BLPop(ctx, timeout, key)
+	if err != nil {
+		return err
+	}
+	return m.Unmarshal([]byte(rets[1]), obj)
+}
+
+func (rc *redisClient) LRangeObjects(ctx context.Context, key string, start, stop int64, objs any) error {
+	m, err := rc.ObjMarshallerOf(key, objs)
+	if err != nil {
+		return err
+	}
+	rets, err := rc.LRange(ctx, key, start, stop)
+	if err != nil {
+		return err
+	}
+	return unmarshalSliceBy(m, rets, objs)
+}
+
+func (rc *redisClient) SAddObjects(ctx context.Context, key string, members ...any) (int64, error) {
+	l, err := rc.marshalMembers(key, members)
+	if err != nil {
+		return 0, err
+	}
+	return rc.SAdd(ctx, key, l...)
+}
+
+func (rc *redisClient) SRemObjects(ctx context.Context, key string, members ...any) (int64, error) {
+	l, err := rc.marshalMembers(key, members)
+	if err != nil {
+		return 0, err
+	}
+	return rc.SRem(ctx, key, l...)
+}
+
+func (rc *redisClient) SIsMemberObject(ctx context.Context, key string, member any) (bool, error) {
+	m, err := rc.ObjMarshallerOf(key, member)
+	if err != nil {
+		return false, err
+	}
+	data, err := m.Marshal(member)
+	if err != nil {
+		return false, err
+	}
+	return rc.SIsMember(ctx, key, string(data))
+}
+
+func (rc *redisClient) SMembersObjects(ctx context.Context, key string, objs any) error {
+	m, err := rc.ObjMarshallerOf(key, objs)
+	if err != nil {
+		return err
+	}
+	rets, err := rc.SMembers(ctx, key)
+	if err != nil {
+		return err
+	}
+	return unmarshalSliceBy(m, rets, objs)
+}
+
+func (rc *redisClient) SPopObject(ctx context.Context, key string, obj any) error {
+	m, err := rc.ObjMarshallerOf(key, obj)
+	if err != nil {
+		return err
+	}
+	v, err := rc.SPop(ctx, key)
+	if err != nil {
+		return err
+	}
+	return m.Unmarshal([]byte(v), obj)
+}
+
+// StreamObjectField is the field of the stream message which holds the object added by XAddObject
+const StreamObjectField = "obj"
+
+func (rc *redisClient) XAddObject(ctx context.Context, stream string, obj any) (string, error) {
+	// the stream messages are marshalled by the marshaller of the db, XMessageObject does not know the stream
+	if err := rc.CheckKeyObjMatch(stream, obj); err != nil {
+		return "", err
+	}
+	bys, err := rc.objMarshaller.Marshal(obj)
+	if err != nil {
+		return "", err
+	}
+	return rc.XAdd(ctx, &redis.XAddArgs{
+		Stream: stream,
+		Values: []any{StreamObjectField, string(bys)},
+	})
+}
+
+func (rc *redisClient) XMessageObject(msg redis.XMessage, obj any) error {
+	v, ok := msg.Values[StreamObjectField].(string)
+	if !ok {
+		return ErrValueType
+	}
+	return rc.objMarshaller.Unmarshal([]byte(v), obj)
+}
@@ -0,0 +1,326 @@
+package gdb
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultSlowThreshold = 100 * time.Millisecond
+	defaultBigThreshold  = 1 << 20
+	defaultSlowWindow    = 5 * time.Minute
+	slowWindowSlots      = 10 // the window rolls by a tenth
+)
+
+type slowStartKey struct{}
+
+// SlowHook log the commands slower than SlowThreshold and the commands writing or reading more than BigThreshold
+// bytes, and keep the stats of the key prefixes of the last Window, which are queryable by Stats and Handler.
+// The written bytes are the args after the key, such as the marshalled objects of SetObject and HSetObjects, the
+// read bytes are the values of the replies. The zero value is ready to use, use one hook for each client as the
+// namespace of the client created by NewRedisClient with the hook is trimmed from the keys.
+type SlowHook struct {
+	SlowThreshold time.Duration // default 100ms
+	BigThreshold  int           // in bytes, default 1MB
+	Window        time.Duration // the stats cover the last Window, default 5 minutes
+	// KOMapping group the keys by the prefixes, the keys of no prefix are grouped in "", nil groups all the keys
+	// by the part before ':'
+	KOMapping KOMapping
+	Logger    *zap.Logger // default zap.L()
+
+	namespace string
+	now       func() time.Time
+	once      sync.Once
+	prefixes  map[string]bool // the legacy prefixes of KOMapping
+	mu        sync.Mutex
+	stats     map[string]*prefixStats
+}
+
+// SlowStats is the stats of the commands on the keys of a prefix in the last Window of a SlowHook
+type SlowStats struct {
+	Prefix     string        `json:"prefix"`
+	Commands   uint64        `json:"commands"`
+	Slow       uint64        `json:"slow"` // the commands not in pipelines slower than SlowThreshold
+	Big        uint64        `json:"big"`  // the commands writing or reading more than BigThreshold bytes
+	Bytes      uint64        `json:"bytes"`
+	MaxLatency time.Duration `json:"max_latency"` // of the commands not in pipelines
+	MaxSize    int           `json:"max_size"`    // the most bytes written or read by a command
+	MaxSizeKey string        `json:"max_size_key"`
+}
+
+// prefixStats roll the stats of a prefix by slots, slot i holds the stats of the epochs equal to i modulo the
+// slots, an epoch is a tenth of the window since the unix epoch
+type prefixStats struct {
+	slots  [slowWindowSlots]SlowStats
+	epochs [slowWindowSlots]int64
+}
+
+func (h *SlowHook) init() {
+	h.once.Do(func() {
+		if h.now == nil {
+			h.now = time.Now
+		}
+		if h.KOMapping == nil {
+			return
+		}
+		if _, ok := h.KOMapping.(*KeySchemaRegistry); !ok {
+			h.prefixes = make(map[string]bool)
+			for prefix := range buildKOMapping(h.KOMapping.Mapping()) {
+				h.prefixes[prefix] = true
+			}
+		}
+	})
+}
+
+// setNamespace trim the namespace of the client from the keys
+func (h *SlowHook) setNamespace(namespace string) {
+	h.namespace = namespace
+}
+
+func (h *SlowHook) logger() *zap.Logger {
+	if h.Logger != nil {
+		return h.Logger
+	}
+	return zap.L()
+}
+
+func (h *SlowHook) window() time.Duration {
+	if h.Window > 0 {
+		return h.Window
+	}
+	return defaultSlowWindow
+}
+
+// prefixOf return the group of the key
+func (h *SlowHook) prefixOf(key string) string {
+	switch {
+	case h.KOMapping == nil:
+		return strings.Split(key, ":")[0]
+	case h.prefixes == nil:
+		if schema, ok := h.KOMapping.(*KeySchemaRegistry).Lookup(key); ok {
+			return schema.Prefix
+		}
+	default:
+		if prefix := strings.Split(key, ":")[0]; h.prefixes[prefix] {
+			return prefix
+		}
+	}
+	return ""
+}
+
+// cmdKey return the first key of the command without the namespace, "" if it has no key
+func (h *SlowHook) cmdKey(cmd redis.Cmder) string {
+	args := cmd.Args()
+	if len(args) == 0 {
+		return ""
+	}
+	idx, _ := keyIndexes(args)
+	if len(idx) == 0 {
+		return ""
+	}
+	return strings.TrimPrefix(argString(args[idx[0]]), h.namespace)
+}
+
+// valueSize return the bytes of the strings in v
+func valueSize(v any) int {
+	switch v := v.(type) {
+	case string:
+		return len(v)
+	case []byte:
+		return len(v)
+	case []string:
+		n := 0
+		for _, s := range v {
+			n += len(s)
+		}
+		return n
+	case []any:
+		n := 0
+		for _, e := range v {
+			n += valueSize(e)
+		}
+		return n
+	}
+	return 0
+}
+
+// cmdSizes return the bytes written by the args after the first key which are not keys, and the bytes read from
+// the reply. The keys are found by the same key specs as cmdKey, so the args before them, like the script and
+// numkeys of EVAL or the subcommand of OBJECT, are not counted.
+func cmdSizes(cmd redis.Cmder) (int, int) {
+	args := cmd.Args()
+	written := 0
+	if len(args) > 1 {
+		idx, _ := keyIndexes(args)
+		sort.Ints(idx)
+		first := 1
+		if len(idx) > 0 {
+			first = idx[0]
+		} else if spec := namespaceKeySpecs[cmd.Name()]; spec.numkeys > 0 {
+			first = spec.numkeys + 1 // no keys
+		}
+		for i := first; i < len(args); i++ {
+			if len(idx) > 0 && idx[0] == i {
+				idx = idx[1:]
+				continue
+			}
+			written += valueSize(args[i])
+		}
+	}
+	read := 0
+	switch c := cmd.(type) {
+	case *redis.StringCmd:
+		read = len(c.Val())
+	case *redis.StringSliceCmd:
+		read = valueSize(c.Val())
+	case *redis.SliceCmd:
+		read = valueSize(c.Val())
+	case *redis.Cmd:
+		read = valueSize(c.Val())
+	case *redis.StringStringMapCmd:
+		for k, v := range c.Val() {
+			read += len(k) + len(v)
+		}
+	case *redis.ZSliceCmd:
+		for _, z := range c.Val() {
+			read += valueSize(z.Member)
+		}
+	}
+	return written, read
+}
+
+// observe account the command, latency is negative for the commands in pipelines
+func (h *SlowHook) observe(cmd redis.Cmder, latency time.Duration) {
+	key := h.cmdKey(cmd)
+	written, read := cmdSizes(cmd)
+	slow := latency >= 0 && latency > h.slowThreshold()
+	bigThreshold := h.BigThreshold
+	if bigThreshold <= 0 {
+		bigThreshold = defaultBigThreshold
+	}
+	big := written > bigThreshold || read > bigThreshold
+	if slow {
+		h.logger().Warn("slow redis command", zap.String("command", cmd.Name()), zap.String("key", key),
+			zap.Duration("latency", latency))
+	}
+	if big {
+		h.logger().Warn("big redis value", zap.String("command", cmd.Name()), zap.String("key", key),
+			zap.Int("written", written), zap.Int("read", read))
+	}
+
+	prefix := h.prefixOf(key)
+	epoch := h.now().UnixNano() / int64(h.window()/slowWindowSlots)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.stats == nil {
+		h.stats = make(map[string]*prefixStats)
+	}
+	ps, ok := h.stats[prefix]
+	if !ok {
+		ps = &prefixStats{}
+		h.stats[prefix] = ps
+	}
+	i := epoch % slowWindowSlots
+	if ps.epochs[i] != epoch {
+		ps.epochs[i], ps.slots[i] = epoch, SlowStats{}
+	}
+	s := &ps.slots[i]
+	s.Commands++
+	s.Bytes += uint64(written + read)
+	if slow {
+		s.Slow++
+	}
+	if big {
+		s.Big++
+	}
+	s.MaxLatency = max(s.MaxLatency, latency)
+	if size := max(written, read); size > s.MaxSize {
+		s.MaxSize, s.MaxSizeKey = size, key
+	}
+}
+
+func (h *SlowHook) slowThreshold() time.Duration {
+	if h.SlowThreshold > 0 {
+		return h.SlowThreshold
+	}
+	return defaultSlowThreshold
+}
+
+// Stats return the stats of the prefixes of the last Window, sorted by the prefixes
+func (h *SlowHook) Stats() []SlowStats {
+	h.init()
+	epoch := h.now().UnixNano() / int64(h.window()/slowWindowSlots)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var rets []SlowStats
+	for prefix, ps := range h.stats {
+		ret := SlowStats{Prefix: prefix}
+		for i, s := range ps.slots {
+			if epoch-ps.epochs[i] >= slowWindowSlots {
+				continue
+			}
+			ret.Commands += s.Commands
+			ret.Slow += s.Slow
+			ret.Big += s.Big
+			ret.Bytes += s.Bytes
+			ret.MaxLatency = max(ret.MaxLatency, s.MaxLatency)
+			if s.MaxSize > ret.MaxSize {
+				ret.MaxSize, ret.MaxSizeKey = s.MaxSize, s.MaxSizeKey
+			}
+		}
+		if ret.Commands > 0 {
+			rets = append(rets, ret)
+		}
+	}
+	sort.Slice(rets, func(i, j int) bool {
+		return rets[i].Prefix < rets[j].Prefix
+	})
+	return rets
+}
+
+// Handler return a http handler writing Stats in json
+func (h *SlowHook) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(h.Stats())
+	})
+}
+
+func (h *SlowHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, slowStartKey{}, time.Now()), nil
+}
+
+func (h *SlowHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	h.init()
+	latency := time.Duration(0)
+	if start, ok := ctx.Value(slowStartKey{}).(time.Time); ok {
+		latency = time.Since(start)
+	}
+	h.observe(cmd, latency)
+	return nil
+}
+
+func (h *SlowHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, slowStartKey{}, time.Now()), nil
+}
+
+func (h *SlowHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	h.init()
+	if start, ok := ctx.Value(slowStartKey{}).(time.Time); ok {
+		if latency := time.Since(start); latency > h.slowThreshold() {
+			h.logger().Warn("slow redis pipeline", zap.Int("commands", len(cmds)), zap.Duration("latency", latency))
+		}
+	}
+	for _, cmd := range cmds {
+		h.observe(cmd, -1)
+	}
+	return nil
+}
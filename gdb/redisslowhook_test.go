@@ -0,0 +1,139 @@
+package gdb
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/oldjon/gutil/gdb/memredis"
+	gmarshaller "github.com/oldjon/gutil/marshaller"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type koMapping map[string]string
+
+func (m koMapping) Mapping() map[string]string {
+	return m
+}
+
+func newSlowHookClient(t *testing.T, hook *SlowHook) (RedisClient, *fakeClock) {
+	server := memredis.NewServer()
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	hook.now = clock.Now
+	client, err := NewRedisClient(&RedisClientOption{Mode: Single, Addr: "memredis", Namespace: "ns:",
+		Marshaller: &gmarshaller.JsonMarshaller{}, Hooks: []redis.Hook{hook}, Dialer: server.Dial})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = client.Close()
+		_ = server.Close()
+	})
+	return client, clock
+}
+
+func TestSlowHook(t *testing.T) {
+	ctx := context.Background()
+	core, logs := observer.New(zap.WarnLevel)
+	hook := &SlowHook{BigThreshold: 100, Window: time.Minute, Logger: zap.New(core),
+		KOMapping: koMapping{"foo": "gdb.Foo"}}
+	client, clock := newSlowHookClient(t, hook)
+	client.SetKOMapping(map[string]string{"foo": "gdb.Foo"})
+
+	if err := client.SetObject(ctx, "foo:1", &Foo{F: 1}); err != nil {
+		t.Fatal(err)
+	}
+	big := strings.Repeat("x", 200)
+	_ = client.HSet(ctx, "foo:2", "f", big)
+	_, _ = client.HGetAll(ctx, "foo:2")
+	_ = client.Set(ctx, "bar:1", "x")
+	pipe := client.Pipeline()
+	pipe.Get(ctx, "foo:2")
+	pipe.Get(ctx, "bar:1")
+	_, _ = pipe.Exec(ctx)
+
+	if n := logs.FilterMessage("big redis value").Len(); n != 2 {
+		t.Fatalf("big logs = %d", n)
+	}
+	entry := logs.FilterMessage("big redis value").All()[1]
+	if fields := entry.ContextMap(); fields["key"] != "foo:2" || fields["command"] != "hgetall" ||
+		fields["read"] != int64(201) {
+		t.Fatalf("big log = %v", fields)
+	}
+
+	stats := hook.Stats()
+	if len(stats) != 2 || stats[0].Prefix != "" || stats[1].Prefix != "foo" {
+		t.Fatalf("Stats = %+v", stats)
+	}
+	foo := stats[1]
+	if foo.Commands != 4 || foo.Big != 2 || foo.MaxSize != 201 || foo.MaxSizeKey != "foo:2" || foo.Bytes != 416 {
+		t.Fatalf("foo stats = %+v", foo)
+	}
+	// the PING of NewRedisClient has no key
+	if stats[0].Commands != 3 || stats[0].Big != 0 || stats[0].MaxSizeKey != "bar:1" {
+		t.Fatalf("other stats = %+v", stats[0])
+	}
+
+	clock.Add(40 * time.Second)
+	_ = client.Set(ctx, "foo:3", "x")
+	if stats = hook.Stats(); len(stats) != 2 || stats[1].Commands != 5 {
+		t.Fatalf("Stats after 40s = %+v", stats)
+	}
+	clock.Add(30 * time.Second)
+	if stats = hook.Stats(); len(stats) != 1 || stats[0].Commands != 1 || stats[0].Big != 0 {
+		t.Fatalf("Stats after 70s = %+v", stats)
+	}
+
+	rec := httptest.NewRecorder()
+	hook.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/slow", nil))
+	var got []SlowStats
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil || !reflect.DeepEqual(got, stats) {
+		t.Fatalf("Handler = %s, %v", rec.Body.String(), err)
+	}
+}
+
+func TestSlowHookSlowCommands(t *testing.T) {
+	ctx := context.Background()
+	core, logs := observer.New(zap.WarnLevel)
+	registry, err := NewKeySchemaRegistry(KeySchema{Prefix: "foo:", Type: reflect.TypeOf(Foo{})})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hook := &SlowHook{SlowThreshold: time.Nanosecond, Logger: zap.New(core), KOMapping: registry}
+	client, _ := newSlowHookClient(t, hook)
+
+	_, _ = client.Get(ctx, "foo:1")
+	_, _ = client.Get(ctx, "bar:1")
+	if n := logs.FilterMessage("slow redis command").Len(); n != 3 { // with the PING of NewRedisClient
+		t.Fatalf("slow logs = %d", n)
+	}
+	stats := hook.Stats()
+	if len(stats) != 2 || stats[1].Prefix != "foo:" || stats[1].Slow != 1 || stats[1].MaxLatency <= 0 {
+		t.Fatalf("Stats = %+v", stats)
+	}
+}
+
+func TestCmdSizes(t *testing.T) {
+	ctx := context.Background()
+	for _, c := range []struct {
+		cmd     redis.Cmder
+		written int
+	}{
+		{redis.NewStatusCmd(ctx, "set", "key", "value"), 5},
+		{redis.NewCmd(ctx, "eval", "return 1", 2, "k1", "k2", "a", "bc"), 3},
+		{redis.NewCmd(ctx, "evalsha", "0123456789", 0, "a"), 1},
+		{redis.NewStringCmd(ctx, "object", "encoding", "key"), 0},
+		{redis.NewStatusCmd(ctx, "mset", "k1", "v1", "k2", "v22"), 5},
+		{redis.NewIntCmd(ctx, "zunionstore", "dst", 2, "k1", "k2", "weights", "1", "2"), 9},
+	} {
+		if written, _ := cmdSizes(c.cmd); written != c.written {
+			t.Fatalf("cmdSizes(%v) written = %d, want %d", c.cmd.Args(), written, c.written)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package gdb
+
+// Funcs iterate keys and members by SCAN, HSCAN, SSCAN and ZSCAN, and maintain keys in bulk
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const defaultBulkBatchSize = 100
+
+type Scanner interface {
+	// Scan iterate the keys matching the pattern, in cluster and ring mode the keys of every node are iterated.
+	// count is the COUNT hint of every SCAN call, 0 means the redis default.
+	Scan(ctx context.Context, match string, count int64) *ScanIterator
+	// HScan iterate the fields of the hash of the key, Val of the iterator is the value of the field
+	HScan(ctx context.Context, key string, match string, count int64) *ScanIterator
+	// SScan iterate the members of the set of the key
+	SScan(ctx context.Context, key string, match string, count int64) *ScanIterator
+	// ZScan iterate the members of the zset of the key, Val of the iterator is the score of the member
+	ZScan(ctx context.Context, key string, match string, count int64) *ScanIterator
+	// DelByPattern unlink all the keys matching the pattern in batches, it returns the number of deleted keys
+	DelByPattern(ctx context.Context, match string, opt *BulkOption) (int64, error)
+	// ExpireByPattern set the expiration of all the keys matching the pattern in batches,
+	// it returns the number of keys whose expiration is set
+	ExpireByPattern(ctx context.Context, match string, expiration time.Duration, opt *BulkOption) (int64, error)
+}
+
+// BulkOption control the speed of the bulk maintenance methods
+type BulkOption struct {
+	BatchSize int64 // keys per batch, also the COUNT hint of SCAN, default 100
+	Rate      int   // max keys per second, 0 means no limit
+}
+
+// scanPage scan one page from the cursor, it returns the elements and the next cursor
+type scanPage func(ctx context.Context, cursor uint64) ([]string, uint64, error)
+
+// ScanIterator iterate the scanned elements, the pages are fetched on demand.
+//
+//	it := client.Scan(ctx, "player:*", 100)
+//	for it.Next(ctx) {
+//		key := it.Key()
+//	}
+//	if err := it.Err(); err != nil {
+//	}
+type ScanIterator struct {
+	pages   []scanPage // one for each node, iterated in turn
+	step    int        // 2 if every element is followed by its value
+	cursor  uint64
+	started bool
+	buf     []string
+	pos     int
+	key     string
+	val     string
+	err     error
+}
+
+// Next advance the iterator, it returns false when the iteration is done or failed
+func (it *ScanIterator) Next(ctx context.Context) bool {
+	for it.err == nil {
+		if it.pos < len(it.buf) {
+			it.key = it.buf[it.pos]
+			if it.step == 2 {
+				it.val = it.buf[it.pos+1]
+			}
+			it.pos += it.step
+			return true
+		}
+		if len(it.pages) == 0 {
+			return false
+		}
+		if it.started && it.cursor == 0 {
+			it.pages, it.started = it.pages[1:], false
+			continue
+		}
+		it.buf, it.cursor, it.err = it.pages[0](ctx, it.cursor)
+		it.pos, it.started = 0, true
+	}
+	return false
+}
+
+// Key return the key, field or member of the current element
+func (it *ScanIterator) Key() string {
+	return it.key
+}
+
+// Val return the value of the current field of HScan or the score of the current member of ZScan
+func (it *ScanIterator) Val() string {
+	return it.val
+}
+
+func (it *ScanIterator) Err() error {
+	return it.err
+}
+
+// masters return the clients of all the masters in cluster mode or all the live shards in ring mode,
+// or the client itself
+func (rc *redisClient) masters(ctx context.Context) ([]redis.UniversalClient, error) {
+	var mu sync.Mutex
+	var nodes []redis.UniversalClient
+	collect := func(ctx context.Context, client *redis.Client) error {
+		mu.Lock()
+		nodes = append(nodes, client)
+		mu.Unlock()
+		return nil
+	}
+	var err error
+	switch c := rc.client.(type) {
+	case *redis.ClusterClient:
+		err = c.ForEachMaster(ctx, collect)
+	case *redis.Ring:
+		err = c.ForEachShard(ctx, collect)
+	default:
+		return []redis.UniversalClient{rc.client}, nil
+	}
+	return nodes, err
+}
+
+func (rc *redisClient) Scan(ctx context.Context, match string, count int64) *ScanIterator {
+	// the nodes of the cluster are not hooked by the namespace, so the prefix is handled here
+	if rc.namespace != "" {
+		if match == "" {
+			match = "*"
+		}
+		match = escapeGlob(rc.namespace) + match
+	}
+	nodes, err := rc.masters(ctx)
+	it := &ScanIterator{step: 1, err: err}
+	for _, node := range nodes {
+		node := node
+		it.pages = append(it.pages, func(ctx context.Context, cursor uint64) ([]string, uint64, error) {
+			keys, cursor, err := node.Scan(withRawKeys(ctx), cursor, match, count).Result()
+			for i := range keys {
+				keys[i] = strings.TrimPrefix(keys[i], rc.namespace)
+			}
+			return keys, cursor, err
+		})
+	}
+	return it
+}
+
+func (rc *redisClient) HScan(ctx context.Context, key string, match string, count int64) *ScanIterator {
+	return &ScanIterator{step: 2, pages: []scanPage{func(ctx context.Context, cursor uint64) ([]string, uint64, error) {
+		return rc.client.HScan(ctx, key, cursor, match, count).Result()
+	}}}
+}
+
+func (rc *redisClient) SScan(ctx context.Context, key string, match string, count int64) *ScanIterator {
+	return &ScanIterator{step: 1, pages: []scanPage{func(ctx context.Context, cursor uint64) ([]string, uint64, error) {
+		return rc.client.SScan(ctx, key, cursor, match, count).Result()
+	}}}
+}
+
+func (rc *redisClient) ZScan(ctx context.Context, key string, match string, count int64) *ScanIterator {
+	return &ScanIterator{step: 2, pages: []scanPage{func(ctx context.Context, cursor uint64) ([]string, uint64, error) {
+		return rc.client.ZScan(ctx, key, cursor, match, count).Result()
+	}}}
+}
+
+// pacer limit the processing speed to rate keys per second
+type pacer struct {
+	rate  int
+	start time.Time
+	done  int
+}
+
+// wait account n processed keys, and sleep until the speed is under the rate
+func (p *pacer) wait(ctx context.Context, n int) error {
+	p.done += n
+	if p.rate <= 0 {
+		return nil
+	}
+	due := p.start.Add(time.Duration(p.done) * time.Second / time.Duration(p.rate))
+	d := time.Until(due)
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// bulk scan the keys matching the pattern and run fn on every batch of them,
+// fn queues a command for each key into the pipeline, the commands returning 1 are counted
+func (rc *redisClient) bulk(ctx context.Context, match string, opt *BulkOption,
+	fn func(pipe redis.Pipeliner, key string)) (int64, error) {
+	batchSize, rate := int64(defaultBulkBatchSize), 0
+	if opt != nil {
+		if opt.BatchSize > 0 {
+			batchSize = opt.BatchSize
+		}
+		rate = opt.Rate
+	}
+	p := &pacer{rate: rate, start: time.Now()}
+	var total int64
+	flush := func(keys []string) error {
+		cmds, err := rc.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, key := range keys {
+				fn(pipe, key)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, cmd := range cmds {
+			switch c := cmd.(type) {
+			case *redis.IntCmd:
+				total += c.Val()
+			case *redis.BoolCmd:
+				if c.Val() {
+					total++
+				}
+			}
+		}
+		return p.wait(ctx, len(keys))
+	}
+
+	it := rc.Scan(ctx, match, batchSize)
+	keys := make([]string, 0, batchSize)
+	for it.Next(ctx) {
+		keys = append(keys, it.Key())
+		if int64(len(keys)) < batchSize {
+			continue
+		}
+		if err := flush(keys); err != nil {
+			return total, err
+		}
+		keys = keys[:0]
+	}
+	if err := it.Err(); err != nil {
+		return total, err
+	}
+	if len(keys) > 0 {
+		if err := flush(keys); err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+func (rc *redisClient) DelByPattern(ctx context.Context, match string, opt *BulkOption) (int64, error) {
+	return rc.bulk(ctx, match, opt, func(pipe redis.Pipeliner, key string) {
+		pipe.Unlink(ctx, key)
+	})
+}
+
+func (rc *redisClient) ExpireByPattern(ctx context.Context, match string, expiration time.Duration,
+	opt *BulkOption) (int64, error) {
+	return rc.bulk(ctx, match, opt, func(pipe redis.Pipeliner, key string) {
+		pipe.Expire(ctx, key, expiration)
+	})
+}
@@ -0,0 +1,97 @@
+package gdb
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestScan(t *testing.T) {
+	ctx := context.Background()
+	client, _, _ := newMemRedisClient(t)
+
+	for i := 0; i < 25; i++ {
+		if err := client.SetObject(ctx, "foo:"+strconv.Itoa(i), &Foo{F: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = client.HSetObjects(ctx, "hfoo", "f1", &Foo{F: 1}, "f2", &Foo{F: 2})
+	_, _ = client.ZAddObjects(ctx, "zfoo", 1, &Foo{F: 1}, 2, &Foo{F: 2})
+	_, _ = client.SAddObjects(ctx, "sfoo", &Foo{F: 1}, &Foo{F: 2})
+
+	seen := make(map[string]bool)
+	it := client.Scan(ctx, "foo:*", 7)
+	for it.Next(ctx) {
+		if seen[it.Key()] {
+			t.Fatalf("key %s scanned twice", it.Key())
+		}
+		seen[it.Key()] = true
+	}
+	if it.Err() != nil || len(seen) != 25 {
+		t.Fatalf("Scan = %d keys, %v", len(seen), it.Err())
+	}
+
+	var sum int
+	err := ScanObjects[Foo](ctx, client, "foo:*", 10, func(key string, obj *Foo) error {
+		sum += obj.F
+		return nil
+	})
+	if err != nil || sum != 300 {
+		t.Fatalf("ScanObjects = %d, %v", sum, err)
+	}
+
+	fields := make(map[string]int)
+	err = HScanObjects[Foo](ctx, client, "hfoo", "*", 1, func(field string, obj *Foo) error {
+		fields[field] = obj.F
+		return nil
+	})
+	if err != nil || fields["f1"] != 1 || fields["f2"] != 2 {
+		t.Fatalf("HScanObjects = %v, %v", fields, err)
+	}
+	var scores float64
+	err = ZScanObjects[Foo](ctx, client, "zfoo", "", 0, func(obj *Foo, score float64) error {
+		scores += score * float64(obj.F)
+		return nil
+	})
+	if err != nil || scores != 5 {
+		t.Fatalf("ZScanObjects = %v, %v", scores, err)
+	}
+	var members int
+	err = SScanObjects[Foo](ctx, client, "sfoo", "", 0, func(obj *Foo) error {
+		members++
+		return nil
+	})
+	if err != nil || members != 2 {
+		t.Fatalf("SScanObjects = %d, %v", members, err)
+	}
+}
+
+func TestBulkByPattern(t *testing.T) {
+	ctx := context.Background()
+	client, server, _ := newMemRedisClient(t)
+
+	for i := 0; i < 25; i++ {
+		_ = client.SetObject(ctx, "foo:"+strconv.Itoa(i), &Foo{F: i})
+		_ = client.SetObject(ctx, "bar:"+strconv.Itoa(i), &Bar{B: uint(i)})
+	}
+	n, err := client.ExpireByPattern(ctx, "bar:*", time.Minute, &BulkOption{BatchSize: 10})
+	if err != nil || n != 25 {
+		t.Fatalf("ExpireByPattern = %d, %v", n, err)
+	}
+	if ttl, _ := client.TTL(ctx, "bar:3"); ttl <= 0 {
+		t.Fatalf("ttl = %v", ttl)
+	}
+
+	start := time.Now()
+	n, err = client.DelByPattern(ctx, "foo:*", &BulkOption{BatchSize: 5, Rate: 500})
+	if err != nil || n != 25 {
+		t.Fatalf("DelByPattern = %d, %v", n, err)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Fatalf("DelByPattern not rate limited, elapsed %v", elapsed)
+	}
+	if keys := server.Keys(0); len(keys) != 25 {
+		t.Fatalf("keys after DelByPattern = %d", len(keys))
+	}
+}
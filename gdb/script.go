@@ -14,6 +14,8 @@ type Scripter interface {
 	ScriptFlush(ctx context.Context) (string, error)
 	ScriptKill(ctx context.Context) (string, error)
 	ScriptLoad(ctx context.Context, script string) (string, error)
+	// ScriptLoadAll load the script onto every master in cluster mode or every shard in ring mode
+	ScriptLoadAll(ctx context.Context, script string) (string, error)
 }
 
 type Script struct {
@@ -48,7 +50,7 @@ func (rc *redisClient) EvalSha(ctx context.Context, s *Script, keys []string, ar
 	if cmd.Err() == nil {
 		return cmd
 	}
-	if strings.HasPrefix(cmd.Err().Error(), "NOSCRIPT") {
+	if isNoScript(cmd.Err()) {
 		return rc.client.Eval(ctx, s.src, keys, args...)
 	}
 	return cmd
@@ -69,3 +71,22 @@ func (rc *redisClient) ScriptKill(ctx context.Context) (string, error) {
 func (rc *redisClient) ScriptLoad(ctx context.Context, script string) (string, error) {
 	return rc.client.ScriptLoad(ctx, script).Result()
 }
+
+func (rc *redisClient) ScriptLoadAll(ctx context.Context, script string) (string, error) {
+	nodes, err := rc.masters(ctx)
+	if err != nil {
+		return "", err
+	}
+	var hash string
+	for _, node := range nodes {
+		if hash, err = node.ScriptLoad(ctx, script).Result(); err != nil {
+			return "", err
+		}
+	}
+	return hash, nil
+}
+
+// isNoScript return whether the error is the NOSCRIPT reply of EVALSHA
+func isNoScript(err error) bool {
+	return err != nil && strings.HasPrefix(err.Error(), "NOSCRIPT")
+}
@@ -0,0 +1,138 @@
+package gdb
+
+import (
+	"context"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ScriptRegistry keep the named scripts of a client. The scripts are registered at startup and loaded onto
+// every master by Load, Run runs them by EVALSHA and loads them again if a master misses them, for example
+// after a failover or a SCRIPT FLUSH.
+//
+//	r := gdb.NewScriptRegistry(client)
+//	r.Register("incr_capped", incrCappedSrc)
+//	if err := r.Load(ctx); err != nil {
+//	}
+//	n, err := gdb.RunScript[int64](ctx, r, "incr_capped", []string{key}, 100)
+type ScriptRegistry struct {
+	client  RedisClient
+	mu      sync.RWMutex
+	scripts map[string]*Script
+}
+
+// NewScriptRegistry create an empty ScriptRegistry of the client
+func NewScriptRegistry(client RedisClient) *ScriptRegistry {
+	return &ScriptRegistry{client: client, scripts: make(map[string]*Script)}
+}
+
+// Register add the script of the name without loading it, it panics if the name is registered
+func (r *ScriptRegistry) Register(name string, src string) *Script {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.scripts[name]; ok {
+		panic(PanicScriptRegistered)
+	}
+	s := newStaticScript(src)
+	r.scripts[name] = s
+	return s
+}
+
+// Script return the script of the name, nil if it is not registered. It can be run in a pipeline by EvalSha.
+func (r *ScriptRegistry) Script(name string) *Script {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.scripts[name]
+}
+
+// Load load all the scripts onto every master
+func (r *ScriptRegistry) Load(ctx context.Context) error {
+	r.mu.RLock()
+	scripts := make([]*Script, 0, len(r.scripts))
+	for _, s := range r.scripts {
+		scripts = append(scripts, s)
+	}
+	r.mu.RUnlock()
+	for _, s := range scripts {
+		if _, err := r.client.ScriptLoadAll(ctx, s.src); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run run the script of the name by EVALSHA, on NOSCRIPT all the scripts are loaded again and it is retried once.
+// Unlike Scripter.EvalSha it does not fall back to EVAL, so the script source is not sent with every miss.
+func (r *ScriptRegistry) Run(ctx context.Context, name string, keys []string, args ...any) *redis.Cmd {
+	s := r.Script(name)
+	if s == nil {
+		return redis.NewCmdResult(nil, ErrScriptNotRegistered)
+	}
+	v, err := r.evalSha(ctx, s, keys, args)
+	if isNoScript(err) {
+		if err = r.Load(ctx); err != nil {
+			return redis.NewCmdResult(nil, err)
+		}
+		v, err = r.evalSha(ctx, s, keys, args)
+	}
+	return redis.NewCmdResult(v, err)
+}
+
+// evalSha run EVALSHA in a pipeline of one command, which does not fall back to EVAL
+func (r *ScriptRegistry) evalSha(ctx context.Context, s *Script, keys []string, args []any) (any, error) {
+	pipe := r.client.Pipeline()
+	f := pipe.EvalSha(ctx, s, keys, args...)
+	_, _ = pipe.Exec(ctx) // the error is kept by the future
+	return f.Result()
+}
+
+// RunScript run the script of the name by ScriptRegistry.Run and decode the result by ScriptResult
+func RunScript[T any](ctx context.Context, r *ScriptRegistry, name string, keys []string, args ...any) (T, error) {
+	return ScriptResult[T](r.Run(ctx, name, keys, args...).Result())
+}
+
+// ScriptResult decode the result of a script into T, it accepts the results of redis.Cmd and Future[any].
+// T can be string, int, int64, uint64, float64, bool, the slices of them except int, []any or any, the conversions are the
+// same as the methods of redis.Cmd, for example a lua string of digits is decoded into an int64.
+func ScriptResult[T any](v any, err error) (T, error) {
+	var t T
+	if err != nil {
+		return t, err
+	}
+	cmd := redis.NewCmdResult(v, nil)
+	var ret any
+	switch any(t).(type) {
+	case string:
+		ret, err = cmd.Text()
+	case int:
+		ret, err = cmd.Int()
+	case int64:
+		ret, err = cmd.Int64()
+	case uint64:
+		ret, err = cmd.Uint64()
+	case float64:
+		ret, err = cmd.Float64()
+	case bool:
+		ret, err = cmd.Bool()
+	case []string:
+		ret, err = cmd.StringSlice()
+	case []int64:
+		ret, err = cmd.Int64Slice()
+	case []uint64:
+		ret, err = cmd.Uint64Slice()
+	case []float64:
+		ret, err = cmd.Float64Slice()
+	case []bool:
+		ret, err = cmd.BoolSlice()
+	default:
+		ret = v
+	}
+	if err != nil {
+		return t, err
+	}
+	if t, ok := ret.(T); ok {
+		return t, nil
+	}
+	return t, ErrScriptResultType
+}
@@ -0,0 +1,69 @@
+package gdb
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+const testIncrScript = `return redis.call('INCRBY', KEYS[1], ARGV[1])`
+
+const testEchoScript = `return ARGV`
+
+func TestScriptRegistry(t *testing.T) {
+	ctx := context.Background()
+	client, _, _ := newMemRedisClient(t)
+	r := NewScriptRegistry(client)
+	r.Register("incr", testIncrScript)
+	echo := r.Register("echo", testEchoScript)
+	func() {
+		defer func() {
+			if recover() != PanicScriptRegistered {
+				t.Fatal("duplicate Register did not panic")
+			}
+		}()
+		r.Register("incr", testIncrScript)
+	}()
+	if err := r.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if exists, err := client.ScriptExists(ctx, echo.hash); err != nil || !exists[0] {
+		t.Fatalf("ScriptExists = %v, %v", exists, err)
+	}
+
+	if n, err := RunScript[int64](ctx, r, "incr", []string{"counter"}, 2); err != nil || n != 2 {
+		t.Fatalf("RunScript = %d, %v", n, err)
+	}
+	// the scripts are loaded again after a flush
+	if _, err := client.ScriptFlush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := RunScript[int](ctx, r, "incr", []string{"counter"}, 3); err != nil || n != 5 {
+		t.Fatalf("RunScript after flush = %d, %v", n, err)
+	}
+	if exists, _ := client.ScriptExists(ctx, echo.hash); !exists[0] {
+		t.Fatal("echo is not loaded again")
+	}
+	if _, err := RunScript[int64](ctx, r, "missing", nil); !errors.Is(err, ErrScriptNotRegistered) {
+		t.Fatalf("RunScript missing = %v", err)
+	}
+
+	pipe := client.Pipeline()
+	fs := make([]*Future[any], 3)
+	for i := range fs {
+		fs[i] = pipe.EvalSha(ctx, r.Script("echo"), nil, "a", strconv.Itoa(i))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for i, f := range fs {
+		if v, err := ScriptResult[[]string](f.Result()); err != nil || !reflect.DeepEqual(v, []string{"a", strconv.Itoa(i)}) {
+			t.Fatalf("ScriptResult %d = %v, %v", i, v, err)
+		}
+	}
+	if _, err := ScriptResult[map[string]string](fs[0].Result()); !errors.Is(err, ErrScriptResultType) {
+		t.Fatalf("ScriptResult map = %v", err)
+	}
+}
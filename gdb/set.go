@@ -0,0 +1,50 @@
+package gdb
+
+// Funcs handle the redis data type set
+
+import (
+	"context"
+)
+
+type Set interface {
+	SAdd(ctx context.Context, key string, members ...any) (int64, error)
+	SRem(ctx context.Context, key string, members ...any) (int64, error)
+	SMembers(ctx context.Context, key string) ([]string, error)
+	SIsMember(ctx context.Context, key string, member any) (bool, error)
+	SCard(ctx context.Context, key string) (int64, error)
+	SPop(ctx context.Context, key string) (string, error)
+	SPopN(ctx context.Context, key string, count int64) ([]string, error)
+	SRandMemberN(ctx context.Context, key string, count int64) ([]string, error)
+}
+
+func (rc *redisClient) SAdd(ctx context.Context, key string, members ...any) (int64, error) {
+	return rc.client.SAdd(ctx, key, members...).Result()
+}
+
+func (rc *redisClient) SRem(ctx context.Context, key string, members ...any) (int64, error) {
+	return rc.client.SRem(ctx, key, members...).Result()
+}
+
+func (rc *redisClient) SMembers(ctx context.Context, key string) ([]string, error) {
+	return rc.client.SMembers(ctx, key).Result()
+}
+
+func (rc *redisClient) SIsMember(ctx context.Context, key string, member any) (bool, error) {
+	return rc.client.SIsMember(ctx, key, member).Result()
+}
+
+func (rc *redisClient) SCard(ctx context.Context, key string) (int64, error) {
+	return rc.client.SCard(ctx, key).Result()
+}
+
+func (rc *redisClient) SPop(ctx context.Context, key string) (string, error) {
+	return rc.client.SPop(ctx, key).Result()
+}
+
+func (rc *redisClient) SPopN(ctx context.Context, key string, count int64) ([]string, error) {
+	return rc.client.SPopN(ctx, key, count).Result()
+}
+
+func (rc *redisClient) SRandMemberN(ctx context.Context, key string, count int64) ([]string, error) {
+	return rc.client.SRandMemberN(ctx, key, count).Result()
+}
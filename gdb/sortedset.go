@@ -28,21 +28,34 @@ type SortedSet interface {
 	ZScore(ctx context.Context, key string, member any) (float64, error)
 }
 
-func (rc *redisClient) ZAdd(ctx context.Context, key string, values ...any) (int64, error) {
+// zMembers convert the score and member pairs into redis.Z, the members are converted by member
+func zMembers(values []any, member func(v any) (any, error)) ([]*redis.Z, error) {
 	if len(values)%2 != 0 {
 		panic(PanicScoreValueCountUnmatched)
 	}
-	var members = make([]*redis.Z, 0, len(values))
+	var members = make([]*redis.Z, 0, len(values)/2)
 	for i := 0; i < len(values); i += 2 {
 		s, err := toFloat64(values[i])
 		if err != nil {
-			return 0, err
+			return nil, err
+		}
+		m, err := member(values[i+1])
+		if err != nil {
+			return nil, err
 		}
 		members = append(members, &redis.Z{
 			Score:  s,
-			Member: values[i+1],
+			Member: m,
 		})
 	}
+	return members, nil
+}
+
+func (rc *redisClient) ZAdd(ctx context.Context, key string, values ...any) (int64, error) {
+	members, err := zMembers(values, func(v any) (any, error) { return v, nil })
+	if err != nil {
+		return 0, err
+	}
 	return rc.client.ZAdd(ctx, key, members...).Result()
 }
 
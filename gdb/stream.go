@@ -0,0 +1,86 @@
+package gdb
+
+// Funcs handle the redis data type stream
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+type Stream interface {
+	XAdd(ctx context.Context, a *redis.XAddArgs) (string, error)
+	XLen(ctx context.Context, stream string) (int64, error)
+	XRange(ctx context.Context, stream, start, stop string) ([]redis.XMessage, error)
+	XRangeN(ctx context.Context, stream, start, stop string, count int64) ([]redis.XMessage, error)
+	XDel(ctx context.Context, stream string, ids ...string) (int64, error)
+	XTrimMaxLen(ctx context.Context, stream string, maxLen int64) (int64, error)
+	// XGroupCreate create the consumer group, the stream is created if not exists.
+	// It returns nil if the group already exists.
+	XGroupCreate(ctx context.Context, stream, group, start string) error
+	XGroupDestroy(ctx context.Context, stream, group string) (int64, error)
+	// XReadGroup read messages as a consumer of the group, Block 0 means blocking forever in go-redis,
+	// set Block to a negative value to read without blocking. It returns redis.Nil when no message.
+	XReadGroup(ctx context.Context, a *redis.XReadGroupArgs) ([]redis.XStream, error)
+	XAck(ctx context.Context, stream, group string, ids ...string) (int64, error)
+	XPending(ctx context.Context, stream, group string) (*redis.XPending, error)
+	XPendingExt(ctx context.Context, a *redis.XPendingExtArgs) ([]redis.XPendingExt, error)
+	XClaim(ctx context.Context, a *redis.XClaimArgs) ([]redis.XMessage, error)
+}
+
+func (rc *redisClient) XAdd(ctx context.Context, a *redis.XAddArgs) (string, error) {
+	return rc.client.XAdd(ctx, a).Result()
+}
+
+func (rc *redisClient) XLen(ctx context.Context, stream string) (int64, error) {
+	return rc.client.XLen(ctx, stream).Result()
+}
+
+func (rc *redisClient) XRange(ctx context.Context, stream, start, stop string) ([]redis.XMessage, error) {
+	return rc.client.XRange(ctx, stream, start, stop).Result()
+}
+
+func (rc *redisClient) XRangeN(ctx context.Context, stream, start, stop string, count int64) ([]redis.XMessage, error) {
+	return rc.client.XRangeN(ctx, stream, start, stop, count).Result()
+}
+
+func (rc *redisClient) XDel(ctx context.Context, stream string, ids ...string) (int64, error) {
+	return rc.client.XDel(ctx, stream, ids...).Result()
+}
+
+func (rc *redisClient) XTrimMaxLen(ctx context.Context, stream string, maxLen int64) (int64, error) {
+	return rc.client.XTrimMaxLen(ctx, stream, maxLen).Result()
+}
+
+func (rc *redisClient) XGroupCreate(ctx context.Context, stream, group, start string) error {
+	err := rc.client.XGroupCreateMkStream(ctx, stream, group, start).Err()
+	if err != nil && strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return nil
+	}
+	return err
+}
+
+func (rc *redisClient) XGroupDestroy(ctx context.Context, stream, group string) (int64, error) {
+	return rc.client.XGroupDestroy(ctx, stream, group).Result()
+}
+
+func (rc *redisClient) XReadGroup(ctx context.Context, a *redis.XReadGroupArgs) ([]redis.XStream, error) {
+	return rc.client.XReadGroup(ctx, a).Result()
+}
+
+func (rc *redisClient) XAck(ctx context.Context, stream, group string, ids ...string) (int64, error) {
+	return rc.client.XAck(ctx, stream, group, ids...).Result()
+}
+
+func (rc *redisClient) XPending(ctx context.Context, stream, group string) (*redis.XPending, error) {
+	return rc.client.XPending(ctx, stream, group).Result()
+}
+
+func (rc *redisClient) XPendingExt(ctx context.Context, a *redis.XPendingExtArgs) ([]redis.XPendingExt, error) {
+	return rc.client.XPendingExt(ctx, a).Result()
+}
+
+func (rc *redisClient) XClaim(ctx context.Context, a *redis.XClaimArgs) ([]redis.XMessage, error) {
+	return rc.client.XClaim(ctx, a).Result()
+}
@@ -0,0 +1,105 @@
+package gdb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+func TestListSetObjects(t *testing.T) {
+	client, _, _ := newMemRedisClient(t)
+	ctx := context.Background()
+
+	if _, err := client.RPushObjects(ctx, "queue", &Foo{F: 1}, &Foo{F: 2}); err != nil {
+		t.Fatalf("rpushobjects failed: %v", err)
+	}
+	var foos []*Foo
+	if err := client.LRangeObjects(ctx, "queue", 0, -1, &foos); err != nil || len(foos) != 2 || foos[1].F != 2 {
+		t.Fatalf("lrangeobjects = %v, %v", foos, err)
+	}
+	var foo Foo
+	if err := client.BLPopObject(ctx, time.Second, "queue", &foo); err != nil || foo.F != 1 {
+		t.Fatalf("blpopobject = %v, %v", foo, err)
+	}
+	if err := client.RPopObject(ctx, "queue", &foo); err != nil || foo.F != 2 {
+		t.Fatalf("rpopobject = %v, %v", foo, err)
+	}
+	if err := client.BLPopObject(ctx, time.Second, "queue", &foo); !client.IsErrNil(err) {
+		t.Fatalf("blpopobject on empty list should return redis.Nil, got %v", err)
+	}
+
+	if _, err := client.SAddObjects(ctx, "members", Foo{F: 1}, Foo{F: 2}, Foo{F: 1}); err != nil {
+		t.Fatalf("saddobjects failed: %v", err)
+	}
+	ok, err := client.SIsMemberObject(ctx, "members", Foo{F: 2})
+	if err != nil || !ok {
+		t.Fatalf("sismemberobject = %v, %v", ok, err)
+	}
+	var members []Foo
+	if err = client.SMembersObjects(ctx, "members", &members); err != nil || len(members) != 2 {
+		t.Fatalf("smembersobjects = %v, %v", members, err)
+	}
+}
+
+func TestBLPopWakeUp(t *testing.T) {
+	client, _, _ := newMemRedisClient(t)
+	ctx := context.Background()
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		_, _ = client.LPush(ctx, "wake", "v")
+	}()
+	rets, err := client.BLPop(ctx, time.Second, "wake")
+	if err != nil || len(rets) != 2 || rets[1] != "v" {
+		t.Fatalf("blpop = %v, %v", rets, err)
+	}
+}
+
+func TestStreamObjects(t *testing.T) {
+	client, _, clock := newMemRedisClient(t)
+	ctx := context.Background()
+
+	if err := client.XGroupCreate(ctx, "events", "g1", "0"); err != nil {
+		t.Fatalf("xgroupcreate failed: %v", err)
+	}
+	if err := client.XGroupCreate(ctx, "events", "g1", "0"); err != nil {
+		t.Fatalf("xgroupcreate on existing group should succeed, got %v", err)
+	}
+	id, err := client.XAddObject(ctx, "events", &Foo{F: 7})
+	if err != nil {
+		t.Fatalf("xaddobject failed: %v", err)
+	}
+
+	streams, err := client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group: "g1", Consumer: "c1", Streams: []string{"events", ">"}, Count: 10, Block: -1,
+	})
+	if err != nil || len(streams) != 1 || len(streams[0].Messages) != 1 {
+		t.Fatalf("xreadgroup = %v, %v", streams, err)
+	}
+	var foo Foo
+	if err = client.XMessageObject(streams[0].Messages[0], &foo); err != nil || foo.F != 7 {
+		t.Fatalf("xmessageobject = %v, %v", foo, err)
+	}
+
+	clock.Add(time.Minute)
+	pending, err := client.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: "events", Group: "g1", Start: "-", End: "+", Count: 10,
+	})
+	if err != nil || len(pending) != 1 || pending[0].ID != id || pending[0].Idle < time.Minute {
+		t.Fatalf("xpendingext = %v, %v", pending, err)
+	}
+	if n, err := client.XAck(ctx, "events", "g1", id); err != nil || n != 1 {
+		t.Fatalf("xack = %d, %v", n, err)
+	}
+	summary, err := client.XPending(ctx, "events", "g1")
+	if err != nil || summary.Count != 0 {
+		t.Fatalf("xpending = %v, %v", summary, err)
+	}
+	if _, err = client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group: "g1", Consumer: "c1", Streams: []string{"events", ">"}, Block: -1,
+	}); !client.IsErrNil(err) {
+		t.Fatalf("xreadgroup without new message should return redis.Nil, got %v", err)
+	}
+}
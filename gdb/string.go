@@ -17,6 +17,8 @@ type String interface {
 	Get(ctx context.Context, key string) (string, error)
 	Incr(ctx context.Context, key string) (int64, error)
 	IncrBy(ctx context.Context, key string, value int64) (int64, error)
+	// BatchGet get the keys in pipelines, in cluster mode a pipeline for every node, the cmds are in the order of
+	// the keys. With RedisClientOption.BatchPartial the cmds are returned along with a *BatchError.
 	BatchGet(ctx context.Context, keys []string) ([]*redis.StringCmd, error)
 	BatchSet(ctx context.Context, keys []string, values []any, expiration time.Duration) error
 	BatchDel(ctx context.Context, keys []string) error
@@ -47,44 +49,29 @@ func (rc *redisClient) IncrBy(ctx context.Context, key string, value int64) (int
 }
 
 func (rc *redisClient) BatchGet(ctx context.Context, keys []string) ([]*redis.StringCmd, error) {
-	rets, err := rc.client.Pipelined(ctx, func(pipeliner redis.Pipeliner) error {
-		for _, key := range keys {
-			pipeliner.Get(ctx, key)
-		}
-		return nil
+	cmds := make([]*redis.StringCmd, len(keys))
+	err := rc.batch(ctx, keys, func(pipeliner redis.Pipeliner, i int) redis.Cmder {
+		cmds[i] = pipeliner.Get(ctx, keys[i])
+		return cmds[i]
 	})
-	if err != nil && !errors.Is(err, redis.Nil) {
+	var batchErr *BatchError
+	if err != nil && !errors.As(err, &batchErr) {
 		return nil, err
 	}
-	cmds := make([]*redis.StringCmd, len(rets))
-	for i, v := range rets {
-		cmds[i] = v.(*redis.StringCmd)
-	}
-	return cmds, nil
+	return cmds, err
 }
 
 func (rc *redisClient) BatchSet(ctx context.Context, keys []string, values []any, expiration time.Duration) error {
 	if len(keys) != len(values) {
 		panic(PanicKeyValueCountUnmatched)
 	}
-	_, err := rc.client.Pipelined(ctx, func(pipeliner redis.Pipeliner) error {
-		for i, key := range keys {
-			pipeliner.Set(ctx, key, values[i], expiration)
-		}
-		return nil
+	return rc.batch(ctx, keys, func(pipeliner redis.Pipeliner, i int) redis.Cmder {
+		return pipeliner.Set(ctx, keys[i], values[i], expiration)
 	})
-	return err
 }
 
 func (rc *redisClient) BatchDel(ctx context.Context, keys []string) error {
-	if len(keys) == 0 {
-		return nil
-	}
-	_, err := rc.client.Pipelined(ctx, func(pipeliner redis.Pipeliner) error {
-		for _, key := range keys {
-			pipeliner.Del(ctx, key)
-		}
-		return nil
+	return rc.batch(ctx, keys, func(pipeliner redis.Pipeliner, i int) redis.Cmder {
+		return pipeliner.Del(ctx, keys[i])
 	})
-	return err
 }
@@ -0,0 +1,516 @@
+package gdb
+
+// Generic type-safe object accessors, the object type is checked at compile time,
+// and values read from redis are unmarshalled without reflection. The objects of a DB whose ObjectDB is not a
+// RedisClient, for example a document db, are read through the object methods of the ObjectDB.
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/go-redis/redis/v8"
+	gmarshaller "github.com/oldjon/gutil/marshaller"
+)
+
+// ObjPtr is the constraint of the pointer to the object type T,
+// so callers only need to provide T, e.g. gdb.Get[Player](ctx, client, key) returns *Player.
+type ObjPtr[T any] interface {
+	*T
+}
+
+func unmarshalObj[T any, PT ObjPtr[T]](m gmarshaller.Marshaller, data string) (PT, error) {
+	obj := PT(new(T))
+	if err := m.Unmarshal([]byte(data), obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func unmarshalObjs[T any, PT ObjPtr[T]](m gmarshaller.Marshaller, datas []string) ([]PT, error) {
+	objs := make([]PT, len(datas))
+	for i, data := range datas {
+		obj, err := unmarshalObj[T, PT](m, data)
+		if err != nil {
+			return nil, err
+		}
+		objs[i] = obj
+	}
+	return objs, nil
+}
+
+func unmarshalZObjs[T any, PT ObjPtr[T]](m gmarshaller.Marshaller, zs []redis.Z) ([]PT, []float64, error) {
+	objs := make([]PT, len(zs))
+	scores := make([]float64, len(zs))
+	for i, z := range zs {
+		data, ok := z.Member.(string)
+		if !ok {
+			return nil, nil, ErrValueType
+		}
+		obj, err := unmarshalObj[T, PT](m, data)
+		if err != nil {
+			return nil, nil, err
+		}
+		objs[i] = obj
+		scores[i] = z.Score
+	}
+	return objs, scores, nil
+}
+
+// objectSource return the RedisClient the objects of c are read from, or the ObjectDB of c if c is a DB whose
+// ObjectDB is not a RedisClient
+func objectSource(c RedisClient) (RedisClient, ObjectDB) {
+	if db, ok := c.(*DB); ok {
+		if rc, ok := db.ObjectDB.(RedisClient); ok {
+			return rc, nil
+		}
+		return nil, db.ObjectDB
+	}
+	return c, nil
+}
+
+// readObjects read the objects into a new slice by the object method of an ObjectDB
+func readObjects[T any, PT ObjPtr[T]](read func(objs any) error) ([]PT, error) {
+	var objs []PT
+	if err := read(&objs); err != nil {
+		return nil, err
+	}
+	return objs, nil
+}
+
+// readZObjects read the objects and their scores into a new slice by the object method of an ObjectDB
+func readZObjects[T any, PT ObjPtr[T]](read func(objs any) ([]float64, error)) ([]PT, []float64, error) {
+	var objs []PT
+	scores, err := read(&objs)
+	if err != nil {
+		return nil, nil, err
+	}
+	return objs, scores, nil
+}
+
+// Get get data from db of the key, and unmarshal into a new object.
+func Get[T any, PT ObjPtr[T]](ctx context.Context, c RedisClient, key string) (PT, error) {
+	rc, odb := objectSource(c)
+	if odb != nil {
+		obj := PT(new(T))
+		if err := odb.GetObject(ctx, key, obj); err != nil {
+			return nil, err
+		}
+		return obj, nil
+	}
+	m, err := rc.ObjMarshallerOf(key, PT(nil))
+	if err != nil {
+		return nil, err
+	}
+	data, err := rc.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalObj[T, PT](m, data)
+}
+
+// MGet get datas from db of all keys, the object of a missing key is nil.
+func MGet[T any, PT ObjPtr[T]](ctx context.Context, c RedisClient, keys []string) ([]PT, error) {
+	rc, odb := objectSource(c)
+	if odb != nil {
+		objs := make([]PT, len(keys))
+		if len(keys) == 0 {
+			return objs, nil
+		}
+		if err := odb.GetObjects(ctx, keys, objs); err != nil {
+			return nil, err
+		}
+		return objs, nil
+	}
+	ms := make([]gmarshaller.Marshaller, len(keys))
+	for i, key := range keys {
+		var err error
+		if ms[i], err = rc.ObjMarshallerOf(key, PT(nil)); err != nil {
+			return nil, err
+		}
+	}
+	cmds, err := rc.BatchGet(ctx, keys)
+	if cmds == nil {
+		return nil, err
+	}
+	objs := make([]PT, len(cmds))
+	for i, cmd := range cmds {
+		if rc.IsErrNil(cmd.Err()) {
+			continue
+		} else if cmd.Err() != nil {
+			if err != nil { // the objects of the failed keys are nil with a *BatchError
+				continue
+			}
+			return nil, cmd.Err()
+		}
+		var uErr error
+		if objs[i], uErr = unmarshalObj[T, PT](ms[i], cmd.Val()); uErr != nil {
+			return nil, uErr
+		}
+	}
+	return objs, err
+}
+
+// HGet get data from db with the key and the field, and unmarshal into a new object.
+func HGet[T any, PT ObjPtr[T]](ctx context.Context, c RedisClient, key, field string) (PT, error) {
+	rc, odb := objectSource(c)
+	if odb != nil {
+		obj := PT(new(T))
+		if err := odb.HGetObject(ctx, key, field, obj); err != nil {
+			return nil, err
+		}
+		return obj, nil
+	}
+	m, err := rc.ObjMarshallerOf(key, PT(nil))
+	if err != nil {
+		return nil, err
+	}
+	data, err := rc.HGet(ctx, key, field)
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalObj[T, PT](m, data)
+}
+
+// HMGet get datas from db with the key and the fields, the object of a missing field is nil.
+func HMGet[T any, PT ObjPtr[T]](ctx context.Context, c RedisClient, key string, fields ...string) ([]PT, error) {
+	rc, odb := objectSource(c)
+	if odb != nil {
+		objs := make([]PT, len(fields))
+		if err := odb.HMGetObjects(ctx, key, fields, objs); err != nil {
+			return nil, err
+		}
+		return objs, nil
+	}
+	m, err := rc.ObjMarshallerOf(key, PT(nil))
+	if err != nil {
+		return nil, err
+	}
+	rets, err := rc.HMGet(ctx, key, fields...)
+	if err != nil {
+		return nil, err
+	}
+	objs := make([]PT, len(rets))
+	for i, v := range rets {
+		data, ok := v.(string)
+		if !ok || data == "" {
+			continue
+		}
+		objs[i], err = unmarshalObj[T, PT](m, data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return objs, nil
+}
+
+// HGetAll get all fields and objects from db with the key.
+func HGetAll[T any, PT ObjPtr[T]](ctx context.Context, c RedisClient, key string) (map[string]PT, error) {
+	rc, odb := objectSource(c)
+	if odb != nil {
+		var fields []string
+		var objs []PT
+		if err := odb.HGetAllObjects(ctx, key, &fields, &objs); err != nil {
+			return nil, err
+		}
+		rets := make(map[string]PT, len(fields))
+		for i, field := range fields {
+			rets[field] = objs[i]
+		}
+		return rets, nil
+	}
+	m, err := rc.ObjMarshallerOf(key, PT(nil))
+	if err != nil {
+		return nil, err
+	}
+	rets, err := rc.HGetAll(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	objs := make(map[string]PT, len(rets))
+	for field, data := range rets {
+		obj, err := unmarshalObj[T, PT](m, data)
+		if err != nil {
+			return nil, err
+		}
+		objs[field] = obj
+	}
+	return objs, nil
+}
+
+// ZRange ZRange members from zset of the key, and unmarshall members into objects.
+func ZRange[T any, PT ObjPtr[T]](ctx context.Context, c RedisClient, key string, start, stop int64) ([]PT, error) {
+	rc, odb := objectSource(c)
+	if odb != nil {
+		return readObjects[T, PT](func(objs any) error { return odb.ZRangeObjects(ctx, key, start, stop, objs) })
+	}
+	m, err := rc.ObjMarshallerOf(key, PT(nil))
+	if err != nil {
+		return nil, err
+	}
+	rets, err := rc.ZRange(ctx, key, start, stop)
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalObjs[T, PT](m, rets)
+}
+
+// ZRevRange ZRevRange members from zset of the key, and unmarshall members into objects.
+func ZRevRange[T any, PT ObjPtr[T]](ctx context.Context, c RedisClient, key string, start, stop int64) ([]PT, error) {
+	rc, odb := objectSource(c)
+	if odb != nil {
+		return readObjects[T, PT](func(objs any) error { return odb.ZRevRangeObjects(ctx, key, start, stop, objs) })
+	}
+	m, err := rc.ObjMarshallerOf(key, PT(nil))
+	if err != nil {
+		return nil, err
+	}
+	rets, err := rc.ZRevRange(ctx, key, start, stop)
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalObjs[T, PT](m, rets)
+}
+
+// ZRangeByScore ZRangeByScore members from zset of the key, and unmarshall members into objects.
+func ZRangeByScore[T any, PT ObjPtr[T]](ctx context.Context, c RedisClient, key string, min, max string) ([]PT, error) {
+	rc, odb := objectSource(c)
+	if odb != nil {
+		return readObjects[T, PT](func(objs any) error { return odb.ZRangeObjectsByScore(ctx, key, min, max, objs) })
+	}
+	m, err := rc.ObjMarshallerOf(key, PT(nil))
+	if err != nil {
+		return nil, err
+	}
+	rets, err := rc.ZRangeByScore(ctx, key, min, max)
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalObjs[T, PT](m, rets)
+}
+
+// ZRevRangeByScore ZRevRangeByScore members from zset of the key, and unmarshall members into objects.
+func ZRevRangeByScore[T any, PT ObjPtr[T]](ctx context.Context, c RedisClient, key string, min, max string) ([]PT, error) {
+	rc, odb := objectSource(c)
+	if odb != nil {
+		return readObjects[T, PT](func(objs any) error { return odb.ZRevRangeObjectsByScore(ctx, key, min, max, objs) })
+	}
+	m, err := rc.ObjMarshallerOf(key, PT(nil))
+	if err != nil {
+		return nil, err
+	}
+	rets, err := rc.ZRevRangeByScore(ctx, key, min, max)
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalObjs[T, PT](m, rets)
+}
+
+// ZRangeWithScores ZRange members with scores from zset of the key, and unmarshall members into objects.
+func ZRangeWithScores[T any, PT ObjPtr[T]](ctx context.Context, c RedisClient, key string, start, stop int64) (
+	[]PT, []float64, error) {
+	rc, odb := objectSource(c)
+	if odb != nil {
+		return readZObjects[T, PT](func(objs any) ([]float64, error) {
+			return odb.ZRangeObjectsWithScores(ctx, key, start, stop, objs)
+		})
+	}
+	m, err := rc.ObjMarshallerOf(key, PT(nil))
+	if err != nil {
+		return nil, nil, err
+	}
+	rets, err := rc.ZRangeWithScores(ctx, key, start, stop)
+	if err != nil {
+		return nil, nil, err
+	}
+	return unmarshalZObjs[T, PT](m, rets)
+}
+
+// ZRevRangeWithScores ZRevRange members with scores from zset of the key, and unmarshall members into objects.
+func ZRevRangeWithScores[T any, PT ObjPtr[T]](ctx context.Context, c RedisClient, key string, start, stop int64) (
+	[]PT, []float64, error) {
+	rc, odb := objectSource(c)
+	if odb != nil {
+		return readZObjects[T, PT](func(objs any) ([]float64, error) {
+			return odb.ZRevRangeObjectsWithScores(ctx, key, start, stop, objs)
+		})
+	}
+	m, err := rc.ObjMarshallerOf(key, PT(nil))
+	if err != nil {
+		return nil, nil, err
+	}
+	rets, err := rc.ZRevRangeWithScores(ctx, key, start, stop)
+	if err != nil {
+		return nil, nil, err
+	}
+	return unmarshalZObjs[T, PT](m, rets)
+}
+
+// LRange LRange values from list of the key, and unmarshall values into objects.
+func LRange[T any, PT ObjPtr[T]](ctx context.Context, c RedisClient, key string, start, stop int64) ([]PT, error) {
+	rc, odb := objectSource(c)
+	if odb != nil {
+		return readObjects[T, PT](func(objs any) error { return odb.LRangeObjects(ctx, key, start, stop, objs) })
+	}
+	m, err := rc.ObjMarshallerOf(key, PT(nil))
+	if err != nil {
+		return nil, err
+	}
+	rets, err := rc.LRange(ctx, key, start, stop)
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalObjs[T, PT](m, rets)
+}
+
+// SMembers get all members from set of the key, and unmarshall members into objects.
+func SMembers[T any, PT ObjPtr[T]](ctx context.Context, c RedisClient, key string) ([]PT, error) {
+	rc, odb := objectSource(c)
+	if odb != nil {
+		return readObjects[T, PT](func(objs any) error { return odb.SMembersObjects(ctx, key, objs) })
+	}
+	m, err := rc.ObjMarshallerOf(key, PT(nil))
+	if err != nil {
+		return nil, err
+	}
+	rets, err := rc.SMembers(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalObjs[T, PT](m, rets)
+}
+
+// ScanObjects iterate the string keys matching the pattern, and call fn with every key and its object.
+// The keys are read in batches of count, keys deleted during the iteration are skipped.
+// The scans return ErrObjectDBNotRedis for a DB whose ObjectDB is not a RedisClient.
+func ScanObjects[T any, PT ObjPtr[T]](ctx context.Context, c RedisClient, match string, count int64,
+	fn func(key string, obj PT) error) error {
+	rc, odb := objectSource(c)
+	if odb != nil {
+		return ErrObjectDBNotRedis
+	}
+	if count <= 0 {
+		count = defaultBulkBatchSize
+	}
+	flush := func(keys []string) error {
+		objs, err := MGet[T, PT](ctx, rc, keys)
+		if err != nil {
+			return err
+		}
+		for i, obj := range objs {
+			if obj == nil {
+				continue
+			}
+			if err = fn(keys[i], obj); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	it := rc.Scan(ctx, match, count)
+	keys := make([]string, 0, count)
+	for it.Next(ctx) {
+		keys = append(keys, it.Key())
+		if int64(len(keys)) < count {
+			continue
+		}
+		if err := flush(keys); err != nil {
+			return err
+		}
+		keys = keys[:0]
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+	if len(keys) > 0 {
+		return flush(keys)
+	}
+	return nil
+}
+
+// HScanObjects iterate the fields of the hash of the key, and call fn with every field and its object.
+func HScanObjects[T any, PT ObjPtr[T]](ctx context.Context, c RedisClient, key string, match string, count int64,
+	fn func(field string, obj PT) error) error {
+	rc, odb := objectSource(c)
+	if odb != nil {
+		return ErrObjectDBNotRedis
+	}
+	m, err := rc.ObjMarshallerOf(key, PT(nil))
+	if err != nil {
+		return err
+	}
+	it := rc.HScan(ctx, key, match, count)
+	for it.Next(ctx) {
+		obj, err := unmarshalObj[T, PT](m, it.Val())
+		if err != nil {
+			return err
+		}
+		if err = fn(it.Key(), obj); err != nil {
+			return err
+		}
+	}
+	return it.Err()
+}
+
+// SScanObjects iterate the members of the set of the key, and call fn with every member object.
+// The match pattern applies to the marshalled members.
+func SScanObjects[T any, PT ObjPtr[T]](ctx context.Context, c RedisClient, key string, match string, count int64,
+	fn func(obj PT) error) error {
+	rc, odb := objectSource(c)
+	if odb != nil {
+		return ErrObjectDBNotRedis
+	}
+	m, err := rc.ObjMarshallerOf(key, PT(nil))
+	if err != nil {
+		return err
+	}
+	it := rc.SScan(ctx, key, match, count)
+	for it.Next(ctx) {
+		obj, err := unmarshalObj[T, PT](m, it.Key())
+		if err != nil {
+			return err
+		}
+		if err = fn(obj); err != nil {
+			return err
+		}
+	}
+	return it.Err()
+}
+
+// ZScanObjects iterate the members of the zset of the key, and call fn with every member object and its score.
+// The match pattern applies to the marshalled members.
+func ZScanObjects[T any, PT ObjPtr[T]](ctx context.Context, c RedisClient, key string, match string, count int64,
+	fn func(obj PT, score float64) error) error {
+	rc, odb := objectSource(c)
+	if odb != nil {
+		return ErrObjectDBNotRedis
+	}
+	m, err := rc.ObjMarshallerOf(key, PT(nil))
+	if err != nil {
+		return err
+	}
+	it := rc.ZScan(ctx, key, match, count)
+	for it.Next(ctx) {
+		obj, err := unmarshalObj[T, PT](m, it.Key())
+		if err != nil {
+			return err
+		}
+		score, err := strconv.ParseFloat(it.Val(), 64)
+		if err != nil {
+			return err
+		}
+		if err = fn(obj, score); err != nil {
+			return err
+		}
+	}
+	return it.Err()
+}
+
+// MessageObject unmarshal the payload of the message published by PublishObject into a new object.
+func MessageObject[T any, PT ObjPtr[T]](m *Message) (PT, error) {
+	obj := PT(new(T))
+	if err := m.Object(obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
@@ -0,0 +1,116 @@
+package gdb
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestTypedAccessors(t *testing.T) {
+	client, _, _ := newMemRedisClient(t)
+	ctx := context.Background()
+	client.SetKOMapping(map[string]string{"foo": "gdb.Foo"})
+
+	if err := client.SetObject(ctx, "foo:1", &Foo{F: 1}); err != nil {
+		t.Fatalf("setobject failed: %v", err)
+	}
+	foo, err := Get[Foo](ctx, client, "foo:1")
+	if err != nil || foo.F != 1 {
+		t.Fatalf("get = %v, %v", foo, err)
+	}
+	foos, err := MGet[Foo](ctx, client, []string{"foo:1", "foo:2"})
+	if err != nil || len(foos) != 2 || foos[0].F != 1 || foos[1] != nil {
+		t.Fatalf("mget = %v, %v", foos, err)
+	}
+
+	if err = client.HSetObjects(ctx, "bars", "b1", &Bar{B: 1}, "b2", &Bar{B: 2}); err != nil {
+		t.Fatalf("hsetobjects failed: %v", err)
+	}
+	bars, err := HGetAll[Bar](ctx, client, "bars")
+	if err != nil || len(bars) != 2 || bars["b2"].B != 2 {
+		t.Fatalf("hgetall = %v, %v", bars, err)
+	}
+	barList, err := HMGet[Bar](ctx, client, "bars", "b1", "b3")
+	if err != nil || barList[0].B != 1 || barList[1] != nil {
+		t.Fatalf("hmget = %v, %v", barList, err)
+	}
+
+	if _, err = client.ZAddObjects(ctx, "rank", 10, &Foo{F: 10}, 20, &Foo{F: 20}); err != nil {
+		t.Fatalf("zaddobjects failed: %v", err)
+	}
+	ranked, scores, err := ZRevRangeWithScores[Foo](ctx, client, "rank", 0, -1)
+	if err != nil || len(ranked) != 2 || ranked[0].F != 20 || scores[1] != 10 {
+		t.Fatalf("zrevrangewithscores = %v, %v, %v", ranked, scores, err)
+	}
+
+	if _, err = Get[Bar](ctx, client, "foo:1"); !errors.Is(err, ErrKeyObjMismatch) {
+		t.Fatalf("get with mismatched type = %v", err)
+	}
+}
+
+func TestTypedAccessorsObjectDB(t *testing.T) {
+	client, _, _ := newMemRedisClient(t)
+	d, _ := newDocObjectDB()
+	db := NewDBWithObjectDB(d, client, nil)
+	ctx := context.Background()
+
+	// the objects are read from the document db, not the redis of the db
+	if err := client.Set(ctx, "foo:1", `{"F":-1}`); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetObject(ctx, "foo:1", &Foo{F: 1}); err != nil {
+		t.Fatal(err)
+	}
+	foo, err := Get[Foo](ctx, db, "foo:1")
+	if err != nil || foo.F != 1 {
+		t.Fatalf("get = %v, %v", foo, err)
+	}
+	if _, err = Get[Foo](ctx, db, "foo:2"); !db.IsErrNil(err) {
+		t.Fatalf("get missing = %v", err)
+	}
+	foos, err := MGet[Foo](ctx, db, []string{"foo:1", "foo:2"})
+	if err != nil || len(foos) != 2 || foos[0].F != 1 || foos[1] != nil {
+		t.Fatalf("mget = %v, %v", foos, err)
+	}
+
+	if err = db.HSetObjects(ctx, "bars", "b1", &Bar{B: 1}, "b2", &Bar{B: 2}); err != nil {
+		t.Fatal(err)
+	}
+	bars, err := HGetAll[Bar](ctx, db, "bars")
+	if err != nil || len(bars) != 2 || bars["b2"].B != 2 {
+		t.Fatalf("hgetall = %v, %v", bars, err)
+	}
+	barList, err := HMGet[Bar](ctx, db, "bars", "b1", "b3")
+	if err != nil || barList[0].B != 1 || barList[1] != nil {
+		t.Fatalf("hmget = %v, %v", barList, err)
+	}
+
+	if _, err = db.ZAddObjects(ctx, "rank", 10, &Foo{F: 10}, 20, &Foo{F: 20}); err != nil {
+		t.Fatal(err)
+	}
+	ranked, scores, err := ZRevRangeWithScores[Foo](ctx, db, "rank", 0, -1)
+	if err != nil || len(ranked) != 2 || ranked[0].F != 20 || scores[1] != 10 {
+		t.Fatalf("zrevrangewithscores = %v, %v, %v", ranked, scores, err)
+	}
+	if ranked, err = ZRangeByScore[Foo](ctx, db, "rank", "15", "+inf"); err != nil || len(ranked) != 1 ||
+		ranked[0].F != 20 {
+		t.Fatalf("zrangebyscore = %v, %v", ranked, err)
+	}
+	if _, err = db.RPushObjects(ctx, "list", &Foo{F: 1}, &Foo{F: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if foos, err = LRange[Foo](ctx, db, "list", 0, -1); err != nil || len(foos) != 2 || foos[1].F != 2 {
+		t.Fatalf("lrange = %v, %v", foos, err)
+	}
+
+	// the scans can not read the objects from redis
+	err = ScanObjects[Foo](ctx, db, "*", 0, func(string, *Foo) error { return nil })
+	if !errors.Is(err, ErrObjectDBNotRedis) {
+		t.Fatalf("scanobjects = %v", err)
+	}
+
+	// a DB of redis objects is read from redis
+	if foo, err = Get[Foo](ctx, NewDB(client, nil), "foo:1"); err != nil || foo.F != -1 {
+		t.Fatalf("get redis db = %v, %v", foo, err)
+	}
+}
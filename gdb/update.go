@@ -0,0 +1,184 @@
+package gdb
+
+// Funcs handle the concurrent updates of objects, by WATCH/MULTI/EXEC or by a versioned compare-and-set script
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// UpdateObjectRetries is the max attempts of UpdateObject and UpdateVersionedObject when the object is modified by others
+const UpdateObjectRetries = 10
+
+// UpdateFunc modify the object read from db, the object is zero if the key does not exist.
+// Returning an error aborts the update and the error is returned by the update method.
+type UpdateFunc func(obj any) error
+
+type VersionedObject interface {
+	// GetVersionedObject get the object stored by SetVersionedObject and its version, it returns redis.Nil if
+	// the key does not exist.
+	GetVersionedObject(ctx context.Context, key string, obj any) (version int64, err error)
+	// SetVersionedObject set the object if the current version of the key is version, version 0 means the key
+	// should not exist. It returns the new version, or ErrVersionConflict if the version does not match.
+	// The ttl of the key is kept, a created key gets the DefaultTTL of its schema.
+	SetVersionedObject(ctx context.Context, key string, obj any, version int64) (newVersion int64, err error)
+	// UpdateVersionedObject get the versioned object into obj, modify it by fn and set it back by compare-and-set,
+	// it retries until no one else modified the object in between.
+	UpdateVersionedObject(ctx context.Context, key string, obj any, fn UpdateFunc) (newVersion int64, err error)
+}
+
+// resetObject set the object pointed by obj to zero, so a retry does not see the fields of the previous attempt
+func resetObject(obj any) {
+	v := reflect.ValueOf(obj)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		panic(PanicValueDstNeedBeAllocated)
+	}
+	v.Elem().Set(reflect.Zero(v.Elem().Type()))
+}
+
+// UpdateObject read the object of the key into obj, modify it by fn, and write it back in a MULTI/EXEC
+// guarded by WATCH. fn may be called several times, it returns ErrUpdateConflict after UpdateObjectRetries attempts.
+// The ttl of the key is kept, a created key gets the DefaultTTL of its schema.
+func (rc *redisClient) UpdateObject(ctx context.Context, key string, obj any, fn UpdateFunc) error {
+	m, err := rc.ObjMarshallerOf(key, obj)
+	if err != nil {
+		return err
+	}
+	ttl := rc.defaultTTL(key)
+	// Watch selects the node by the keys in cluster mode before the hooks run, so the key is prefixed here
+	ctx, key = withRawKeys(ctx), rc.nsKey(key)
+	for i := 0; i < UpdateObjectRetries; i++ {
+		err := rc.client.Watch(ctx, func(tx *redis.Tx) error {
+			resetObject(obj)
+			expiration := time.Duration(redis.KeepTTL)
+			v, err := tx.Get(ctx, key).Result()
+			if err == nil {
+				err = m.Unmarshal([]byte(v), obj)
+			} else if rc.IsErrNil(err) {
+				expiration, err = ttl, nil
+			}
+			if err != nil {
+				return err
+			}
+			if err = fn(obj); err != nil {
+				return err
+			}
+			bys, err := m.Marshal(obj)
+			if err != nil {
+				return err
+			}
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.Set(ctx, key, bys, expiration)
+				return nil
+			})
+			return err
+		}, key)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+	}
+	return ErrUpdateConflict
+}
+
+// VersionedCASScript set KEYS[1] to "<version+1>:<ARGV[2]>" if its version is ARGV[1], a created key expires in
+// ARGV[3] milliseconds if it is positive. It returns the new version or -1 if the version does not match
+const VersionedCASScript = `
+local cur = redis.call('GET', KEYS[1])
+local ver = 0
+if cur then
+	ver = tonumber(string.match(cur, '^(%d+):')) or 0
+end
+if ver ~= tonumber(ARGV[1]) then
+	return -1
+end
+local ttl = tonumber(ARGV[3])
+if not cur and ttl > 0 then
+	redis.call('SET', KEYS[1], (ver + 1) .. ':' .. ARGV[2], 'PX', ttl)
+else
+	redis.call('SET', KEYS[1], (ver + 1) .. ':' .. ARGV[2], 'KEEPTTL')
+end
+return ver + 1
+`
+
+var versionedCASScript = newStaticScript(VersionedCASScript)
+
+// newStaticScript create a Script of the package without loading it, EvalSha loads it on the first NOSCRIPT
+func newStaticScript(src string) *Script {
+	sum := sha1.Sum([]byte(src))
+	return &Script{src: src, hash: hex.EncodeToString(sum[:])}
+}
+
+// splitVersioned split the stored value into the version and the marshalled object
+func splitVersioned(v string) (int64, []byte, error) {
+	ver, data, ok := strings.Cut(v, ":")
+	if !ok {
+		return 0, nil, ErrValue
+	}
+	version, err := strconv.ParseInt(ver, 10, 64)
+	if err != nil {
+		return 0, nil, ErrValue
+	}
+	return version, []byte(data), nil
+}
+
+func (rc *redisClient) GetVersionedObject(ctx context.Context, key string, obj any) (int64, error) {
+	m, err := rc.ObjMarshallerOf(key, obj)
+	if err != nil {
+		return 0, err
+	}
+	v, err := rc.Get(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	version, data, err := splitVersioned(v)
+	if err != nil {
+		return 0, err
+	}
+	return version, m.Unmarshal(data, obj)
+}
+
+func (rc *redisClient) SetVersionedObject(ctx context.Context, key string, obj any, version int64) (int64, error) {
+	m, err := rc.ObjMarshallerOf(key, obj)
+	if err != nil {
+		return 0, err
+	}
+	bys, err := m.Marshal(obj)
+	if err != nil {
+		return 0, err
+	}
+	newVersion, err := rc.EvalSha(ctx, versionedCASScript, []string{key}, version, bys,
+		rc.defaultTTL(key).Milliseconds()).Int64()
+	if err != nil {
+		return 0, err
+	}
+	if newVersion < 0 {
+		return 0, ErrVersionConflict
+	}
+	return newVersion, nil
+}
+
+func (rc *redisClient) UpdateVersionedObject(ctx context.Context, key string, obj any, fn UpdateFunc) (int64, error) {
+	for i := 0; i < UpdateObjectRetries; i++ {
+		resetObject(obj)
+		version, err := rc.GetVersionedObject(ctx, key, obj)
+		if err != nil && !rc.IsErrNil(err) {
+			return 0, err
+		}
+		if err = fn(obj); err != nil {
+			return 0, err
+		}
+		newVersion, err := rc.SetVersionedObject(ctx, key, obj, version)
+		if !errors.Is(err, ErrVersionConflict) {
+			return newVersion, err
+		}
+	}
+	return 0, ErrUpdateConflict
+}
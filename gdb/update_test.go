@@ -0,0 +1,167 @@
+package gdb
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// updateConcurrently run n increments of Foo.F of the key in every of the workers, retrying on ErrUpdateConflict
+func updateConcurrently(t *testing.T, workers, n int, update func(fn UpdateFunc) error) {
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < n; {
+				err := update(func(obj any) error {
+					obj.(*Foo).F++
+					return nil
+				})
+				if errors.Is(err, ErrUpdateConflict) {
+					continue
+				}
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				i++
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestUpdateObject(t *testing.T) {
+	ctx := context.Background()
+	client, _, clock := newMemRedisClient(t)
+
+	if err := client.SetObjectEX(ctx, "foo", &Foo{F: 1}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	updateConcurrently(t, 4, 5, func(fn UpdateFunc) error {
+		return client.UpdateObject(ctx, "foo", &Foo{}, fn)
+	})
+	var foo Foo
+	if err := client.GetObject(ctx, "foo", &foo); err != nil || foo.F != 21 {
+		t.Fatalf("GetObject = %v, %v", foo, err)
+	}
+	if ttl, err := client.TTL(ctx, "foo"); err != nil || ttl <= 0 {
+		t.Fatalf("ttl not kept, %v %v", ttl, err)
+	}
+
+	abort := errors.New("abort")
+	err := client.UpdateObject(ctx, "foo", &foo, func(obj any) error {
+		obj.(*Foo).F = 0
+		return abort
+	})
+	if !errors.Is(err, abort) {
+		t.Fatalf("UpdateObject abort = %v", err)
+	}
+	clock.Add(time.Minute)
+	if err = client.UpdateObject(ctx, "foo", &foo, func(any) error { return nil }); err != nil || foo.F != 0 {
+		t.Fatalf("UpdateObject of expired key = %v, %v", foo, err)
+	}
+}
+
+func TestVersionedObject(t *testing.T) {
+	ctx := context.Background()
+	client, _, _ := newMemRedisClient(t)
+
+	if v, err := client.SetVersionedObject(ctx, "foo", &Foo{F: 1}, 0); err != nil || v != 1 {
+		t.Fatalf("SetVersionedObject = %d, %v", v, err)
+	}
+	if _, err := client.SetVersionedObject(ctx, "foo", &Foo{F: 2}, 0); !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("SetVersionedObject stale version = %v", err)
+	}
+	// the ttl is kept by the script
+	if _, err := client.Expire(ctx, "foo", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	updateConcurrently(t, 4, 5, func(fn UpdateFunc) error {
+		_, err := client.UpdateVersionedObject(ctx, "foo", &Foo{}, fn)
+		return err
+	})
+	var foo Foo
+	if v, err := client.GetVersionedObject(ctx, "foo", &foo); err != nil || v != 21 || foo.F != 21 {
+		t.Fatalf("GetVersionedObject = %d %v, %v", v, foo, err)
+	}
+	if ttl, err := client.TTL(ctx, "foo"); err != nil || ttl != time.Minute {
+		t.Fatalf("TTL = %v, %v", ttl, err)
+	}
+}
+
+func TestDocObjectDBUpdateObject(t *testing.T) {
+	ctx := context.Background()
+	d, _ := newDocObjectDB()
+	updateConcurrently(t, 4, 5, func(fn UpdateFunc) error {
+		return d.UpdateObject(ctx, "foo", &Foo{}, fn)
+	})
+	var foo Foo
+	if err := d.GetObject(ctx, "foo", &foo); err != nil || foo.F != 20 {
+		t.Fatalf("GetObject = %v, %v", foo, err)
+	}
+}
+
+func TestUpdateObjectDefaultTTL(t *testing.T) {
+	ctx := context.Background()
+	client, _, _ := newMemRedisClient(t)
+	d, clock := newDocObjectDB()
+	r, err := NewKeySchemaRegistry(KeySchema{Prefix: "foo:", Type: reflect.TypeOf(Foo{}), DefaultTTL: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetKeySchemas(r)
+	d.SetKeySchemas(r)
+	incr := func(obj any) error {
+		obj.(*Foo).F++
+		return nil
+	}
+
+	// the created keys get the default ttl
+	if err = client.UpdateObject(ctx, "foo:1", &Foo{}, incr); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.SetVersionedObject(ctx, "foo:2", &Foo{F: 1}, 0); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"foo:1", "foo:2"} {
+		if ttl, err := client.TTL(ctx, key); err != nil || ttl != time.Minute {
+			t.Fatalf("TTL %s = %v, %v", key, ttl, err)
+		}
+	}
+	if err = d.UpdateObject(ctx, "foo:1", &Foo{}, incr); err != nil {
+		t.Fatal(err)
+	}
+	if doc, err := d.store.FindOne(ctx, "foo:1"); err != nil || !doc.ExpireAt.Equal(clock.Now().Add(time.Minute)) {
+		t.Fatalf("doc = %+v, %v", doc, err)
+	}
+
+	// the ttl of the existing keys is kept
+	for _, key := range []string{"foo:1", "foo:2"} {
+		if _, err = client.Expire(ctx, key, time.Hour); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = client.UpdateObject(ctx, "foo:1", &Foo{}, incr); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.UpdateVersionedObject(ctx, "foo:2", &Foo{}, incr); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"foo:1", "foo:2"} {
+		if ttl, err := client.TTL(ctx, key); err != nil || ttl != time.Hour {
+			t.Fatalf("TTL %s after update = %v, %v", key, ttl, err)
+		}
+	}
+	clock.Add(time.Second)
+	if err = d.UpdateObject(ctx, "foo:1", &Foo{}, incr); err != nil {
+		t.Fatal(err)
+	}
+	if doc, err := d.store.FindOne(ctx, "foo:1"); err != nil || !doc.ExpireAt.Equal(clock.Now().Add(59*time.Second)) {
+		t.Fatalf("doc after update = %+v, %v", doc, err)
+	}
+}
@@ -0,0 +1,13 @@
+package gjobqueue
+
+import (
+	"errors"
+)
+
+var (
+	// ErrInvalidOptions is returned when the options of a queue are invalid
+	ErrInvalidOptions = errors.New("ERR_INVALID_OPTIONS")
+	// ErrJobNotFound is returned when the job is not in the state the call expects, for example it is settled
+	// by another worker after its visibility timeout
+	ErrJobNotFound = errors.New("ERR_JOB_NOT_FOUND")
+)
@@ -0,0 +1,287 @@
+package gjobqueue
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/oldjon/gutil/gdb"
+	gmarshaller "github.com/oldjon/gutil/marshaller"
+	"go.uber.org/zap"
+)
+
+const (
+	keyPrefix                = "jq:"
+	claimScriptName          = "jobqueue_claim"
+	settleScriptName         = "jobqueue_settle"
+	requeueScriptName        = "jobqueue_requeue"
+	defaultVisibilityTimeout = 30 * time.Second
+	defaultMaxRetries        = 3
+	defaultBackoffBase       = time.Second
+	defaultBackoffMax        = 10 * time.Minute
+	defaultConcurrency       = 4
+	defaultPollInterval      = time.Second
+	defaultShutdownTimeout   = 30 * time.Second
+)
+
+// Options of a Queue
+type Options struct {
+	Name       string                 // the keys are "jq:{Name}:*"
+	Marshaller gmarshaller.Marshaller // the marshaller of the payloads, default json
+	// VisibilityTimeout is how long a claimed job is hidden from the other workers, a job not settled in time
+	// is retried, default 30s
+	VisibilityTimeout time.Duration
+	// MaxRetries of a failed job before it is moved to the dead-letter list, 0 means the default 3 and -1
+	// disables the retries
+	MaxRetries int
+	// Backoff return the delay before the retry of the attempt, default doubling from a second up to 10 minutes
+	Backoff func(attempt int) time.Duration
+
+	Concurrency     int           // the workers of Serve, default 4
+	PollInterval    time.Duration // how often the idle workers claim the due jobs, default a second
+	ShutdownTimeout time.Duration // how long Serve waits the running jobs after ctx is done, default 30s
+	Logger          *zap.Logger
+}
+
+func (opt *Options) init() error {
+	if opt.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidOptions)
+	}
+	if opt.VisibilityTimeout < 0 || opt.MaxRetries < -1 || opt.Concurrency < 0 || opt.PollInterval < 0 ||
+		opt.ShutdownTimeout < 0 {
+		return fmt.Errorf("%w: visibility timeout %v, max retries %d, concurrency %d, poll interval %v, "+
+			"shutdown timeout %v", ErrInvalidOptions, opt.VisibilityTimeout, opt.MaxRetries, opt.Concurrency,
+			opt.PollInterval, opt.ShutdownTimeout)
+	}
+	if opt.Marshaller == nil {
+		opt.Marshaller = &gmarshaller.JsonMarshaller{}
+	}
+	if opt.VisibilityTimeout == 0 {
+		opt.VisibilityTimeout = defaultVisibilityTimeout
+	}
+	switch opt.MaxRetries {
+	case 0:
+		opt.MaxRetries = defaultMaxRetries
+	case -1:
+		opt.MaxRetries = 0
+	}
+	if opt.Backoff == nil {
+		opt.Backoff = ExponentialBackoff(defaultBackoffBase, defaultBackoffMax)
+	}
+	if opt.Concurrency == 0 {
+		opt.Concurrency = defaultConcurrency
+	}
+	if opt.PollInterval == 0 {
+		opt.PollInterval = defaultPollInterval
+	}
+	if opt.ShutdownTimeout == 0 {
+		opt.ShutdownTimeout = defaultShutdownTimeout
+	}
+	if opt.Logger == nil {
+		opt.Logger = zap.NewNop()
+	}
+	return nil
+}
+
+// ExponentialBackoff return a Backoff doubling from base for every attempt up to max
+func ExponentialBackoff(base, max time.Duration) func(attempt int) time.Duration {
+	return func(attempt int) time.Duration {
+		d := base
+		for i := 1; i < attempt && d < max; i++ {
+			d *= 2
+		}
+		return min(d, max)
+	}
+}
+
+// Job is a claimed job
+type Job struct {
+	ID      string
+	Attempt int // from 1
+	data    []byte
+	m       gmarshaller.Marshaller
+}
+
+// Unmarshal unmarshal the payload of the job into v
+func (j *Job) Unmarshal(v any) error {
+	return j.m.Unmarshal(j.data, v)
+}
+
+// Data return the marshalled payload of the job
+func (j *Job) Data() []byte {
+	return j.data
+}
+
+// Stats of a Queue
+type Stats struct {
+	Scheduled int64 // the jobs waiting for their time, including the due ones
+	Active    int64 // the claimed jobs
+	Dead      int64 // the jobs in the dead-letter list
+}
+
+// Queue of delayed jobs. The jobs wait in a zset by their time, a claimed job moves to another zset by its
+// visibility deadline until it is acked, retried or moved to the dead-letter list after the last attempt.
+type Queue struct {
+	client  gdb.RedisClient
+	scripts *gdb.ScriptRegistry
+	opt     *Options
+	// keys, they share the hash tag of the name
+	scheduled, active, jobs, attempts, dead string
+	now                                     func() time.Time
+}
+
+// New create a Queue on the client, the scripts are loaded onto every master
+func New(ctx context.Context, client gdb.RedisClient, opt *Options) (*Queue, error) {
+	if opt == nil {
+		return nil, fmt.Errorf("%w: nil options", ErrInvalidOptions)
+	}
+	o := *opt // the defaults are filled into a copy, opt may be shared by other queues
+	if err := o.init(); err != nil {
+		return nil, err
+	}
+	prefix := keyPrefix + "{" + o.Name + "}:"
+	q := &Queue{
+		client:    client,
+		scripts:   gdb.NewScriptRegistry(client),
+		opt:       &o,
+		scheduled: prefix + "scheduled",
+		active:    prefix + "active",
+		jobs:      prefix + "jobs",
+		attempts:  prefix + "attempts",
+		dead:      prefix + "dead",
+		now:       time.Now,
+	}
+	q.scripts.Register(claimScriptName, ClaimScript)
+	q.scripts.Register(settleScriptName, SettleScript)
+	q.scripts.Register(requeueScriptName, RequeueScript)
+	if err := q.scripts.Load(ctx); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+func newID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// Enqueue add a job of the payload to run after the delay, it returns the id of the job
+func (q *Queue) Enqueue(ctx context.Context, payload any, delay time.Duration) (string, error) {
+	return q.EnqueueAt(ctx, payload, q.now().Add(delay))
+}
+
+// EnqueueAt add a job of the payload to run at the time, it returns the id of the job
+func (q *Queue) EnqueueAt(ctx context.Context, payload any, at time.Time) (string, error) {
+	data, err := q.opt.Marshaller.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	id := newID()
+	pipe := q.client.TxPipeline()
+	pipe.HSet(ctx, q.jobs, id, data)
+	pipe.ZAdd(ctx, q.scheduled, at.UnixMilli(), id)
+	if _, err = pipe.Exec(ctx); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// Claim claim up to count due jobs, they are hidden from the other workers for the visibility timeout and
+// must be settled by Ack or Fail
+func (q *Queue) Claim(ctx context.Context, count int) ([]*Job, error) {
+	rets, err := gdb.RunScript[[]any](ctx, q.scripts, claimScriptName,
+		[]string{q.scheduled, q.active, q.jobs, q.attempts, q.dead},
+		q.now().UnixMilli(), q.opt.VisibilityTimeout.Milliseconds(), count, q.opt.MaxRetries+1)
+	if err != nil {
+		return nil, err
+	}
+	jobs := make([]*Job, 0, len(rets)/3)
+	for i := 0; i+2 < len(rets); i += 3 {
+		id, _ := rets[i].(string)
+		attempt, _ := rets[i+1].(int64)
+		data, _ := rets[i+2].(string)
+		jobs = append(jobs, &Job{ID: id, Attempt: int(attempt), data: []byte(data), m: q.opt.Marshaller})
+	}
+	return jobs, nil
+}
+
+func (q *Queue) settle(ctx context.Context, job *Job, action string, at time.Time) error {
+	ok, err := gdb.RunScript[bool](ctx, q.scripts, settleScriptName,
+		[]string{q.active, q.scheduled, q.dead, q.jobs, q.attempts}, job.ID, action, at.UnixMilli(),
+		job.Attempt)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrJobNotFound
+	}
+	return nil
+}
+
+// Ack delete the done job, ErrJobNotFound if its visibility timeout passed and it is retried or claimed again
+func (q *Queue) Ack(ctx context.Context, job *Job) error {
+	return q.settle(ctx, job, "ack", time.Time{})
+}
+
+// Fail retry the failed job after the backoff, or move it to the dead-letter list after the last attempt
+func (q *Queue) Fail(ctx context.Context, job *Job) error {
+	if job.Attempt > q.opt.MaxRetries {
+		return q.settle(ctx, job, "dead", time.Time{})
+	}
+	return q.settle(ctx, job, "retry", q.now().Add(q.opt.Backoff(job.Attempt)))
+}
+
+// Dead return the jobs of the dead-letter list from start to stop, the latest first,
+// their Attempt is the attempts made
+func (q *Queue) Dead(ctx context.Context, start, stop int64) ([]*Job, error) {
+	ids, err := q.client.LRange(ctx, q.dead, start, stop)
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+	pipe := q.client.Pipeline()
+	datas := pipe.HMGet(ctx, q.jobs, ids...)
+	attempts := pipe.HMGet(ctx, q.attempts, ids...)
+	if _, err = pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	jobs := make([]*Job, len(ids))
+	for i, id := range ids {
+		data, _ := datas.Val()[i].(string)
+		attempt, _ := attempts.Val()[i].(string)
+		jobs[i] = &Job{ID: id, data: []byte(data), m: q.opt.Marshaller}
+		jobs[i].Attempt, _ = strconv.Atoi(attempt)
+	}
+	return jobs, nil
+}
+
+// Requeue move the dead job back to run now with its attempts reset, ErrJobNotFound if it is not dead
+func (q *Queue) Requeue(ctx context.Context, id string) error {
+	ok, err := gdb.RunScript[bool](ctx, q.scripts, requeueScriptName, []string{q.dead, q.scheduled, q.attempts},
+		id, q.now().UnixMilli())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrJobNotFound
+	}
+	return nil
+}
+
+// Stats return the numbers of the jobs of the queue
+func (q *Queue) Stats(ctx context.Context) (*Stats, error) {
+	pipe := q.client.Pipeline()
+	scheduled := pipe.ZCard(ctx, q.scheduled)
+	active := pipe.ZCard(ctx, q.active)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	dead, err := q.client.LLen(ctx, q.dead)
+	if err != nil {
+		return nil, err
+	}
+	return &Stats{Scheduled: scheduled.Val(), Active: active.Val(), Dead: dead}, nil
+}
@@ -0,0 +1,272 @@
+package gjobqueue
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gmemtest "github.com/oldjon/gutil/gdb/memtest"
+)
+
+type payload struct {
+	N int `json:"n"`
+}
+
+func newTestQueue(t *testing.T, opt *Options) (*Queue, *gmemtest.Clock) {
+	client, _, clock := gmemtest.NewClient(t, time.Date(2024, 5, 8, 12, 0, 0, 0, time.UTC))
+	q, err := New(context.Background(), client, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.now = clock.Now
+	return q, clock
+}
+
+func claimOne(t *testing.T, q *Queue) *Job {
+	t.Helper()
+	jobs, err := q.Claim(context.Background(), 10)
+	if err != nil || len(jobs) != 1 {
+		t.Fatalf("Claim = %v, %v", jobs, err)
+	}
+	return jobs[0]
+}
+
+func TestOptions(t *testing.T) {
+	for _, opt := range []*Options{{}, {Name: "q", MaxRetries: -2}, {Name: "q", VisibilityTimeout: -1}} {
+		if err := opt.init(); !errors.Is(err, ErrInvalidOptions) {
+			t.Fatalf("init(%+v) = %v", opt, err)
+		}
+	}
+	opt := &Options{Name: "q", MaxRetries: -1}
+	if err := opt.init(); err != nil || opt.MaxRetries != 0 || opt.Concurrency != defaultConcurrency {
+		t.Fatalf("init = %+v, %v", opt, err)
+	}
+	// the defaults are not written back into the shared options
+	shared := &Options{Name: "q", MaxRetries: -1}
+	for i := 0; i < 2; i++ {
+		if q, _ := newTestQueue(t, shared); q.opt.MaxRetries != 0 || shared.MaxRetries != -1 {
+			t.Fatalf("queue %d max retries = %d, shared %+v", i, q.opt.MaxRetries, shared)
+		}
+	}
+	if _, err := New(context.Background(), nil, nil); !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("New(nil) = %v", err)
+	}
+	backoff := ExponentialBackoff(time.Second, 5*time.Second)
+	for attempt, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second,
+		4: 5 * time.Second, 100: 5 * time.Second} {
+		if d := backoff(attempt); d != want {
+			t.Fatalf("backoff(%d) = %v", attempt, d)
+		}
+	}
+}
+
+func TestDelayAndAck(t *testing.T) {
+	ctx := context.Background()
+	q, clock := newTestQueue(t, &Options{Name: "mail"})
+	id, err := q.Enqueue(ctx, &payload{N: 1}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jobs, err := q.Claim(ctx, 10); err != nil || len(jobs) != 0 {
+		t.Fatalf("Claim before due = %v, %v", jobs, err)
+	}
+	clock.Add(time.Minute)
+	job := claimOne(t, q)
+	var p payload
+	if err = job.Unmarshal(&p); err != nil || job.ID != id || job.Attempt != 1 || p.N != 1 {
+		t.Fatalf("job = %+v, %+v, %v", job, p, err)
+	}
+	if jobs, err := q.Claim(ctx, 10); err != nil || len(jobs) != 0 {
+		t.Fatalf("Claim of claimed = %v, %v", jobs, err)
+	}
+	if err = q.Ack(ctx, job); err != nil {
+		t.Fatal(err)
+	}
+	if err = q.Ack(ctx, job); !errors.Is(err, ErrJobNotFound) {
+		t.Fatalf("Ack twice = %v", err)
+	}
+	stats, err := q.Stats(ctx)
+	if err != nil || *stats != (Stats{}) {
+		t.Fatalf("Stats = %+v, %v", stats, err)
+	}
+}
+
+func TestRetryAndDeadLetter(t *testing.T) {
+	ctx := context.Background()
+	q, clock := newTestQueue(t, &Options{Name: "mail", MaxRetries: 2, VisibilityTimeout: 10 * time.Second,
+		Backoff: func(attempt int) time.Duration { return time.Duration(attempt) * time.Minute }})
+	id, err := q.Enqueue(ctx, &payload{N: 2}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// attempt 1 fails, retried after a minute
+	if err = q.Fail(ctx, claimOne(t, q)); err != nil {
+		t.Fatal(err)
+	}
+	clock.Add(59 * time.Second)
+	if jobs, _ := q.Claim(ctx, 10); len(jobs) != 0 {
+		t.Fatalf("Claim before backoff = %v", jobs)
+	}
+	clock.Add(time.Second)
+	// attempt 2 times out and is retried at once
+	if job := claimOne(t, q); job.Attempt != 2 {
+		t.Fatalf("attempt = %d", job.Attempt)
+	}
+	clock.Add(10 * time.Second)
+	job := claimOne(t, q)
+	if job.Attempt != 3 {
+		t.Fatalf("attempt = %d", job.Attempt)
+	}
+	// the last attempt fails into the dead-letter list
+	if err = q.Fail(ctx, job); err != nil {
+		t.Fatal(err)
+	}
+	dead, err := q.Dead(ctx, 0, -1)
+	var p payload
+	if err != nil || len(dead) != 1 || dead[0].ID != id || dead[0].Attempt != 3 || dead[0].Unmarshal(&p) != nil ||
+		p.N != 2 {
+		t.Fatalf("Dead = %v, %v", dead, err)
+	}
+	if err = q.Requeue(ctx, "missing"); !errors.Is(err, ErrJobNotFound) {
+		t.Fatalf("Requeue missing = %v", err)
+	}
+	if err = q.Requeue(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	if job = claimOne(t, q); job.ID != id || job.Attempt != 1 {
+		t.Fatalf("requeued job = %+v", job)
+	}
+}
+
+func TestVisibilityTimeoutToDead(t *testing.T) {
+	ctx := context.Background()
+	q, clock := newTestQueue(t, &Options{Name: "mail", MaxRetries: -1, VisibilityTimeout: time.Second})
+	if _, err := q.Enqueue(ctx, &payload{}, 0); err != nil {
+		t.Fatal(err)
+	}
+	job := claimOne(t, q)
+	clock.Add(time.Second)
+	if jobs, err := q.Claim(ctx, 10); err != nil || len(jobs) != 0 {
+		t.Fatalf("Claim = %v, %v", jobs, err)
+	}
+	// the expired job can not be settled by the late worker
+	if err := q.Ack(ctx, job); !errors.Is(err, ErrJobNotFound) {
+		t.Fatalf("late Ack = %v", err)
+	}
+	if stats, err := q.Stats(ctx); err != nil || *stats != (Stats{Dead: 1}) {
+		t.Fatalf("Stats = %+v, %v", stats, err)
+	}
+}
+
+func TestStaleSettleAfterReclaim(t *testing.T) {
+	ctx := context.Background()
+	q, clock := newTestQueue(t, &Options{Name: "mail", VisibilityTimeout: time.Second})
+	if _, err := q.Enqueue(ctx, &payload{N: 3}, 0); err != nil {
+		t.Fatal(err)
+	}
+	stale := claimOne(t, q)
+	clock.Add(time.Second)
+	job := claimOne(t, q)
+	if job.ID != stale.ID || job.Attempt != 2 {
+		t.Fatalf("reclaimed job = %+v", job)
+	}
+	// the late worker can not settle the job claimed again
+	if err := q.Ack(ctx, stale); !errors.Is(err, ErrJobNotFound) {
+		t.Fatalf("stale Ack = %v", err)
+	}
+	if err := q.Fail(ctx, stale); !errors.Is(err, ErrJobNotFound) {
+		t.Fatalf("stale Fail = %v", err)
+	}
+	if stats, err := q.Stats(ctx); err != nil || *stats != (Stats{Active: 1}) {
+		t.Fatalf("Stats = %+v, %v", stats, err)
+	}
+	if err := q.Ack(ctx, job); err != nil {
+		t.Fatal(err)
+	}
+	if stats, err := q.Stats(ctx); err != nil || *stats != (Stats{}) {
+		t.Fatalf("Stats = %+v, %v", stats, err)
+	}
+}
+
+func TestServe(t *testing.T) {
+	ctx := context.Background()
+	q, _ := newTestQueue(t, &Options{Name: "mail", Concurrency: 2, PollInterval: 10 * time.Millisecond,
+		Backoff: func(int) time.Duration { return 0 }})
+	for i := 0; i < 5; i++ {
+		if _, err := q.Enqueue(ctx, &payload{N: i}, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var sum, calls, running, peak int32
+	all := make(chan struct{})
+	serveCtx, stop := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		q.Serve(serveCtx, func(ctx context.Context, job *Job) error {
+			n := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for p := atomic.LoadInt32(&peak); n > p && !atomic.CompareAndSwapInt32(&peak, p, n); {
+				p = atomic.LoadInt32(&peak)
+			}
+			var p payload
+			if err := job.Unmarshal(&p); err != nil {
+				return err
+			}
+			if p.N == 3 && job.Attempt == 1 {
+				panic("first attempt")
+			}
+			atomic.AddInt32(&sum, int32(p.N))
+			if atomic.AddInt32(&calls, 1) == 5 {
+				close(all)
+			}
+			return nil
+		})
+		close(done)
+	}()
+	select {
+	case <-all:
+	case <-time.After(5 * time.Second):
+		t.Fatal("jobs not done")
+	}
+	stop()
+	<-done
+	if sum != 10 || peak > 2 {
+		t.Fatalf("sum = %d, peak = %d", sum, peak)
+	}
+	if stats, err := q.Stats(ctx); err != nil || *stats != (Stats{}) {
+		t.Fatalf("Stats = %+v, %v", stats, err)
+	}
+}
+
+func TestServeShutdownTimeout(t *testing.T) {
+	ctx := context.Background()
+	q, _ := newTestQueue(t, &Options{Name: "mail", PollInterval: 10 * time.Millisecond,
+		ShutdownTimeout: 50 * time.Millisecond, Backoff: func(int) time.Duration { return time.Hour }})
+	if _, err := q.Enqueue(ctx, &payload{}, 0); err != nil {
+		t.Fatal(err)
+	}
+	started := make(chan struct{})
+	serveCtx, stop := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		q.Serve(serveCtx, func(ctx context.Context, job *Job) error {
+			close(started)
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		close(done)
+	}()
+	<-started
+	stop()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve not returned")
+	}
+	// the canceled job is retried
+	if stats, err := q.Stats(ctx); err != nil || *stats != (Stats{Scheduled: 1}) {
+		t.Fatalf("Stats = %+v, %v", stats, err)
+	}
+}
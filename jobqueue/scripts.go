@@ -0,0 +1,67 @@
+package gjobqueue
+
+// The scripts take the time of the calling client in milliseconds, the keys of a queue share the hash tag of
+// its name so they are in one slot in cluster mode.
+
+// ClaimScript move the expired jobs of active back to scheduled, or to dead after their last attempt, then
+// claim up to count due jobs of scheduled into active until their visibility timeout. KEYS are scheduled,
+// active, jobs, attempts and dead, ARGV are now, the visibility timeout, count and the max attempts.
+// It returns the id, attempt and payload of every claimed job.
+const ClaimScript = `
+local now, vis, count, max = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3]), tonumber(ARGV[4])
+local expired = redis.call('ZRANGEBYSCORE', KEYS[2], '-inf', ARGV[1])
+for _, id in ipairs(expired) do
+	redis.call('ZREM', KEYS[2], id)
+	if tonumber(redis.call('HGET', KEYS[4], id) or '0') >= max then
+		redis.call('LPUSH', KEYS[5], id)
+	else
+		redis.call('ZADD', KEYS[1], ARGV[1], id)
+	end
+end
+local jobs = {}
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, count)
+for _, id in ipairs(ids) do
+	redis.call('ZREM', KEYS[1], id)
+	local payload = redis.call('HGET', KEYS[3], id)
+	if payload then
+		redis.call('ZADD', KEYS[2], string.format('%d', now + vis), id)
+		table.insert(jobs, id)
+		table.insert(jobs, redis.call('HINCRBY', KEYS[4], id, 1))
+		table.insert(jobs, payload)
+	end
+end
+return jobs
+`
+
+// SettleScript settle a claimed job if it is still in active and not claimed again. KEYS are active,
+// scheduled, dead, jobs and attempts, ARGV are the id, the action ("ack", "retry" or "dead"), the time to
+// retry and the attempt of the claim. It returns 1 if the job is settled, 0 if it is not in active or the
+// claim is stale.
+const SettleScript = `
+if redis.call('HGET', KEYS[5], ARGV[1]) ~= ARGV[4] then
+	return 0
+end
+if redis.call('ZREM', KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+if ARGV[2] == 'ack' then
+	redis.call('HDEL', KEYS[4], ARGV[1])
+	redis.call('HDEL', KEYS[5], ARGV[1])
+elseif ARGV[2] == 'retry' then
+	redis.call('ZADD', KEYS[2], ARGV[3], ARGV[1])
+else
+	redis.call('LPUSH', KEYS[3], ARGV[1])
+end
+return 1
+`
+
+// RequeueScript move a dead job back to scheduled with its attempts reset. KEYS are dead, scheduled and
+// attempts, ARGV are the id and the time to run. It returns 1 if the job is requeued, 0 if it is not dead.
+const RequeueScript = `
+if redis.call('LREM', KEYS[1], 1, ARGV[1]) == 0 then
+	return 0
+end
+redis.call('HDEL', KEYS[3], ARGV[1])
+redis.call('ZADD', KEYS[2], ARGV[2], ARGV[1])
+return 1
+`
@@ -0,0 +1,100 @@
+package gjobqueue
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Handler handle a claimed job, the job is acked if it returns nil, otherwise it is failed and retried.
+// ctx is canceled if the job is still running ShutdownTimeout after Serve is stopped.
+type Handler func(ctx context.Context, job *Job) error
+
+// Serve claim the due jobs and run them by the handler in Concurrency workers until ctx is done. Then it stops
+// claiming and waits the running jobs for ShutdownTimeout before canceling their contexts, it returns after
+// all of them are settled. A panic of the handler fails the job.
+func (q *Queue) Serve(ctx context.Context, handler Handler) {
+	jobCtx, cancelJobs := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelJobs()
+	sem := make(chan struct{}, q.opt.Concurrency)
+	freed := make(chan struct{}, 1)
+	var wg sync.WaitGroup
+	ticker := time.NewTicker(q.opt.PollInterval)
+	defer ticker.Stop()
+	for ctx.Err() == nil {
+		if free := q.opt.Concurrency - len(sem); free > 0 {
+			jobs, err := q.Claim(ctx, free)
+			if err != nil && ctx.Err() == nil {
+				q.opt.Logger.Error("claim jobs failed", zap.String("queue", q.opt.Name), zap.Error(err))
+			}
+			for _, job := range jobs {
+				sem <- struct{}{}
+				wg.Add(1)
+				go func(job *Job) {
+					defer func() {
+						<-sem
+						wg.Done()
+						select {
+						case freed <- struct{}{}:
+						default:
+						}
+					}()
+					q.handle(jobCtx, handler, job)
+				}(job)
+			}
+			if len(jobs) == free {
+				continue // more jobs may be due
+			}
+		}
+		select {
+		case <-ctx.Done():
+		case <-ticker.C:
+		case <-freed:
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	timer := time.NewTimer(q.opt.ShutdownTimeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+		q.opt.Logger.Warn("shutdown timeout, cancel the running jobs", zap.String("queue", q.opt.Name))
+		cancelJobs()
+		<-done
+	}
+}
+
+// handle run the job and settle it, the settlement is not canceled with the job
+func (q *Queue) handle(ctx context.Context, handler Handler, job *Job) {
+	err := q.run(ctx, handler, job)
+	settleCtx := context.WithoutCancel(ctx)
+	if err == nil {
+		err = q.Ack(settleCtx, job)
+	} else {
+		q.opt.Logger.Warn("job failed", zap.String("queue", q.opt.Name), zap.String("id", job.ID),
+			zap.Int("attempt", job.Attempt), zap.Error(err))
+		err = q.Fail(settleCtx, job)
+	}
+	if err != nil {
+		q.opt.Logger.Error("settle job failed", zap.String("queue", q.opt.Name), zap.String("id", job.ID),
+			zap.Error(err))
+	}
+}
+
+// run run the handler, a panic is returned as an error
+func (q *Queue) run(ctx context.Context, handler Handler, job *Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return handler(ctx, job)
+}
@@ -0,0 +1,215 @@
+package gleaderboard
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/oldjon/gutil/gdb"
+)
+
+// Board is the board of a season, the reads of the shards are merged
+type Board struct {
+	lb     *Leaderboard
+	season Season
+	keys   []string // a key for every shard
+}
+
+// Season return the season of the board
+func (b *Board) Season() Season {
+	return b.season
+}
+
+// better return whether the composite score x ranks higher than y, the same way ZREVRANGE or ZRANGE orders them
+func (b *Board) better(x, y redis.Z) bool {
+	if x.Score != y.Score {
+		return (x.Score > y.Score) != b.lb.composer.ascending
+	}
+	return (x.Member.(string) > y.Member.(string)) != b.lb.composer.ascending
+}
+
+// ranges read the ranges of the shards in their orders, start and stop of the shard i are starts[i] and stops[i]
+func (b *Board) ranges(ctx context.Context, starts, stops []int64) ([]redis.Z, error) {
+	pipe := b.lb.client.Pipeline()
+	fs := make([]*gdb.Future[[]redis.Z], len(b.keys))
+	for i, key := range b.keys {
+		if b.lb.composer.ascending {
+			fs[i] = pipe.ZRangeWithScores(ctx, key, starts[i], stops[i])
+		} else {
+			fs[i] = pipe.ZRevRangeWithScores(ctx, key, starts[i], stops[i])
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	var zs []redis.Z
+	for _, f := range fs {
+		zs = append(zs, f.Val()...)
+	}
+	sort.Slice(zs, func(i, j int) bool { return b.better(zs[i], zs[j]) })
+	return zs, nil
+}
+
+func (b *Board) entry(z redis.Z, rank int64) *Entry {
+	score, at := b.lb.composer.decompose(z.Score, b.season.Start)
+	return &Entry{Member: z.Member.(string), Score: score, Time: at, Rank: rank}
+}
+
+// Top return the page of limit entries from offset, the first entry is the top. Every shard reads offset+limit
+// entries, so the deep pages of a sharded board are expensive.
+func (b *Board) Top(ctx context.Context, offset, limit int64) ([]*Entry, error) {
+	starts := make([]int64, len(b.keys))
+	stops := make([]int64, len(b.keys))
+	for i := range stops {
+		stops[i] = offset + limit - 1
+	}
+	zs, err := b.ranges(ctx, starts, stops)
+	if err != nil || int64(len(zs)) <= offset {
+		return nil, err
+	}
+	// zs are the first offset+limit members of the board, the same composite scores share the rank
+	var entries []*Entry
+	rank := int64(1)
+	for i, z := range zs[:min(int64(len(zs)), offset+limit)] {
+		if i > 0 && z.Score != zs[i-1].Score {
+			rank = int64(i) + 1
+		}
+		if int64(i) >= offset {
+			entries = append(entries, b.entry(z, rank))
+		}
+	}
+	return entries, nil
+}
+
+// betterCounts return the counts of the members ranking higher than every composite score in every shard,
+// counts[i][j] is the count of composites[i] in the shard j
+func (b *Board) betterCounts(ctx context.Context, composites ...float64) ([][]int64, error) {
+	pipe := b.lb.client.Pipeline()
+	fs := make([][]*gdb.Future[int64], len(composites))
+	for i, composite := range composites {
+		s := "(" + strconv.FormatFloat(composite, 'f', -1, 64)
+		fs[i] = make([]*gdb.Future[int64], len(b.keys))
+		for j, key := range b.keys {
+			if b.lb.composer.ascending {
+				fs[i][j] = pipe.ZCount(ctx, key, "-inf", s)
+			} else {
+				fs[i][j] = pipe.ZCount(ctx, key, s, "+inf")
+			}
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	counts := make([][]int64, len(fs))
+	for i := range fs {
+		counts[i] = make([]int64, len(fs[i]))
+		for j, f := range fs[i] {
+			counts[i][j] = f.Val()
+		}
+	}
+	return counts, nil
+}
+
+// sumRank return the rank of the counts of betterCounts
+func sumRank(counts []int64) int64 {
+	rank := int64(1)
+	for _, n := range counts {
+		rank += n
+	}
+	return rank
+}
+
+// rank return the entry of the member and the counts of betterCounts
+func (b *Board) rank(ctx context.Context, member string) (*Entry, []int64, error) {
+	composite, err := b.lb.client.ZScore(ctx, b.keys[b.lb.shard(member)], member)
+	if errors.Is(err, redis.Nil) {
+		return nil, nil, ErrMemberNotRanked
+	} else if err != nil {
+		return nil, nil, err
+	}
+	counts, err := b.betterCounts(ctx, composite)
+	if err != nil {
+		return nil, nil, err
+	}
+	return b.entry(redis.Z{Score: composite, Member: member}, sumRank(counts[0])), counts[0], nil
+}
+
+// Rank return the entry of the member, ErrMemberNotRanked if the member is not on the board
+func (b *Board) Rank(ctx context.Context, member string) (*Entry, error) {
+	e, _, err := b.rank(ctx, member)
+	return e, err
+}
+
+// Around return the entries of the n members above the member, the member and the n members below it
+func (b *Board) Around(ctx context.Context, member string, n int64) ([]*Entry, error) {
+	_, counts, err := b.rank(ctx, member)
+	if err != nil {
+		return nil, err
+	}
+	// every shard reads the n members above the position of the member in it and the n+1 from the position
+	starts := make([]int64, len(b.keys))
+	stops := make([]int64, len(b.keys))
+	for i, count := range counts {
+		starts[i], stops[i] = max(0, count-n), count+n
+	}
+	zs, err := b.ranges(ctx, starts, stops)
+	if err != nil {
+		return nil, err
+	}
+	idx := 0
+	for i, z := range zs {
+		if z.Member == member {
+			idx = i
+			break
+		}
+	}
+	window := zs[max(0, idx-int(n)):min(len(zs), idx+int(n)+1)]
+	composites := make([]float64, len(window))
+	for i, z := range window {
+		composites[i] = z.Score
+	}
+	// the window may not start at the top, so the ranks are counted like Rank
+	windowCounts, err := b.betterCounts(ctx, composites...)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]*Entry, len(window))
+	for i, z := range window {
+		entries[i] = b.entry(z, sumRank(windowCounts[i]))
+	}
+	return entries, nil
+}
+
+// Count return the number of the members on the board
+func (b *Board) Count(ctx context.Context) (int64, error) {
+	pipe := b.lb.client.Pipeline()
+	fs := make([]*gdb.Future[int64], len(b.keys))
+	for i, key := range b.keys {
+		fs[i] = pipe.ZCard(ctx, key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	var n int64
+	for _, f := range fs {
+		n += f.Val()
+	}
+	return n, nil
+}
+
+// Remove remove the members from the board
+func (b *Board) Remove(ctx context.Context, members ...string) error {
+	byShard := make(map[int][]any)
+	for _, m := range members {
+		i := b.lb.shard(m)
+		byShard[i] = append(byShard[i], m)
+	}
+	pipe := b.lb.client.Pipeline()
+	for i, ms := range byShard {
+		pipe.ZRem(ctx, b.keys[i], ms...)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
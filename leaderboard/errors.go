@@ -0,0 +1,14 @@
+package gleaderboard
+
+import (
+	"errors"
+)
+
+var (
+	// ErrInvalidOptions is returned when the options of a leaderboard are invalid
+	ErrInvalidOptions = errors.New("ERR_INVALID_OPTIONS")
+	// ErrScoreOutOfRange is returned when a score does not fit in the composite score, see Options.TieBits
+	ErrScoreOutOfRange = errors.New("ERR_SCORE_OUT_OF_RANGE")
+	// ErrMemberNotRanked is returned when the member is not on the board
+	ErrMemberNotRanked = errors.New("ERR_MEMBER_NOT_RANKED")
+)
@@ -0,0 +1,274 @@
+package gleaderboard
+
+import (
+	"context"
+	"fmt"
+	"hash/crc32"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oldjon/gutil/gdb"
+	"github.com/oldjon/gutil/timeutil"
+)
+
+const (
+	keyPrefix       = "lb:"
+	defaultTieBits  = 22
+	defaultTieUnit  = time.Second
+	seasonIDLayout  = "20060102"
+	noSeasonID      = "all"
+	seasonsKeyField = "seasons"
+)
+
+// Period of the seasons of a leaderboard
+type Period uint8
+
+const (
+	// NoSeason keep a single board forever
+	NoSeason Period = iota
+	// Daily start a new season at RefreshHour:RefreshMinute every day
+	Daily
+	// Weekly start a new season at RefreshHour:RefreshMinute of RefreshWeekday every week
+	Weekly
+)
+
+// Mode of Submit
+type Mode string
+
+const (
+	// Replace set the score of the member
+	Replace Mode = "set"
+	// Best keep the better of the score and the current score of the member
+	Best Mode = "best"
+	// Incr add the score to the current score of the member
+	Incr Mode = "incr"
+)
+
+// Options of a Leaderboard
+type Options struct {
+	Name      string // the keys are "lb:{Name}:{season}:{shard}"
+	Ascending bool   // the lower scores rank higher, for example the finishing times
+	// TieBits is the bits of the composite score for the time the score was reached, the earlier member ranks
+	// higher among the members of the same score. The scores are limited to 53-TieBits bits. Default 22.
+	TieBits uint
+	// TieUnit is the unit of the time of TieBits, default a second, so 22 bits cover 48 days of a season
+	TieUnit time.Duration
+	// Epoch is the start of the times of a NoSeason board, it is required by NoSeason
+	Epoch time.Time
+
+	Period         Period
+	RefreshWeekday time.Weekday
+	RefreshHour    int
+	RefreshMinute  int
+	Location       *time.Location // the location of the refresh time, default time.Local
+	// ArchiveTTL keep the boards of the past seasons for the duration after their end, 0 keeps them forever
+	ArchiveTTL time.Duration
+
+	// Shards split a huge board by member into the zsets, which are read together. Default 1.
+	Shards int
+}
+
+func (opt *Options) init() error {
+	if opt.TieBits == 0 {
+		opt.TieBits = defaultTieBits
+	}
+	if opt.TieUnit == 0 {
+		opt.TieUnit = defaultTieUnit
+	}
+	if opt.Shards == 0 {
+		opt.Shards = 1
+	}
+	if opt.Location == nil {
+		opt.Location = time.Local
+	}
+	switch {
+	case opt.Name == "":
+		return fmt.Errorf("%w: name is required", ErrInvalidOptions)
+	case opt.TieBits > 52 || opt.TieUnit < 0 || opt.Shards < 0 || opt.ArchiveTTL < 0:
+		return fmt.Errorf("%w: tie bits %d, tie unit %v, shards %d, archive ttl %v", ErrInvalidOptions,
+			opt.TieBits, opt.TieUnit, opt.Shards, opt.ArchiveTTL)
+	case opt.Period > Weekly:
+		return fmt.Errorf("%w: unknown period %d", ErrInvalidOptions, opt.Period)
+	case opt.Period == NoSeason && opt.Epoch.IsZero():
+		return fmt.Errorf("%w: epoch is required without seasons", ErrInvalidOptions)
+	}
+	return nil
+}
+
+// Season of a leaderboard, ID is the date of the start, End is zero for NoSeason
+type Season struct {
+	ID    string
+	Start time.Time
+	End   time.Time
+}
+
+// Entry of a member on a board
+type Entry struct {
+	Member string
+	Score  int64
+	Time   time.Time // when the score was reached, truncated to TieUnit
+	Rank   int64     // from 1, the members of the same composite score share the rank
+}
+
+// Leaderboard rank the members by score in seasons. The members submit to the board of the current season,
+// the boards of the past seasons are archived and can still be read.
+//
+//	lb, err := gleaderboard.New(ctx, client, &gleaderboard.Options{Name: "arena", Period: gleaderboard.Weekly,
+//		RefreshWeekday: time.Monday, RefreshHour: 5, ArchiveTTL: 28 * 24 * time.Hour})
+//	_, err = lb.Submit(ctx, "player1", 1200, gleaderboard.Best)
+//	top, err := lb.Current().Top(ctx, 0, 100)
+type Leaderboard struct {
+	client   gdb.RedisClient
+	scripts  *gdb.ScriptRegistry
+	opt      *Options
+	composer composer
+	indexed  sync.Map // season id => struct{}, the seasons added to the index by this process
+	now      func() time.Time
+}
+
+// New create a Leaderboard on the client, the script is loaded onto every master
+func New(ctx context.Context, client gdb.RedisClient, opt *Options) (*Leaderboard, error) {
+	if opt == nil {
+		return nil, fmt.Errorf("%w: nil options", ErrInvalidOptions)
+	}
+	o := *opt // the defaults are filled into a copy, opt may be shared by other leaderboards
+	if err := o.init(); err != nil {
+		return nil, err
+	}
+	lb := &Leaderboard{
+		client:   client,
+		scripts:  gdb.NewScriptRegistry(client),
+		opt:      &o,
+		composer: newComposer(&o),
+		now:      time.Now,
+	}
+	lb.scripts.Register(submitScriptName, SubmitScript)
+	if err := lb.scripts.Load(ctx); err != nil {
+		return nil, err
+	}
+	return lb, nil
+}
+
+// SeasonOf return the season containing t
+func (lb *Leaderboard) SeasonOf(t time.Time) Season {
+	opt := lb.opt
+	switch opt.Period {
+	case Daily:
+		return lb.seasonFrom(timeutil.LastRegionDayRefreshTime(t, opt.RefreshHour, opt.RefreshMinute, opt.Location))
+	case Weekly:
+		return lb.seasonFrom(timeutil.LastRegionWeekRefreshTime(t, opt.RefreshWeekday, opt.RefreshHour,
+			opt.RefreshMinute, opt.Location))
+	default:
+		return Season{ID: noSeasonID, Start: opt.Epoch}
+	}
+}
+
+// seasonFrom return the season of the start
+func (lb *Leaderboard) seasonFrom(start time.Time) Season {
+	days := 1
+	if lb.opt.Period == Weekly {
+		days = 7
+	}
+	return Season{ID: start.Format(seasonIDLayout), Start: start, End: start.AddDate(0, 0, days)}
+}
+
+// Current return the board of the current season
+func (lb *Leaderboard) Current() *Board {
+	return lb.Board(lb.SeasonOf(lb.now()))
+}
+
+// Board return the board of the season
+func (lb *Leaderboard) Board(season Season) *Board {
+	b := &Board{lb: lb, season: season, keys: make([]string, lb.opt.Shards)}
+	for i := range b.keys {
+		b.keys[i] = keyPrefix + lb.opt.Name + ":" + season.ID + ":" + strconv.Itoa(i)
+	}
+	return b
+}
+
+// shard return the index of the shard of the member
+func (lb *Leaderboard) shard(member string) int {
+	return int(crc32.ChecksumIEEE([]byte(member)) % uint32(lb.opt.Shards))
+}
+
+func (lb *Leaderboard) seasonsKey() string {
+	return keyPrefix + lb.opt.Name + ":" + seasonsKeyField
+}
+
+// Submit submit the score of the member to the board of the current season by the mode,
+// it returns the score of the member on the board after the submission, ErrInvalidOptions if the mode is unknown
+func (lb *Leaderboard) Submit(ctx context.Context, member string, score int64, mode Mode) (int64, error) {
+	switch mode {
+	case Replace, Best, Incr:
+	default:
+		return 0, fmt.Errorf("%w: unknown mode %q", ErrInvalidOptions, mode)
+	}
+	now := lb.now()
+	season := lb.SeasonOf(now)
+	if !lb.composer.inRange(score) {
+		return 0, ErrScoreOutOfRange
+	}
+	var expireAt int64
+	if !season.End.IsZero() && lb.opt.ArchiveTTL > 0 {
+		expireAt = season.End.Add(lb.opt.ArchiveTTL).UnixMilli()
+	}
+	if err := lb.index(ctx, season); err != nil {
+		return 0, err
+	}
+	c := lb.composer
+	asc := "0"
+	if c.ascending {
+		asc = "1"
+	}
+	key := lb.Board(season).keys[lb.shard(member)]
+	ret, err := gdb.RunScript[int64](ctx, lb.scripts, submitScriptName, []string{key},
+		member, score, int64(c.tie(now, season.Start)), int64(c.mult), c.maxScore, string(mode), asc, expireAt)
+	if err != nil && strings.HasSuffix(err.Error(), ErrScoreOutOfRange.Error()) {
+		return 0, ErrScoreOutOfRange
+	}
+	return ret, err
+}
+
+// index add the season to the index of the seasons once
+func (lb *Leaderboard) index(ctx context.Context, season Season) error {
+	if lb.opt.Period == NoSeason {
+		return nil
+	}
+	if _, ok := lb.indexed.Load(season.ID); ok {
+		return nil
+	}
+	if _, err := lb.client.ZAdd(ctx, lb.seasonsKey(), season.Start.Unix(), season.ID); err != nil {
+		return err
+	}
+	lb.indexed.Store(season.ID, struct{}{})
+	return nil
+}
+
+// Archived return the past seasons which still have boards, from the earliest
+func (lb *Leaderboard) Archived(ctx context.Context) ([]Season, error) {
+	if lb.opt.Period == NoSeason {
+		return nil, nil
+	}
+	now := lb.now()
+	if lb.opt.ArchiveTTL > 0 {
+		// the seasons started before the season of now-ArchiveTTL have ended ArchiveTTL ago
+		expired := "(" + strconv.FormatInt(lb.SeasonOf(now.Add(-lb.opt.ArchiveTTL)).Start.Unix(), 10)
+		if _, err := lb.client.ZRemRangeByScore(ctx, lb.seasonsKey(), "-inf", expired); err != nil {
+			return nil, err
+		}
+	}
+	current := lb.SeasonOf(now).Start.Unix()
+	zs, err := lb.client.ZRangeByScoreWithScores(ctx, lb.seasonsKey(), "-inf", "("+strconv.FormatInt(current, 10))
+	if err != nil {
+		return nil, err
+	}
+	seasons := make([]Season, 0, len(zs))
+	for _, z := range zs {
+		seasons = append(seasons, lb.seasonFrom(time.Unix(int64(z.Score), 0).In(lb.opt.Location)))
+	}
+	sort.Slice(seasons, func(i, j int) bool { return seasons[i].Start.Before(seasons[j].Start) })
+	return seasons, nil
+}
@@ -0,0 +1,224 @@
+package gleaderboard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/oldjon/gutil/gdb"
+	"github.com/oldjon/gutil/gdb/memredis"
+	gmemtest "github.com/oldjon/gutil/gdb/memtest"
+)
+
+func newTestLeaderboard(t *testing.T, opt *Options) (*Leaderboard, *gmemtest.Clock) {
+	// Wednesday
+	client, _, clock := gmemtest.NewClient(t, time.Date(2024, 5, 8, 12, 0, 0, 0, time.UTC))
+	lb, err := New(context.Background(), client, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lb.now = clock.Now
+	return lb, clock
+}
+
+// summary of the entries as member:score:rank
+func summary(entries []*Entry) []string {
+	s := make([]string, len(entries))
+	for i, e := range entries {
+		s[i] = fmt.Sprintf("%s:%d:%d", e.Member, e.Score, e.Rank)
+	}
+	return s
+}
+
+func TestLeaderboardRanks(t *testing.T) {
+	for _, shards := range []int{1, 3} {
+		ctx := context.Background()
+		lb, clock := newTestLeaderboard(t, &Options{Name: "arena", Period: Weekly, RefreshWeekday: time.Monday,
+			RefreshHour: 5, Location: time.UTC, Shards: shards})
+		submits := []struct {
+			member string
+			score  int64
+		}{{"a", 100}, {"b", 300}, {"c", 100}, {"d", 200}, {"e", 50}, {"f", 100}}
+		for _, s := range submits {
+			if _, err := lb.Submit(ctx, s.member, s.score, Replace); err != nil {
+				t.Fatal(err)
+			}
+			clock.Add(time.Second)
+		}
+		board := lb.Current()
+		// a, c and f tie at 100 and rank by the time they reached it
+		want := []string{"b:300:1", "d:200:2", "a:100:3", "c:100:4", "f:100:5", "e:50:6"}
+		top, err := board.Top(ctx, 0, 10)
+		if err != nil || !reflect.DeepEqual(summary(top), want) {
+			t.Fatalf("shards %d: Top = %v, %v", shards, summary(top), err)
+		}
+		if top[2].Time != time.Date(2024, 5, 8, 12, 0, 0, 0, time.UTC) {
+			t.Fatalf("shards %d: Time = %v", shards, top[2].Time)
+		}
+		if page, _ := board.Top(ctx, 2, 2); !reflect.DeepEqual(summary(page), want[2:4]) {
+			t.Fatalf("shards %d: page = %v", shards, summary(page))
+		}
+		if page, _ := board.Top(ctx, 6, 2); len(page) != 0 {
+			t.Fatalf("shards %d: past the end = %v", shards, summary(page))
+		}
+		if e, err := board.Rank(ctx, "f"); err != nil || e.Rank != 5 || e.Score != 100 {
+			t.Fatalf("shards %d: Rank = %+v, %v", shards, e, err)
+		}
+		if _, err = board.Rank(ctx, "x"); !errors.Is(err, ErrMemberNotRanked) {
+			t.Fatalf("shards %d: Rank missing = %v", shards, err)
+		}
+		if around, err := board.Around(ctx, "c", 2); err != nil || !reflect.DeepEqual(summary(around), want[1:6]) {
+			t.Fatalf("shards %d: Around = %v, %v", shards, summary(around), err)
+		}
+		if around, _ := board.Around(ctx, "b", 1); !reflect.DeepEqual(summary(around), want[0:2]) {
+			t.Fatalf("shards %d: Around top = %v", shards, summary(around))
+		}
+		if n, err := board.Count(ctx); err != nil || n != 6 {
+			t.Fatalf("shards %d: Count = %d, %v", shards, n, err)
+		}
+		if err = board.Remove(ctx, "b", "d"); err != nil {
+			t.Fatal(err)
+		}
+		if e, _ := board.Rank(ctx, "a"); e.Rank != 1 {
+			t.Fatalf("shards %d: Rank after Remove = %+v", shards, e)
+		}
+	}
+}
+
+func TestLeaderboardSharedRanks(t *testing.T) {
+	for _, shards := range []int{1, 3} {
+		ctx := context.Background()
+		lb, _ := newTestLeaderboard(t, &Options{Name: "arena", Period: Weekly, Location: time.UTC, Shards: shards})
+		// a and b reach 100 at the same time, so they have the same composite score
+		for _, s := range []struct {
+			member string
+			score  int64
+		}{{"a", 100}, {"b", 100}, {"c", 300}, {"d", 50}} {
+			if _, err := lb.Submit(ctx, s.member, s.score, Replace); err != nil {
+				t.Fatal(err)
+			}
+		}
+		board := lb.Current()
+		want := []string{"c:300:1", "b:100:2", "a:100:2", "d:50:4"}
+		if top, err := board.Top(ctx, 0, 10); err != nil || !reflect.DeepEqual(summary(top), want) {
+			t.Fatalf("shards %d: Top = %v, %v", shards, summary(top), err)
+		}
+		if page, _ := board.Top(ctx, 2, 2); !reflect.DeepEqual(summary(page), want[2:]) {
+			t.Fatalf("shards %d: page = %v", shards, summary(page))
+		}
+		if e, err := board.Rank(ctx, "a"); err != nil || e.Rank != 2 {
+			t.Fatalf("shards %d: Rank = %+v, %v", shards, e, err)
+		}
+		if around, _ := board.Around(ctx, "d", 1); !reflect.DeepEqual(summary(around), want[2:]) {
+			t.Fatalf("shards %d: Around = %v", shards, summary(around))
+		}
+	}
+}
+
+func TestLeaderboardSubmitModes(t *testing.T) {
+	ctx := context.Background()
+	lb, _ := newTestLeaderboard(t, &Options{Name: "speedrun", Ascending: true, Epoch: time.Unix(0, 0), TieBits: 40})
+	cases := []struct {
+		score int64
+		mode  Mode
+		want  int64
+	}{
+		{90, Replace, 90},
+		{95, Best, 90},
+		{80, Best, 80},
+		{5, Incr, 85},
+		{100, Replace, 100},
+	}
+	for i, c := range cases {
+		if got, err := lb.Submit(ctx, "a", c.score, c.mode); err != nil || got != c.want {
+			t.Fatalf("case %d: Submit = %d, %v", i, got, err)
+		}
+	}
+	if _, err := lb.Submit(ctx, "a", 1<<13, Replace); !errors.Is(err, ErrScoreOutOfRange) {
+		t.Fatalf("Submit out of range = %v", err)
+	}
+	if _, err := lb.Submit(ctx, "a", 1<<13-100, Incr); !errors.Is(err, ErrScoreOutOfRange) {
+		t.Fatalf("Incr out of range = %v", err)
+	}
+	if _, err := lb.Submit(ctx, "a", 1, Mode("max")); !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("Submit unknown mode = %v", err)
+	}
+	if _, err := lb.Submit(ctx, "b", 10, Replace); err != nil {
+		t.Fatal(err)
+	}
+	if top, _ := lb.Current().Top(ctx, 0, 10); !reflect.DeepEqual(summary(top), []string{"b:10:1", "a:100:2"}) {
+		t.Fatalf("ascending Top = %v", summary(top))
+	}
+}
+
+func TestLeaderboardSeasons(t *testing.T) {
+	ctx := context.Background()
+	lb, clock := newTestLeaderboard(t, &Options{Name: "daily", Period: Daily, RefreshHour: 5, Location: time.UTC,
+		ArchiveTTL: 48 * time.Hour})
+	first := lb.Current().Season()
+	if first.ID != "20240508" || first.End != time.Date(2024, 5, 9, 5, 0, 0, 0, time.UTC) {
+		t.Fatalf("season = %+v", first)
+	}
+	if _, err := lb.Submit(ctx, "a", 10, Replace); err != nil {
+		t.Fatal(err)
+	}
+
+	// the next season starts empty and the last one is archived
+	clock.Add(24 * time.Hour)
+	if n, _ := lb.Current().Count(ctx); n != 0 {
+		t.Fatalf("new season Count = %d", n)
+	}
+	if _, err := lb.Submit(ctx, "b", 20, Replace); err != nil {
+		t.Fatal(err)
+	}
+	archived, err := lb.Archived(ctx)
+	if err != nil || len(archived) != 1 || archived[0] != first {
+		t.Fatalf("Archived = %+v, %v", archived, err)
+	}
+	if top, _ := lb.Board(archived[0]).Top(ctx, 0, 10); !reflect.DeepEqual(summary(top), []string{"a:10:1"}) {
+		t.Fatalf("archived Top = %v", summary(top))
+	}
+
+	// the archive of the first season expires 48 hours after its end
+	clock.Add(48 * time.Hour)
+	if archived, _ = lb.Archived(ctx); len(archived) != 1 || archived[0].ID != "20240509" {
+		t.Fatalf("Archived after ttl = %+v", archived)
+	}
+	if n, _ := lb.Board(first).Count(ctx); n != 0 {
+		t.Fatalf("expired board Count = %d", n)
+	}
+}
+
+func TestNewLeaderboardInvalid(t *testing.T) {
+	client, err := gdb.NewMemRedisClient(memredis.NewServer(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	for _, opt := range []*Options{
+		{Period: Daily},
+		{Name: "a"},
+		{Name: "a", Period: Daily, TieBits: 53},
+		{Name: "a", Period: Period(7)},
+	} {
+		if _, err = New(context.Background(), client, opt); !errors.Is(err, ErrInvalidOptions) {
+			t.Fatalf("New(%+v) = %v", opt, err)
+		}
+	}
+	if _, err = New(context.Background(), client, nil); !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("New(nil) = %v", err)
+	}
+	// the defaults are not written back into the shared options
+	opt := &Options{Name: "a", Period: Daily}
+	for i := 0; i < 2; i++ {
+		if lb, err := New(context.Background(), client, opt); err != nil || lb.opt.TieBits != defaultTieBits {
+			t.Fatalf("New = %+v, %v", lb, err)
+		}
+	}
+	if *opt != (Options{Name: "a", Period: Daily}) {
+		t.Fatalf("options = %+v", opt)
+	}
+}
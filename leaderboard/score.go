@@ -0,0 +1,50 @@
+package gleaderboard
+
+import (
+	"math"
+	"time"
+)
+
+// The composite score of a member is score * 2^TieBits + tie, the tie is the time the score was reached in
+// TieUnits since the start of the season, reversed for the descending boards so the earlier member ranks higher.
+// A float64 holds 53 bits exactly, so the scores are limited to (-2^(53-TieBits), 2^(53-TieBits)).
+
+type composer struct {
+	mult      float64 // 2^TieBits
+	maxScore  int64   // 2^(53-TieBits) - 1
+	unit      time.Duration
+	ascending bool
+}
+
+func newComposer(opt *Options) composer {
+	return composer{
+		mult:      math.Ldexp(1, int(opt.TieBits)),
+		maxScore:  1<<(53-opt.TieBits) - 1,
+		unit:      opt.TieUnit,
+		ascending: opt.Ascending,
+	}
+}
+
+// tie return the tie of the time since epoch, the times beyond the range of TieBits are clamped
+func (c composer) tie(at, epoch time.Time) float64 {
+	u := math.Floor(float64(at.Sub(epoch) / c.unit))
+	u = math.Max(0, math.Min(c.mult-1, u))
+	if c.ascending {
+		return u
+	}
+	return c.mult - 1 - u
+}
+
+func (c composer) inRange(score int64) bool {
+	return score >= -c.maxScore && score <= c.maxScore
+}
+
+// decompose return the score and the time of the composite score
+func (c composer) decompose(composite float64, epoch time.Time) (int64, time.Time) {
+	score := math.Floor(composite / c.mult)
+	u := composite - score*c.mult
+	if !c.ascending {
+		u = c.mult - 1 - u
+	}
+	return int64(score), epoch.Add(time.Duration(u) * c.unit)
+}
@@ -0,0 +1,28 @@
+package gleaderboard
+
+const submitScriptName = "leaderboard_submit"
+
+// SubmitScript set the composite score of a member, KEYS[1] is the zset of the member's shard. ARGV are the
+// member, score, tie, 2^TieBits, max score, mode, ascending ("1" or "0") and the expire time in milliseconds
+// ("0" for none). It returns the score of the member after the call.
+const SubmitScript = `
+local member, score, tie, mult = ARGV[1], tonumber(ARGV[2]), tonumber(ARGV[3]), tonumber(ARGV[4])
+local max_score, mode, asc, expire_at = tonumber(ARGV[5]), ARGV[6], ARGV[7] == '1', tonumber(ARGV[8])
+local cur = tonumber(redis.call('ZSCORE', KEYS[1], member))
+if cur ~= nil then
+	local old = math.floor(cur / mult)
+	if mode == 'incr' then
+		score = old + score
+	elseif mode == 'best' and ((asc and score >= old) or (not asc and score <= old)) then
+		return old
+	end
+end
+if math.abs(score) > max_score then
+	return redis.error_reply('ERR_SCORE_OUT_OF_RANGE')
+end
+redis.call('ZADD', KEYS[1], string.format('%.0f', score * mult + tie), member)
+if expire_at > 0 then
+	redis.call('PEXPIREAT', KEYS[1], string.format('%.0f', expire_at))
+end
+return score
+`
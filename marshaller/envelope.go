@@ -0,0 +1,86 @@
+package gmarshaller
+
+import (
+	"sync/atomic"
+)
+
+// newMarshaller return the marshaller of the type and its format byte
+func newMarshaller(typ string) (Marshaller, byte, error) {
+	switch typ {
+	case MarshallerTypeJSON:
+		return &JsonMarshaller{}, MarshallerTypeJSONNum, nil
+	case MarshallerTypeProtoBuf:
+		return &ProtoMarshaller{}, MarshallerTypeProtoBufNum, nil
+	case MarshallerTypeProtoBufComp:
+		return &ProtoCompressMarshaller{}, MarshallerTypeProtoBufCompNum, nil
+	default:
+		return nil, 0, ErrUnknownMarshallerType
+	}
+}
+
+// EnvelopeMarshaller write the data with a leading format byte, the MarshallerType*Num of the marshaller, and
+// read the data of every format by its format byte. So the format can be switched without a flag day, the old
+// values are decoded by their formats and rewritten in the new one on the next write.
+// The data without a format byte was written before the envelope was used, it is read by the legacy marshaller
+// and counted by LegacyReads. json never starts with a format byte, neither does protobuf since field 0 is invalid.
+// The members of sets and sorted sets are compared by their data, a legacy member is not matched by the new one.
+type EnvelopeMarshaller struct {
+	format      byte
+	marshallers map[byte]Marshaller
+	legacy      Marshaller
+	legacyReads atomic.Uint64
+}
+
+// NewEnvelopeMarshaller create an EnvelopeMarshaller writing in the format of typ, the data without a format
+// byte is read by the marshaller of legacy, an empty legacy means such data is rejected
+func NewEnvelopeMarshaller(typ string, legacy string) (*EnvelopeMarshaller, error) {
+	em := &EnvelopeMarshaller{marshallers: make(map[byte]Marshaller, 3)}
+	for _, t := range []string{MarshallerTypeJSON, MarshallerTypeProtoBuf, MarshallerTypeProtoBufComp} {
+		m, format, _ := newMarshaller(t)
+		em.marshallers[format] = m
+		if t == typ {
+			em.format = format
+		}
+	}
+	if em.format == 0 {
+		return nil, ErrUnknownMarshallerType
+	}
+	if legacy != "" {
+		m, _, err := newMarshaller(legacy)
+		if err != nil {
+			return nil, err
+		}
+		em.legacy = m
+	}
+	return em, nil
+}
+
+func (em *EnvelopeMarshaller) Marshal(v interface{}) ([]byte, error) {
+	data, err := em.marshallers[em.format].Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	newData := make([]byte, len(data)+1)
+	newData[0] = em.format
+	copy(newData[1:], data)
+	return newData, nil
+}
+
+func (em *EnvelopeMarshaller) Unmarshal(data []byte, v interface{}) error {
+	if len(data) > 0 {
+		if m, ok := em.marshallers[data[0]]; ok {
+			return m.Unmarshal(data[1:], v)
+		}
+	}
+	if em.legacy == nil {
+		return ErrUnmarshalFailed
+	}
+	em.legacyReads.Add(1)
+	return em.legacy.Unmarshal(data, v)
+}
+
+// LegacyReads return the count of the data read without a format byte, the migration is done when it stops
+// growing after all the keys are read
+func (em *EnvelopeMarshaller) LegacyReads() uint64 {
+	return em.legacyReads.Load()
+}
@@ -9,4 +9,5 @@ var (
 	ErrDecompressFailed             = errors.New("ERR_DECOMPRESS_FAILED")
 	ErrMarshalFailed                = errors.New("ERR_DB_MARSHAL_FAILED")
 	ErrUnmarshalFailed              = errors.New("ERR_DB_UNMARSHAL_FAILED")
+	ErrUnknownMarshallerType        = errors.New("ERR_UNKNOWN_MARSHALLER_TYPE")
 )
@@ -0,0 +1,116 @@
+package gratelimit
+
+// The algorithms work in microseconds, the lua scripts in scripts.go do the same steps with the state in redis
+
+import (
+	"math"
+)
+
+// decision of taking events from a state
+type decision struct {
+	allowed    bool
+	remaining  int64
+	retryAfter int64 // microseconds, -1 means never
+	ttl        int64 // microseconds the state is needed
+}
+
+func (d decision) result() *Result {
+	r := &Result{Allowed: d.allowed, Remaining: d.remaining, RetryAfter: -1}
+	if d.retryAfter >= 0 {
+		r.RetryAfter = microseconds(d.retryAfter)
+	}
+	return r
+}
+
+// window is the state of SlidingWindow, the counts of the current and the previous fixed windows.
+// The count of the sliding window is the previous count weighted by its part still in the sliding window
+// plus the current count.
+type window struct {
+	start, curr, prev int64
+}
+
+func (w *window) take(now int64, l Limit, n int64) decision {
+	p := l.Period.Microseconds()
+	start := now - now%p
+	switch w.start {
+	case start:
+	case start - p:
+		w.prev, w.curr = w.curr, 0
+	default:
+		w.prev, w.curr = 0, 0
+	}
+	w.start = start
+	count := float64(w.prev)*float64(p-(now-start))/float64(p) + float64(w.curr)
+	d := decision{ttl: start + 2*p - now}
+	if count+float64(n) <= float64(l.Rate) {
+		w.curr += n
+		d.allowed = true
+		d.remaining = int64(math.Floor(float64(l.Rate) - count - float64(n)))
+		return d
+	}
+	d.remaining = max(0, int64(math.Floor(float64(l.Rate)-count)))
+	switch {
+	case n > l.Rate:
+		d.retryAfter = -1
+	case w.curr+n > l.Rate:
+		// the current count is weighted down in the next window until it leaves room for n
+		d.retryAfter = start + p + int64(math.Ceil(float64(p)*(1-float64(l.Rate-n)/float64(w.curr)))) - now
+	default:
+		d.retryAfter = start + int64(math.Ceil(float64(p)*(1-float64(l.Rate-w.curr-n)/float64(w.prev)))) - now
+	}
+	return d
+}
+
+// bucket is the state of TokenBucket, the tokens left at ts, a ts of 0 means a new full bucket
+type bucket struct {
+	tokens float64
+	ts     int64
+}
+
+func (b *bucket) take(now int64, l Limit, n int64) decision {
+	perUS := float64(l.Rate) / float64(l.Period.Microseconds())
+	burst := float64(l.Burst)
+	if b.ts == 0 {
+		b.tokens = burst
+	} else {
+		b.tokens = math.Min(burst, b.tokens+float64(max(0, now-b.ts))*perUS)
+	}
+	b.ts = now
+	d := decision{ttl: int64(math.Ceil(burst / perUS))}
+	switch {
+	case float64(n) <= b.tokens:
+		b.tokens -= float64(n)
+		d.allowed = true
+	case n > l.Burst:
+		d.retryAfter = -1
+	default:
+		d.retryAfter = int64(math.Ceil((float64(n) - b.tokens) / perUS))
+	}
+	d.remaining = int64(math.Floor(b.tokens))
+	return d
+}
+
+// gcra is the state of GCRA, the theoretical arrival time of the next event. An event is allowed if it does not
+// arrive earlier than the tat by more than the burst of intervals, a tat of 0 means a new key.
+type gcra struct {
+	tat float64
+}
+
+func (g *gcra) take(now int64, l Limit, n int64) decision {
+	interval := float64(l.Period.Microseconds()) / float64(l.Rate)
+	tolerance := interval * float64(l.Burst)
+	tat := math.Max(g.tat, float64(now))
+	newTat := tat + float64(n)*interval
+	diff := float64(now) - (newTat - tolerance)
+	remaining := int64(math.Floor(diff / interval))
+	if n > l.Burst || remaining < 0 {
+		d := decision{retryAfter: -1, ttl: int64(math.Ceil(tat - float64(now)))}
+		if n <= l.Burst {
+			d.retryAfter = int64(math.Ceil(-diff))
+		}
+		d.remaining = max(0, int64(math.Floor((float64(now)-(tat-tolerance))/interval)))
+		return d
+	}
+	g.tat = newTat
+	return decision{allowed: true, remaining: remaining, ttl: int64(math.Ceil(newTat - float64(now)))}
+}
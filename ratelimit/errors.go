@@ -0,0 +1,16 @@
+package gratelimit
+
+import (
+	"errors"
+)
+
+var (
+	// ErrInvalidOptions is returned when the options of NewLimiter are nil
+	ErrInvalidOptions = errors.New("ERR_INVALID_OPTIONS")
+	// ErrInvalidLimit is returned when a limit of the options is invalid
+	ErrInvalidLimit = errors.New("ERR_INVALID_LIMIT")
+)
+
+var (
+	PanicEventsNotPositive = "rate limit events need be positive"
+)
@@ -0,0 +1,64 @@
+package gratelimit
+
+import (
+	"sync"
+)
+
+const localSweepCalls = 1024
+
+// taker is the state of a key of an algorithm
+type taker interface {
+	take(now int64, l Limit, n int64) decision
+}
+
+type localEntry struct {
+	state    taker
+	expireAt int64
+}
+
+// localLimiter keep the states of the keys in memory, it is the fallback when redis fails
+type localLimiter struct {
+	mu      sync.Mutex
+	entries map[string]*localEntry
+	calls   int
+}
+
+func newLocalLimiter() *localLimiter {
+	return &localLimiter{entries: make(map[string]*localEntry)}
+}
+
+func (ll *localLimiter) allow(key string, l Limit, n int64, now int64) *Result {
+	ll.mu.Lock()
+	defer ll.mu.Unlock()
+	if ll.calls++; ll.calls%localSweepCalls == 0 {
+		ll.sweep(now)
+	}
+	e, ok := ll.entries[key]
+	if !ok || e.expireAt <= now {
+		e = &localEntry{state: newState(l.Algorithm)}
+		ll.entries[key] = e
+	}
+	d := e.state.take(now, l, n)
+	e.expireAt = max(e.expireAt, now+d.ttl)
+	return d.result()
+}
+
+// sweep delete the expired states
+func (ll *localLimiter) sweep(now int64) {
+	for key, e := range ll.entries {
+		if e.expireAt <= now {
+			delete(ll.entries, key)
+		}
+	}
+}
+
+func newState(algorithm Algorithm) taker {
+	switch algorithm {
+	case TokenBucket:
+		return &bucket{}
+	case GCRA:
+		return &gcra{}
+	default:
+		return &window{}
+	}
+}
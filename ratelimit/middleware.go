@@ -0,0 +1,64 @@
+package gratelimit
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/oldjon/gutil/ip"
+)
+
+// KeyFunc return the limiter key of the request, the request of an empty key is not limited
+type KeyFunc func(r *http.Request) string
+
+// ByClientIP limit the requests by the client ip, the keys are "ip:" followed by the ip
+func ByClientIP(options ip.GetHTTPClientIPOptions) KeyFunc {
+	return func(r *http.Request) string {
+		if clientIP := ip.GetHTTPClientIP(r, options); clientIP != "" {
+			return "ip:" + clientIP
+		}
+		return ""
+	}
+}
+
+// ByUser limit the requests by the user returned by user, for example the subject of the verified token put
+// into the request context by the auth middleware, the keys are "user:" followed by the user
+func ByUser(user func(r *http.Request) string) KeyFunc {
+	return func(r *http.Request) string {
+		if u := user(r); u != "" {
+			return "user:" + u
+		}
+		return ""
+	}
+}
+
+// Middleware limit the requests to the handler by the limiter, a request takes an event of its key.
+// The denied requests get 429 with the Retry-After header. If the limiter fails the requests are let through.
+//
+//	l, err := gratelimit.NewLimiter(ctx, client, &gratelimit.Options{Default: gratelimit.PerSecond(gratelimit.GCRA, 10)})
+//	mux.Handle("/api/", gratelimit.Middleware(l, gratelimit.ByClientIP(ip.GetHTTPClientIPOptions{}))(api))
+func Middleware(l Limiter, keyFunc KeyFunc) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := keyFunc(r)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ret, err := l.Allow(r.Context(), key, 1)
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Set("X-RateLimit-Remaining", strconv.FormatInt(ret.Remaining, 10))
+			if !ret.Allowed {
+				if ret.RetryAfter >= 0 {
+					w.Header().Set("Retry-After", strconv.FormatInt(int64(math.Ceil(ret.RetryAfter.Seconds())), 10))
+				}
+				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
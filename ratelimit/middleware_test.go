@@ -0,0 +1,33 @@
+package gratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/oldjon/gutil/ip"
+)
+
+func TestMiddleware(t *testing.T) {
+	l, _, _ := newTestLimiter(t, &Options{Default: PerMinute(SlidingWindow, 1)})
+	handler := Middleware(l, ByClientIP(ip.GetHTTPClientIPOptions{}))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }))
+
+	serve := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	if rec := serve("10.0.0.1:1000"); rec.Code != http.StatusNoContent {
+		t.Fatalf("first = %d", rec.Code)
+	}
+	rec := serve("10.0.0.1:1001")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "100" {
+		t.Fatalf("second = %d, Retry-After %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if rec = serve("10.0.0.2:1000"); rec.Code != http.StatusNoContent {
+		t.Fatalf("other ip = %d", rec.Code)
+	}
+}
@@ -0,0 +1,181 @@
+package gratelimit
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/oldjon/gutil/gdb"
+	"go.uber.org/zap"
+)
+
+const defaultKeyPrefix = "rl:"
+
+// Algorithm of a Limit
+type Algorithm uint8
+
+const (
+	// SlidingWindow count the events of the last Period, approximated by two fixed windows
+	SlidingWindow Algorithm = iota
+	// TokenBucket refill Rate tokens every Period into a bucket of Burst tokens, an event takes a token
+	TokenBucket
+	// GCRA space the events by Period/Rate and tolerate a burst of Burst events, it keeps a single value per key
+	GCRA
+)
+
+var scriptNames = map[Algorithm]string{
+	SlidingWindow: "sliding_window",
+	TokenBucket:   "token_bucket",
+	GCRA:          "gcra",
+}
+
+// Limit allow Rate events every Period
+type Limit struct {
+	Algorithm Algorithm
+	Rate      int64
+	Period    time.Duration
+	Burst     int64 // the max events at once of TokenBucket and GCRA, 0 means Rate
+}
+
+// PerSecond return a Limit of rate events every second
+func PerSecond(algorithm Algorithm, rate int64) Limit {
+	return Limit{Algorithm: algorithm, Rate: rate, Period: time.Second}
+}
+
+// PerMinute return a Limit of rate events every minute
+func PerMinute(algorithm Algorithm, rate int64) Limit {
+	return Limit{Algorithm: algorithm, Rate: rate, Period: time.Minute}
+}
+
+func (l *Limit) init() error {
+	if _, ok := scriptNames[l.Algorithm]; !ok {
+		return fmt.Errorf("%w: unknown algorithm %d", ErrInvalidLimit, l.Algorithm)
+	}
+	if l.Rate <= 0 || l.Period < time.Millisecond || l.Burst < 0 {
+		return fmt.Errorf("%w: rate %d, period %v, burst %d", ErrInvalidLimit, l.Rate, l.Period, l.Burst)
+	}
+	if l.Burst == 0 {
+		l.Burst = l.Rate
+	}
+	return nil
+}
+
+// Result of Limiter.Allow
+type Result struct {
+	Allowed   bool
+	Remaining int64 // the events still allowed now
+	// RetryAfter is the time after which the denied events would be allowed, -1 if they never would since
+	// they are more than the limit allows at once
+	RetryAfter time.Duration
+	Local      bool // decided by the local fallback since redis failed
+}
+
+type Limiter interface {
+	// Allow take n events of the key if the limit of the key allows them, the denied events are not taken
+	Allow(ctx context.Context, key string, n int64) (*Result, error)
+}
+
+// Options of NewLimiter
+type Options struct {
+	KeyPrefix string // the prefix of the redis keys, default "rl:"
+	Default   Limit  // the limit of the keys matching none of Limits
+	// Limits by key prefix, the longest matching prefix wins, for example "ip:" and "user:vip:"
+	Limits map[string]Limit
+	// LocalFallback decide by an in-process limiter of the same limits when redis fails, so every process
+	// allows the full limit until redis is back
+	LocalFallback bool
+	Logger        *zap.Logger
+}
+
+type limiter struct {
+	scripts  *gdb.ScriptRegistry
+	opt      *Options
+	prefixes []string // the prefixes of Limits, the longest first
+	logger   *zap.Logger
+	local    *localLimiter
+	now      func() time.Time
+}
+
+// NewLimiter create a Limiter running the lua scripts on the client, the scripts are loaded onto every master
+func NewLimiter(ctx context.Context, client gdb.RedisClient, options *Options) (Limiter, error) {
+	if options == nil {
+		return nil, fmt.Errorf("%w: nil options", ErrInvalidOptions)
+	}
+	// the defaults are filled into a copy, options may be shared by other limiters
+	opt := *options
+	opt.Limits = make(map[string]Limit, len(options.Limits))
+	l := &limiter{
+		scripts: gdb.NewScriptRegistry(client),
+		opt:     &opt,
+		logger:  opt.Logger,
+		local:   newLocalLimiter(),
+		now:     time.Now,
+	}
+	if opt.KeyPrefix == "" {
+		opt.KeyPrefix = defaultKeyPrefix
+	}
+	if l.logger == nil {
+		l.logger = zap.NewNop()
+	}
+	if err := opt.Default.init(); err != nil {
+		return nil, err
+	}
+	for prefix, limit := range options.Limits {
+		if err := limit.init(); err != nil {
+			return nil, fmt.Errorf("%w, prefix %s", err, prefix)
+		}
+		opt.Limits[prefix] = limit
+		l.prefixes = append(l.prefixes, prefix)
+	}
+	sort.Slice(l.prefixes, func(i, j int) bool { return len(l.prefixes[i]) > len(l.prefixes[j]) })
+
+	l.scripts.Register(scriptNames[SlidingWindow], SlidingWindowScript)
+	l.scripts.Register(scriptNames[TokenBucket], TokenBucketScript)
+	l.scripts.Register(scriptNames[GCRA], GCRAScript)
+	if err := l.scripts.Load(ctx); err != nil {
+		if !opt.LocalFallback {
+			return nil, err
+		}
+		// loaded again by the first Allow after redis is back
+		l.logger.Warn("RateLimiter load scripts failed", zap.Error(err))
+	}
+	return l, nil
+}
+
+// limitOf return the limit of the longest prefix matching the key
+func (l *limiter) limitOf(key string) Limit {
+	for _, prefix := range l.prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return l.opt.Limits[prefix]
+		}
+	}
+	return l.opt.Default
+}
+
+func (l *limiter) Allow(ctx context.Context, key string, n int64) (*Result, error) {
+	if n <= 0 {
+		panic(PanicEventsNotPositive)
+	}
+	limit := l.limitOf(key)
+	vals, err := gdb.RunScript[[]int64](ctx, l.scripts, scriptNames[limit.Algorithm], []string{l.opt.KeyPrefix + key},
+		limit.Period.Microseconds(), limit.Rate, limit.Burst, n)
+	if err == nil && len(vals) != 3 {
+		err = gdb.ErrScriptResultType
+	}
+	if err != nil {
+		if !l.opt.LocalFallback {
+			return nil, err
+		}
+		l.logger.Warn("RateLimiter fall back to local", zap.String("key", key), zap.Error(err))
+		r := l.local.allow(key, limit, n, l.now().UnixMicro())
+		r.Local = true
+		return r, nil
+	}
+	return decision{allowed: vals[0] == 1, remaining: vals[1], retryAfter: vals[2]}.result(), nil
+}
+
+func microseconds(us int64) time.Duration {
+	return time.Duration(us) * time.Microsecond
+}
@@ -0,0 +1,113 @@
+package gratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/oldjon/gutil/gdb"
+	"github.com/oldjon/gutil/gdb/memredis"
+	gmemtest "github.com/oldjon/gutil/gdb/memtest"
+)
+
+func newTestLimiter(t *testing.T, opt *Options) (Limiter, *memredis.Server, *gmemtest.Clock) {
+	client, server, clock := gmemtest.NewClient(t, time.Unix(1700000000, 0))
+	l, err := NewLimiter(context.Background(), client, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.(*limiter).now = clock.Now
+	return l, server, clock
+}
+
+type allowCase struct {
+	advance time.Duration
+	key     string
+	n       int64
+	want    Result
+}
+
+func checkAllows(t *testing.T, l Limiter, clock *gmemtest.Clock, cases []allowCase) {
+	t.Helper()
+	for i, c := range cases {
+		clock.Add(c.advance)
+		ret, err := l.Allow(context.Background(), c.key, c.n)
+		if err != nil {
+			t.Fatalf("case %d: %v", i, err)
+		}
+		if *ret != c.want {
+			t.Fatalf("case %d: Allow = %+v, want %+v", i, *ret, c.want)
+		}
+	}
+}
+
+func TestLimiterAlgorithms(t *testing.T) {
+	l, _, clock := newTestLimiter(t, &Options{
+		Default: PerSecond(SlidingWindow, 3),
+		Limits: map[string]Limit{
+			"tb:": {Algorithm: TokenBucket, Rate: 10, Period: time.Second, Burst: 5},
+			"gc:": {Algorithm: GCRA, Rate: 10, Period: time.Second, Burst: 2},
+		},
+	})
+	checkAllows(t, l, clock, []allowCase{
+		// sliding window
+		{key: "sw", n: 1, want: Result{Allowed: true, Remaining: 2}},
+		{key: "sw", n: 2, want: Result{Allowed: true, Remaining: 0}},
+		{key: "sw", n: 1, want: Result{RetryAfter: time.Second + 333334*time.Microsecond}},
+		{key: "sw", n: 4, want: Result{RetryAfter: -1}},
+		{advance: 1400 * time.Millisecond, key: "sw", n: 1, want: Result{Allowed: true, Remaining: 0}},
+		// token bucket
+		{key: "tb:1", n: 5, want: Result{Allowed: true, Remaining: 0}},
+		{key: "tb:1", n: 1, want: Result{RetryAfter: 100 * time.Millisecond}},
+		{key: "tb:1", n: 6, want: Result{RetryAfter: -1}},
+		{advance: 100 * time.Millisecond, key: "tb:1", n: 1, want: Result{Allowed: true, Remaining: 0}},
+		// gcra
+		{key: "gc:1", n: 1, want: Result{Allowed: true, Remaining: 1}},
+		{key: "gc:1", n: 1, want: Result{Allowed: true, Remaining: 0}},
+		{key: "gc:1", n: 1, want: Result{RetryAfter: 100 * time.Millisecond}},
+		{advance: 100 * time.Millisecond, key: "gc:1", n: 1, want: Result{Allowed: true, Remaining: 0}},
+	})
+}
+
+func TestLimiterLocalFallback(t *testing.T) {
+	l, server, clock := newTestLimiter(t, &Options{Default: PerSecond(GCRA, 1), LocalFallback: true})
+	_ = server.Close()
+	checkAllows(t, l, clock, []allowCase{
+		{key: "a", n: 1, want: Result{Allowed: true, Remaining: 0, Local: true}},
+		{key: "a", n: 1, want: Result{RetryAfter: time.Second, Local: true}},
+		{key: "b", n: 1, want: Result{Allowed: true, Remaining: 0, Local: true}},
+		{advance: time.Second, key: "a", n: 1, want: Result{Allowed: true, Remaining: 0, Local: true}},
+	})
+}
+
+func TestNewLimiterInvalid(t *testing.T) {
+	client, err := gdb.NewMemRedisClient(memredis.NewServer(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	for _, opt := range []*Options{
+		{},
+		{Default: PerSecond(Algorithm(9), 1)},
+		{Default: PerSecond(GCRA, 1), Limits: map[string]Limit{"a:": {Rate: 1, Period: time.Microsecond}}},
+	} {
+		if _, err = NewLimiter(context.Background(), client, opt); !errors.Is(err, ErrInvalidLimit) {
+			t.Fatalf("NewLimiter(%+v) = %v", opt, err)
+		}
+	}
+	if _, err = NewLimiter(context.Background(), client, nil); !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("NewLimiter(nil) = %v", err)
+	}
+}
+
+func TestNewLimiterSharedOptions(t *testing.T) {
+	opt := &Options{Default: PerSecond(GCRA, 2), Limits: map[string]Limit{"ip:": PerSecond(TokenBucket, 1)}}
+	for i := 0; i < 2; i++ {
+		newTestLimiter(t, opt)
+	}
+	// the defaults are not written back into the shared options
+	if opt.KeyPrefix != "" || opt.Default.Burst != 0 || opt.Limits["ip:"].Burst != 0 {
+		t.Fatalf("options = %+v", opt)
+	}
+}
@@ -0,0 +1,85 @@
+package gratelimit
+
+// The scripts take ARGV period (microseconds), rate, burst and n, and return {allowed, remaining, retry_after}
+// with retry_after in microseconds. The clock is the TIME of redis, so the clocks of the clients do not matter.
+// The numbers written back are formatted by string.format, redis would format them with only 14 digits.
+
+const scriptHeader = `
+redis.replicate_commands()
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+local period, rate, burst, n = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3]), tonumber(ARGV[4])
+`
+
+// SlidingWindowScript is the lua script of SlidingWindow, KEYS[1] is a hash of start, curr and prev
+const SlidingWindowScript = scriptHeader + `
+local s = redis.call('HMGET', KEYS[1], 'start', 'curr', 'prev')
+local start = now - now % period
+local last, curr, prev = tonumber(s[1]), tonumber(s[2]) or 0, tonumber(s[3]) or 0
+if last == start - period then
+	prev, curr = curr, 0
+elseif last ~= start then
+	prev, curr = 0, 0
+end
+local count = prev * (period - (now - start)) / period + curr
+local allowed, remaining, retry = 0, 0, 0
+if count + n <= rate then
+	curr = curr + n
+	allowed, remaining = 1, math.floor(rate - count - n)
+else
+	remaining = math.max(0, math.floor(rate - count))
+	if n > rate then
+		retry = -1
+	elseif curr + n > rate then
+		retry = start + period + math.ceil(period * (1 - (rate - n) / curr)) - now
+	else
+		retry = start + math.ceil(period * (1 - (rate - curr - n) / prev)) - now
+	end
+end
+redis.call('HSET', KEYS[1], 'start', string.format('%d', start), 'curr', curr, 'prev', prev)
+redis.call('PEXPIRE', KEYS[1], math.max(1, math.ceil((start + 2 * period - now) / 1000)))
+return {allowed, remaining, retry}
+`
+
+// TokenBucketScript is the lua script of TokenBucket, KEYS[1] is a hash of tokens and ts
+const TokenBucketScript = scriptHeader + `
+local s = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local per = rate / period
+local tokens, ts = tonumber(s[1]), tonumber(s[2])
+if tokens == nil then
+	tokens = burst
+else
+	tokens = math.min(burst, tokens + math.max(0, now - ts) * per)
+end
+local allowed, retry = 0, 0
+if n <= tokens then
+	tokens = tokens - n
+	allowed = 1
+elseif n > burst then
+	retry = -1
+else
+	retry = math.ceil((n - tokens) / per)
+end
+redis.call('HSET', KEYS[1], 'tokens', string.format('%.6f', tokens), 'ts', string.format('%d', now))
+redis.call('PEXPIRE', KEYS[1], math.max(1, math.ceil(burst / per / 1000)))
+return {allowed, math.floor(tokens), retry}
+`
+
+// GCRAScript is the lua script of GCRA, KEYS[1] is a string of the theoretical arrival time
+const GCRAScript = scriptHeader + `
+local interval = period / rate
+local tolerance = interval * burst
+local tat = math.max(tonumber(redis.call('GET', KEYS[1])) or now, now)
+local new_tat = tat + n * interval
+local diff = now - (new_tat - tolerance)
+local remaining = math.floor(diff / interval)
+if n > burst or remaining < 0 then
+	local retry = -1
+	if n <= burst then
+		retry = math.ceil(-diff)
+	end
+	return {0, math.max(0, math.floor((now - (tat - tolerance)) / interval)), retry}
+end
+redis.call('SET', KEYS[1], string.format('%.3f', new_tat), 'PX', math.max(1, math.ceil((new_tat - now) / 1000)))
+return {1, remaining, 0}
+`
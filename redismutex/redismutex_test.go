@@ -7,6 +7,7 @@ import (
 	"github.com/go-redis/redis/v8"
 	"github.com/oldjon/gutil/conv"
 	"github.com/oldjon/gutil/gdb"
+	"github.com/oldjon/gutil/gdb/memredis"
 	gmarshaller "github.com/oldjon/gutil/marshaller"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -142,3 +143,41 @@ func TestClient(t *testing.T) {
 	}
 	rc.SetNX(context.Background(), "casda111", 1, 0)
 }
+
+func TestRedisMuxMemRedis(t *testing.T) {
+	server := memredis.NewServer()
+	defer server.Close()
+	rc, err := gdb.NewMemRedisClient(server, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	ctx := context.Background()
+	rm, err := NewRedisMux(ctx, rc, &RedisMuxOption{RetryTimes: 1}, zap.NewNop(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = rm.Lock(ctx, "test", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err = rm.Lock(ctx, "test", 2); !errors.Is(err, errRedisLockBusy) {
+		t.Fatalf("lock a locked key = %v", err)
+	}
+	// the unlock of another value keeps the lock
+	if err = rm.Unlock(ctx, "test", 2); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := rc.Get(ctx, redisMuxPrefix+"test"); v != "1" {
+		t.Fatalf("lock value = %s", v)
+	}
+	if err = rm.Unlock(ctx, "test", 1); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := rc.Exists(ctx, redisMuxPrefix+"test"); ok {
+		t.Fatal("lock is not released")
+	}
+	if err = rm.Safely(ctx, "test", func() error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,145 @@
+// Command gsnapshot export the redis keys into a snapshot file and import them back.
+//
+//	gsnapshot export -addr 127.0.0.1:6379 -prefix player:,guild: -file players.snap
+//	gsnapshot import -addr 10.0.0.1:7000,10.0.0.2:7000 -cluster -rewrite player:=p:player: -file players.snap
+//
+// The password is read from REDIS_PASSWORD if -password is not set. The values are re-marshalled only by the
+// library, as the object types are needed, see gsnapshot.ImportOption.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/oldjon/gutil/gdb"
+	gsnapshot "github.com/oldjon/gutil/snapshot"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: gsnapshot export|import [flags], -h for the flags\n")
+	os.Exit(2)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	cmd := os.Args[1]
+	fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+	addr := fs.String("addr", "127.0.0.1:6379", "the redis address, comma separated in cluster mode")
+	cluster := fs.Bool("cluster", false, "connect to a redis cluster")
+	username := fs.String("username", "", "the ACL user")
+	password := fs.String("password", os.Getenv("REDIS_PASSWORD"), "the password, default $REDIS_PASSWORD")
+	db := fs.Int("db", 0, "the database in single mode")
+	namespace := fs.String("namespace", "", "the namespace of the keys")
+	file := fs.String("file", "-", "the snapshot file, - for stdout or stdin")
+	var prefixes []string
+	var rewrites [][2]string
+	switch cmd {
+	case "export":
+		fs.Func("prefix", "the comma separated prefixes of the exported keys, default all the keys", func(s string) error {
+			prefixes = append(prefixes, strings.Split(s, ",")...)
+			return nil
+		})
+	case "import":
+		fs.Func("rewrite", "replace the key prefix from with to, as from=to, repeatable", func(s string) error {
+			from, to, ok := strings.Cut(s, "=")
+			if !ok || from == "" {
+				return fmt.Errorf("rewrite %q is not from=to", s)
+			}
+			rewrites = append(rewrites, [2]string{from, to})
+			return nil
+		})
+	default:
+		usage()
+	}
+	_ = fs.Parse(os.Args[2:])
+
+	option := &gdb.RedisClientOption{Mode: gdb.Single, Addr: *addr, Db: *db, Username: *username,
+		Password: *password, Namespace: *namespace}
+	if *cluster {
+		option.Mode, option.Addr, option.ClusterAddrs = gdb.Cluster, "", strings.Split(*addr, ",")
+	}
+	client, err := gdb.NewRedisClient(option)
+	if err != nil {
+		fatal(err)
+	}
+	defer client.Close()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var stats *gsnapshot.Stats
+	if cmd == "export" {
+		stats, err = export(ctx, client, *file, prefixes)
+	} else {
+		stats, err = restore(ctx, client, *file, rewrites)
+	}
+	if stats != nil {
+		fmt.Fprintf(os.Stderr, "%s: %d keys, %d skipped\n", cmd, stats.Keys, stats.Skipped)
+	}
+	if err != nil {
+		fatal(err)
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "gsnapshot: %v\n", err)
+	os.Exit(1)
+}
+
+func export(ctx context.Context, client gdb.RedisClient, file string, prefixes []string) (*gsnapshot.Stats, error) {
+	var out io.WriteCloser = os.Stdout
+	if file != "-" {
+		f, err := os.Create(file)
+		if err != nil {
+			return nil, err
+		}
+		out = f
+	}
+	w := gsnapshot.NewWriter(out)
+	stats, err := gsnapshot.Export(ctx, client, w, &gsnapshot.ExportOption{Prefixes: prefixes})
+	if cErr := w.Close(); err == nil {
+		err = cErr
+	}
+	if cErr := out.Close(); err == nil {
+		err = cErr
+	}
+	return stats, err
+}
+
+func restore(ctx context.Context, client gdb.RedisClient, file string, rewrites [][2]string) (*gsnapshot.Stats,
+	error) {
+	var in io.ReadCloser = os.Stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		in = f
+	}
+	defer in.Close()
+	r := gsnapshot.NewReader(in)
+	defer r.Close()
+	opt := &gsnapshot.ImportOption{}
+	if len(rewrites) > 0 {
+		fns := make([]func(key string) string, len(rewrites))
+		for i, rw := range rewrites {
+			fns[i] = gsnapshot.RewritePrefix(rw[0], rw[1])
+		}
+		// the first matched prefix is rewritten
+		opt.Rewrite = func(key string) string {
+			for i, fn := range fns {
+				if strings.HasPrefix(key, rewrites[i][0]) {
+					return fn(key)
+				}
+			}
+			return key
+		}
+	}
+	return gsnapshot.Import(ctx, client, r, opt)
+}
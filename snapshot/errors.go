@@ -0,0 +1,12 @@
+package gsnapshot
+
+import (
+	"errors"
+)
+
+var (
+	// ErrInvalidOptions is returned when the options of Import are invalid
+	ErrInvalidOptions = errors.New("ERR_INVALID_OPTIONS")
+	// ErrUnsupportedRecord is returned when a record of the snapshot is not of a supported type
+	ErrUnsupportedRecord = errors.New("ERR_UNSUPPORTED_RECORD")
+)
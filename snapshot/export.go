@@ -0,0 +1,172 @@
+package gsnapshot
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/oldjon/gutil/gdb"
+)
+
+const defaultScanCount = 100
+
+// ExportOption of Export
+type ExportOption struct {
+	// Prefixes of the exported keys, empty means all the keys. Prefixes take them from a KOMapping.
+	Prefixes []string
+	Count    int64 // the COUNT hint of SCAN, default 100
+}
+
+// Stats count the keys of Export and Import
+type Stats struct {
+	Keys int64 // the keys written
+	// Skipped is the keys of the other types, the ones expired while exporting, or the ones rewritten to ""
+	Skipped int64
+}
+
+// Prefixes return the key prefixes of the mapping. The prefixes of a *gdb.KeySchemaRegistry are used as they
+// are, the legacy ones are the part before ':' followed by ':', the same as SetKOMapping matches the keys.
+func Prefixes(mapping gdb.KOMapping) []string {
+	_, registry := mapping.(*gdb.KeySchemaRegistry)
+	var prefixes []string
+	for prefix := range mapping.Mapping() {
+		if prefix == "" {
+			continue
+		}
+		if !registry {
+			prefix = strings.Split(prefix, ":")[0] + ":"
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes
+}
+
+// scanPatterns return the SCAN patterns of the prefixes, the prefixes covered by the shorter ones are dropped
+// so no key is exported twice by them
+func scanPatterns(prefixes []string) []string {
+	if len(prefixes) == 0 {
+		return []string{"*"}
+	}
+	sorted := append([]string(nil), prefixes...)
+	sort.Strings(sorted)
+	var patterns []string
+	last := ""
+	for _, prefix := range sorted {
+		if len(patterns) > 0 && strings.HasPrefix(prefix, last) {
+			continue
+		}
+		patterns = append(patterns, escapeGlob(prefix)+"*")
+		last = prefix
+	}
+	return patterns
+}
+
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`*?[]\`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Export scan the keys with the prefixes and write the strings, hashes and zsets with their ttls into w, the
+// keys of the other types are skipped. SCAN may return a key more than once, so may the snapshot, which is
+// harmless to Import. w is not closed.
+func Export(ctx context.Context, client gdb.RedisClient, w *Writer, opt *ExportOption) (*Stats, error) {
+	if opt == nil {
+		opt = &ExportOption{}
+	}
+	count := opt.Count
+	if count <= 0 {
+		count = defaultScanCount
+	}
+	stats := &Stats{}
+	for _, pattern := range scanPatterns(opt.Prefixes) {
+		it := client.Scan(ctx, pattern, count)
+		for it.Next(ctx) {
+			record, err := readRecord(ctx, client, it.Key())
+			if err != nil {
+				return stats, err
+			}
+			if record == nil {
+				stats.Skipped++
+				continue
+			}
+			if err = w.Write(record); err != nil {
+				return stats, err
+			}
+			stats.Keys++
+		}
+		if err := it.Err(); err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
+
+// readRecord read the key, it returns nil if the key is of another type or is missing. The type, the ttl and the
+// reads of every exported type are sent in a single MULTI, the reads of the other types fail with WRONGTYPE.
+func readRecord(ctx context.Context, client gdb.RedisClient, key string) (*Record, error) {
+	pipe := client.TxPipeline()
+	typ := pipe.Type(ctx, key)
+	ttlF := pipe.TTL(ctx, key)
+	get := pipe.Get(ctx, key)
+	hgetall := pipe.HGetAll(ctx, key)
+	zrange := pipe.ZRangeWithScores(ctx, key, 0, -1)
+	_, _ = pipe.Exec(ctx) // the errors are read from the futures of the type
+	t, err := typ.Result()
+	if err != nil {
+		return nil, err
+	}
+	record := &Record{Key: key, Type: t}
+	switch t {
+	case TypeString:
+		v, err := get.Result()
+		if client.IsErrNil(err) {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		record.Value = []byte(v)
+	case TypeHash:
+		fields, err := hgetall.Result()
+		if err != nil || len(fields) == 0 {
+			return nil, err
+		}
+		for name, value := range fields {
+			record.Fields = append(record.Fields, Field{Name: []byte(name), Value: []byte(value)})
+		}
+		sort.Slice(record.Fields, func(i, j int) bool {
+			return string(record.Fields[i].Name) < string(record.Fields[j].Name)
+		})
+	case TypeZSet:
+		members, err := zrange.Result()
+		if err != nil || len(members) == 0 {
+			return nil, err
+		}
+		for _, z := range members {
+			member, _ := z.Member.(string)
+			record.Members = append(record.Members, Member{Member: []byte(member), Score: formatScore(z.Score)})
+		}
+	default:
+		return nil, nil
+	}
+
+	ttl, err := ttlF.Result()
+	switch {
+	case errors.Is(err, gdb.ErrTTLKeyNotExpireSet):
+	case errors.Is(err, gdb.ErrTTLKeyNotExist):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	case ttl <= 0: // expiring in a second
+		return nil, nil
+	default:
+		record.TTL = ttl
+	}
+	return record, nil
+}
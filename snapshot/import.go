@@ -0,0 +1,149 @@
+package gsnapshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/oldjon/gutil/gdb"
+	gmarshaller "github.com/oldjon/gutil/marshaller"
+)
+
+// ImportOption of Import
+type ImportOption struct {
+	// Rewrite return the new key of the key of the snapshot, the record is skipped if it returns "".
+	// nil keeps the keys.
+	Rewrite func(key string) string
+	// Schemas give the object types of the keys of the snapshot by their prefixes. The values of the keys whose
+	// schema has a Type are unmarshalled by From and marshalled by To: the values of the strings, the field
+	// values of the hashes and the members of the zsets. The other keys are imported as they are.
+	Schemas  *gdb.KeySchemaRegistry
+	From, To gmarshaller.Marshaller
+}
+
+// RewritePrefix return a Rewrite replacing the prefix from of the keys with to, the other keys are kept
+func RewritePrefix(from, to string) func(key string) string {
+	return func(key string) string {
+		if strings.HasPrefix(key, from) {
+			return to + key[len(from):]
+		}
+		return key
+	}
+}
+
+// Import restore the records of r, the keys are overwritten and expire after the ttls of the records.
+// r is not closed.
+func Import(ctx context.Context, client gdb.RedisClient, r *Reader, opt *ImportOption) (*Stats, error) {
+	if opt == nil {
+		opt = &ImportOption{}
+	}
+	if opt.Schemas != nil && (opt.From == nil || opt.To == nil) {
+		return nil, fmt.Errorf("%w: the marshallers from and to are required to re-marshal", ErrInvalidOptions)
+	}
+	stats := &Stats{}
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return stats, err
+		}
+		key := record.Key
+		if opt.Rewrite != nil {
+			key = opt.Rewrite(key)
+		}
+		if key == "" {
+			stats.Skipped++
+			continue
+		}
+		if err = opt.remarshal(record); err != nil {
+			return stats, err
+		}
+		// a transaction per record, so the key is never seen deleted or half written
+		pipe := client.TxPipeline()
+		if err = queueRecord(ctx, pipe, key, record); err != nil {
+			return stats, err
+		}
+		if _, err = pipe.Exec(ctx); err != nil {
+			return stats, err
+		}
+		stats.Keys++
+	}
+	return stats, nil
+}
+
+// remarshal convert the values of the record from From to To by the type of its key
+func (opt *ImportOption) remarshal(record *Record) error {
+	if opt.Schemas == nil {
+		return nil
+	}
+	schema, ok := opt.Schemas.Lookup(record.Key)
+	if !ok || schema.Type == nil {
+		return nil
+	}
+	convert := func(data []byte) ([]byte, error) {
+		obj := reflect.New(schema.Type).Interface()
+		if err := opt.From.Unmarshal(data, obj); err != nil {
+			return nil, fmt.Errorf("unmarshal key %s: %w", record.Key, err)
+		}
+		data, err := opt.To.Marshal(obj)
+		if err != nil {
+			return nil, fmt.Errorf("marshal key %s: %w", record.Key, err)
+		}
+		return data, nil
+	}
+	var err error
+	if record.Value != nil {
+		if record.Value, err = convert(record.Value); err != nil {
+			return err
+		}
+	}
+	for i := range record.Fields {
+		if record.Fields[i].Value, err = convert(record.Fields[i].Value); err != nil {
+			return err
+		}
+	}
+	for i := range record.Members {
+		if record.Members[i].Member, err = convert(record.Members[i].Member); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// queueRecord queue the commands replacing the key with the record
+func queueRecord(ctx context.Context, pipe gdb.Pipeliner, key string, record *Record) error {
+	switch record.Type {
+	case TypeString:
+		pipe.Del(ctx, key)
+		pipe.Set(ctx, key, record.Value)
+	case TypeHash:
+		values := make([]any, 0, 2*len(record.Fields))
+		for _, f := range record.Fields {
+			values = append(values, f.Name, f.Value)
+		}
+		pipe.Del(ctx, key)
+		pipe.HSet(ctx, key, values...)
+	case TypeZSet:
+		values := make([]any, 0, 2*len(record.Members))
+		for _, m := range record.Members {
+			score, err := strconv.ParseFloat(m.Score, 64)
+			if err != nil {
+				return fmt.Errorf("%w: key %s has the score %q", ErrUnsupportedRecord, record.Key, m.Score)
+			}
+			values = append(values, score, m.Member)
+		}
+		pipe.Del(ctx, key)
+		pipe.ZAdd(ctx, key, values...)
+	default:
+		return fmt.Errorf("%w: key %s of type %s", ErrUnsupportedRecord, record.Key, record.Type)
+	}
+	if record.TTL > 0 {
+		pipe.Expire(ctx, key, record.TTL)
+	}
+	return nil
+}
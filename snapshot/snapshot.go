@@ -0,0 +1,102 @@
+// Package gsnapshot export the redis keys into snapshot files and import them back, for the backups and the
+// migrations between clusters. A snapshot is a zstd compressed stream of JSON lines, a Record per key.
+package gsnapshot
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/DataDog/zstd"
+)
+
+// the types of the records, the same as TYPE of redis
+const (
+	TypeString = "string"
+	TypeHash   = "hash"
+	TypeZSet   = "zset"
+)
+
+// Record is a key of the snapshot, the values are []byte so the binary ones are kept as they are
+type Record struct {
+	Key  string `json:"key"`
+	Type string `json:"type"`
+	// TTL is the remaining ttl when the key is exported, in seconds precision, 0 means no expiration
+	TTL     time.Duration `json:"ttl,omitempty"`
+	Value   []byte        `json:"value,omitempty"`   // the value of a string
+	Fields  []Field       `json:"fields,omitempty"`  // the fields of a hash
+	Members []Member      `json:"members,omitempty"` // the members of a zset
+}
+
+// Field is a field of a hash
+type Field struct {
+	Name  []byte `json:"name"`
+	Value []byte `json:"value"`
+}
+
+// Member is a member of a zset
+type Member struct {
+	Member []byte `json:"member"`
+	// Score is formatted like the replies of redis, inf and -inf are the infinities which JSON numbers can not hold
+	Score string `json:"score"`
+}
+
+// formatScore format the score like redis
+func formatScore(score float64) string {
+	switch {
+	case math.IsInf(score, 1):
+		return "inf"
+	case math.IsInf(score, -1):
+		return "-inf"
+	}
+	return strconv.FormatFloat(score, 'g', -1, 64)
+}
+
+// Writer write the records into a snapshot
+type Writer struct {
+	zw  *zstd.Writer
+	enc *json.Encoder
+}
+
+// NewWriter create a Writer writing the snapshot into w
+func NewWriter(w io.Writer) *Writer {
+	zw := zstd.NewWriter(w)
+	return &Writer{zw: zw, enc: json.NewEncoder(zw)}
+}
+
+func (w *Writer) Write(r *Record) error {
+	return w.enc.Encode(r)
+}
+
+// Close flush the snapshot, the underlying writer is not closed
+func (w *Writer) Close() error {
+	return w.zw.Close()
+}
+
+// Reader read the records of a snapshot
+type Reader struct {
+	zr  io.ReadCloser
+	dec *json.Decoder
+}
+
+// NewReader create a Reader reading the snapshot from r
+func NewReader(r io.Reader) *Reader {
+	zr := zstd.NewReader(r)
+	return &Reader{zr: zr, dec: json.NewDecoder(zr)}
+}
+
+// Read return the next record, or io.EOF at the end of the snapshot
+func (r *Reader) Read() (*Record, error) {
+	var record Record
+	if err := r.dec.Decode(&record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// Close release the decompressor, the underlying reader is not closed
+func (r *Reader) Close() error {
+	return r.zr.Close()
+}
@@ -0,0 +1,207 @@
+package gsnapshot
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/oldjon/gutil/gdb"
+	"github.com/oldjon/gutil/gdb/memredis"
+	gmarshaller "github.com/oldjon/gutil/marshaller"
+)
+
+type player struct {
+	ID    int
+	Level int
+}
+
+type koMapping map[string]string
+
+func (m koMapping) Mapping() map[string]string {
+	return m
+}
+
+type gobMarshaller struct{}
+
+func (gobMarshaller) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(v)
+	return buf.Bytes(), err
+}
+
+func (gobMarshaller) Unmarshal(data []byte, v any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// roundTrips count the single commands other than SCAN and the pipelines sent by the client
+type roundTrips struct {
+	cmds, pipelines atomic.Int32
+}
+
+func (h *roundTrips) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	if cmd.Name() != "scan" {
+		h.cmds.Add(1)
+	}
+	return ctx, nil
+}
+
+func (h *roundTrips) AfterProcess(context.Context, redis.Cmder) error {
+	return nil
+}
+
+func (h *roundTrips) BeforeProcessPipeline(ctx context.Context, _ []redis.Cmder) (context.Context, error) {
+	h.pipelines.Add(1)
+	return ctx, nil
+}
+
+func (h *roundTrips) AfterProcessPipeline(context.Context, []redis.Cmder) error {
+	return nil
+}
+
+func newTestClient(t *testing.T, hooks ...redis.Hook) gdb.RedisClient {
+	server := memredis.NewServer()
+	now := time.Unix(1700000000, 0)
+	server.SetClock(func() time.Time { return now })
+	client, err := gdb.NewMemRedisClient(server, nil, hooks...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = client.Close()
+		_ = server.Close()
+	})
+	return client
+}
+
+func TestPrefixes(t *testing.T) {
+	prefixes := Prefixes(koMapping{"player": "a.Player", "guild:": "a.Guild", "": "a.X"})
+	sort.Strings(prefixes)
+	if want := []string{"guild:", "player:"}; !reflect.DeepEqual(prefixes, want) {
+		t.Fatalf("Prefixes = %v", prefixes)
+	}
+	registry, err := gdb.NewKeySchemaRegistry(gdb.KeySchema{Prefix: "pl", Type: reflect.TypeOf(player{})})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prefixes = Prefixes(registry); !reflect.DeepEqual(prefixes, []string{"pl"}) {
+		t.Fatalf("Prefixes of registry = %v", prefixes)
+	}
+	if patterns := scanPatterns([]string{"player:vip:", "guild:", "player:", "a*"}); !reflect.DeepEqual(patterns,
+		[]string{`a\**`, "guild:*", "player:*"}) {
+		t.Fatalf("scanPatterns = %v", patterns)
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	ctx := context.Background()
+	trips := &roundTrips{}
+	src, dst := newTestClient(t, trips), newTestClient(t)
+	data, _ := json.Marshal(&player{ID: 1, Level: 3})
+	if err := src.SetEX(ctx, "player:1", data, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	data2, _ := json.Marshal(&player{ID: 2, Level: 5})
+	if err := src.HSet(ctx, "player:guild", "2", data2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := src.ZAdd(ctx, "player:rank", 5, data2, 3, data); err != nil {
+		t.Fatal(err)
+	}
+	data3, _ := json.Marshal(&player{ID: 3})
+	if _, err := src.ZAdd(ctx, "player:scores", math.Inf(-1), data, 0.1, data2, math.Inf(1), data3); err != nil {
+		t.Fatal(err)
+	}
+	_ = src.Set(ctx, "player:bin", "\xff\x00")
+	_, _ = src.LPush(ctx, "player:list", "x")
+	_ = src.Set(ctx, "other:1", "x")
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	trips.cmds.Store(0)
+	stats, err := Export(ctx, src, w, &ExportOption{Prefixes: []string{"player:"}, Count: 2})
+	if err != nil || stats.Keys != 5 || stats.Skipped != 1 {
+		t.Fatalf("Export = %+v, %v", stats, err)
+	}
+	// every key is read by a single pipeline
+	if cmds, pipelines := trips.cmds.Load(), trips.pipelines.Load(); cmds != 0 || pipelines != 6 {
+		t.Fatalf("Export round trips = %d commands, %d pipelines", cmds, pipelines)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	schemas, _ := gdb.NewKeySchemaRegistry(gdb.KeySchema{Prefix: "player:", Type: reflect.TypeOf(player{})},
+		gdb.KeySchema{Prefix: "player:bin", Marshaller: gobMarshaller{}})
+	r := NewReader(&buf)
+	defer r.Close()
+	stats, err = Import(ctx, dst, r, &ImportOption{
+		Rewrite: func(key string) string {
+			if key == "player:guild" {
+				return ""
+			}
+			return RewritePrefix("player:", "p2:")(key)
+		},
+		Schemas: schemas,
+		From:    &gmarshaller.JsonMarshaller{},
+		To:      gobMarshaller{},
+	})
+	if err != nil || stats.Keys != 4 || stats.Skipped != 1 {
+		t.Fatalf("Import = %+v, %v", stats, err)
+	}
+
+	var p player
+	v, err := dst.Get(ctx, "p2:1")
+	if err != nil || (gobMarshaller{}).Unmarshal([]byte(v), &p) != nil || p.Level != 3 {
+		t.Fatalf("p2:1 = %+v, %v", p, err)
+	}
+	if ttl, err := dst.TTL(ctx, "p2:1"); err != nil || ttl != time.Minute {
+		t.Fatalf("TTL = %v, %v", ttl, err)
+	}
+	if v, err = dst.Get(ctx, "p2:bin"); err != nil || v != "\xff\x00" {
+		t.Fatalf("p2:bin = %q, %v", v, err)
+	}
+	members, err := dst.ZRangeWithScores(ctx, "p2:rank", 0, -1)
+	if err != nil || len(members) != 2 || members[1].Score != 5 {
+		t.Fatalf("p2:rank = %v, %v", members, err)
+	}
+	if (gobMarshaller{}).Unmarshal([]byte(members[1].Member.(string)), &p) != nil || p.ID != 2 {
+		t.Fatalf("p2:rank member = %+v", p)
+	}
+	// the infinite scores are kept
+	members, err = dst.ZRangeWithScores(ctx, "p2:scores", 0, -1)
+	if err != nil || len(members) != 3 || !math.IsInf(members[0].Score, -1) || members[1].Score != 0.1 ||
+		!math.IsInf(members[2].Score, 1) {
+		t.Fatalf("p2:scores = %v, %v", members, err)
+	}
+	if ok, _ := dst.Exists(ctx, "p2:guild"); ok {
+		t.Fatal("p2:guild is not skipped")
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t)
+	schemas, _ := gdb.NewKeySchemaRegistry(gdb.KeySchema{Prefix: "player:", Type: reflect.TypeOf(player{})})
+	if _, err := Import(ctx, client, nil, &ImportOption{Schemas: schemas}); !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("Import without marshallers = %v", err)
+	}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	_ = w.Write(&Record{Key: "player:1", Type: "list"})
+	_ = w.Close()
+	r := NewReader(&buf)
+	defer r.Close()
+	if _, err := Import(ctx, client, r, nil); !errors.Is(err, ErrUnsupportedRecord) {
+		t.Fatalf("Import list = %v", err)
+	}
+}
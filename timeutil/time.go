@@ -208,6 +208,20 @@ func LastRegionWeekRefreshTime(t time.Time, refreshDay time.Weekday, refreshHour
 	return time.Date(year, month, day-int(weekday-refreshDay), refreshHour, refreshMinutes, 0, 0, loc)
 }
 
+// LastRegionDayRefreshTime return the last refresh time for the event's refresh daily based on region,
+// the refresh minute itself belongs to the last day like LastRegionWeekRefreshTime
+// If t is 2018-04-02 12:59 0000 UTC which is 2018-04-02 07:59 -0500 EST would return 2018-04-01 08:00 -0500 EST
+// If t is 2018-04-02 13:01 0000 UTC which is 2018-04-02 08:01 -0500 EST would return 2018-04-02 08:00 -0500 EST
+func LastRegionDayRefreshTime(t time.Time, refreshHour int, refreshMinutes int, loc *time.Location) time.Time {
+	tt := t.In(loc)
+	year, month, day := tt.Date()
+	hour, minute, _ := tt.Clock()
+	if hour < refreshHour || (hour == refreshHour && minute <= refreshMinutes) {
+		return time.Date(year, month, day-1, refreshHour, refreshMinutes, 0, 0, loc)
+	}
+	return time.Date(year, month, day, refreshHour, refreshMinutes, 0, 0, loc)
+}
+
 // RegionMonday5HourDateTime return region Monday 05am date, format: 2006-01-02 04:00:00
 func RegionMonday4HourDateTime(loc *time.Location) string {
 	now := time.Now().In(loc)